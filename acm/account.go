@@ -16,27 +16,50 @@ package acm
 
 import (
 	"bytes"
+	"crypto/subtle"
+	bin "encoding/binary"
+	"encoding/gob"
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/hyperledger/burrow/execution/errors"
 
 	amino "github.com/tendermint/go-amino"
+	hex "github.com/tmthrgd/go-hex"
 
 	"github.com/hyperledger/burrow/binary"
 	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/crypto/sha3"
 	"github.com/hyperledger/burrow/event/query"
 	"github.com/hyperledger/burrow/permission"
 )
 
 var GlobalPermissionsAddress = crypto.Address(binary.Zero160)
 
+// NewAccount returns an otherwise empty account for pubKey, whose address is derived with AddressFromPublicKeyCached
 func NewAccount(pubKey crypto.PublicKey) *Account {
 	return &Account{
-		Address:   pubKey.GetAddress(),
+		Address:   AddressFromPublicKeyCached(pubKey),
 		PublicKey: pubKey,
 	}
 }
 
+// NewAccountChecked is like NewAccount but returns an error rather than an account with an address derived from
+// nothing if pubKey is unset or is not a valid key of its curve type
+func NewAccountChecked(pubKey crypto.PublicKey) (*Account, error) {
+	if pubKey.CurveType == crypto.CurveTypeUnset {
+		return nil, errors.ErrorCodef(errors.ErrorCodeInvalidAddress,
+			"cannot create account from a public key with no curve type")
+	}
+	if !pubKey.IsValid() {
+		return nil, errors.ErrorCodef(errors.ErrorCodeInvalidAddress,
+			"cannot create account from %v public key of %d bytes, expected %d", pubKey.CurveType,
+			len(pubKey.PublicKey), crypto.PublicKeyLength(pubKey.CurveType))
+	}
+	return NewAccount(pubKey), nil
+}
+
 func NewAccountFromSecret(secret string) *Account {
 	return NewAccount(crypto.PrivateKeyFromSecret(secret, crypto.CurveTypeEd25519).GetPublicKey())
 }
@@ -45,24 +68,212 @@ func (acc *Account) GetAddress() crypto.Address {
 	return acc.Address
 }
 
+// AddressEqual compares the account's address with other in constant time. Use it rather than == or bytes.Equal where
+// the comparison guards access (for example when authenticating an input against an account) so that the time
+// taken does not leak how many leading bytes matched. For ordinary lookups and bookkeeping plain equality is fine.
+func (acc *Account) AddressEqual(other crypto.Address) bool {
+	return subtle.ConstantTimeCompare(acc.Address[:], other[:]) == 1
+}
+
+// PublicKeyEqual compares the account's public key (curve type and key bytes) with other in constant time, see
+// AddressEqual for when this matters. Keys of different lengths compare unequal immediately since key length is
+// not secret.
+func (acc *Account) PublicKeyEqual(other crypto.PublicKey) bool {
+	curveTypeEqual := subtle.ConstantTimeByteEq(acc.PublicKey.CurveType.Byte(), other.CurveType.Byte())
+	keyEqual := subtle.ConstantTimeCompare(acc.PublicKey.PublicKey, other.PublicKey)
+	return curveTypeEqual&keyEqual == 1
+}
+
+// AddToBalance adds amount to the balance of the account, returning a BalanceError on overflow
 func (acc *Account) AddToBalance(amount uint64) error {
 	if binary.IsUint64SumOverflow(acc.Balance, amount) {
-		return errors.ErrorCodef(errors.ErrorCodeIntegerOverflow,
-			"uint64 overflow: attempt to add %v to the balance of %s", amount, acc.Address)
+		return &BalanceError{
+			Address:   acc.Address,
+			Operation: AddBalance,
+			Amount:    amount,
+			Balance:   acc.Balance,
+			Code:      errors.ErrorCodeIntegerOverflow,
+		}
 	}
 	acc.Balance += amount
 	return nil
 }
 
+// SubtractFromBalance subtracts amount from the balance of the account, returning a BalanceError if the balance is
+// insufficient
 func (acc *Account) SubtractFromBalance(amount uint64) error {
 	if amount > acc.Balance {
-		return errors.ErrorCodef(errors.ErrorCodeInsufficientBalance,
-			"insufficient funds: attempt to subtract %v from the balance of %s", amount, acc.Address)
+		return &BalanceError{
+			Address:   acc.Address,
+			Operation: SubtractBalance,
+			Amount:    amount,
+			Balance:   acc.Balance,
+			Code:      errors.ErrorCodeInsufficientBalance,
+		}
 	}
 	acc.Balance -= amount
 	return nil
 }
 
+// AddGas adds amount to the gas credit of the account, returning a BalanceError on overflow. The gas credit is a
+// budget of gas for metering schemes layered on Burrow, it is zero unless set and Burrow itself does not spend it.
+func (acc *Account) AddGas(amount uint64) error {
+	if binary.IsUint64SumOverflow(acc.GasCredit, amount) {
+		return &BalanceError{
+			Address:   acc.Address,
+			Operation: AddGas,
+			Amount:    amount,
+			Balance:   acc.GasCredit,
+			Code:      errors.ErrorCodeIntegerOverflow,
+		}
+	}
+	acc.GasCredit += amount
+	return nil
+}
+
+// SpendGas subtracts amount from the gas credit of the account, returning a BalanceError if the gas credit is
+// insufficient
+func (acc *Account) SpendGas(amount uint64) error {
+	if amount > acc.GasCredit {
+		return &BalanceError{
+			Address:   acc.Address,
+			Operation: SpendGas,
+			Amount:    amount,
+			Balance:   acc.GasCredit,
+			Code:      errors.ErrorCodeInsufficientGas,
+		}
+	}
+	acc.GasCredit -= amount
+	return nil
+}
+
+// Validate checks the internal consistency of the account, including that no two of its roles differ only in case
+func (acc *Account) Validate() error {
+	if acc.PublicKey.IsSet() && acc.PublicKey.GetAddress() != acc.Address {
+		return fmt.Errorf("account address %v does not match the address %v derived from its public key %v",
+			acc.Address, acc.PublicKey.GetAddress(), acc.PublicKey)
+	}
+	if !acc.Permissions.Base.Perms.IsValid() {
+		return fmt.Errorf("account %v has invalid base permissions: %v", acc.Address,
+			permission.ErrInvalidPermission(acc.Permissions.Base.Perms))
+	}
+	if !acc.Permissions.Base.SetBit.IsValid() {
+		return fmt.Errorf("account %v has invalid base permissions set bit: %v", acc.Address,
+			permission.ErrInvalidPermission(acc.Permissions.Base.SetBit))
+	}
+	if err := acc.Permissions.ValidateRoles(permission.RoleCaseAsIs); err != nil {
+		return fmt.Errorf("account %v has invalid roles: %v", acc.Address, err)
+	}
+	return nil
+}
+
+// IsContract returns whether the account holds EVM or WASM code
+func (acc *Account) IsContract() bool {
+	return len(acc.EVMCode) > 0 || len(acc.WASMCode) > 0
+}
+
+// IsEmpty returns whether the account holds nothing: no balance, no sequence, no code, no roles, and no base
+// permissions set (so that the global permissions apply). The address and public key are not considered, see IsZero.
+// Empty and nil code and roles are treated alike so an account from FromAddressable is empty. The EVM may delete an
+// empty account.
+func (acc *Account) IsEmpty() bool {
+	if acc == nil {
+		return true
+	}
+	return acc.Balance == 0 && acc.Sequence == 0 && !acc.IsContract() && len(acc.Permissions.Roles) == 0 &&
+		acc.Permissions.Base.Perms == permission.ZeroBasePermissions.Perms &&
+		acc.Permissions.Base.SetBit == permission.ZeroBasePermissions.SetBit
+}
+
+// EffectivePermissions returns the base permissions the account actually has: those it sets explicitly with any it
+// leaves unset inherited from global, the account at GlobalPermissionsAddress, as the EVM resolves them. Permissions
+// set by neither remain unset. A nil global contributes nothing.
+func (acc *Account) EffectivePermissions(global *Account) permission.BasePermissions {
+	var perms permission.BasePermissions
+	if acc != nil {
+		perms = acc.Permissions.Base
+	}
+	if global == nil {
+		return perms.Compose(permission.ZeroBasePermissions)
+	}
+	return perms.Compose(global.Permissions.Base)
+}
+
+// GrantPermissions explicitly grants the account each permission in flags, which may combine several permissions. An
+// invalid or empty flags is an ErrInvalidPermission and leaves the account unchanged.
+func (acc *Account) GrantPermissions(flags permission.PermFlag) error {
+	return acc.SetPermissionsMask(flags, flags)
+}
+
+// RevokePermissions explicitly denies the account each permission in flags, so they are not inherited from the global
+// permissions either. Use Permissions.Base.Unset to have the account inherit them instead.
+func (acc *Account) RevokePermissions(flags permission.PermFlag) error {
+	return acc.SetPermissionsMask(flags, 0)
+}
+
+// SetPermissionsMask explicitly sets each permission in set to whether it is in value, leaving the other permissions
+// of the account as they are. It is an ErrInvalidPermission for set to be invalid or empty or for value to have
+// permissions outside set, in which case the account is left unchanged.
+func (acc *Account) SetPermissionsMask(set, value permission.PermFlag) error {
+	if set == 0 || !set.IsValid() {
+		return permission.ErrInvalidPermission(set)
+	}
+	if value&^set != 0 {
+		return permission.ErrInvalidPermission(value &^ set)
+	}
+	base := &acc.Permissions.Base
+	base.SetBit |= set
+	base.Perms = base.Perms&^set | value
+	return nil
+}
+
+// IsZero returns whether the account has the zero address, as an account that has never been initialised does
+func (acc *Account) IsZero() bool {
+	return acc == nil || acc.Address == crypto.Address{}
+}
+
+// AssertContractInvariants checks that an account holding code is shaped like a contract: it holds only one kind of
+// code and, since a contract cannot sign, has neither a public key nor an explicit grant of the Input permission.
+// Accounts without code always pass.
+func (acc *Account) AssertContractInvariants() error {
+	if !acc.IsContract() {
+		return nil
+	}
+	if len(acc.EVMCode) > 0 && len(acc.WASMCode) > 0 {
+		return fmt.Errorf("contract account %v has both EVM and WASM code", acc.Address)
+	}
+	if acc.PublicKey.IsSet() {
+		return fmt.Errorf("contract account %v has public key %v but contracts cannot sign", acc.Address,
+			acc.PublicKey)
+	}
+	if acc.Permissions.Base.ResultantPerms()&permission.Input != 0 {
+		return fmt.Errorf("contract account %v is granted the %v permission but contracts cannot sign transactions",
+			acc.Address, permission.Input)
+	}
+	return nil
+}
+
+// WithAddedBalance returns a copy of the account with amount added to its balance leaving the receiver unchanged
+func (acc *Account) WithAddedBalance(amount uint64) (*Account, error) {
+	accCopy := acc.Copy()
+	err := accCopy.AddToBalance(amount)
+	if err != nil {
+		return nil, err
+	}
+	return accCopy, nil
+}
+
+// WithSubtractedBalance returns a copy of the account with amount subtracted from its balance leaving the receiver
+// unchanged
+func (acc *Account) WithSubtractedBalance(amount uint64) (*Account, error) {
+	accCopy := acc.Copy()
+	err := accCopy.SubtractFromBalance(amount)
+	if err != nil {
+		return nil, err
+	}
+	return accCopy, nil
+}
+
 ///---- Serialisation methods
 
 var cdc = amino.NewCodec()
@@ -80,6 +291,96 @@ func Decode(accBytes []byte) (*Account, error) {
 	return ca, nil
 }
 
+// gobAccount is the gob form of Account. It uses only plain types so that gob does not fall back on the text
+// marshalling of the field types, and records which slices are empty rather than nil since gob does not distinguish
+// between the two.
+type gobAccount struct {
+	Address    [crypto.AddressLength]byte
+	CurveType  uint32
+	PublicKey  gobBytes
+	Sequence   uint64
+	Balance    uint64
+	EVMCode    gobBytes
+	Perms      uint64
+	SetBit     uint64
+	Roles      []string
+	EmptyRoles bool
+	WASMCode   gobBytes
+	GasCredit  uint64
+}
+
+type gobBytes struct {
+	Bytes []byte
+	Empty bool
+}
+
+func newGobBytes(bs []byte) gobBytes {
+	return gobBytes{Bytes: bs, Empty: bs != nil && len(bs) == 0}
+}
+
+func (gb gobBytes) bytes() []byte {
+	if gb.Empty {
+		return []byte{}
+	}
+	return gb.Bytes
+}
+
+// EncodeGob encodes the account with encoding/gob for tooling that would rather not depend on amino, Encode remains
+// the canonical encoding used by Burrow
+func (acc *Account) EncodeGob() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	err := gob.NewEncoder(buf).Encode(gobAccount{
+		Address:    acc.Address,
+		CurveType:  uint32(acc.PublicKey.CurveType),
+		PublicKey:  newGobBytes(acc.PublicKey.PublicKey),
+		Sequence:   acc.Sequence,
+		Balance:    acc.Balance,
+		EVMCode:    newGobBytes(acc.EVMCode),
+		Perms:      uint64(acc.Permissions.Base.Perms),
+		SetBit:     uint64(acc.Permissions.Base.SetBit),
+		Roles:      acc.Permissions.Roles,
+		EmptyRoles: acc.Permissions.Roles != nil && len(acc.Permissions.Roles) == 0,
+		WASMCode:   newGobBytes(acc.WASMCode),
+		GasCredit:  acc.GasCredit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeGob decodes an account encoded with EncodeGob
+func DecodeGob(accBytes []byte) (*Account, error) {
+	ga := new(gobAccount)
+	err := gob.NewDecoder(bytes.NewReader(accBytes)).Decode(ga)
+	if err != nil {
+		return nil, err
+	}
+	acc := &Account{
+		Address: ga.Address,
+		PublicKey: crypto.PublicKey{
+			CurveType: crypto.CurveType(ga.CurveType),
+			PublicKey: ga.PublicKey.bytes(),
+		},
+		Sequence: ga.Sequence,
+		Balance:  ga.Balance,
+		EVMCode:  ga.EVMCode.bytes(),
+		Permissions: permission.AccountPermissions{
+			Base: permission.BasePermissions{
+				Perms:  permission.PermFlag(ga.Perms),
+				SetBit: permission.PermFlag(ga.SetBit),
+			},
+			Roles: ga.Roles,
+		},
+		WASMCode:  ga.WASMCode.bytes(),
+		GasCredit: ga.GasCredit,
+	}
+	if ga.EmptyRoles {
+		acc.Permissions.Roles = []string{}
+	}
+	return acc, nil
+}
+
 // Conversions
 //
 // Using the naming convention is this package of 'As<Type>' being
@@ -99,43 +400,194 @@ func FromAddressable(addressable crypto.Addressable) *Account {
 	}
 }
 
-// Copies all mutable parts of account
+// Copies all mutable parts of account, including the contents of its code, public key, and other byte slices, so that
+// neither the copy nor the original can be changed through the other
 func (acc *Account) Copy() *Account {
 	if acc == nil {
 		return nil
 	}
 	accCopy := *acc
+	accCopy.PublicKey.PublicKey = copyBytes(acc.PublicKey.PublicKey)
+	accCopy.PublicKey.XXX_unrecognized = copyBytes(acc.PublicKey.XXX_unrecognized)
+	accCopy.EVMCode = copyBytes(acc.EVMCode)
+	accCopy.WASMCode = copyBytes(acc.WASMCode)
 	accCopy.Permissions.Roles = make([]string, len(acc.Permissions.Roles))
 	copy(accCopy.Permissions.Roles, acc.Permissions.Roles)
+	accCopy.Permissions.XXX_unrecognized = copyBytes(acc.Permissions.XXX_unrecognized)
+	accCopy.Permissions.Base.XXX_unrecognized = copyBytes(acc.Permissions.Base.XXX_unrecognized)
+	accCopy.XXX_unrecognized = copyBytes(acc.XXX_unrecognized)
 	return &accCopy
 }
 
+// copyBytes returns a copy of bs that does not share its backing array, or nil if bs is nil
+func copyBytes(bs []byte) []byte {
+	if bs == nil {
+		return nil
+	}
+	return append([]byte{}, bs...)
+}
+
+// Equal compares accounts by their CanonicalBytes
 func (acc *Account) Equal(accOther *Account) bool {
-	accEnc, err := acc.Encode()
-	if err != nil {
-		return false
+	if acc == nil || accOther == nil {
+		return acc == accOther
 	}
-	accOtherEnc, err := acc.Encode()
-	if err != nil {
-		return false
+	return bytes.Equal(acc.CanonicalBytes(), accOther.CanonicalBytes())
+}
+
+// AccountKey is a comparable identity of an account for use as a map key, see Account.Key
+type AccountKey struct {
+	Address crypto.Address
+	// The sha3 of the EVM and WASM code of the account, or zero for no code
+	EVMCodeHash  [32]byte
+	WASMCodeHash [32]byte
+}
+
+// Key returns the identity of the account as the address and the code it holds. It does not depend on the balance,
+// sequence, permissions, or gas credit of the account so copies of an account keep the same key as these change.
+// The key is computed from field values alone so it is the same in every process, but fields may be added to
+// AccountKey in future so it should not be persisted.
+func (acc *Account) Key() AccountKey {
+	key := AccountKey{Address: acc.Address}
+	if len(acc.EVMCode) > 0 {
+		copy(key.EVMCodeHash[:], sha3.Sha3(acc.EVMCode))
+	}
+	if len(acc.WASMCode) > 0 {
+		copy(key.WASMCodeHash[:], sha3.Sha3(acc.WASMCode))
 	}
-	return bytes.Equal(accEnc, accOtherEnc)
+	return key
 }
 
+// Prefixes the canonical encoding so that it can be changed in future without ambiguity
+const canonicalEncodingVersion byte = 1
+
+// CanonicalBytes returns an encoding of the account that depends only on its field values. Fields are written in a
+// fixed order with integers as fixed-width big-endian and variable-length fields prefixed by their length, and roles
+// are sorted, so unlike Encode it does not depend on the codec implementation or on the order in which roles were
+// added. This is the encoding to use for hashing or comparing accounts (and the bytes signed by
+// SignedAccount), it is not used for storage.
+func (acc *Account) CanonicalBytes() []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteByte(canonicalEncodingVersion)
+	buf.Write(acc.Address[:])
+	buf.WriteByte(acc.PublicKey.CurveType.Byte())
+	writeCanonicalBytes(buf, acc.PublicKey.PublicKey)
+	writeCanonicalUint64(buf, acc.Sequence)
+	writeCanonicalUint64(buf, acc.Balance)
+	writeCanonicalBytes(buf, acc.EVMCode)
+	writeCanonicalBytes(buf, acc.WASMCode)
+	writeCanonicalUint64(buf, uint64(acc.Permissions.Base.Perms))
+	writeCanonicalUint64(buf, uint64(acc.Permissions.Base.SetBit))
+	roles := make([]string, len(acc.Permissions.Roles))
+	copy(roles, acc.Permissions.Roles)
+	sort.Strings(roles)
+	writeCanonicalUint64(buf, uint64(len(roles)))
+	for _, role := range roles {
+		writeCanonicalBytes(buf, []byte(role))
+	}
+	// Only written when set so that the encoding (and hash) of accounts without gas credit is unchanged
+	if acc.GasCredit != 0 {
+		writeCanonicalUint64(buf, acc.GasCredit)
+	}
+	return buf.Bytes()
+}
+
+// Hash returns the sha3 of the account's CanonicalBytes
+func (acc *Account) Hash() []byte {
+	return sha3.Sha3(acc.CanonicalBytes())
+}
+
+func writeCanonicalUint64(buf *bytes.Buffer, i uint64) {
+	var bs [8]byte
+	bin.BigEndian.PutUint64(bs[:], i)
+	buf.Write(bs[:])
+}
+
+func writeCanonicalBytes(buf *bytes.Buffer, bs []byte) {
+	writeCanonicalUint64(buf, uint64(len(bs)))
+	buf.Write(bs)
+}
+
+// Number of bytes of the code hash to include in String()
+const shortCodeHashLength = 4
+
 func (acc Account) String() string {
-	return fmt.Sprintf("Account{Address: %s; Sequence: %v; PublicKey: %v Balance: %v; CodeLength: %v; Permissions: %v}",
-		acc.Address, acc.Sequence, acc.PublicKey, acc.Balance, len(acc.EVMCode), acc.Permissions)
+	return fmt.Sprintf("Account{Address: %s; Sequence: %v; PublicKey: %v; Balance: %v; Code: %s; Permissions: %s}",
+		acc.Address, acc.Sequence, acc.PublicKey, acc.Balance, acc.codeSummary(), acc.permissionsSummary())
+}
+
+// GoString makes %#v print the same one-line summary as String rather than a Go literal, so that the full EVM code
+// is never dumped into logs
+func (acc Account) GoString() string {
+	return acc.String()
+}
+
+func (acc Account) codeSummary() string {
+	if len(acc.EVMCode) == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d bytes sha3 %s..", len(acc.EVMCode), acc.shortCodeHash())
+}
+
+// Returns a truncated hex encoding of the sha3 of the code only hashing (not copying) the code
+func (acc Account) shortCodeHash() string {
+	if len(acc.EVMCode) == 0 {
+		return "none"
+	}
+	return hex.EncodeUpperToString(sha3.Sha3(acc.EVMCode)[:shortCodeHashLength])
+}
+
+func (acc Account) permissionsSummary() string {
+	perms := strings.Join(permission.BasePermissionsToStringList(acc.Permissions.Base), "|")
+	if perms == "" {
+		perms = "none"
+	}
+	if len(acc.Permissions.Roles) == 0 {
+		return perms
+	}
+	return fmt.Sprintf("%s roles %s", perms, strings.Join(acc.Permissions.Roles, ","))
 }
 
+// Prefix of the boolean tags exposing each named permission flag, e.g. Perm.CreateContract
+const PermissionTagPrefix = "Perm."
+
+// Prefix of the boolean tags marking each role the account has, e.g. Role.admin
+const RoleTagPrefix = "Role."
+
 func (acc *Account) Tagged() query.Tagged {
 	return &TaggedAccount{
 		Account: acc,
-		Tagged: query.MergeTags(query.MustReflectTags(acc, "Address", "Balance", "Sequence", "EVMCode"),
+		Tagged: query.MergeTags(query.MustReflectTags(acc, "Address", "Balance", "Sequence", "EVMCode", "GasCredit"),
 			query.TagMap{
 				"Permissions": acc.Permissions.Base.ResultantPerms(),
 				"Roles":       acc.Permissions.Roles,
-			}),
+			},
+			permissionTags(acc.Permissions.Base.ResultantPerms()),
+			roleTags(acc.Permissions.Roles)),
+	}
+}
+
+// Tags each permission flag by its capitalised name so it can be queried directly, e.g. Perm.Send = 'true'
+func permissionTags(perms permission.PermFlag) query.TagMap {
+	tags := make(query.TagMap, permission.NumPermissions)
+	for i := uint(0); i < permission.NumPermissions; i++ {
+		flag := permission.PermFlag(1) << i
+		name := flag.String()
+		tags[PermissionTagPrefix+strings.ToUpper(name[:1])+name[1:]] = perms&flag != 0
+	}
+	return tags
+}
+
+// Tags each role of the account true so membership can be queried directly, e.g. Role.admin = 'true'. Roles are
+// tagged without the zero padding added by AddRole and in the case they are stored in, as hasRole matches them. Roles
+// the account does not have are not tagged so match neither 'true' nor 'false', and roles containing whitespace,
+// quotes, brackets, or comparison operators cannot be named in a query.
+func roleTags(roles []string) query.TagMap {
+	tags := make(query.TagMap, len(roles))
+	for _, role := range roles {
+		tags[RoleTagPrefix+strings.TrimRight(role, "\x00")] = true
 	}
+	return tags
 }
 
 type TaggedAccount struct {
@@ -0,0 +1,81 @@
+package acm
+
+import (
+	"bufio"
+	bin "encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The longest encoded account an AccountReader will read, so that a corrupt length prefix cannot exhaust memory
+const MaxStreamedAccountLength = 1 << 26
+
+// AccountWriter writes accounts to a stream one at a time, each encoded with Encode and prefixed by its length as a
+// uvarint, to be read back with an AccountReader
+type AccountWriter struct {
+	w      io.Writer
+	prefix [bin.MaxVarintLen64]byte
+}
+
+// NewAccountWriter returns an AccountWriter writing to w, which may want buffering for large streams
+func NewAccountWriter(w io.Writer) *AccountWriter {
+	return &AccountWriter{w: w}
+}
+
+// Write appends acc to the stream
+func (aw *AccountWriter) Write(acc *Account) error {
+	bs, err := acc.Encode()
+	if err != nil {
+		return fmt.Errorf("could not encode account %v for stream: %v", acc.Address, err)
+	}
+	n := bin.PutUvarint(aw.prefix[:], uint64(len(bs)))
+	if _, err = aw.w.Write(aw.prefix[:n]); err != nil {
+		return err
+	}
+	_, err = aw.w.Write(bs)
+	return err
+}
+
+// AccountReader reads accounts written by an AccountWriter one at a time, so a large set of accounts can be processed
+// without holding all of them in memory
+type AccountReader struct {
+	r *bufio.Reader
+	// Reused between accounts
+	buf []byte
+}
+
+// NewAccountReader returns an AccountReader reading from r
+func NewAccountReader(r io.Reader) *AccountReader {
+	return &AccountReader{r: bufio.NewReader(r)}
+}
+
+// Next returns the next account of the stream, or io.EOF if the stream ended after the last account. A stream that
+// ends part way through an account gives io.ErrUnexpectedEOF.
+func (ar *AccountReader) Next() (*Account, error) {
+	length, err := bin.ReadUvarint(ar.r)
+	if err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("could not read length of streamed account: %v", err)
+	}
+	if length > MaxStreamedAccountLength {
+		return nil, fmt.Errorf("streamed account of %d bytes exceeds maximum of %d", length,
+			MaxStreamedAccountLength)
+	}
+	if uint64(cap(ar.buf)) < length {
+		ar.buf = make([]byte, length)
+	}
+	ar.buf = ar.buf[:length]
+	if _, err = io.ReadFull(ar.r, ar.buf); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	acc, err := Decode(ar.buf)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode streamed account: %v", err)
+	}
+	return acc, nil
+}
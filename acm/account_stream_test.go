@@ -0,0 +1,65 @@
+package acm
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/hyperledger/burrow/permission"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountStream(t *testing.T) {
+	const numAccounts = 2000
+	accounts := make([]*Account, numAccounts)
+	for i := range accounts {
+		acc := NewAccountFromSecret(fmt.Sprintf("Stream %d", i))
+		acc.Balance = uint64(i)
+		acc.Sequence = uint64(i * 2)
+		acc.Permissions = permission.AccountPermissions{Roles: []string{fmt.Sprintf("role-%d", i)}}
+		if i%10 == 0 {
+			acc.EVMCode = bytes.Repeat([]byte{byte(i)}, i)
+		}
+		accounts[i] = acc
+	}
+
+	buf := new(bytes.Buffer)
+	writer := NewAccountWriter(buf)
+	for _, acc := range accounts {
+		require.NoError(t, writer.Write(acc))
+	}
+
+	reader := NewAccountReader(buf)
+	var previous *Account
+	for i := 0; i < numAccounts; i++ {
+		acc, err := reader.Next()
+		require.NoError(t, err)
+		require.True(t, accounts[i].Equal(acc), "account %d: expected %v but got %v", i, accounts[i], acc)
+		// Accounts do not share memory with the reused buffer
+		if previous != nil {
+			require.True(t, accounts[i-1].Equal(previous))
+		}
+		previous = acc
+	}
+	_, err := reader.Next()
+	assert.Equal(t, io.EOF, err)
+	// Memory is bounded by the largest account rather than the stream
+	assert.True(t, cap(reader.buf) < 2*numAccounts, "buffer of %d bytes", cap(reader.buf))
+}
+
+func TestAccountStreamTruncated(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, NewAccountWriter(buf).Write(NewAccountFromSecret("Truncated")))
+	bs := buf.Bytes()
+
+	_, err := NewAccountReader(bytes.NewReader(bs[:len(bs)-1])).Next()
+	assert.Equal(t, io.ErrUnexpectedEOF, err)
+
+	_, err = NewAccountReader(bytes.NewReader(nil)).Next()
+	assert.Equal(t, io.EOF, err)
+
+	_, err = NewAccountReader(bytes.NewReader([]byte{0xff, 0xff, 0xff, 0xff, 0x7f})).Next()
+	assert.Error(t, err)
+}
@@ -17,9 +17,11 @@ package acm
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"testing"
 
 	"github.com/hyperledger/burrow/event/query"
+	"github.com/hyperledger/burrow/execution/errors"
 	"github.com/hyperledger/burrow/execution/solidity"
 
 	"github.com/hyperledger/burrow/crypto"
@@ -49,6 +51,28 @@ func TestAddress(t *testing.T) {
 	assert.Equal(t, addr, addrFromWord256)
 }
 
+func TestNewAccountChecked(t *testing.T) {
+	_, err := NewAccountChecked(crypto.PublicKey{})
+	require.Error(t, err)
+	assert.Equal(t, errors.ErrorCodeInvalidAddress, errors.AsException(err).ErrorCode())
+
+	for _, curveType := range []crypto.CurveType{crypto.CurveTypeEd25519, crypto.CurveTypeSecp256k1} {
+		publicKey := crypto.PrivateKeyFromSecret("Checked", curveType).GetPublicKey()
+		acc, err := NewAccountChecked(publicKey)
+		require.NoError(t, err)
+		assert.Equal(t, publicKey.GetAddress(), acc.Address)
+		assert.Equal(t, publicKey, acc.PublicKey)
+
+		// A key of the curve with no bytes would otherwise hash to a valid-looking address
+		_, err = NewAccountChecked(crypto.PublicKey{CurveType: curveType})
+		require.Error(t, err)
+		assert.Equal(t, errors.ErrorCodeInvalidAddress, errors.AsException(err).ErrorCode())
+
+		_, err = NewAccountChecked(crypto.PublicKey{CurveType: curveType, PublicKey: publicKey.PublicKey[1:]})
+		require.Error(t, err)
+	}
+}
+
 func TestDecodeConcrete(t *testing.T) {
 	concreteAcc := NewAccountFromSecret("Super Semi Secret")
 	concreteAcc.Permissions = permission.AccountPermissions{
@@ -83,6 +107,57 @@ func TestDecode(t *testing.T) {
 	assert.Nil(t, accOut)
 }
 
+func TestDecodeGob(t *testing.T) {
+	full := NewAccountFromSecret("Super Semi Secret")
+	full.Sequence = 7
+	full.Balance = math.MaxUint64
+	full.EVMCode = Bytecode{0x60, 0x01}
+	full.WASMCode = Bytecode{0x00, 0x61, 0x73, 0x6d}
+	full.Permissions = permission.AccountPermissions{
+		Base: permission.BasePermissions{
+			Perms:  permission.Send | permission.Call,
+			SetBit: permission.Send | permission.Call | permission.Bond,
+		},
+		Roles: []string{"bums", "chums"},
+	}
+
+	for name, acc := range map[string]*Account{
+		"zero":         {},
+		"nil slices":   NewAccountFromSecret("Super Semi Secret"),
+		"empty slices": FromAddressable(NewAccountFromSecret("Super Semi Secret")),
+		"empty public key": {
+			PublicKey: crypto.PublicKey{PublicKey: []byte{}},
+			WASMCode:  Bytecode{},
+		},
+		"full": full,
+	} {
+		t.Run(name, func(t *testing.T) {
+			encodedAcc, err := acc.EncodeGob()
+			require.NoError(t, err)
+			accOut, err := DecodeGob(encodedAcc)
+			require.NoError(t, err)
+
+			assert.Equal(t, acc.Address, accOut.Address)
+			assert.Equal(t, acc.PublicKey, accOut.PublicKey)
+			assert.Equal(t, acc.Sequence, accOut.Sequence)
+			assert.Equal(t, acc.Balance, accOut.Balance)
+			assert.Equal(t, acc.EVMCode, accOut.EVMCode)
+			assert.Equal(t, acc.WASMCode, accOut.WASMCode)
+			assert.Equal(t, acc.Permissions, accOut.Permissions)
+			// Nil slices should not come back empty nor empty slices nil
+			assert.Equal(t, acc.PublicKey.PublicKey == nil, accOut.PublicKey.PublicKey == nil)
+			assert.Equal(t, acc.EVMCode == nil, accOut.EVMCode == nil)
+			assert.Equal(t, acc.WASMCode == nil, accOut.WASMCode == nil)
+			assert.Equal(t, acc.Permissions.Roles == nil, accOut.Permissions.Roles == nil)
+			assert.Equal(t, acc, accOut)
+		})
+	}
+
+	accOut, err := DecodeGob([]byte("flungepliffery munknut tolopops"))
+	require.Error(t, err)
+	assert.Nil(t, accOut)
+}
+
 func TestMarshalJSON(t *testing.T) {
 	acc := NewAccountFromSecret("Super Semi Secret")
 	acc.EVMCode = []byte{60, 23, 45}
@@ -103,6 +178,79 @@ func TestMarshalJSON(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestAssertContractInvariants(t *testing.T) {
+	// An externally owned account
+	eoa := NewAccountFromSecret("eoa")
+	eoa.Permissions = permission.DefaultAccountPermissions
+	assert.False(t, eoa.IsContract())
+	require.NoError(t, eoa.AssertContractInvariants())
+
+	// A contract as created by the EVM
+	contract := &Account{
+		Address: crypto.NewContractAddress(eoa.Address, []byte{1}),
+		EVMCode: solidity.Bytecode_StrangeLoop,
+	}
+	assert.True(t, contract.IsContract())
+	require.NoError(t, contract.AssertContractInvariants())
+
+	// Code on an account that can sign
+	signer := NewAccountFromSecret("signer")
+	signer.EVMCode = solidity.Bytecode_StrangeLoop
+	err := signer.AssertContractInvariants()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "contracts cannot sign")
+
+	grantedInput := contract.Copy()
+	grantedInput.Permissions = permission.NewAccountPermissions(permission.Call, permission.Input)
+	err = grantedInput.AssertContractInvariants()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "input permission")
+
+	bothCode := contract.Copy()
+	bothCode.WASMCode = []byte{0x00, 0x61, 0x73, 0x6d}
+	err = bothCode.AssertContractInvariants()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "both EVM and WASM code")
+}
+
+func TestIsEmptyAndIsZero(t *testing.T) {
+	assert.True(t, (*Account)(nil).IsEmpty())
+	assert.True(t, (*Account)(nil).IsZero())
+
+	zero := &Account{}
+	assert.True(t, zero.IsEmpty())
+	assert.True(t, zero.IsZero())
+
+	// Initialised but holding nothing, with empty rather than nil slices
+	initialised := FromAddressable(NewAccountFromSecret("empty"))
+	assert.True(t, initialised.IsEmpty())
+	assert.False(t, initialised.IsZero())
+	initialised.WASMCode = Bytecode{}
+	assert.True(t, initialised.IsEmpty())
+
+	// Holding something, whatever the address
+	for name, fill := range map[string]func(acc *Account){
+		"balance":   func(acc *Account) { acc.Balance = 1 },
+		"sequence":  func(acc *Account) { acc.Sequence = 1 },
+		"EVM code":  func(acc *Account) { acc.EVMCode = solidity.Bytecode_StrangeLoop },
+		"WASM code": func(acc *Account) { acc.WASMCode = Bytecode{0x00, 0x61, 0x73, 0x6d} },
+		"roles":     func(acc *Account) { acc.Permissions.Roles = []string{"frogs"} },
+		"permissions": func(acc *Account) {
+			acc.Permissions.Base = permission.BasePermissions{Perms: permission.Send, SetBit: permission.Send}
+		},
+		// Denying a permission is still a setting
+		"denied permissions": func(acc *Account) {
+			acc.Permissions.Base = permission.BasePermissions{SetBit: permission.Send}
+		},
+	} {
+		for _, acc := range []*Account{{}, FromAddressable(NewAccountFromSecret("full"))} {
+			fill(acc)
+			assert.False(t, acc.IsEmpty(), "account with %s should not be empty", name)
+			assert.Equal(t, acc.Address == crypto.Address{}, acc.IsZero())
+		}
+	}
+}
+
 func TestAccountTags(t *testing.T) {
 	perms := permission.DefaultAccountPermissions
 	perms.Roles = []string{"frogs", "dogs"}
@@ -111,7 +259,8 @@ func TestAccountTags(t *testing.T) {
 		EVMCode:     solidity.Bytecode_StrangeLoop,
 	}
 	tagged := acc.Tagged()
-	assert.Equal(t, []string{"Address", "Balance", "Sequence", "EVMCode", "Permissions", "Roles"}, tagged.Keys())
+	assert.Equal(t, []string{"Address", "Balance", "Sequence", "EVMCode", "GasCredit", "Permissions", "Roles"},
+		tagged.Keys()[:7])
 	str, _ := tagged.Get("Permissions")
 	assert.Equal(t, "send | call | createContract | createAccount | bond | name | proposal | input | batch | hasBase | hasRole", str)
 	str, _ = tagged.Get("Roles")
@@ -121,3 +270,420 @@ func TestAccountTags(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, qry.Matches(tagged))
 }
+
+func TestAccountPermissionTags(t *testing.T) {
+	acc := &Account{
+		Permissions: permission.NewAccountPermissions(permission.Send, permission.CreateContract),
+	}
+	tagged := acc.Tagged()
+	assert.Len(t, tagged.Keys(), 7+int(permission.NumPermissions))
+	str, ok := tagged.Get("Perm.CreateContract")
+	require.True(t, ok)
+	assert.Equal(t, "true", str)
+	str, ok = tagged.Get("Perm.RemoveRole")
+	require.True(t, ok)
+	assert.Equal(t, "false", str)
+
+	qry, err := query.New("Perm.Send = 'true' AND Perm.Call = 'false'")
+	require.NoError(t, err)
+	assert.True(t, qry.Matches(tagged))
+
+	qry, err = query.New("Perm.Call = 'true'")
+	require.NoError(t, err)
+	assert.False(t, qry.Matches(tagged))
+
+	// Numeric tag is still present
+	str, _ = tagged.Get("Permissions")
+	assert.Equal(t, "send | createContract", str)
+}
+
+func TestAccountGrantRevokePermissions(t *testing.T) {
+	acc := &Account{}
+	require.NoError(t, acc.GrantPermissions(permission.Send|permission.Call|permission.CreateContract))
+	assert.Equal(t, permission.Send|permission.Call|permission.CreateContract, acc.Permissions.Base.ResultantPerms())
+
+	require.NoError(t, acc.RevokePermissions(permission.Call|permission.Bond))
+	assert.Equal(t, permission.Send|permission.CreateContract, acc.Permissions.Base.ResultantPerms())
+	// Revoked permissions are set so they are not inherited
+	assert.True(t, acc.Permissions.Base.IsSet(permission.Call|permission.Bond))
+	global := &Account{Permissions: permission.NewAccountPermissions(permission.Bond | permission.Name)}
+	assert.Equal(t, permission.Send|permission.CreateContract|permission.Name,
+		acc.EffectivePermissions(global).ResultantPerms())
+
+	tagged := acc.Tagged()
+	str, _ := tagged.Get("Permissions")
+	assert.Equal(t, "send | createContract", str)
+	str, _ = tagged.Get("Perm.Call")
+	assert.Equal(t, "false", str)
+	str, _ = tagged.Get("Perm.CreateContract")
+	assert.Equal(t, "true", str)
+
+	// Only the masked permissions change
+	require.NoError(t, acc.SetPermissionsMask(permission.Send|permission.Call|permission.Name, permission.Call))
+	assert.Equal(t, permission.Call|permission.CreateContract, acc.Permissions.Base.ResultantPerms())
+	assert.Equal(t, permission.Send|permission.Call|permission.CreateContract|permission.Bond|permission.Name,
+		acc.Permissions.Base.SetBit)
+	str, _ = acc.Tagged().Get("Permissions")
+	assert.Equal(t, "call | createContract", str)
+
+	before := acc.Permissions.Base
+	assert.Error(t, acc.GrantPermissions(0))
+	assert.Error(t, acc.RevokePermissions(permission.AllPermFlags+1))
+	assert.Error(t, acc.SetPermissionsMask(permission.Send, permission.Send|permission.Call))
+	assert.Equal(t, before, acc.Permissions.Base)
+	require.NoError(t, acc.Validate())
+}
+
+func TestAccountRoleTags(t *testing.T) {
+	acc := &Account{
+		Permissions: permission.AccountPermissions{Roles: []string{"admin", "validator"}},
+	}
+	tagged := acc.Tagged()
+	str, ok := tagged.Get("Role.admin")
+	require.True(t, ok)
+	assert.Equal(t, "true", str)
+	_, ok = tagged.Get("Role.root")
+	assert.False(t, ok)
+
+	qry, err := query.New("Role.admin = 'true' AND Role.validator = 'true'")
+	require.NoError(t, err)
+	assert.True(t, qry.Matches(tagged))
+
+	qry, err = query.New("Role.root = 'true'")
+	require.NoError(t, err)
+	assert.False(t, qry.Matches(tagged))
+
+	// The slice is still tagged for listing
+	str, ok = tagged.Get("Roles")
+	require.True(t, ok)
+	assert.Contains(t, str, "admin")
+	assert.Contains(t, str, "validator")
+
+	// Roles granted on chain are padded by AddRole
+	acc = &Account{}
+	require.True(t, acc.Permissions.AddRole("minter"))
+	tagged = acc.Tagged()
+	str, ok = tagged.Get("Role.minter")
+	require.True(t, ok)
+	assert.Equal(t, "true", str)
+	qry, err = query.New("Role.minter = 'true'")
+	require.NoError(t, err)
+	assert.True(t, qry.Matches(tagged))
+}
+
+func TestEffectivePermissions(t *testing.T) {
+	global := &Account{
+		Address:     GlobalPermissionsAddress,
+		Permissions: permission.NewAccountPermissions(permission.Send, permission.Call),
+	}
+	// Explicitly denies Call and grants CreateContract, leaving Send unset
+	acc := &Account{Permissions: permission.NewAccountPermissions(permission.CreateContract)}
+	require.NoError(t, acc.Permissions.Base.Set(permission.Call, false))
+
+	perms := acc.EffectivePermissions(global)
+	for perm, expected := range map[permission.PermFlag]bool{
+		// Inherited from global
+		permission.Send: true,
+		// Explicit bits win over global
+		permission.Call:           false,
+		permission.CreateContract: true,
+	} {
+		value, err := perms.Get(perm)
+		require.NoError(t, err)
+		assert.Equal(t, expected, value, "%v", perm)
+	}
+	// Set by neither
+	assert.False(t, perms.IsSet(permission.Bond))
+	assert.Equal(t, permission.Send|permission.CreateContract, perms.ResultantPerms())
+
+	// Without global only the explicit bits apply
+	perms = acc.EffectivePermissions(nil)
+	assert.Equal(t, permission.CreateContract, perms.ResultantPerms())
+	assert.False(t, perms.IsSet(permission.Send))
+}
+
+func TestWithBalance(t *testing.T) {
+	acc := NewAccountFromSecret("Balance")
+	acc.Balance = 10
+
+	accAdded, err := acc.WithAddedBalance(5)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(15), accAdded.Balance)
+	assert.Equal(t, uint64(10), acc.Balance)
+
+	accSubtracted, err := acc.WithSubtractedBalance(10)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), accSubtracted.Balance)
+	assert.Equal(t, uint64(10), acc.Balance)
+
+	_, err = acc.WithAddedBalance(math.MaxUint64)
+	require.Error(t, err)
+	assert.Equal(t, errors.ErrorCodeIntegerOverflow, errors.AsException(err).ErrorCode())
+	assert.Equal(t, uint64(10), acc.Balance)
+
+	_, err = acc.WithSubtractedBalance(11)
+	require.Error(t, err)
+	assert.Equal(t, errors.ErrorCodeInsufficientBalance, errors.AsException(err).ErrorCode())
+	assert.Equal(t, uint64(10), acc.Balance)
+}
+
+func TestBalanceError(t *testing.T) {
+	acc := NewAccountFromSecret("Balance")
+	acc.Balance = 10
+
+	err := acc.AddToBalance(math.MaxUint64)
+	require.Error(t, err)
+	balanceErr, ok := err.(*BalanceError)
+	require.True(t, ok)
+	assert.Equal(t, BalanceError{
+		Address:   acc.Address,
+		Operation: AddBalance,
+		Amount:    math.MaxUint64,
+		Balance:   10,
+		Code:      errors.ErrorCodeIntegerOverflow,
+	}, *balanceErr)
+	assert.Equal(t, errors.ErrorCodeIntegerOverflow, errors.AsException(err).ErrorCode())
+	assert.Contains(t, err.Error(), "integer overflow")
+	assert.Contains(t, err.Error(), acc.Address.String())
+
+	err = acc.SubtractFromBalance(11)
+	require.Error(t, err)
+	balanceErr, ok = err.(*BalanceError)
+	require.True(t, ok)
+	assert.Equal(t, BalanceError{
+		Address:   acc.Address,
+		Operation: SubtractBalance,
+		Amount:    11,
+		Balance:   10,
+		Code:      errors.ErrorCodeInsufficientBalance,
+	}, *balanceErr)
+	assert.Equal(t, errors.ErrorCodeInsufficientBalance, errors.AsException(err).ErrorCode())
+	assert.Equal(t, balanceErr.String(), errors.AsException(err).String())
+	assert.Equal(t, uint64(10), acc.Balance)
+}
+
+func TestGasCredit(t *testing.T) {
+	acc := NewAccountFromSecret("Gas")
+	hash := acc.Hash()
+	require.NoError(t, acc.AddGas(10))
+	require.NoError(t, acc.SpendGas(4))
+	assert.Equal(t, uint64(6), acc.GasCredit)
+	assert.NotEqual(t, hash, acc.Hash())
+
+	err := acc.SpendGas(7)
+	require.Error(t, err)
+	balanceErr, ok := err.(*BalanceError)
+	require.True(t, ok)
+	assert.Equal(t, BalanceError{
+		Address:   acc.Address,
+		Operation: SpendGas,
+		Amount:    7,
+		Balance:   6,
+		Code:      errors.ErrorCodeInsufficientGas,
+	}, *balanceErr)
+	assert.Contains(t, err.Error(), "insufficient gas")
+	assert.Equal(t, uint64(6), acc.GasCredit)
+
+	err = acc.AddGas(math.MaxUint64)
+	require.Error(t, err)
+	balanceErr, ok = err.(*BalanceError)
+	require.True(t, ok)
+	assert.Equal(t, AddGas, balanceErr.Operation)
+	assert.Equal(t, errors.ErrorCodeIntegerOverflow, errors.AsException(err).ErrorCode())
+	assert.Equal(t, uint64(6), acc.GasCredit)
+
+	// Carried by copies and the encodings
+	assert.Equal(t, uint64(6), acc.Copy().GasCredit)
+	encoded, err := acc.Encode()
+	require.NoError(t, err)
+	decoded, err := Decode(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), decoded.GasCredit)
+	encoded, err = acc.EncodeGob()
+	require.NoError(t, err)
+	decoded, err = DecodeGob(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), decoded.GasCredit)
+	str, ok := acc.Tagged().Get("GasCredit")
+	require.True(t, ok)
+	assert.Equal(t, "6", str)
+
+	// Unset gas credit leaves the account as it was
+	require.NoError(t, acc.SpendGas(6))
+	assert.Equal(t, hash, acc.Hash())
+}
+
+func TestAccountString(t *testing.T) {
+	eoa := NewAccountFromSecret("eoa")
+	eoa.Balance = 10
+	eoa.Permissions = permission.AccountPermissions{
+		Base: permission.BasePermissions{
+			Perms:  permission.Send | permission.Call,
+			SetBit: permission.Send | permission.Call,
+		},
+	}
+	assert.Equal(t, fmt.Sprintf("Account{Address: %v; Sequence: 0; PublicKey: %v; Balance: 10; Code: none; "+
+		"Permissions: send|call}", eoa.Address, eoa.PublicKey), eoa.String())
+	assert.Equal(t, eoa.String(), fmt.Sprintf("%#v", eoa))
+
+	contract := &Account{
+		Address:  crypto.Address{1, 2, 3},
+		Sequence: 2,
+		// Large enough that dumping the code would be obvious
+		EVMCode: make([]byte, 1<<16),
+		Permissions: permission.AccountPermissions{
+			Roles: []string{"foo", "bar"},
+		},
+	}
+	str := contract.String()
+	assert.Equal(t, fmt.Sprintf("Account{Address: %v; Sequence: 2; PublicKey: ; Balance: 0; "+
+		"Code: 65536 bytes sha3 %s..; Permissions: none roles foo,bar}", contract.Address,
+		contract.shortCodeHash()), str)
+	assert.Len(t, contract.shortCodeHash(), 2*shortCodeHashLength)
+	assert.True(t, len(str) < 200)
+	assert.Equal(t, str, fmt.Sprintf("%#v", contract))
+}
+
+func TestAddressAndPublicKeyEqual(t *testing.T) {
+	acc := NewAccountFromSecret("Super Semi Secret")
+	other := NewAccountFromSecret("Another Secret")
+
+	assert.True(t, acc.AddressEqual(acc.Address))
+	assert.False(t, acc.AddressEqual(other.Address))
+	assert.False(t, acc.AddressEqual(crypto.Address{}))
+	// Differ only in the last byte
+	almost := acc.Address
+	almost[crypto.AddressLength-1] ^= 1
+	assert.False(t, acc.AddressEqual(almost))
+
+	assert.True(t, acc.PublicKeyEqual(acc.PublicKey))
+	assert.False(t, acc.PublicKeyEqual(other.PublicKey))
+	assert.False(t, acc.PublicKeyEqual(crypto.PublicKey{}))
+	// Same bytes on a different curve
+	assert.False(t, acc.PublicKeyEqual(crypto.PublicKey{
+		CurveType: crypto.CurveTypeSecp256k1,
+		PublicKey: acc.PublicKey.PublicKey,
+	}))
+	// Unset keys are equal to each other
+	assert.True(t, (&Account{}).PublicKeyEqual(crypto.PublicKey{}))
+}
+
+func TestCanonicalBytes(t *testing.T) {
+	newAccount := func(roles ...string) *Account {
+		acc := NewAccountFromSecret("canonical")
+		acc.Balance = 42
+		acc.Sequence = 3
+		acc.EVMCode = solidity.Bytecode_StrangeLoop
+		acc.Permissions = permission.NewAccountPermissions(permission.Send | permission.Call)
+		for _, role := range roles {
+			require.True(t, acc.Permissions.AddRole(role))
+		}
+		return acc
+	}
+
+	accA := newAccount("frogs", "dogs", "newts")
+	accB := newAccount("newts", "frogs", "dogs")
+	require.NotEqual(t, accA.Permissions.Roles, accB.Permissions.Roles)
+	rolesA := append([]string(nil), accA.Permissions.Roles...)
+	assert.Equal(t, accA.CanonicalBytes(), accB.CanonicalBytes())
+	assert.Equal(t, accA.Hash(), accB.Hash())
+	assert.True(t, accA.Equal(accB))
+	// Roles are not sorted in place
+	assert.Equal(t, rolesA, accA.Permissions.Roles)
+
+	// Round trips through the storage encoding
+	encoded, err := accA.Encode()
+	require.NoError(t, err)
+	decoded, err := Decode(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, accA.Hash(), decoded.Hash())
+
+	accB.Balance++
+	assert.NotEqual(t, accA.Hash(), accB.Hash())
+	assert.False(t, accA.Equal(accB))
+	assert.False(t, accA.Equal(newAccount("frogs", "dogs")))
+
+	// Adjacent variable-length fields cannot be confused
+	accA.EVMCode, accA.WASMCode = Bytecode{1, 2}, Bytecode{}
+	accB = accA.Copy()
+	accB.EVMCode, accB.WASMCode = Bytecode{1}, Bytecode{2}
+	assert.False(t, accA.Equal(accB))
+}
+
+func TestAccountKey(t *testing.T) {
+	contract := NewAccountFromSecret("contract")
+	contract.PublicKey = crypto.PublicKey{}
+	contract.EVMCode = solidity.Bytecode_StrangeLoop
+	user := NewAccountFromSecret("user")
+
+	seen := make(map[AccountKey]*Account)
+	var duplicates []*Account
+	for _, acc := range []*Account{contract, user, contract.Copy(), user.Copy()} {
+		if _, ok := seen[acc.Key()]; ok {
+			duplicates = append(duplicates, acc)
+			continue
+		}
+		seen[acc.Key()] = acc
+	}
+	assert.Len(t, seen, 2)
+	require.Len(t, duplicates, 2)
+	assert.Equal(t, contract.Address, duplicates[0].Address)
+	assert.Equal(t, user.Address, duplicates[1].Address)
+
+	// Unchanged by updates to balance, sequence, and permissions
+	updated := contract.Copy()
+	require.NoError(t, updated.AddToBalance(10))
+	updated.Sequence++
+	updated.Permissions = permission.NewAccountPermissions(permission.Call)
+	assert.Equal(t, contract.Key(), updated.Key())
+
+	// But distinguishes the code held at an address
+	updated.EVMCode = solidity.Bytecode_ZeroReset
+	assert.NotEqual(t, contract.Key(), updated.Key())
+	updated.EVMCode = nil
+	assert.Equal(t, AccountKey{Address: contract.Address}, updated.Key())
+	updated.WASMCode = solidity.Bytecode_StrangeLoop
+	assert.NotEqual(t, contract.Key(), updated.Key())
+}
+
+func TestAccountCopy(t *testing.T) {
+	privAcc := GeneratePrivateAccountFromSecret("original")
+	acc := NewAccount(privAcc.GetPublicKey())
+	acc.EVMCode = Bytecode{1, 2, 3}
+	acc.WASMCode = Bytecode{4, 5, 6}
+	acc.Permissions.Roles = []string{"admin"}
+	acc.Permissions.XXX_unrecognized = []byte{7}
+	acc.Permissions.Base.XXX_unrecognized = []byte{8}
+	acc.XXX_unrecognized = []byte{9}
+	publicKey := append([]byte{}, acc.PublicKey.PublicKey...)
+
+	// Mutate each reference field of the copy in place
+	accCopy := acc.Copy()
+	assert.True(t, acc.Equal(accCopy))
+	accCopy.EVMCode[0] = 0xFF
+	accCopy.WASMCode[0] = 0xFF
+	accCopy.PublicKey.PublicKey[0] ^= 0xFF
+	accCopy.Permissions.Roles[0] = "root"
+	accCopy.Permissions.XXX_unrecognized[0] = 0xFF
+	accCopy.Permissions.Base.XXX_unrecognized[0] = 0xFF
+	accCopy.XXX_unrecognized[0] = 0xFF
+
+	assert.Equal(t, Bytecode{1, 2, 3}, acc.EVMCode)
+	assert.Equal(t, Bytecode{4, 5, 6}, acc.WASMCode)
+	assert.Equal(t, publicKey, []byte(acc.PublicKey.PublicKey))
+	assert.Equal(t, []string{"admin"}, acc.Permissions.Roles)
+	assert.Equal(t, []byte{7}, acc.Permissions.XXX_unrecognized)
+	assert.Equal(t, []byte{8}, acc.Permissions.Base.XXX_unrecognized)
+	assert.Equal(t, []byte{9}, acc.XXX_unrecognized)
+
+	// Nor does mutating the original change the copy
+	accCopy = acc.Copy()
+	acc.EVMCode[1] = 0xFF
+	acc.PublicKey.PublicKey[1] ^= 0xFF
+	assert.Equal(t, Bytecode{1, 2, 3}, accCopy.EVMCode)
+	assert.Equal(t, publicKey, []byte(accCopy.PublicKey.PublicKey))
+
+	// Absent code stays absent
+	acc.WASMCode = nil
+	assert.Nil(t, acc.Copy().WASMCode)
+}
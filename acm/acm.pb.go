@@ -36,6 +36,7 @@ type Account struct {
 	EVMCode              Bytecode                                     `protobuf:"bytes,5,opt,name=EVMCode,proto3,customtype=Bytecode" json:"EVMCode"`
 	Permissions          permission.AccountPermissions                `protobuf:"bytes,6,opt,name=Permissions,proto3" json:"Permissions"`
 	WASMCode             Bytecode                                     `protobuf:"bytes,7,opt,name=WASMCode,proto3,customtype=Bytecode" json:",omitempty"`
+	GasCredit            uint64                                       `protobuf:"varint,8,opt,name=GasCredit,proto3" json:"GasCredit,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}                                     `json:"-"`
 	XXX_unrecognized     []byte                                       `json:"-"`
 	XXX_sizecache        int32                                        `json:"-"`
@@ -101,6 +102,13 @@ func (m *Account) GetPermissions() permission.AccountPermissions {
 	return permission.AccountPermissions{}
 }
 
+func (m *Account) GetGasCredit() uint64 {
+	if m != nil {
+		return m.GasCredit
+	}
+	return 0
+}
+
 func (*Account) XXX_MessageName() string {
 	return "acm.Account"
 }
@@ -113,30 +121,31 @@ func init() { proto.RegisterFile("acm.proto", fileDescriptor_49ed775bc0a6adf6) }
 func init() { golang_proto.RegisterFile("acm.proto", fileDescriptor_49ed775bc0a6adf6) }
 
 var fileDescriptor_49ed775bc0a6adf6 = []byte{
-	// 355 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x51, 0xbf, 0x4f, 0xfa, 0x40,
-	0x1c, 0xe5, 0xa0, 0x5f, 0x0a, 0x07, 0x03, 0xdf, 0x9b, 0x1a, 0x86, 0x16, 0x9d, 0x88, 0xc1, 0x36,
-	0xf1, 0x47, 0x4c, 0xd8, 0xa8, 0xd1, 0xc5, 0x68, 0x48, 0x49, 0x34, 0x71, 0x6b, 0xaf, 0x67, 0x69,
-	0xc2, 0x71, 0xf5, 0x7a, 0x8d, 0xe9, 0x7f, 0xe2, 0xe8, 0x9f, 0xe2, 0xc8, 0xe8, 0xec, 0x40, 0x0c,
-	0x6c, 0xfe, 0x0d, 0x0e, 0x86, 0xe3, 0xa8, 0x8d, 0x83, 0x5b, 0x5f, 0xdf, 0x7b, 0x9f, 0xf7, 0xf2,
-	0x0e, 0x36, 0x7d, 0x4c, 0xed, 0x84, 0x33, 0xc1, 0x50, 0xcd, 0xc7, 0xb4, 0x7b, 0x18, 0xc5, 0x62,
-	0x9a, 0x05, 0x36, 0x66, 0xd4, 0x89, 0x58, 0xc4, 0x1c, 0xc9, 0x05, 0xd9, 0x83, 0x44, 0x12, 0xc8,
-	0xaf, 0xad, 0xa7, 0xdb, 0x49, 0x08, 0xa7, 0x71, 0x9a, 0xc6, 0x6c, 0xae, 0xfe, 0xb4, 0x31, 0xcf,
-	0x13, 0xa1, 0xf8, 0xfd, 0xaf, 0x2a, 0xd4, 0x47, 0x18, 0xb3, 0x6c, 0x2e, 0xd0, 0x0d, 0xd4, 0x47,
-	0x61, 0xc8, 0x49, 0x9a, 0x1a, 0xa0, 0x07, 0xfa, 0x6d, 0xf7, 0x64, 0xb1, 0xb4, 0x2a, 0xef, 0x4b,
-	0x6b, 0x50, 0xca, 0x9c, 0xe6, 0x09, 0xe1, 0x33, 0x12, 0x46, 0x84, 0x3b, 0x41, 0xc6, 0x39, 0x7b,
-	0x72, 0xd4, 0x41, 0xe5, 0xf5, 0x76, 0x47, 0xd0, 0x29, 0x6c, 0x8e, 0xb3, 0x60, 0x16, 0xe3, 0x2b,
-	0x92, 0x1b, 0xd5, 0x1e, 0xe8, 0xb7, 0x8e, 0xfe, 0xdb, 0x4a, 0x5c, 0x10, 0xae, 0xb6, 0x09, 0xf1,
-	0x7e, 0x94, 0xa8, 0x0b, 0x1b, 0x13, 0xf2, 0x98, 0x91, 0x39, 0x26, 0x46, 0xad, 0x07, 0xfa, 0x9a,
-	0x57, 0x60, 0x64, 0x40, 0xdd, 0xf5, 0x67, 0xfe, 0x86, 0xd2, 0x24, 0xb5, 0x83, 0xe8, 0x00, 0xea,
-	0x17, 0xb7, 0xd7, 0xe7, 0x2c, 0x24, 0xc6, 0x3f, 0x59, 0xbe, 0xa3, 0xca, 0x37, 0xdc, 0x5c, 0x10,
-	0xcc, 0x42, 0xe2, 0xed, 0x04, 0xe8, 0x12, 0xb6, 0xc6, 0xc5, 0x2c, 0xa9, 0x51, 0x97, 0xd5, 0x4c,
-	0xbb, 0x34, 0x95, 0x9a, 0xa4, 0xa4, 0x52, 0x3d, 0xcb, 0x46, 0x34, 0x84, 0x8d, 0xbb, 0xd1, 0x64,
-	0x1b, 0xaa, 0xcb, 0x50, 0xf3, 0x77, 0xe8, 0xe7, 0xd2, 0x82, 0x03, 0x46, 0x63, 0x41, 0x68, 0x22,
-	0x72, 0xaf, 0xd0, 0x0f, 0xb5, 0xe7, 0x17, 0xab, 0xe2, 0x9e, 0x2d, 0x56, 0x26, 0x78, 0x5b, 0x99,
-	0xe0, 0x63, 0x65, 0x82, 0xd7, 0xb5, 0x09, 0x16, 0x6b, 0x13, 0xdc, 0xef, 0xfd, 0xbd, 0xb7, 0x8f,
-	0x69, 0x50, 0x97, 0xcf, 0x77, 0xfc, 0x1d, 0x00, 0x00, 0xff, 0xff, 0x65, 0x02, 0xa3, 0x8e, 0x1f,
-	0x02, 0x00, 0x00,
+	// 370 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x51, 0x4d, 0x4f, 0xea, 0x40,
+	0x14, 0x65, 0x1e, 0x7d, 0xb4, 0x0c, 0x2c, 0x78, 0xb3, 0x6a, 0xc8, 0x4b, 0x8b, 0xae, 0x88, 0xc1,
+	0x36, 0xf1, 0x23, 0x26, 0xec, 0x28, 0x51, 0x17, 0x46, 0x43, 0x4a, 0xa2, 0x89, 0xbb, 0x76, 0x3a,
+	0x96, 0x26, 0x94, 0xa9, 0xd3, 0x69, 0x4c, 0xff, 0x89, 0x71, 0xe5, 0x4f, 0x71, 0xc9, 0xd2, 0xb5,
+	0x0b, 0x62, 0x60, 0xe7, 0xaf, 0x30, 0x0c, 0x43, 0x69, 0x5c, 0xb8, 0x9b, 0x73, 0xcf, 0xb9, 0xf7,
+	0x9c, 0xb9, 0x17, 0xd6, 0x3d, 0x1c, 0x5b, 0x09, 0xa3, 0x9c, 0xa2, 0xaa, 0x87, 0xe3, 0xf6, 0x61,
+	0x18, 0xf1, 0x49, 0xe6, 0x5b, 0x98, 0xc6, 0x76, 0x48, 0x43, 0x6a, 0x0b, 0xce, 0xcf, 0x1e, 0x04,
+	0x12, 0x40, 0xbc, 0x36, 0x3d, 0xed, 0x56, 0x42, 0x58, 0x1c, 0xa5, 0x69, 0x44, 0x67, 0xb2, 0xd2,
+	0xc4, 0x2c, 0x4f, 0xb8, 0xe4, 0xf7, 0x5f, 0xaa, 0x50, 0x1d, 0x60, 0x4c, 0xb3, 0x19, 0x47, 0x37,
+	0x50, 0x1d, 0x04, 0x01, 0x23, 0x69, 0xaa, 0x83, 0x0e, 0xe8, 0x36, 0x9d, 0x93, 0xf9, 0xc2, 0xac,
+	0x7c, 0x2c, 0xcc, 0x5e, 0xc9, 0x73, 0x92, 0x27, 0x84, 0x4d, 0x49, 0x10, 0x12, 0x66, 0xfb, 0x19,
+	0x63, 0xf4, 0xc9, 0x96, 0x03, 0x65, 0xaf, 0xbb, 0x1d, 0x82, 0x4e, 0x61, 0x7d, 0x94, 0xf9, 0xd3,
+	0x08, 0x5f, 0x91, 0x5c, 0xff, 0xd3, 0x01, 0xdd, 0xc6, 0xd1, 0x3f, 0x4b, 0x8a, 0x0b, 0xc2, 0x51,
+	0xd6, 0x26, 0xee, 0x4e, 0x89, 0xda, 0x50, 0x1b, 0x93, 0xc7, 0x8c, 0xcc, 0x30, 0xd1, 0xab, 0x1d,
+	0xd0, 0x55, 0xdc, 0x02, 0x23, 0x1d, 0xaa, 0x8e, 0x37, 0xf5, 0xd6, 0x94, 0x22, 0xa8, 0x2d, 0x44,
+	0x07, 0x50, 0x3d, 0xbf, 0xbd, 0x1e, 0xd2, 0x80, 0xe8, 0x7f, 0x45, 0xf8, 0x96, 0x0c, 0xaf, 0x39,
+	0x39, 0x27, 0x98, 0x06, 0xc4, 0xdd, 0x0a, 0xd0, 0x05, 0x6c, 0x8c, 0x8a, 0xb5, 0xa4, 0x7a, 0x4d,
+	0x44, 0x33, 0xac, 0xd2, 0xaa, 0xe4, 0x4a, 0x4a, 0x2a, 0x99, 0xb3, 0xdc, 0x88, 0xfa, 0x50, 0xbb,
+	0x1b, 0x8c, 0x37, 0xa6, 0xaa, 0x30, 0x35, 0x7e, 0x9a, 0x7e, 0x2d, 0x4c, 0xd8, 0xa3, 0x71, 0xc4,
+	0x49, 0x9c, 0xf0, 0xdc, 0x2d, 0xf4, 0xe8, 0x3f, 0xac, 0x5f, 0x7a, 0xe9, 0x90, 0x91, 0x20, 0xe2,
+	0xba, 0x26, 0xfe, 0xb2, 0x2b, 0xf4, 0x95, 0xe7, 0x57, 0xb3, 0xe2, 0x9c, 0xcd, 0x97, 0x06, 0x78,
+	0x5f, 0x1a, 0xe0, 0x73, 0x69, 0x80, 0xb7, 0x95, 0x01, 0xe6, 0x2b, 0x03, 0xdc, 0xef, 0xfd, 0x7e,
+	0x0d, 0x0f, 0xc7, 0x7e, 0x4d, 0x1c, 0xf7, 0xf8, 0x7b, 0x00, 0x24, 0x0f, 0x58, 0x03, 0x3d, 0x02,
+	0x00, 0x00,
 }
 
 func (m *Account) Marshal() (dAtA []byte, err error) {
@@ -204,6 +213,11 @@ func (m *Account) MarshalTo(dAtA []byte) (int, error) {
 		return 0, err
 	}
 	i += n5
+	if m.GasCredit != 0 {
+		dAtA[i] = 0x40
+		i++
+		i = encodeVarintAcm(dAtA, i, uint64(m.GasCredit))
+	}
 	if m.XXX_unrecognized != nil {
 		i += copy(dAtA[i:], m.XXX_unrecognized)
 	}
@@ -241,6 +255,9 @@ func (m *Account) Size() (n int) {
 	n += 1 + l + sovAcm(uint64(l))
 	l = m.WASMCode.Size()
 	n += 1 + l + sovAcm(uint64(l))
+	if m.GasCredit != 0 {
+		n += 1 + sovAcm(uint64(m.GasCredit))
+	}
 	if m.XXX_unrecognized != nil {
 		n += len(m.XXX_unrecognized)
 	}
@@ -492,6 +509,25 @@ func (m *Account) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field GasCredit", wireType)
+			}
+			m.GasCredit = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowAcm
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.GasCredit |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipAcm(dAtA[iNdEx:])
@@ -96,7 +96,11 @@ type IterableReaderWriter interface {
 	Writer
 }
 
+// Get the account at GlobalPermissionsAddress, or nil if there is no getter
 func GlobalPermissionsAccount(getter AccountGetter) *acm.Account {
+	if getter == nil {
+		return nil
+	}
 	acc, err := getter.GetAccount(acm.GlobalPermissionsAddress)
 	if err != nil {
 		panic("Could not get global permission account, but this must exist")
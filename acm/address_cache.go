@@ -0,0 +1,56 @@
+package acm
+
+import (
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/hyperledger/burrow/crypto"
+)
+
+// The number of public keys whose addresses are remembered by AddressFromPublicKeyCached unless changed with
+// SetAddressCacheSize
+const DefaultAddressCacheSize = 4096
+
+var addressCache = struct {
+	sync.RWMutex
+	*lru.Cache
+}{Cache: newAddressCache(DefaultAddressCacheSize)}
+
+// The derivation is a variable so tests can count the hashing done
+var deriveAddress = crypto.PublicKey.GetAddress
+
+// AddressFromPublicKeyCached returns the address derived from publicKey like publicKey.GetAddress() but remembers the
+// addresses of recently seen keys, so converting the same key repeatedly (for example when loading many accounts that
+// share keys) only hashes it once. It is safe to call concurrently.
+func AddressFromPublicKeyCached(publicKey crypto.PublicKey) crypto.Address {
+	addressCache.RLock()
+	cache := addressCache.Cache
+	addressCache.RUnlock()
+	if cache == nil || !publicKey.IsSet() {
+		return deriveAddress(publicKey)
+	}
+	key := string(publicKey.EncodeFixedWidth())
+	if address, ok := cache.Get(key); ok {
+		return address.(crypto.Address)
+	}
+	address := deriveAddress(publicKey)
+	cache.Add(key, address)
+	return address
+}
+
+// SetAddressCacheSize replaces the cache used by AddressFromPublicKeyCached (and so NewAccount) with an empty one
+// holding up to size addresses, a size of zero or less disables the cache
+func SetAddressCacheSize(size int) {
+	addressCache.Lock()
+	defer addressCache.Unlock()
+	addressCache.Cache = newAddressCache(size)
+}
+
+func newAddressCache(size int) *lru.Cache {
+	if size <= 0 {
+		return nil
+	}
+	// Only fails for a non-positive size
+	cache, _ := lru.New(size)
+	return cache
+}
@@ -0,0 +1,96 @@
+package acm
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddressFromPublicKeyCached(t *testing.T) {
+	defer SetAddressCacheSize(DefaultAddressCacheSize)
+	derivations, restore := countDerivations()
+	defer restore()
+
+	publicKeys := sharedPublicKeys(10)
+	SetAddressCacheSize(DefaultAddressCacheSize)
+	for i := 0; i < 100; i++ {
+		publicKey := publicKeys[i%len(publicKeys)]
+		assert.Equal(t, publicKey.GetAddress(), NewAccount(publicKey).Address)
+	}
+	// Each key was only hashed the first time it was seen
+	assert.Equal(t, len(publicKeys), *derivations)
+
+	// Keys are told apart by curve as well as bytes
+	secp256k1 := crypto.PrivateKeyFromSecret("Secp256k1", crypto.CurveTypeSecp256k1).GetPublicKey()
+	assert.Equal(t, secp256k1.GetAddress(), AddressFromPublicKeyCached(secp256k1))
+
+	SetAddressCacheSize(0)
+	*derivations = 0
+	for i := 0; i < 100; i++ {
+		publicKey := publicKeys[i%len(publicKeys)]
+		assert.Equal(t, publicKey.GetAddress(), AddressFromPublicKeyCached(publicKey))
+	}
+	assert.Equal(t, 100, *derivations)
+}
+
+func TestAddressFromPublicKeyCachedConcurrent(t *testing.T) {
+	defer SetAddressCacheSize(DefaultAddressCacheSize)
+	SetAddressCacheSize(4)
+
+	publicKeys := sharedPublicKeys(10)
+	wg := new(sync.WaitGroup)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				publicKey := publicKeys[(g+i)%len(publicKeys)]
+				if AddressFromPublicKeyCached(publicKey) != publicKey.GetAddress() {
+					t.Errorf("wrong address for %v", publicKey)
+					return
+				}
+				if i == 100 && g == 0 {
+					SetAddressCacheSize(8)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// Loading many accounts that share a few keys hashes each key once with the cache and once per account without it
+func BenchmarkNewAccountSharedKeys(b *testing.B) {
+	defer SetAddressCacheSize(DefaultAddressCacheSize)
+	publicKeys := sharedPublicKeys(16)
+
+	for _, size := range []int{0, DefaultAddressCacheSize} {
+		b.Run(fmt.Sprintf("cache size %d", size), func(b *testing.B) {
+			SetAddressCacheSize(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				NewAccount(publicKeys[i%len(publicKeys)])
+			}
+		})
+	}
+}
+
+func sharedPublicKeys(n int) []crypto.PublicKey {
+	publicKeys := make([]crypto.PublicKey, n)
+	for i := range publicKeys {
+		publicKeys[i] = crypto.PrivateKeyFromSecret(fmt.Sprintf("Shared%d", i), crypto.CurveTypeEd25519).GetPublicKey()
+	}
+	return publicKeys
+}
+
+func countDerivations() (derivations *int, restore func()) {
+	derivations = new(int)
+	derive := deriveAddress
+	deriveAddress = func(publicKey crypto.PublicKey) crypto.Address {
+		*derivations++
+		return derive(publicKey)
+	}
+	return derivations, func() { deriveAddress = derive }
+}
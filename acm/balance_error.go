@@ -0,0 +1,62 @@
+package acm
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/errors"
+)
+
+// BalanceOperation is the change to a balance attempted by an operation that returned a BalanceError
+type BalanceOperation string
+
+const (
+	AddBalance      BalanceOperation = "add"
+	SubtractBalance BalanceOperation = "subtract"
+	AddGas          BalanceOperation = "add gas"
+	SpendGas        BalanceOperation = "spend gas"
+)
+
+// BalanceError is returned by AddToBalance and SubtractFromBalance, and by AddGas and SpendGas for the gas credit, so
+// that callers can recover (by type assertion or errors.As) the account and amounts involved, for example to build a
+// precise message for a user. It is a CodedError with ErrorCodeIntegerOverflow, ErrorCodeInsufficientBalance, or
+// ErrorCodeInsufficientGas so existing error code handling applies.
+type BalanceError struct {
+	Address   crypto.Address
+	Operation BalanceOperation
+	// The amount that could not be added or subtracted
+	Amount uint64
+	// The balance of the account, or its gas credit for AddGas and SpendGas, which is left unchanged
+	Balance uint64
+	Code    errors.Code
+}
+
+var _ errors.CodedError = &BalanceError{}
+
+func (be *BalanceError) ErrorCode() errors.Code {
+	return be.Code
+}
+
+func (be *BalanceError) String() string {
+	switch be.Operation {
+	case AddBalance:
+		return fmt.Sprintf("uint64 overflow: attempt to add %v to the balance %v of %s", be.Amount, be.Balance,
+			be.Address)
+	case SubtractBalance:
+		return fmt.Sprintf("insufficient funds: attempt to subtract %v from the balance %v of %s", be.Amount,
+			be.Balance, be.Address)
+	case AddGas:
+		return fmt.Sprintf("uint64 overflow: attempt to add %v to the gas credit %v of %s", be.Amount, be.Balance,
+			be.Address)
+	case SpendGas:
+		return fmt.Sprintf("insufficient gas: attempt to spend %v from the gas credit %v of %s", be.Amount,
+			be.Balance, be.Address)
+	default:
+		return fmt.Sprintf("could not %s %v with balance %v of %s", be.Operation, be.Amount, be.Balance, be.Address)
+	}
+}
+
+// Error formats the error in the same way as an Exception with the same code
+func (be *BalanceError) Error() string {
+	return errors.NewException(be.Code, be.String()).Error()
+}
@@ -0,0 +1,118 @@
+package acm
+
+import (
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/permission"
+)
+
+// AccountBuilder accumulates the fields of an Account so that it can be constructed in a single expression, e.g.:
+//
+//	acc, err := acm.NewAccountBuilder(address).WithBalance(100).WithRoles("admin").Build()
+type AccountBuilder struct {
+	address     crypto.Address
+	publicKey   crypto.PublicKey
+	balance     uint64
+	sequence    uint64
+	code        Bytecode
+	permissions *permission.AccountPermissions
+	roles       []string
+	roleCase    permission.RoleCase
+}
+
+// NewAccountBuilder starts building an account at address (which will be replaced by the address derived from any
+// public key provided with WithPublicKey)
+func NewAccountBuilder(address crypto.Address) *AccountBuilder {
+	return &AccountBuilder{
+		address: address,
+	}
+}
+
+func (ab *AccountBuilder) WithPublicKey(publicKey crypto.PublicKey) *AccountBuilder {
+	ab.publicKey = publicKey
+	ab.address = publicKey.GetAddress()
+	return ab
+}
+
+func (ab *AccountBuilder) WithBalance(balance uint64) *AccountBuilder {
+	ab.balance = balance
+	return ab
+}
+
+func (ab *AccountBuilder) WithSequence(sequence uint64) *AccountBuilder {
+	ab.sequence = sequence
+	return ab
+}
+
+func (ab *AccountBuilder) WithCode(code Bytecode) *AccountBuilder {
+	ab.code = code
+	return ab
+}
+
+// WithPermissions sets the base permissions (and any roles) of the account, roles passed to WithRoles are added to
+// these
+func (ab *AccountBuilder) WithPermissions(permissions permission.AccountPermissions) *AccountBuilder {
+	perms := permissions.Clone()
+	ab.permissions = &perms
+	return ab
+}
+
+func (ab *AccountBuilder) WithRoles(roles ...string) *AccountBuilder {
+	ab.roles = append(ab.roles, roles...)
+	return ab
+}
+
+// WithRoleCase normalises the roles of the account (including any given with WithPermissions) with roleCase, so roles
+// given in different cases are the same role
+func (ab *AccountBuilder) WithRoleCase(roleCase permission.RoleCase) *AccountBuilder {
+	ab.roleCase = roleCase
+	return ab
+}
+
+// Build returns the Account with empty rather than nil code and roles (as with FromAddressable) and checks it with
+// Validate
+func (ab *AccountBuilder) Build() (*Account, error) {
+	acc := &Account{
+		Address:   ab.address,
+		PublicKey: ab.publicKey,
+		Sequence:  ab.sequence,
+		Balance:   ab.balance,
+		// Since nil slices and maps compare differently to empty ones
+		EVMCode: Bytecode{},
+		Permissions: permission.AccountPermissions{
+			Roles: []string{},
+		},
+	}
+	if ab.code != nil {
+		acc.EVMCode = make(Bytecode, len(ab.code))
+		copy(acc.EVMCode, ab.code)
+	}
+	var roles []string
+	if ab.permissions != nil {
+		acc.Permissions.Base = ab.permissions.Base
+		roles = append(roles, ab.permissions.Roles...)
+	}
+	err := ab.roleCase.Validate()
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range append(roles, ab.roles...) {
+		role = ab.roleCase.Normalize(role)
+		if !containsRole(acc.Permissions.Roles, role) {
+			acc.Permissions.Roles = append(acc.Permissions.Roles, role)
+		}
+	}
+	err = acc.Validate()
+	if err != nil {
+		return nil, err
+	}
+	return acc, nil
+}
+
+func containsRole(roles []string, role string) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package acm
+
+import (
+	"testing"
+
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/solidity"
+	"github.com/hyperledger/burrow/permission"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountBuilder(t *testing.T) {
+	publicKey := crypto.PrivateKeyFromSecret("Builder", crypto.CurveTypeEd25519).GetPublicKey()
+
+	t.Run("EOA", func(t *testing.T) {
+		acc, err := NewAccountBuilder(crypto.Address{}).WithPublicKey(publicKey).Build()
+		require.NoError(t, err)
+		assert.Equal(t, FromAddressable(crypto.NewAddressable(publicKey)), acc)
+	})
+
+	t.Run("Contract", func(t *testing.T) {
+		address := crypto.Address{1, 2, 3}
+		perms := permission.NewAccountPermissions(permission.Send, permission.Call)
+		perms.Roles = []string{"bar"}
+		acc, err := NewAccountBuilder(address).
+			WithBalance(1000).
+			WithSequence(3).
+			WithCode(solidity.Bytecode_StrangeLoop).
+			WithPermissions(perms).
+			WithRoles("foo", "bar").
+			Build()
+		require.NoError(t, err)
+		assert.Equal(t, address, acc.Address)
+		assert.False(t, acc.PublicKey.IsSet())
+		assert.Equal(t, uint64(1000), acc.Balance)
+		assert.Equal(t, uint64(3), acc.Sequence)
+		assert.Equal(t, Bytecode(solidity.Bytecode_StrangeLoop), acc.EVMCode)
+		assert.Equal(t, perms.Base, acc.Permissions.Base)
+		assert.Equal(t, []string{"bar", "foo"}, acc.Permissions.Roles)
+	})
+
+	t.Run("RoleCase", func(t *testing.T) {
+		perms := permission.NewAccountPermissions(permission.Send)
+		perms.Roles = []string{"Admin"}
+		acc, err := NewAccountBuilder(crypto.Address{1}).
+			WithPermissions(perms).
+			WithRoles("ADMIN", "Minter").
+			WithRoleCase(permission.RoleCaseLower).
+			Build()
+		require.NoError(t, err)
+		assert.Equal(t, []string{"admin", "minter"}, acc.Permissions.Roles)
+
+		// Without normalisation roles differing only in case are rejected
+		_, err = NewAccountBuilder(crypto.Address{1}).WithPermissions(perms).WithRoles("ADMIN").Build()
+		require.Error(t, err)
+
+		_, err = NewAccountBuilder(crypto.Address{1}).WithRoleCase("title").Build()
+		require.Error(t, err)
+	})
+
+	t.Run("InvalidPermissions", func(t *testing.T) {
+		perms := permission.NewAccountPermissions(permission.AllPermFlags + 1)
+		_, err := NewAccountBuilder(crypto.Address{1}).WithPermissions(perms).Build()
+		require.Error(t, err)
+	})
+}
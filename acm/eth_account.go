@@ -0,0 +1,113 @@
+package acm
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	hex "github.com/tmthrgd/go-hex"
+
+	"github.com/hyperledger/burrow/crypto"
+)
+
+// ethAccount is the Ethereum-style JSON form of Account used by MarshalEthJSON and UnmarshalEthJSON. The fields
+// correspond as follows:
+//
+//	address     <-> Address, as 0x-prefixed lowercase hex
+//	balance     <-> Balance, as an Ethereum hex quantity
+//	nonce       <-> Sequence, as an Ethereum hex quantity
+//	code        <-> EVMCode, as 0x-prefixed hex
+//	storageRoot  -  not produced since account storage is held in the state rather than the Account, ignored when read
+//
+// The PublicKey, Permissions (including Roles), WASMCode, and GasCredit of an Account have no Ethereum analog so are
+// dropped on export and left zero on import.
+type ethAccount struct {
+	Address     string `json:"address"`
+	Balance     string `json:"balance"`
+	Nonce       string `json:"nonce"`
+	Code        string `json:"code"`
+	StorageRoot string `json:"storageRoot,omitempty"`
+}
+
+// MarshalEthJSON encodes the account in a JSON shape resembling the account JSON of Ethereum tooling for migrations
+// to and from Ethereum-derived chains, see ethAccount for the fields carried
+func (acc *Account) MarshalEthJSON() ([]byte, error) {
+	return json.Marshal(ethAccount{
+		Address: "0x" + hex.EncodeToString(acc.Address[:]),
+		Balance: encodeEthQuantity(acc.Balance),
+		Nonce:   encodeEthQuantity(acc.Sequence),
+		Code:    "0x" + hex.EncodeToString(acc.EVMCode),
+	})
+}
+
+// UnmarshalEthJSON replaces the account with one decoded from the Ethereum-style JSON produced by MarshalEthJSON (or
+// Ethereum tooling). Fields with no Ethereum analog are left zero, so the account has no permissions of its own.
+func (acc *Account) UnmarshalEthJSON(data []byte) error {
+	ea := new(ethAccount)
+	err := json.Unmarshal(data, ea)
+	if err != nil {
+		return err
+	}
+	bs, err := decodeEthHex(ea.Address)
+	if err != nil {
+		return fmt.Errorf("could not decode Ethereum address: %v", err)
+	}
+	address, err := crypto.AddressFromBytes(bs)
+	if err != nil {
+		return fmt.Errorf("could not decode Ethereum address: %v", err)
+	}
+	balance, err := decodeEthQuantity(ea.Balance)
+	if err != nil {
+		return fmt.Errorf("could not decode balance of Ethereum account %v: %v", address, err)
+	}
+	sequence, err := decodeEthQuantity(ea.Nonce)
+	if err != nil {
+		return fmt.Errorf("could not decode nonce of Ethereum account %v: %v", address, err)
+	}
+	code, err := decodeEthHex(ea.Code)
+	if err != nil {
+		return fmt.Errorf("could not decode code of Ethereum account %v: %v", address, err)
+	}
+	*acc = Account{
+		Address:  address,
+		Balance:  balance,
+		Sequence: sequence,
+	}
+	if len(code) > 0 {
+		acc.EVMCode = code
+	}
+	return nil
+}
+
+// encodeEthQuantity encodes i as an Ethereum hex quantity: 0x-prefixed lowercase hex without leading zeros
+func encodeEthQuantity(i uint64) string {
+	return "0x" + strconv.FormatUint(i, 16)
+}
+
+// decodeEthQuantity decodes an Ethereum hex quantity (treating an absent one as zero), quantities that do not fit in
+// a uint64 cannot be held by an Account
+func decodeEthQuantity(str string) (uint64, error) {
+	if str == "" {
+		return 0, nil
+	}
+	if !strings.HasPrefix(str, "0x") && !strings.HasPrefix(str, "0X") {
+		return 0, fmt.Errorf("hex quantity '%s' should start with 0x", str)
+	}
+	i, err := strconv.ParseUint(str[2:], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not decode hex quantity '%s': %v", str, err)
+	}
+	return i, nil
+}
+
+// decodeEthHex decodes 0x-prefixed hex (treating absent hex as no bytes)
+func decodeEthHex(str string) ([]byte, error) {
+	if str == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(str, "0x") && !strings.HasPrefix(str, "0X") {
+		return nil, fmt.Errorf("hex '%s' should start with 0x", str)
+	}
+	return hex.DecodeString(str[2:])
+}
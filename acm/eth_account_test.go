@@ -0,0 +1,76 @@
+package acm
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/burrow/permission"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEthJSON(t *testing.T) {
+	full := NewAccountFromSecret("Super Semi Secret")
+	full.Sequence = 7
+	full.Balance = math.MaxUint64
+	full.EVMCode = Bytecode{0x60, 0x01}
+
+	for name, acc := range map[string]*Account{
+		"zero": {},
+		"full": full,
+	} {
+		t.Run(name, func(t *testing.T) {
+			bs, err := acc.MarshalEthJSON()
+			require.NoError(t, err)
+			accOut := new(Account)
+			require.NoError(t, accOut.UnmarshalEthJSON(bs))
+			assert.Equal(t, acc.Address, accOut.Address)
+			assert.Equal(t, acc.Sequence, accOut.Sequence)
+			assert.Equal(t, acc.Balance, accOut.Balance)
+			assert.Equal(t, acc.EVMCode, accOut.EVMCode)
+		})
+	}
+
+	bs, err := full.MarshalEthJSON()
+	require.NoError(t, err)
+	address := "0x" + strings.ToLower(full.Address.String())
+	assert.JSONEq(t, `{"address":"`+address+`","balance":"0xffffffffffffffff","nonce":"0x7","code":"0x6001"}`,
+		string(bs))
+
+	// Fields without an Ethereum analog are dropped
+	acc := full.Copy()
+	acc.GasCredit = 3
+	acc.WASMCode = Bytecode{0x00, 0x61, 0x73, 0x6d}
+	acc.Permissions = permission.AccountPermissions{
+		Base:  permission.BasePermissions{Perms: permission.Send, SetBit: permission.Send},
+		Roles: []string{"bums"},
+	}
+	bs, err = acc.MarshalEthJSON()
+	require.NoError(t, err)
+	accOut := new(Account)
+	require.NoError(t, accOut.UnmarshalEthJSON(bs))
+	assert.Equal(t, &Account{Address: full.Address, Sequence: 7, Balance: math.MaxUint64, EVMCode: Bytecode{0x60, 0x01}},
+		accOut)
+
+	// Ethereum tooling may include a storage root and leave out empty fields
+	accOut = new(Account)
+	require.NoError(t, accOut.UnmarshalEthJSON([]byte(`{"address":"0x00000000000000000000000000000000000000ff",
+		"balance":"0x0DE0B6B3A7640000","storageRoot":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"}`)))
+	assert.Equal(t, uint64(1000000000000000000), accOut.Balance)
+	assert.Equal(t, uint64(0), accOut.Sequence)
+	assert.Nil(t, accOut.EVMCode)
+	assert.Equal(t, byte(0xff), accOut.Address[19])
+
+	for name, data := range map[string]string{
+		"bad json":            `flungepliffery`,
+		"short address":       `{"address":"0x01"}`,
+		"unprefixed balance":  `{"address":"0x00000000000000000000000000000000000000ff","balance":"10"}`,
+		"overflowing balance": `{"address":"0x00000000000000000000000000000000000000ff","balance":"0x10000000000000000"}`,
+		"bad code":            `{"address":"0x00000000000000000000000000000000000000ff","code":"0xzz"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, new(Account).UnmarshalEthJSON([]byte(data)))
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package acm
+
+import (
+	"github.com/hyperledger/burrow/execution/errors"
+	"github.com/hyperledger/burrow/permission"
+)
+
+// MergeRule decides how a field of an overlay account combines with the same field of a base account
+type MergeRule uint8
+
+const (
+	// The overlay's value replaces the base's
+	MergeOverlay MergeRule = iota
+	// The values are combined as described for each field of MergePolicy
+	MergeCombine
+)
+
+// MergePolicy gives the MergeRule for each field of the accounts passed to Merge. The zero value takes every field
+// from the overlay.
+type MergePolicy struct {
+	// MergeCombine sums the balances
+	Balance MergeRule
+	// MergeCombine takes the greater sequence
+	Sequence MergeRule
+	// MergeCombine takes the union of the roles, with those of base first
+	Roles MergeRule
+	// MergeCombine grants a base permission if it is granted by either account and sets it if set by either
+	Permissions MergeRule
+	// MergeCombine takes the code of the overlay if it has any, otherwise that of base
+	Code MergeRule
+	// MergeCombine sums the gas credits
+	GasCredit MergeRule
+}
+
+// Merge returns a new account combining overlay with base according to policy, for example to apply a delta to an
+// account from a snapshot. The accounts must have the same address and may not have different public keys. If either
+// account is nil a copy of the other is returned. The merged account shares no memory with either input.
+func Merge(base, overlay *Account, policy MergePolicy) (*Account, error) {
+	if base == nil {
+		return overlay.Copy(), nil
+	}
+	if overlay == nil {
+		return base.Copy(), nil
+	}
+	if base.Address != overlay.Address {
+		return nil, errors.ErrorCodef(errors.ErrorCodeInvalidAddress,
+			"cannot merge account %v into account %v with a different address", overlay.Address, base.Address)
+	}
+	merged := base.Copy()
+	// Take fields from a copy of the overlay so the merged account does not share its byte slices
+	overlay = overlay.Copy()
+
+	if overlay.PublicKey.IsSet() {
+		if merged.PublicKey.IsSet() && !merged.PublicKeyEqual(overlay.PublicKey) {
+			return nil, errors.ErrorCodef(errors.ErrorCodeInvalidAddress,
+				"cannot merge accounts for %v with different public keys %v and %v", base.Address,
+				base.PublicKey, overlay.PublicKey)
+		}
+		merged.PublicKey = overlay.PublicKey
+	}
+
+	switch policy.Balance {
+	case MergeCombine:
+		err := merged.AddToBalance(overlay.Balance)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		merged.Balance = overlay.Balance
+	}
+
+	switch policy.GasCredit {
+	case MergeCombine:
+		err := merged.AddGas(overlay.GasCredit)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		merged.GasCredit = overlay.GasCredit
+	}
+
+	switch policy.Sequence {
+	case MergeCombine:
+		if overlay.Sequence > merged.Sequence {
+			merged.Sequence = overlay.Sequence
+		}
+	default:
+		merged.Sequence = overlay.Sequence
+	}
+
+	switch policy.Roles {
+	case MergeCombine:
+		// Roles are compared as stored rather than with AddRole, which pads them
+		roles := make(map[string]bool, len(merged.Permissions.Roles))
+		for _, role := range merged.Permissions.Roles {
+			roles[role] = true
+		}
+		for _, role := range overlay.Permissions.Roles {
+			if !roles[role] {
+				roles[role] = true
+				merged.Permissions.Roles = append(merged.Permissions.Roles, role)
+			}
+		}
+	default:
+		merged.Permissions.Roles = overlay.Permissions.Roles
+	}
+
+	switch policy.Permissions {
+	case MergeCombine:
+		baseBase, overlayBase := base.Permissions.Base, overlay.Permissions.Base
+		// Only the permissions that are set count
+		merged.Permissions.Base = permission.BasePermissions{
+			Perms:  baseBase.Perms&baseBase.SetBit | overlayBase.Perms&overlayBase.SetBit,
+			SetBit: baseBase.SetBit | overlayBase.SetBit,
+		}
+	default:
+		merged.Permissions.Base = overlay.Permissions.Base
+	}
+
+	// The kinds of code are taken together so the merged account cannot end up with both
+	switch policy.Code {
+	case MergeCombine:
+		if overlay.IsContract() {
+			merged.EVMCode, merged.WASMCode = overlay.EVMCode, overlay.WASMCode
+		}
+	default:
+		merged.EVMCode, merged.WASMCode = overlay.EVMCode, overlay.WASMCode
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,175 @@
+package acm
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/errors"
+	"github.com/hyperledger/burrow/permission"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeBalance(t *testing.T) {
+	base, overlay := newMergeAccounts()
+	base.Balance = 100
+	overlay.Balance = 23
+
+	merged, err := Merge(base, overlay, MergePolicy{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(23), merged.Balance)
+
+	merged, err = Merge(base, overlay, MergePolicy{Balance: MergeCombine})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(123), merged.Balance)
+	// The inputs are untouched
+	assert.Equal(t, uint64(100), base.Balance)
+
+	overlay.Balance = math.MaxUint64
+	_, err = Merge(base, overlay, MergePolicy{Balance: MergeCombine})
+	require.Error(t, err)
+	assert.Equal(t, errors.ErrorCodeIntegerOverflow, errors.AsException(err).ErrorCode())
+}
+
+func TestMergeGasCredit(t *testing.T) {
+	base, overlay := newMergeAccounts()
+	base.GasCredit = 100
+	overlay.GasCredit = 23
+
+	merged, err := Merge(base, overlay, MergePolicy{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(23), merged.GasCredit)
+
+	merged, err = Merge(base, overlay, MergePolicy{GasCredit: MergeCombine})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(123), merged.GasCredit)
+	assert.Equal(t, uint64(100), base.GasCredit)
+
+	overlay.GasCredit = math.MaxUint64
+	_, err = Merge(base, overlay, MergePolicy{GasCredit: MergeCombine})
+	require.Error(t, err)
+	assert.Equal(t, errors.ErrorCodeIntegerOverflow, errors.AsException(err).ErrorCode())
+}
+
+func TestMergeSequence(t *testing.T) {
+	base, overlay := newMergeAccounts()
+	base.Sequence = 9
+	overlay.Sequence = 4
+
+	merged, err := Merge(base, overlay, MergePolicy{})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), merged.Sequence)
+
+	merged, err = Merge(base, overlay, MergePolicy{Sequence: MergeCombine})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(9), merged.Sequence)
+
+	overlay.Sequence = 10
+	merged, err = Merge(base, overlay, MergePolicy{Sequence: MergeCombine})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), merged.Sequence)
+}
+
+func TestMergeRoles(t *testing.T) {
+	base, overlay := newMergeAccounts()
+	base.Permissions.Roles = []string{"validator", "admin"}
+	overlay.Permissions.Roles = []string{"admin", "auditor"}
+
+	merged, err := Merge(base, overlay, MergePolicy{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"admin", "auditor"}, merged.Permissions.Roles)
+
+	merged, err = Merge(base, overlay, MergePolicy{Roles: MergeCombine})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"validator", "admin", "auditor"}, merged.Permissions.Roles)
+	assert.Equal(t, []string{"validator", "admin"}, base.Permissions.Roles)
+}
+
+func TestMergePermissions(t *testing.T) {
+	base, overlay := newMergeAccounts()
+	base.Permissions.Base = permission.BasePermissions{
+		Perms: permission.Send | permission.Call,
+		// Call is not set so is not granted
+		SetBit: permission.Send,
+	}
+	overlay.Permissions.Base = permission.BasePermissions{
+		Perms:  permission.Bond,
+		SetBit: permission.Bond | permission.Name,
+	}
+
+	merged, err := Merge(base, overlay, MergePolicy{})
+	require.NoError(t, err)
+	assert.Equal(t, overlay.Permissions.Base, merged.Permissions.Base)
+
+	merged, err = Merge(base, overlay, MergePolicy{Permissions: MergeCombine})
+	require.NoError(t, err)
+	assert.Equal(t, permission.BasePermissions{
+		Perms:  permission.Send | permission.Bond,
+		SetBit: permission.Send | permission.Bond | permission.Name,
+	}, merged.Permissions.Base)
+}
+
+func TestMergeCode(t *testing.T) {
+	base, overlay := newMergeAccounts()
+	base.EVMCode = Bytecode{0x60, 0x01}
+
+	// Without code in the overlay the base code is kept when combining
+	merged, err := Merge(base, overlay, MergePolicy{Code: MergeCombine})
+	require.NoError(t, err)
+	assert.Equal(t, base.EVMCode, merged.EVMCode)
+
+	merged, err = Merge(base, overlay, MergePolicy{})
+	require.NoError(t, err)
+	assert.Nil(t, merged.EVMCode)
+
+	// The overlay's code replaces the base's entirely so the result never has both kinds
+	overlay.WASMCode = Bytecode{0x00, 0x61, 0x73, 0x6d}
+	merged, err = Merge(base, overlay, MergePolicy{Code: MergeCombine})
+	require.NoError(t, err)
+	assert.Nil(t, merged.EVMCode)
+	assert.Equal(t, overlay.WASMCode, merged.WASMCode)
+}
+
+func TestMergeIdentity(t *testing.T) {
+	base, overlay := newMergeAccounts()
+	base.PublicKey = crypto.PublicKey{}
+
+	// The public key is added from the overlay
+	merged, err := Merge(base, overlay, MergePolicy{})
+	require.NoError(t, err)
+	assert.Equal(t, overlay.PublicKey, merged.PublicKey)
+
+	// But cannot conflict
+	base.PublicKey = NewAccountFromSecret("Another").PublicKey
+	_, err = Merge(base, overlay, MergePolicy{})
+	require.Error(t, err)
+
+	_, err = Merge(base, NewAccountFromSecret("Another"), MergePolicy{})
+	require.Error(t, err)
+	assert.Equal(t, errors.ErrorCodeInvalidAddress, errors.AsException(err).ErrorCode())
+
+	merged, err = Merge(nil, overlay, MergePolicy{})
+	require.NoError(t, err)
+	assert.Equal(t, overlay.Address, merged.Address)
+	merged, err = Merge(base, nil, MergePolicy{})
+	require.NoError(t, err)
+	assert.Equal(t, base.Address, merged.Address)
+}
+
+func TestMergeDoesNotShareMemory(t *testing.T) {
+	base, overlay := newMergeAccounts()
+	overlay.EVMCode = Bytecode{0x60, 0x01}
+
+	merged, err := Merge(base, overlay, MergePolicy{})
+	require.NoError(t, err)
+
+	merged.EVMCode[0] = 0xff
+	merged.PublicKey.PublicKey[0] ^= 0xff
+	assert.Equal(t, Bytecode{0x60, 0x01}, overlay.EVMCode)
+	assert.Equal(t, NewAccountFromSecret("Merge").PublicKey, overlay.PublicKey)
+}
+
+func newMergeAccounts() (base, overlay *Account) {
+	return NewAccountFromSecret("Merge"), NewAccountFromSecret("Merge")
+}
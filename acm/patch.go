@@ -0,0 +1,247 @@
+package acm
+
+import (
+	"bytes"
+	bin "encoding/binary"
+
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/errors"
+	"github.com/hyperledger/burrow/permission"
+)
+
+// The fields of an account that may appear in a patch, a patch starts with the bitwise or of those it contains
+const (
+	patchBalance byte = 1 << iota
+	patchSequence
+	patchPermissions
+	patchRoles
+	patchEVMCode
+	patchWASMCode
+	patchPublicKey
+	patchGasCredit
+	patchAllFields = patchBalance | patchSequence | patchPermissions | patchRoles | patchEVMCode | patchWASMCode |
+		patchPublicKey | patchGasCredit
+)
+
+// Diff returns a compact patch that ApplyPatch can apply to from to reconstruct to, for example to ship account updates
+// between nodes without sending whole accounts. Only the fields that differ are included: the balance as a signed
+// delta, the sequence, the base permissions, the roles added and removed, any code or public key that changed, and the
+// gas credit.
+// The patch for an unchanged account is empty. The accounts must have the same address.
+//
+// Roles are compared as sets so the order of the roles of the patched account may differ from that of to, the
+// accounts are nonetheless Equal.
+func Diff(from, to *Account) ([]byte, error) {
+	if from == nil || to == nil {
+		return nil, errors.ErrorCodef(errors.ErrorCodeInvalidAddress, "cannot diff a nil account")
+	}
+	if from.Address != to.Address {
+		return nil, errors.ErrorCodef(errors.ErrorCodeInvalidAddress,
+			"cannot diff account %v against account %v with a different address", to.Address, from.Address)
+	}
+	var fields byte
+	body := new(bytes.Buffer)
+
+	if to.Balance != from.Balance {
+		fields |= patchBalance
+		// Wraps around for deltas outside the range of an int64 but applying it wraps back
+		writePatchVarint(body, int64(to.Balance-from.Balance))
+	}
+	if to.Sequence != from.Sequence {
+		fields |= patchSequence
+		writePatchUvarint(body, to.Sequence)
+	}
+	if to.Permissions.Base.Perms != from.Permissions.Base.Perms || to.Permissions.Base.SetBit != from.Permissions.Base.SetBit {
+		fields |= patchPermissions
+		writePatchUvarint(body, uint64(to.Permissions.Base.Perms))
+		writePatchUvarint(body, uint64(to.Permissions.Base.SetBit))
+	}
+	removed := subtractRoles(from.Permissions.Roles, to.Permissions.Roles)
+	added := subtractRoles(to.Permissions.Roles, from.Permissions.Roles)
+	if len(removed) > 0 || len(added) > 0 {
+		fields |= patchRoles
+		writePatchStrings(body, removed)
+		writePatchStrings(body, added)
+	}
+	if !bytes.Equal(to.EVMCode, from.EVMCode) {
+		fields |= patchEVMCode
+		writePatchBytes(body, to.EVMCode)
+	}
+	if !bytes.Equal(to.WASMCode, from.WASMCode) {
+		fields |= patchWASMCode
+		writePatchBytes(body, to.WASMCode)
+	}
+	if !from.PublicKeyEqual(to.PublicKey) {
+		fields |= patchPublicKey
+		body.WriteByte(to.PublicKey.CurveType.Byte())
+		writePatchBytes(body, to.PublicKey.PublicKey)
+	}
+	if to.GasCredit != from.GasCredit {
+		fields |= patchGasCredit
+		writePatchUvarint(body, to.GasCredit)
+	}
+
+	if fields == 0 {
+		return []byte{}, nil
+	}
+	return append([]byte{fields}, body.Bytes()...), nil
+}
+
+// ApplyPatch returns a copy of old with a patch produced by Diff applied to it, old itself is not modified
+func ApplyPatch(old *Account, patch []byte) (*Account, error) {
+	if old == nil {
+		return nil, errors.ErrorCodef(errors.ErrorCodeInvalidAddress, "cannot apply patch to a nil account")
+	}
+	acc := old.Copy()
+	if len(patch) == 0 {
+		return acc, nil
+	}
+	fields := patch[0]
+	if fields&^patchAllFields != 0 {
+		return nil, errors.ErrorCodef(errors.ErrorCodeGeneric, "account patch for %v has unknown fields %#x",
+			old.Address, fields&^patchAllFields)
+	}
+	r := &patchReader{buf: bytes.NewReader(patch[1:])}
+
+	if fields&patchBalance != 0 {
+		acc.Balance += uint64(r.varint())
+	}
+	if fields&patchSequence != 0 {
+		acc.Sequence = r.uvarint()
+	}
+	if fields&patchPermissions != 0 {
+		acc.Permissions.Base.Perms = permission.PermFlag(r.uvarint())
+		acc.Permissions.Base.SetBit = permission.PermFlag(r.uvarint())
+	}
+	if fields&patchRoles != 0 {
+		removed := r.strings()
+		acc.Permissions.Roles = append(subtractRoles(acc.Permissions.Roles, removed), r.strings()...)
+	}
+	if fields&patchEVMCode != 0 {
+		acc.EVMCode = r.bytes()
+	}
+	if fields&patchWASMCode != 0 {
+		acc.WASMCode = r.bytes()
+	}
+	if fields&patchPublicKey != 0 {
+		acc.PublicKey = crypto.PublicKey{CurveType: crypto.CurveType(r.byte())}
+		acc.PublicKey.PublicKey = r.bytes()
+	}
+	if fields&patchGasCredit != 0 {
+		acc.GasCredit = r.uvarint()
+	}
+
+	if r.err == nil && r.buf.Len() > 0 {
+		r.err = errors.ErrorCodef(errors.ErrorCodeGeneric, "%d unexpected trailing bytes", r.buf.Len())
+	}
+	if r.err != nil {
+		return nil, errors.ErrorCodef(errors.ErrorCodeGeneric, "could not apply malformed account patch for %v: %v",
+			old.Address, r.err)
+	}
+	return acc, nil
+}
+
+// Returns the roles of from that are not in roles, in the order of from
+func subtractRoles(from, roles []string) []string {
+	exclude := make(map[string]bool, len(roles))
+	for _, role := range roles {
+		exclude[role] = true
+	}
+	var difference []string
+	for _, role := range from {
+		if !exclude[role] {
+			difference = append(difference, role)
+		}
+	}
+	return difference
+}
+
+func writePatchVarint(buf *bytes.Buffer, i int64) {
+	var bs [bin.MaxVarintLen64]byte
+	buf.Write(bs[:bin.PutVarint(bs[:], i)])
+}
+
+func writePatchUvarint(buf *bytes.Buffer, i uint64) {
+	var bs [bin.MaxVarintLen64]byte
+	buf.Write(bs[:bin.PutUvarint(bs[:], i)])
+}
+
+func writePatchBytes(buf *bytes.Buffer, bs []byte) {
+	writePatchUvarint(buf, uint64(len(bs)))
+	buf.Write(bs)
+}
+
+func writePatchStrings(buf *bytes.Buffer, strs []string) {
+	writePatchUvarint(buf, uint64(len(strs)))
+	for _, str := range strs {
+		writePatchBytes(buf, []byte(str))
+	}
+}
+
+// patchReader reads the fields of a patch, remembering the first error so that it only needs checking once
+type patchReader struct {
+	buf *bytes.Reader
+	err error
+}
+
+func (r *patchReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	var i int64
+	i, r.err = bin.ReadVarint(r.buf)
+	return i
+}
+
+func (r *patchReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	var i uint64
+	i, r.err = bin.ReadUvarint(r.buf)
+	return i
+}
+
+func (r *patchReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	var b byte
+	b, r.err = r.buf.ReadByte()
+	return b
+}
+
+func (r *patchReader) bytes() []byte {
+	length := r.uvarint()
+	if r.err != nil {
+		return nil
+	}
+	if length > uint64(r.buf.Len()) {
+		r.err = errors.ErrorCodef(errors.ErrorCodeGeneric, "field of %d bytes overruns the %d remaining", length,
+			r.buf.Len())
+		return nil
+	}
+	bs := make([]byte, length)
+	if length > 0 {
+		_, r.err = r.buf.Read(bs)
+	}
+	return bs
+}
+
+func (r *patchReader) strings() []string {
+	n := r.uvarint()
+	if r.err != nil {
+		return nil
+	}
+	// Each string takes at least a byte for its length
+	if n > uint64(r.buf.Len()) {
+		r.err = errors.ErrorCodef(errors.ErrorCodeGeneric, "%d strings overrun the %d bytes remaining", n,
+			r.buf.Len())
+		return nil
+	}
+	strs := make([]string, 0, n)
+	for i := uint64(0); i < n && r.err == nil; i++ {
+		strs = append(strs, string(r.bytes()))
+	}
+	return strs
+}
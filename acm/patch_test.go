@@ -0,0 +1,117 @@
+package acm
+
+import (
+	"testing"
+
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/permission"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffUnchanged(t *testing.T) {
+	acc := newPatchAccount()
+	patch, err := Diff(acc, acc.Copy())
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+
+	patched, err := ApplyPatch(acc, patch)
+	require.NoError(t, err)
+	assert.True(t, acc.Equal(patched))
+}
+
+func TestDiffRoundTrip(t *testing.T) {
+	old := newPatchAccount()
+
+	updated := old.Copy()
+	updated.Balance -= 300
+	updated.Sequence++
+	updated.Permissions.Base.Set(permission.CreateContract, true)
+	updated.Permissions.Base.Unset(permission.Send)
+	updated.Permissions.RemoveRole("foo")
+	updated.Permissions.AddRole("baz")
+	updated.EVMCode = Bytecode{0x60, 0x01, 0x60, 0x02}
+	updated.WASMCode = Bytecode{}
+	updated.GasCredit = 1000
+	assertRoundTrip(t, old, updated)
+
+	// A lone change only costs its own field
+	updated = old.Copy()
+	updated.Sequence++
+	patch := assertRoundTrip(t, old, updated)
+	assert.Equal(t, []byte{patchSequence, 0x0b}, patch)
+}
+
+func TestDiffBalance(t *testing.T) {
+	old := newPatchAccount()
+	updated := old.Copy()
+	updated.Balance = 0
+	assertRoundTrip(t, old, updated)
+
+	old.Balance = 0
+	updated.Balance = 1<<64 - 1
+	assertRoundTrip(t, old, updated)
+}
+
+func TestDiffPublicKey(t *testing.T) {
+	old := &Account{Address: crypto.Address{1, 2, 3}}
+	updated := old.Copy()
+	updated.PublicKey = crypto.PrivateKeyFromSecret("Patch", crypto.CurveTypeEd25519).GetPublicKey()
+	assertRoundTrip(t, old, updated)
+}
+
+func TestDiffDifferentAddress(t *testing.T) {
+	_, err := Diff(newPatchAccount(), NewAccountFromSecret("Other"))
+	require.Error(t, err)
+}
+
+func TestApplyMalformedPatch(t *testing.T) {
+	old := newPatchAccount()
+	updated := old.Copy()
+	updated.EVMCode = Bytecode{1, 2, 3}
+	updated.Permissions.AddRole("baz")
+	patch, err := Diff(old, updated)
+	require.NoError(t, err)
+
+	for i := 1; i < len(patch); i++ {
+		_, err = ApplyPatch(old, patch[:i])
+		assert.Error(t, err, "truncated to %d bytes", i)
+	}
+	_, err = ApplyPatch(old, append(patch, 0))
+	assert.Error(t, err, "trailing bytes")
+	_, err = ApplyPatch(old, []byte{0x80})
+	assert.Error(t, err, "unknown field")
+}
+
+func assertRoundTrip(t *testing.T, old, updated *Account) []byte {
+	t.Helper()
+	oldBytes := old.CanonicalBytes()
+	patch, err := Diff(old, updated)
+	require.NoError(t, err)
+	assert.NotEmpty(t, patch)
+	encoded, err := updated.Encode()
+	require.NoError(t, err)
+	assert.True(t, len(patch) < len(encoded), "patch of %d bytes should be smaller than the account's %d",
+		len(patch), len(encoded))
+
+	patched, err := ApplyPatch(old, patch)
+	require.NoError(t, err)
+	assert.True(t, updated.Equal(patched), "expected %v but got %v", updated, patched)
+	assert.Equal(t, oldBytes, old.CanonicalBytes(), "old account should be untouched")
+	return patch
+}
+
+func newPatchAccount() *Account {
+	acc := NewAccountFromSecret("Patch")
+	acc.Balance = 1000
+	acc.Sequence = 10
+	acc.EVMCode = Bytecode{0x60, 0x01}
+	acc.Permissions = permission.AccountPermissions{
+		Base: permission.BasePermissions{
+			Perms:  permission.Send | permission.Call,
+			SetBit: permission.Send | permission.Call,
+		},
+		Roles: []string{"foo", "bar"},
+	}
+	return acc
+}
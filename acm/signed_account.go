@@ -0,0 +1,49 @@
+package acm
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/burrow/crypto"
+)
+
+// SignedAccount is an Account with a signature of its CanonicalBytes by the account's own key, for higher-assurance
+// storage of accounts outside the state where tampering should be detectable. The signature is kept alongside the
+// Account rather than in it so that the accounts stored in state do not carry it.
+type SignedAccount struct {
+	Account   *Account
+	Signature *crypto.Signature `json:",omitempty"`
+}
+
+// Sign sets the Signature to a signature of the CanonicalBytes of the Account by privKey, which must be the key of the
+// account's public key. Any change to the Account after signing invalidates the signature, so the account must be
+// signed again after each change.
+func (sa *SignedAccount) Sign(privKey crypto.PrivateKey) error {
+	acc := sa.Account
+	if !acc.PublicKey.IsSet() {
+		return fmt.Errorf("cannot sign account %v without a public key", acc.Address)
+	}
+	if !acc.PublicKeyEqual(privKey.GetPublicKey()) {
+		return fmt.Errorf("cannot sign account %v with a private key for public key %v rather than %v",
+			acc.Address, privKey.GetPublicKey(), acc.PublicKey)
+	}
+	sig, err := privKey.Sign(acc.CanonicalBytes())
+	if err != nil {
+		return fmt.Errorf("could not sign account %v: %v", acc.Address, err)
+	}
+	sa.Signature = sig
+	return nil
+}
+
+// VerifySignature returns whether the Signature is a valid signature of the CanonicalBytes of the Account by its
+// public key, so false if the account has been changed since it was signed. It returns an error if the account is
+// not signed or has no public key to verify against.
+func (sa *SignedAccount) VerifySignature() (bool, error) {
+	acc := sa.Account
+	if sa.Signature == nil {
+		return false, fmt.Errorf("account %v is not signed", acc.Address)
+	}
+	if !acc.PublicKey.IsSet() {
+		return false, fmt.Errorf("cannot verify signature of account %v without a public key", acc.Address)
+	}
+	return acc.PublicKey.Verify(acc.CanonicalBytes(), sa.Signature) == nil, nil
+}
@@ -0,0 +1,63 @@
+package acm
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/burrow/permission"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignedAccount(t *testing.T) {
+	privAcc := GeneratePrivateAccountFromSecret("signer")
+	acc := NewAccount(privAcc.GetPublicKey())
+	acc.Balance = 100
+	acc.Permissions = permission.NewAccountPermissions(permission.Send)
+	encoded, err := acc.Encode()
+	require.NoError(t, err)
+
+	signed := &SignedAccount{Account: acc}
+	_, err = signed.VerifySignature()
+	require.Error(t, err)
+
+	require.NoError(t, signed.Sign(privAcc.PrivateKey()))
+	require.NotNil(t, signed.Signature)
+	valid, err := signed.VerifySignature()
+	require.NoError(t, err)
+	assert.True(t, valid)
+	// The account itself is stored as before
+	reencoded, err := acc.Encode()
+	require.NoError(t, err)
+	assert.Equal(t, encoded, reencoded)
+
+	// Survives JSON
+	bs, err := json.Marshal(signed)
+	require.NoError(t, err)
+	decoded := new(SignedAccount)
+	require.NoError(t, json.Unmarshal(bs, decoded))
+	valid, err = decoded.VerifySignature()
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	// Tampering after signing is detected
+	tampered := &SignedAccount{Account: acc.Copy(), Signature: signed.Signature}
+	require.NoError(t, tampered.Account.AddToBalance(1000))
+	valid, err = tampered.VerifySignature()
+	require.NoError(t, err)
+	assert.False(t, valid)
+	tampered.Account = acc.Copy()
+	tampered.Account.Permissions.Base.Set(permission.CreateAccount, true)
+	valid, err = tampered.VerifySignature()
+	require.NoError(t, err)
+	assert.False(t, valid)
+	// The original is unaffected
+	valid, err = signed.VerifySignature()
+	require.NoError(t, err)
+	assert.True(t, valid)
+
+	// Only the account's own key can sign it
+	other := GeneratePrivateAccountFromSecret("other")
+	require.Error(t, signed.Sign(other.PrivateKey()))
+	require.Error(t, (&SignedAccount{Account: &Account{Address: acc.Address}}).Sign(privAcc.PrivateKey()))
+}
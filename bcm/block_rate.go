@@ -0,0 +1,44 @@
+package bcm
+
+import "time"
+
+// BlockRate returns the rate at which blocks have been produced in blocks per second, according to the times of the
+// blocks committed within the window set with WithBlockRateWindow (by default the last DefaultBlockRateCommits). At
+// startup the rate is taken over as many blocks as have been committed since, and is zero until there are two.
+// Unlike LastCommitDuration, which measures the latency of a single block, it measures throughput.
+func (bc *Blockchain) BlockRate() float64 {
+	if bc == nil {
+		return 0
+	}
+	bc.RLock()
+	defer bc.RUnlock()
+	if len(bc.blockTimes) < 2 {
+		return 0
+	}
+	elapsed := bc.blockTimes[len(bc.blockTimes)-1].Sub(bc.blockTimes[0])
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(len(bc.blockTimes)-1) / elapsed.Seconds()
+}
+
+// Must be called holding the write lock. Blocks committed again at earlier heights restart the window since the times
+// of the blocks they replace no longer apply.
+func (bc *Blockchain) recordBlockTime(blockTime time.Time, overwrite bool) {
+	if overwrite {
+		bc.blockTimes = bc.blockTimes[:0]
+	}
+	bc.blockTimes = append(bc.blockTimes, blockTime)
+	first := 0
+	if excess := len(bc.blockTimes) - bc.blockRateCommits; excess > 0 {
+		first = excess
+	}
+	if bc.blockRatePeriod > 0 {
+		for first < len(bc.blockTimes)-1 && blockTime.Sub(bc.blockTimes[first]) > bc.blockRatePeriod {
+			first++
+		}
+	}
+	if first > 0 {
+		bc.blockTimes = append(bc.blockTimes[:0], bc.blockTimes[first:]...)
+	}
+}
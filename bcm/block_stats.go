@@ -0,0 +1,72 @@
+package bcm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/burrow/txs"
+	"github.com/hyperledger/burrow/txs/payload"
+)
+
+// BlockStats are summary statistics of a committed block derived from the BlockStore
+type BlockStats struct {
+	Height uint64
+	// The number of transactions in the block
+	NumTxs uint64
+	// The number of transactions in the chain up to and including the block
+	TotalTxs uint64
+	// The sum of the gas limits of the call transactions in the block, which bounds the gas they used. The gas actually
+	// used is part of the execution results which are not kept in the BlockStore.
+	GasLimit uint64
+}
+
+// BlockStats returns the BlockStats of the block at height, which must lie in [1, LastBlockHeight()]. The counts come
+// from the block header but a block with transactions is read in full to total its gas.
+func (bc *Blockchain) BlockStats(height uint64) (BlockStats, error) {
+	const errHeader = "BlockStats():"
+	if bc == nil || bc.blockStore == nil {
+		return BlockStats{}, newBlockError(ErrNoBlockStore, "%s could not get block stats because Blockchain has "+
+			"not been given access to tendermint BlockStore", errHeader)
+	}
+	header, err := bc.GetBlockHeader(height)
+	if err != nil {
+		return BlockStats{}, wrapBlockError(errHeader, err)
+	}
+	stats := BlockStats{
+		Height:   height,
+		NumTxs:   uint64(header.NumTxs),
+		TotalTxs: uint64(header.TotalTxs),
+	}
+	if stats.NumTxs == 0 {
+		return stats, nil
+	}
+
+	block, err := bc.block(height)
+	if err != nil {
+		return BlockStats{}, fmt.Errorf("%s %v", errHeader, err)
+	}
+	err = block.Transactions(func(txEnv *txs.Envelope) error {
+		if callTx, ok := txEnv.Tx.Payload.(*payload.CallTx); ok {
+			stats.GasLimit += callTx.GasLimit
+		}
+		return nil
+	})
+	if err != nil {
+		return BlockStats{}, fmt.Errorf("%s could not decode transactions of block at height %d: %v", errHeader,
+			height, err)
+	}
+	return stats, nil
+}
+
+// Reads the block at height from the first of the BlockStore and archive stores that has it
+func (bc *Blockchain) block(height uint64) (*Block, error) {
+	var errs []string
+	for i, bs := range bc.blockStores() {
+		block, err := bs.Block(int64(height))
+		if err == nil {
+			return block, nil
+		}
+		errs = append(errs, storeName(i)+": "+err.Error())
+	}
+	return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+}
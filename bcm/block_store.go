@@ -51,6 +51,21 @@ func (bs *BlockStore) BlockMeta(height int64) (_ *types.BlockMeta, err error) {
 	return bs.LoadBlockMeta(height), nil
 }
 
+// Commit returns the commit for the block at height, which is stored with the following block, falling back to the
+// seen commit for the latest block. Returns nil if neither is found.
+func (bs *BlockStore) Commit(height int64) (_ *types.Commit, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("BlockStore.Commit() could not get Commit at height %d: %v", height, r)
+		}
+	}()
+	commit := bs.LoadBlockCommit(height)
+	if commit == nil {
+		commit = bs.LoadSeenCommit(height)
+	}
+	return commit, nil
+}
+
 // Iterate over blocks between start (inclusive) and end (exclusive)
 func (bs *BlockStore) Blocks(start, end int64, iter func(*Block) error) error {
 	if end > 0 && start >= end {
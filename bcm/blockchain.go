@@ -16,12 +16,18 @@ package bcm
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/tendermint/tendermint/types"
 
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/crypto/sha3"
 	"github.com/hyperledger/burrow/genesis"
 	"github.com/hyperledger/burrow/logging"
 	amino "github.com/tendermint/go-amino"
@@ -30,9 +36,13 @@ import (
 
 var stateKey = []byte("BlockchainState")
 
+// BlockchainInfo is safe to use from a nil *Blockchain, as may be seen during initial load, in which case each method
+// returns the zero value (or an error) as if no blocks had been committed
 type BlockchainInfo interface {
 	GenesisHash() []byte
 	GenesisDoc() genesis.GenesisDoc
+	// GenesisAccounts returns copies of the accounts of the genesis document
+	GenesisAccounts() []genesis.Account
 	ChainID() string
 	LastBlockHeight() uint64
 	LastBlockTime() time.Time
@@ -40,10 +50,13 @@ type BlockchainInfo interface {
 	LastCommitDuration() time.Duration
 	LastBlockHash() []byte
 	AppHashAfterLastBlock() []byte
-	// Gets the BlockHash at a height (or nil if no BlockStore mounted or block could not be found)
+	// Gets the BlockHash at a height (or nil if no BlockStore mounted or block could not be found, GetBlockHeader
+	// distinguishes the two with ErrNoBlockStore and ErrBlockNotFound)
 	BlockHash(height uint64) []byte
 	// GetBlockHash returns	hash of the specific block
 	GetBlockHeader(blockNumber uint64) (*types.Header, error)
+	// BlockTime returns the time of the block at a height
+	BlockTime(height uint64) (time.Time, error)
 }
 
 type Blockchain struct {
@@ -51,11 +64,36 @@ type Blockchain struct {
 	persistedState PersistedState
 	// Non-persisted state
 	db                 dbm.DB
+	codec              StateCodec
 	blockStore         *BlockStore
 	genesisDoc         genesis.GenesisDoc
 	lastBlockHash      []byte
 	lastCommitTime     time.Time
 	lastCommitDuration time.Duration
+	// Only sync every saveInterval saves
+	saveInterval  uint64
+	unsyncedSaves uint64
+	// The last encoded state written
+	lastSaved []byte
+	// Headers of committed blocks by height
+	headerCache     *lru.Cache
+	headerCacheSize int
+	// Deadline for BlockStore reads made without a context
+	blockStoreTimeout time.Duration
+	// Slower stores of older blocks tried in turn when blockStore does not have a block
+	archiveStores []*BlockStore
+	// Whether LoadOrNewBlockchain may replace state from a different genesis
+	allowGenesisReset bool
+	// Called by LoadOrNewBlockchain with loaded state, if nil loaded state is trusted
+	appHashVerifier AppHashVerifier
+	// The length committed app hashes must have, if 0 (the default) app hashes are not checked
+	appHashLength int
+	// Called in order on each block commit
+	commitHooks []CommitHook
+	// The times of the recent blocks from which BlockRate is calculated, oldest first
+	blockTimes       []time.Time
+	blockRateCommits int
+	blockRatePeriod  time.Duration
 }
 
 var _ BlockchainInfo = &Blockchain{}
@@ -67,12 +105,111 @@ type PersistedState struct {
 	GenesisHash           []byte
 }
 
-// LoadOrNewBlockchain returns true if state already exists
-func LoadOrNewBlockchain(db dbm.DB, genesisDoc *genesis.GenesisDoc, logger *logging.Logger) (_ *Blockchain, exists bool, _ error) {
+// StateCodec encodes and decodes the PersistedState stored in the database
+type StateCodec interface {
+	MarshalBinaryBare(o interface{}) ([]byte, error)
+	UnmarshalBinaryBare(bz []byte, ptr interface{}) error
+}
+
+type BlockchainOption func(*Blockchain)
+
+// WithCodec replaces the default amino codec used to encode PersistedState - a database must be read with the same
+// codec it was written with
+func WithCodec(codec StateCodec) BlockchainOption {
+	return func(bc *Blockchain) {
+		bc.codec = codec
+	}
+}
+
+// WithSaveInterval only syncs the persisted state to disk on every interval-th commit, writing it without a sync
+// in between. This trades a window of at most interval heights that may need to be replayed (which Tendermint will do)
+// after a crash for commit throughput on slow disks. An interval of 0 or 1 syncs on every commit (the default).
+func WithSaveInterval(interval uint64) BlockchainOption {
+	return func(bc *Blockchain) {
+		bc.saveInterval = interval
+	}
+}
+
+// The default number of block headers to cache
+const DefaultHeaderCacheSize = 1024
+
+// WithHeaderCacheSize sets the number of recently read block headers (from which BlockTime is also served) to keep in
+// memory. A size of 0 disables the cache.
+func WithHeaderCacheSize(size int) BlockchainOption {
+	return func(bc *Blockchain) {
+		bc.headerCacheSize = size
+	}
+}
+
+// The default deadline for BlockStore reads made without a context (e.g. by GetBlockHeader and BlockHash)
+const DefaultBlockStoreTimeout = 10 * time.Second
+
+// WithBlockStoreTimeout sets the deadline for BlockStore reads made without a context, so that a slow BlockStore
+// cannot hang callers indefinitely. A timeout of 0 waits for as long as the read takes.
+func WithBlockStoreTimeout(timeout time.Duration) BlockchainOption {
+	return func(bc *Blockchain) {
+		bc.blockStoreTimeout = timeout
+	}
+}
+
+// WithAllowGenesisReset makes LoadOrNewBlockchain discard existing state that was made from a different GenesisDoc
+// and start a new blockchain from the GenesisDoc it was passed, rather than failing. This is only intended for
+// development where the genesis is changed deliberately - any existing chain is lost - so it is off by default.
+func WithAllowGenesisReset(allow bool) BlockchainOption {
+	return func(bc *Blockchain) {
+		bc.allowGenesisReset = allow
+	}
+}
+
+// AppHashVerifier confirms that appHash, the AppHashAfterLastBlock of state loaded at height, is consistent with the
+// application's own state, returning an error if it is not
+type AppHashVerifier func(height uint64, appHash []byte) error
+
+// WithAppHashVerifier makes LoadOrNewBlockchain call verify with the stored app hash and height of any existing state
+// it loads, failing if verify returns an error. This catches blockchain state that has got out of step with the
+// application state (for instance when one has been rolled back or restored from a backup without the other) before
+// it can be built on. A nil verify (the default) trusts the stored app hash.
+func WithAppHashVerifier(verify AppHashVerifier) BlockchainOption {
+	return func(bc *Blockchain) {
+		bc.appHashVerifier = verify
+	}
+}
+
+// The length of the sha256 root hash of the application state trees, for use with WithAppHashLength
+const DefaultAppHashLength = sha256.Size
+
+// WithAppHashLength makes CommitBlock, CommitBlockAtHeight, and CommitWithAppHash reject app hashes that are nil or not
+// length bytes long, so that a buggy application cannot persist junk as its app hash. A length of 0 (the default)
+// turns the check off and any app hash is accepted.
+func WithAppHashLength(length int) BlockchainOption {
+	return func(bc *Blockchain) {
+		bc.appHashLength = length
+	}
+}
+
+// The default number of recent commits over which BlockRate is calculated
+const DefaultBlockRateCommits = 100
+
+// WithBlockRateWindow sets the window over which BlockRate is calculated to the last commits blocks committed, of
+// those only the blocks with times within period of the last block count. A commits of 0 uses
+// DefaultBlockRateCommits and a period of 0 counts all of them.
+func WithBlockRateWindow(commits int, period time.Duration) BlockchainOption {
+	return func(bc *Blockchain) {
+		if commits > 0 {
+			bc.blockRateCommits = commits
+		}
+		bc.blockRatePeriod = period
+	}
+}
+
+// LoadOrNewBlockchain returns true if state already exists. With a nil db there is never any state so it returns a new
+// ephemeral blockchain (see NewEphemeralBlockchain).
+func LoadOrNewBlockchain(db dbm.DB, genesisDoc *genesis.GenesisDoc, logger *logging.Logger,
+	options ...BlockchainOption) (_ *Blockchain, exists bool, _ error) {
 	logger = logger.WithScope("LoadOrNewBlockchain")
 	logger.InfoMsg("Trying to load blockchain state from database",
 		"database_key", stateKey)
-	bc, err := loadBlockchain(db, genesisDoc)
+	bc, err := loadBlockchain(db, genesisDoc, options...)
 	if err != nil {
 		return nil, false, fmt.Errorf("error loading blockchain state from database: %v", err)
 	}
@@ -80,6 +217,13 @@ func LoadOrNewBlockchain(db dbm.DB, genesisDoc *genesis.GenesisDoc, logger *logg
 		dbHash := bc.GenesisHash()
 		argHash := genesisDoc.Hash()
 		if !bytes.Equal(dbHash, argHash) {
+			if bc.allowGenesisReset {
+				logger.InfoMsg("WARNING: GenesisDoc passed to LoadOrNewBlockchain does not match the one found in "+
+					"database, DISCARDING existing blockchain state and making new blockchain because genesis reset "+
+					"is allowed", "passed_genesis_hash", argHash, "database_genesis_hash", dbHash,
+					"discarded_last_block_height", bc.LastBlockHeight())
+				return NewBlockchain(db, genesisDoc, options...), false, nil
+			}
 			return nil, false, fmt.Errorf("GenesisDoc passed to LoadOrNewBlockchain has hash: 0x%X, which does not "+
 				"match the one found in database: 0x%X, database genesis:\n%v\npassed genesis:\n%v\n",
 				argHash, dbHash, bc.genesisDoc.JSONString(), genesisDoc.JSONString())
@@ -88,27 +232,92 @@ func LoadOrNewBlockchain(db dbm.DB, genesisDoc *genesis.GenesisDoc, logger *logg
 			return nil, false, fmt.Errorf("LastBlockTime %v from loaded Blockchain is before GenesisTime %v",
 				bc.LastBlockTime(), genesisDoc.GenesisTime)
 		}
+		if bc.appHashVerifier != nil {
+			err = bc.appHashVerifier(bc.LastBlockHeight(), bc.AppHashAfterLastBlock())
+			if err != nil {
+				return nil, false, fmt.Errorf("AppHashAfterLastBlock 0x%X at height %d loaded from database is not "+
+					"consistent with application state: %v", bc.AppHashAfterLastBlock(), bc.LastBlockHeight(), err)
+			}
+		}
 		return bc, true, nil
 	}
 
 	logger.InfoMsg("No existing blockchain state found in database, making new blockchain")
-	return NewBlockchain(db, genesisDoc), false, nil
+	return NewBlockchain(db, genesisDoc, options...), false, nil
+}
+
+// Bootstrap returns a Blockchain ready for use, loading its state from db if there is any or making a new blockchain
+// from genesisDoc if not (see LoadOrNewBlockchain). It checks that the genesis hash and chain ID of the returned
+// Blockchain are those derived from genesisDoc, and that a new blockchain starts from the genesis app hash.
+func Bootstrap(db dbm.DB, genesisDoc *genesis.GenesisDoc, logger *logging.Logger,
+	options ...BlockchainOption) (*Blockchain, error) {
+	const errHeader = "Bootstrap():"
+	if genesisDoc == nil {
+		return nil, fmt.Errorf("%s cannot bootstrap blockchain without a GenesisDoc", errHeader)
+	}
+	bc, exists, err := LoadOrNewBlockchain(db, genesisDoc, logger, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%s %v", errHeader, err)
+	}
+	genesisHash := genesisDoc.Hash()
+	if !bytes.Equal(bc.GenesisHash(), genesisHash) {
+		return nil, fmt.Errorf("%s blockchain has genesis hash 0x%X but GenesisDoc has hash 0x%X", errHeader,
+			bc.GenesisHash(), genesisHash)
+	}
+	if bc.ChainID() != genesisDoc.ChainID() {
+		return nil, fmt.Errorf("%s blockchain has chain ID %s but GenesisDoc has chain ID %s", errHeader,
+			bc.ChainID(), genesisDoc.ChainID())
+	}
+	logger = logger.WithScope("Bootstrap")
+	if exists {
+		logger.InfoMsg("Loaded existing blockchain state",
+			"chain_id", bc.ChainID(),
+			"last_block_height", bc.LastBlockHeight())
+		return bc, nil
+	}
+	if bc.LastBlockHeight() != 0 || !bytes.Equal(bc.AppHashAfterLastBlock(), genesisHash) {
+		return nil, fmt.Errorf("%s new blockchain has height %d and app hash 0x%X rather than starting from genesis",
+			errHeader, bc.LastBlockHeight(), bc.AppHashAfterLastBlock())
+	}
+	logger.InfoMsg("Created new blockchain from genesis",
+		"chain_id", bc.ChainID(),
+		"genesis_hash", genesisHash)
+	return bc, nil
 }
 
 // NewBlockchain returns a pointer to blockchain state initialised from genesis
-func NewBlockchain(db dbm.DB, genesisDoc *genesis.GenesisDoc) *Blockchain {
+func NewBlockchain(db dbm.DB, genesisDoc *genesis.GenesisDoc, options ...BlockchainOption) *Blockchain {
 	bc := &Blockchain{
-		db: db,
+		db:    db,
+		codec: cdc,
 		persistedState: PersistedState{
 			AppHashAfterLastBlock: genesisDoc.Hash(),
 			GenesisHash:           genesisDoc.Hash(),
 			LastBlockTime:         genesisDoc.GenesisTime,
 		},
-		genesisDoc: *genesisDoc,
+		genesisDoc:        *genesisDoc,
+		headerCacheSize:   DefaultHeaderCacheSize,
+		blockStoreTimeout: DefaultBlockStoreTimeout,
+		blockRateCommits:  DefaultBlockRateCommits,
+	}
+	for _, option := range options {
+		option(bc)
+	}
+	if bc.headerCacheSize > 0 {
+		// Only errors on non-positive size
+		bc.headerCache, _ = lru.New(bc.headerCacheSize)
 	}
 	return bc
 }
 
+// NewEphemeralBlockchain returns a Blockchain initialised from genesis whose state is held only in memory, for
+// simulations and tests. Blocks can be committed and the state read back as for any Blockchain but nothing is ever
+// saved, so the state is lost with the Blockchain and Flush does nothing. It is the same as NewBlockchain with a nil
+// db.
+func NewEphemeralBlockchain(genesisDoc *genesis.GenesisDoc, options ...BlockchainOption) *Blockchain {
+	return NewBlockchain(nil, genesisDoc, options...)
+}
+
 func GetSyncInfo(blockchain BlockchainInfo) *SyncInfo {
 	return &SyncInfo{
 		LatestBlockHeight:   blockchain.LastBlockHeight(),
@@ -120,12 +329,16 @@ func GetSyncInfo(blockchain BlockchainInfo) *SyncInfo {
 	}
 }
 
-func loadBlockchain(db dbm.DB, genesisDoc *genesis.GenesisDoc) (*Blockchain, error) {
+func loadBlockchain(db dbm.DB, genesisDoc *genesis.GenesisDoc, options ...BlockchainOption) (*Blockchain, error) {
+	// There is never any saved state without a db
+	if db == nil {
+		return nil, nil
+	}
 	buf := db.Get(stateKey)
 	if len(buf) == 0 {
 		return nil, nil
 	}
-	bc, err := decodeBlockchain(buf, genesisDoc)
+	bc, err := decodeBlockchain(buf, genesisDoc, options...)
 	if err != nil {
 		return nil, err
 	}
@@ -134,75 +347,218 @@ func loadBlockchain(db dbm.DB, genesisDoc *genesis.GenesisDoc) (*Blockchain, err
 }
 
 func (bc *Blockchain) CommitBlock(blockTime time.Time, blockHash, appHash []byte) error {
-	return bc.CommitBlockAtHeight(blockTime, blockHash, appHash, bc.persistedState.LastBlockHeight+1)
+	bc.Lock()
+	defer bc.Unlock()
+	// Read the height under the same lock as the commit so that concurrent commits cannot claim the same height
+	return bc.commitBlockAtHeight(blockTime, blockHash, appHash, bc.persistedState.LastBlockHeight+1)
 }
 
 func (bc *Blockchain) CommitBlockAtHeight(blockTime time.Time, blockHash, appHash []byte, height uint64) error {
 	bc.Lock()
 	defer bc.Unlock()
+	return bc.commitBlockAtHeight(blockTime, blockHash, appHash, height)
+}
+
+// Must be called holding the write lock
+func (bc *Blockchain) commitBlockAtHeight(blockTime time.Time, blockHash, appHash []byte, height uint64) error {
+	err := bc.validateAppHash(appHash)
+	if err != nil {
+		return fmt.Errorf("cannot commit block at height %d: %v", height, err)
+	}
 	// Checkpoint on the _previous_ block. If we die, this is where we will resume since we know all intervening state
 	// has been written successfully since we are committing the next block.
 	// If we fall over we can resume a safe committed state and Tendermint will catch us up
-	err := bc.save()
+	err = bc.save()
 	if err != nil {
 		return err
 	}
+	// Overwriting previous heights invalidates their headers
+	if height <= bc.persistedState.LastBlockHeight && bc.headerCache != nil {
+		bc.headerCache.Purge()
+	}
+	bc.recordBlockTime(blockTime, height <= bc.persistedState.LastBlockHeight)
 	bc.lastCommitDuration = blockTime.Sub(bc.persistedState.LastBlockTime)
 	bc.lastBlockHash = blockHash
 	bc.persistedState.LastBlockHeight = height
 	bc.persistedState.LastBlockTime = blockTime
 	bc.persistedState.AppHashAfterLastBlock = appHash
 	bc.lastCommitTime = time.Now().UTC()
+	for i, hook := range bc.commitHooks {
+		err = hook(height, appHash)
+		if err != nil {
+			return fmt.Errorf("commit hook %d failed for block at height %d, which remains committed: %v", i, height,
+				err)
+		}
+	}
 	return nil
 }
 
-func (bc *Blockchain) CommitWithAppHash(appHash []byte) error {
-	bc.persistedState.AppHashAfterLastBlock = appHash
+// CommitHook is called with the height and app hash of each block committed with CommitBlock or CommitBlockAtHeight
+type CommitHook func(height uint64, appHash []byte) error
+
+// RegisterCommitHook adds a hook to be run each time a block is committed, after the checkpoint has been saved and
+// the block has become the last block. Hooks run synchronously in the commit path in the order they were registered
+// and hold the Blockchain's lock, so they must not call its methods. The first hook to return an error stops those
+// after it from running and its error is returned by the commit, but the block itself stays committed. Since the
+// state of a block is only saved on the commit of the next, after a crash the last block may be committed (and hooks
+// called for it) again, so hooks should be idempotent.
+func (bc *Blockchain) RegisterCommitHook(hook CommitHook) {
 	bc.Lock()
 	defer bc.Unlock()
+	bc.commitHooks = append(bc.commitHooks, hook)
+}
 
+// CommitWithAppHash replaces the app hash of the last committed block and saves the state. It is atomic with respect to
+// CommitBlock and CommitBlockAtHeight - if they run concurrently the app hash applies to whichever block was last
+// committed when the lock was taken and a subsequent block commit will overwrite it.
+func (bc *Blockchain) CommitWithAppHash(appHash []byte) error {
+	bc.Lock()
+	defer bc.Unlock()
+	err := bc.validateAppHash(appHash)
+	if err != nil {
+		return fmt.Errorf("cannot commit app hash: %v", err)
+	}
+	bc.persistedState.AppHashAfterLastBlock = appHash
 	return bc.save()
 }
 
+func (bc *Blockchain) validateAppHash(appHash []byte) error {
+	if bc.appHashLength <= 0 {
+		return nil
+	}
+	if appHash == nil {
+		return fmt.Errorf("app hash is nil")
+	}
+	if len(appHash) != bc.appHashLength {
+		return fmt.Errorf("app hash 0x%X has length %d but app hashes should be %d bytes long", appHash,
+			len(appHash), bc.appHashLength)
+	}
+	return nil
+}
+
 func (bc *Blockchain) save() error {
 	if bc.db != nil {
 		encodedState, err := bc.Encode()
 		if err != nil {
 			return err
 		}
+		bc.lastSaved = encodedState
+		if bc.unsyncedSaves+1 < bc.saveInterval {
+			bc.unsyncedSaves++
+			bc.db.Set(stateKey, encodedState)
+			return nil
+		}
+		bc.unsyncedSaves = 0
 		bc.db.SetSync(stateKey, encodedState)
 	}
 	return nil
 }
 
-var cdc = amino.NewCodec()
+// Flush syncs the last saved state to disk - it should be called on shutdown when using WithSaveInterval
+func (bc *Blockchain) Flush() error {
+	bc.Lock()
+	defer bc.Unlock()
+	if bc.db != nil && bc.lastSaved != nil {
+		bc.db.SetSync(stateKey, bc.lastSaved)
+		bc.unsyncedSaves = 0
+	}
+	return nil
+}
+
+// The default StateCodec
+var cdc StateCodec = amino.NewCodec()
 
 func (bc *Blockchain) Encode() ([]byte, error) {
-	encodedState, err := cdc.MarshalBinaryBare(bc.persistedState)
+	encodedState, err := bc.codec.MarshalBinaryBare(bc.persistedState)
 	if err != nil {
 		return nil, err
 	}
 	return encodedState, nil
 }
 
-func decodeBlockchain(encodedState []byte, genesisDoc *genesis.GenesisDoc) (*Blockchain, error) {
-	bc := NewBlockchain(nil, genesisDoc)
-	err := cdc.UnmarshalBinaryBare(encodedState, &bc.persistedState)
+// Persisted state is always checksummed with amino, whatever codec it is stored with
+var checksumCodec = amino.NewCodec()
+
+// StateChecksum returns the sha3 of the amino encoding of the PersistedState (app hash, last block height and time, and
+// genesis hash). It is independent of the configured StateCodec and is the same for any node with the same state so
+// operators can compare it across replicas to detect divergence.
+func (bc *Blockchain) StateChecksum() []byte {
+	bc.RLock()
+	defer bc.RUnlock()
+	return sha3.Sha3(checksumCodec.MustMarshalBinaryBare(bc.persistedState))
+}
+
+func decodeBlockchain(encodedState []byte, genesisDoc *genesis.GenesisDoc, options ...BlockchainOption) (*Blockchain,
+	error) {
+	bc := NewBlockchain(nil, genesisDoc, options...)
+	err := bc.codec.UnmarshalBinaryBare(encodedState, &bc.persistedState)
 	if err != nil {
 		return nil, err
 	}
 	return bc, nil
 }
 
+// Ephemeral returns whether the Blockchain has no db to save its state to, see NewEphemeralBlockchain
+func (bc *Blockchain) Ephemeral() bool {
+	return bc == nil || bc.db == nil
+}
+
+// GenesisHash returns the hash of the genesis document, which is also the app hash the chain started from (the
+// AppHashAfterLastBlock before any block is committed). Unlike AppHashAfterLastBlock it does not change as blocks are
+// committed.
 func (bc *Blockchain) GenesisHash() []byte {
+	if bc == nil {
+		return nil
+	}
 	return bc.persistedState.GenesisHash
 }
 
 func (bc *Blockchain) GenesisDoc() genesis.GenesisDoc {
+	if bc == nil {
+		return genesis.GenesisDoc{}
+	}
 	return bc.genesisDoc
 }
 
+// GenesisStats summarises the initial state described by the genesis document
+type GenesisStats struct {
+	Validators int
+	Accounts   int
+}
+
+func (bc *Blockchain) GenesisStats() GenesisStats {
+	if bc == nil {
+		return GenesisStats{}
+	}
+	return GenesisStats{
+		Validators: len(bc.genesisDoc.Validators),
+		Accounts:   len(bc.genesisDoc.Accounts),
+	}
+}
+
+// GenesisAccounts returns a deep copy of the accounts of the genesis document so callers cannot mutate the genesis
+// held by the Blockchain
+func (bc *Blockchain) GenesisAccounts() []genesis.Account {
+	if bc == nil || bc.genesisDoc.Accounts == nil {
+		return nil
+	}
+	accounts := make([]genesis.Account, len(bc.genesisDoc.Accounts))
+	for i, account := range bc.genesisDoc.Accounts {
+		accounts[i] = account
+		if account.PublicKey.PublicKey != nil {
+			accounts[i].PublicKey.PublicKey = append([]byte{}, account.PublicKey.PublicKey...)
+		}
+		if account.Permissions.Roles != nil {
+			accounts[i].Permissions.Roles = append([]string{}, account.Permissions.Roles...)
+		}
+	}
+	return accounts
+}
+
 func (bc *Blockchain) ChainID() string {
+	if bc == nil {
+		return ""
+	}
 	return bc.genesisDoc.ChainID()
 }
 
@@ -216,30 +572,45 @@ func (bc *Blockchain) LastBlockHeight() uint64 {
 }
 
 func (bc *Blockchain) LastBlockTime() time.Time {
+	if bc == nil {
+		return time.Time{}
+	}
 	bc.RLock()
 	defer bc.RUnlock()
 	return bc.persistedState.LastBlockTime
 }
 
 func (bc *Blockchain) LastCommitTime() time.Time {
+	if bc == nil {
+		return time.Time{}
+	}
 	bc.RLock()
 	defer bc.RUnlock()
 	return bc.lastCommitTime
 }
 
 func (bc *Blockchain) LastCommitDuration() time.Duration {
+	if bc == nil {
+		return 0
+	}
 	bc.RLock()
 	defer bc.RUnlock()
 	return bc.lastCommitDuration
 }
 
 func (bc *Blockchain) LastBlockHash() []byte {
+	if bc == nil {
+		return nil
+	}
 	bc.RLock()
 	defer bc.RUnlock()
 	return bc.lastBlockHash
 }
 
 func (bc *Blockchain) AppHashAfterLastBlock() []byte {
+	if bc == nil {
+		return nil
+	}
 	bc.RLock()
 	defer bc.RUnlock()
 	return bc.persistedState.AppHashAfterLastBlock
@@ -251,6 +622,19 @@ func (bc *Blockchain) SetBlockStore(bs *BlockStore) {
 	bc.blockStore = bs
 }
 
+// AddArchiveStore adds a BlockStore to fall back on for blocks the BlockStore does not have, for example a slower
+// store of archived blocks. Archive stores are tried in the order they were added. Like SetBlockStore it must be
+// called before the Blockchain is used concurrently.
+func (bc *Blockchain) AddArchiveStore(bs *BlockStore) {
+	bc.archiveStores = append(bc.archiveStores, bs)
+}
+
+// The BlockStore followed by the archive stores, in the order they are tried
+func (bc *Blockchain) blockStores() []*BlockStore {
+	return append([]*BlockStore{bc.blockStore}, bc.archiveStores...)
+}
+
+// BlockHash returns the hash of the header at height or nil if GetBlockHeader fails, its error tells why
 func (bc *Blockchain) BlockHash(height uint64) []byte {
 	header, err := bc.GetBlockHeader(height)
 	if err != nil {
@@ -259,15 +643,220 @@ func (bc *Blockchain) BlockHash(height uint64) []byte {
 	return header.Hash()
 }
 
+// PreviousBlockHash returns the hash of the block at height-1 as recorded in the LastBlockID of the header at height,
+// so that the link between two blocks can be checked against BlockHash(height-1) by reading a single header. Height
+// must lie in [2, LastBlockHeight()] since the first block has no previous block.
+func (bc *Blockchain) PreviousBlockHash(height uint64) ([]byte, error) {
+	const errHeader = "PreviousBlockHash():"
+	if height <= 1 {
+		return nil, newBlockError(ErrBlockNotFound, "%s height %d has no previous block, the first block is at "+
+			"height 1", errHeader, height)
+	}
+	header, err := bc.GetBlockHeader(height)
+	if err != nil {
+		return nil, wrapBlockError(errHeader, err)
+	}
+	return header.LastBlockID.Hash, nil
+}
+
+// GetBlockID returns the BlockID of the block at height, which must lie in [1, LastBlockHeight()], from its BlockMeta.
+// Unlike BlockHash this includes the header of the block's parts so it is the full target of the commit for the block,
+// as light clients need to verify it.
+func (bc *Blockchain) GetBlockID(height uint64) (types.BlockID, error) {
+	const errHeader = "GetBlockID():"
+	if bc == nil || bc.blockStore == nil {
+		return types.BlockID{}, newBlockError(ErrNoBlockStore, "%s could not get BlockID because Blockchain has not "+
+			"been given access to tendermint BlockStore", errHeader)
+	}
+	lastBlockHeight := bc.LastBlockHeight()
+	if height == 0 || height > lastBlockHeight {
+		return types.BlockID{}, newBlockError(ErrBlockNotFound, "%s height %d is out of range, committed blocks have "+
+			"heights 1 to %d", errHeader, height, lastBlockHeight)
+	}
+	ctx := context.Background()
+	if bc.blockStoreTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, bc.blockStoreTimeout)
+		defer cancel()
+	}
+	blockMeta, err := bc.blockMeta(ctx, height)
+	if err != nil {
+		return types.BlockID{}, fmt.Errorf("%s could not get BlockMeta: %v", errHeader, err)
+	}
+	if blockMeta == nil {
+		return types.BlockID{}, newBlockError(ErrBlockNotFound, "%s no such block: BlockMeta at height %d not found",
+			errHeader, height)
+	}
+	return blockMeta.BlockID, nil
+}
+
+// GetBlockHeader returns the header of the block at height, which must lie in [1, LastBlockHeight()]. Height 0 is
+// rejected since the genesis state is not represented by a block in the BlockStore. Reads from the BlockStore are
+// abandoned after the configured BlockStore timeout, see GetBlockHeaderContext. Errors for a Blockchain without a
+// BlockStore are ErrNoBlockStore and for heights without a block ErrBlockNotFound (according to errors.Cause).
 func (bc *Blockchain) GetBlockHeader(height uint64) (*types.Header, error) {
+	ctx := context.Background()
+	if bc != nil && bc.blockStoreTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, bc.blockStoreTimeout)
+		defer cancel()
+	}
+	return bc.GetBlockHeaderContext(ctx, height)
+}
+
+// GetBlockHeaderContext is GetBlockHeader but returns the context's error if it is done before the header has been
+// read from the BlockStore (the read itself cannot be interrupted and is left to finish in the background)
+func (bc *Blockchain) GetBlockHeaderContext(ctx context.Context, height uint64) (*types.Header, error) {
 	const errHeader = "GetBlockHeader():"
-	if bc == nil {
-		return nil, fmt.Errorf("%s could not get block hash because Blockchain has not been given access to "+
-			"tendermint BlockStore", errHeader)
+	if bc == nil || bc.blockStore == nil {
+		return nil, newBlockError(ErrNoBlockStore, "%s could not get block hash because Blockchain has not been "+
+			"given access to tendermint BlockStore", errHeader)
+	}
+	if height == 0 {
+		return nil, newBlockError(ErrBlockNotFound, "%s no such block: height 0 refers to genesis which has no block "+
+			"header", errHeader)
+	}
+	lastBlockHeight := bc.LastBlockHeight()
+	if height > lastBlockHeight {
+		return nil, newBlockError(ErrBlockNotFound, "%s no such block: height %d is above last committed height %d",
+			errHeader, height, lastBlockHeight)
 	}
-	blockMeta, err := bc.blockStore.BlockMeta(int64(height))
+	if bc.headerCache != nil {
+		if cached, ok := bc.headerCache.Get(height); ok {
+			// Return a copy so callers cannot modify the cached header
+			header := cached.(types.Header)
+			return &header, nil
+		}
+	}
+	blockMeta, err := bc.blockMeta(ctx, height)
 	if err != nil {
 		return nil, fmt.Errorf("%s could not get BlockMeta: %v", errHeader, err)
 	}
+	if blockMeta == nil {
+		if len(bc.archiveStores) > 0 {
+			return nil, newBlockError(ErrBlockNotFound, "%s no such block: BlockMeta at height %d not found in "+
+				"BlockStore or any of %d archive stores", errHeader, height, len(bc.archiveStores))
+		}
+		return nil, newBlockError(ErrBlockNotFound, "%s no such block: BlockMeta at height %d not found in BlockStore",
+			errHeader, height)
+	}
+	if bc.headerCache != nil {
+		bc.headerCache.Add(height, blockMeta.Header)
+	}
 	return &blockMeta.Header, nil
 }
+
+// Reads the BlockMeta at height from the first of the BlockStore and archive stores that has it unless ctx is done
+// first, returns nil if none has it. Errors from the stores are combined.
+func (bc *Blockchain) blockMeta(ctx context.Context, height uint64) (*types.BlockMeta, error) {
+	var errs []string
+	for i, bs := range bc.blockStores() {
+		blockMeta, err := readBlockMeta(ctx, bs, height)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			errs = append(errs, storeName(i)+": "+err.Error())
+			continue
+		}
+		if blockMeta != nil {
+			return blockMeta, nil
+		}
+	}
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil, nil
+}
+
+// The name in errors of the store at index i of blockStores()
+func storeName(i int) string {
+	if i == 0 {
+		return "BlockStore"
+	}
+	return fmt.Sprintf("archive store %d", i)
+}
+
+// Reads the BlockMeta at height from bs unless ctx is done first
+func readBlockMeta(ctx context.Context, bs *BlockStore, height uint64) (*types.BlockMeta, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	type result struct {
+		blockMeta *types.BlockMeta
+		err       error
+	}
+	// Buffered so that an abandoned read does not leak its goroutine
+	resultCh := make(chan result, 1)
+	go func() {
+		blockMeta, err := bs.BlockMeta(int64(height))
+		resultCh <- result{blockMeta: blockMeta, err: err}
+	}()
+	select {
+	case r := <-resultCh:
+		return r.blockMeta, r.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("gave up waiting for BlockStore to read height %d: %v", height, ctx.Err())
+	}
+}
+
+// BlockTime returns the time of the block at height from its header, which must lie in [1, LastBlockHeight()]
+func (bc *Blockchain) BlockTime(height uint64) (time.Time, error) {
+	const errHeader = "BlockTime():"
+	lastBlockHeight := bc.LastBlockHeight()
+	if height == 0 || height > lastBlockHeight {
+		return time.Time{}, newBlockError(ErrBlockNotFound, "%s height %d is out of range, committed blocks have "+
+			"heights 1 to %d", errHeader, height, lastBlockHeight)
+	}
+	header, err := bc.GetBlockHeader(height)
+	if err != nil {
+		return time.Time{}, wrapBlockError(errHeader, err)
+	}
+	return header.Time, nil
+}
+
+// ProposerAddress returns the address of the validator that proposed the block at height
+func (bc *Blockchain) ProposerAddress(height uint64) (crypto.Address, error) {
+	header, err := bc.GetBlockHeader(height)
+	if err != nil {
+		return crypto.Address{}, err
+	}
+	return crypto.AddressFromBytes(header.ProposerAddress)
+}
+
+// GetCommitSigners returns the addresses of the validators that signed the commit for the block at height as recorded
+// in the BlockStore. Validators absent from the commit are left out.
+func (bc *Blockchain) GetCommitSigners(height uint64) ([]crypto.Address, error) {
+	const errHeader = "GetCommitSigners():"
+	// Checks bounds and BlockStore
+	_, err := bc.GetBlockHeader(height)
+	if err != nil {
+		return nil, wrapBlockError(errHeader, err)
+	}
+	var commit *types.Commit
+	for i, bs := range bc.blockStores() {
+		commit, err = bs.Commit(int64(height))
+		if err != nil {
+			return nil, fmt.Errorf("%s could not get Commit from %s: %v", errHeader, storeName(i), err)
+		}
+		if commit != nil {
+			break
+		}
+	}
+	if commit == nil {
+		return nil, fmt.Errorf("%s BlockStore has no commit data for block at height %d", errHeader, height)
+	}
+	var signers []crypto.Address
+	for _, precommit := range commit.Precommits {
+		// Absent validators have nil precommits
+		if precommit == nil {
+			continue
+		}
+		address, err := crypto.AddressFromBytes(precommit.ValidatorAddress)
+		if err != nil {
+			return nil, fmt.Errorf("%s invalid validator address in commit at height %d: %v", errHeader, height, err)
+		}
+		signers = append(signers, address)
+	}
+	return signers, nil
+}
@@ -1,15 +1,27 @@
 package bcm
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/hyperledger/burrow/crypto/sha3"
 	"github.com/hyperledger/burrow/genesis"
 	"github.com/hyperledger/burrow/logging"
+	"github.com/hyperledger/burrow/txs"
+	"github.com/hyperledger/burrow/txs/payload"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	dbm "github.com/tendermint/tendermint/libs/db"
+	"github.com/tendermint/tendermint/state"
+	"github.com/tendermint/tendermint/types"
 )
 
 func TestLoadOrNewBlockchain(t *testing.T) {
@@ -70,13 +82,1159 @@ func TestLoadOrNewBlockchain(t *testing.T) {
 	assertState(t, blockchain, 2, blockTime2b, appHash2b)
 }
 
+func TestBootstrap(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	db := dbm.NewMemDB()
+
+	// Fresh chain
+	blockchain, err := Bootstrap(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), blockchain.LastBlockHeight())
+	assert.Equal(t, genesisDoc.Hash(), blockchain.GenesisHash())
+	assert.Equal(t, genesisDoc.Hash(), blockchain.AppHashAfterLastBlock())
+	assert.Equal(t, genesisDoc.ChainID(), blockchain.ChainID())
+
+	blockTime1 := genesisDoc.GenesisTime.Add(time.Second)
+	appHash1 := sha3.Sha3([]byte("appHash"))
+	require.NoError(t, blockchain.CommitBlock(blockTime1, sha3.Sha3([]byte("blockHash")), appHash1))
+	require.NoError(t, blockchain.CommitBlock(blockTime1.Add(time.Second), sha3.Sha3([]byte("blockHash2")),
+		sha3.Sha3([]byte("appHash2"))))
+
+	// Existing state (at checkpoint)
+	blockchain, err = Bootstrap(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	assertState(t, blockchain, 1, blockTime1, appHash1)
+	assert.Equal(t, genesisDoc.Hash(), blockchain.GenesisHash())
+	assert.Equal(t, genesisDoc.ChainID(), blockchain.ChainID())
+
+	// Existing state from another genesis
+	otherGenesisDoc, _, _ := genesis.NewDeterministicGenesis(123).GenesisDoc(5, 10)
+	_, err = Bootstrap(db, otherGenesisDoc, logging.NewNoopLogger())
+	require.Error(t, err)
+
+	_, err = Bootstrap(db, nil, logging.NewNoopLogger())
+	require.Error(t, err)
+}
+
+func TestLoadOrNewBlockchainAppHashVerifier(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	db := dbm.NewMemDB()
+
+	// Only existing state is verified
+	blockchain, exists, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger(),
+		WithAppHashVerifier(func(height uint64, appHash []byte) error {
+			return fmt.Errorf("should not be called for a new blockchain")
+		}))
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	blockTime1 := genesisDoc.GenesisTime.Add(time.Second)
+	appHash1 := sha3.Sha3([]byte("appHash"))
+	require.NoError(t, blockchain.CommitBlock(blockTime1, sha3.Sha3([]byte("blockHash")), appHash1))
+	require.NoError(t, blockchain.CommitBlock(blockTime1.Add(time.Second), sha3.Sha3([]byte("blockHash2")),
+		sha3.Sha3([]byte("appHash2"))))
+
+	// The application state holds the app hash of each height it has committed
+	appHashes := map[uint64][]byte{1: appHash1}
+	verify := WithAppHashVerifier(func(height uint64, appHash []byte) error {
+		if !bytes.Equal(appHashes[height], appHash) {
+			return fmt.Errorf("application has app hash 0x%X at height %d", appHashes[height], height)
+		}
+		return nil
+	})
+
+	blockchain, exists, err = LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger(), verify)
+	require.NoError(t, err)
+	require.True(t, exists)
+	assertState(t, blockchain, 1, blockTime1, appHash1)
+
+	// The application state has been rolled back without the blockchain state
+	delete(appHashes, 1)
+	_, _, err = LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger(), verify)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "is not consistent with application state")
+
+	// Without a verifier the stored app hash is trusted
+	_, exists, err = LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	require.True(t, exists)
+}
+
+func TestNewEphemeralBlockchain(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain := NewEphemeralBlockchain(genesisDoc, WithSaveInterval(2))
+	assert.True(t, blockchain.Ephemeral())
+	assert.Equal(t, uint64(0), blockchain.LastBlockHeight())
+	assert.Equal(t, genesisDoc.Hash(), blockchain.AppHashAfterLastBlock())
+
+	var committed []uint64
+	blockchain.RegisterCommitHook(func(height uint64, appHash []byte) error {
+		committed = append(committed, height)
+		return nil
+	})
+	blockTime := genesisDoc.GenesisTime
+	var appHash []byte
+	for i := byte(1); i <= 5; i++ {
+		blockTime = blockTime.Add(time.Second)
+		appHash = sha3.Sha3([]byte{i})
+		require.NoError(t, blockchain.CommitBlock(blockTime, sha3.Sha3([]byte{'b', i}), appHash))
+	}
+	assertState(t, blockchain, 5, blockTime, appHash)
+	assert.Equal(t, []uint64{1, 2, 3, 4, 5}, committed)
+	assert.Equal(t, sha3.Sha3([]byte{'b', 5}), blockchain.LastBlockHash())
+
+	// Overwriting a height and replacing the app hash work without a db
+	require.NoError(t, blockchain.CommitBlockAtHeight(blockTime, sha3.Sha3([]byte("again")), appHash, 5))
+	appHash = sha3.Sha3([]byte("replaced"))
+	require.NoError(t, blockchain.CommitWithAppHash(appHash))
+	assertState(t, blockchain, 5, blockTime, appHash)
+	require.NoError(t, blockchain.Flush())
+
+	// Nothing was saved, the state round trips through its encoding though
+	encoded, err := blockchain.Encode()
+	require.NoError(t, err)
+	decoded, err := decodeBlockchain(encoded, genesisDoc)
+	require.NoError(t, err)
+	assertState(t, decoded, 5, blockTime, appHash)
+	assert.Equal(t, blockchain.StateChecksum(), decoded.StateChecksum())
+
+	// LoadOrNewBlockchain and Bootstrap make a fresh ephemeral blockchain without a db
+	blockchain, exists, err := LoadOrNewBlockchain(nil, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.True(t, blockchain.Ephemeral())
+	assert.Equal(t, uint64(0), blockchain.LastBlockHeight())
+	blockchain, err = Bootstrap(nil, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	assert.True(t, blockchain.Ephemeral())
+
+	assert.False(t, NewBlockchain(dbm.NewMemDB(), genesisDoc).Ephemeral())
+}
+
+func TestLoadOrNewBlockchainGenesisMismatch(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	db := dbm.NewMemDB()
+	blockchain, _, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	blockTime := genesisDoc.GenesisTime
+	for i := byte(1); i <= 3; i++ {
+		blockTime = blockTime.Add(time.Second)
+		require.NoError(t, blockchain.CommitBlock(blockTime, sha3.Sha3([]byte{i}), sha3.Sha3([]byte{i})))
+	}
+
+	otherGenesisDoc, _, _ := genesis.NewDeterministicGenesis(123).GenesisDoc(5, 10)
+	require.NotEqual(t, genesisDoc.Hash(), otherGenesisDoc.Hash())
+
+	// Fails by default
+	_, _, err = LoadOrNewBlockchain(db, otherGenesisDoc, logging.NewNoopLogger())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match the one found in database")
+	_, _, err = LoadOrNewBlockchain(db, otherGenesisDoc, logging.NewNoopLogger(), WithAllowGenesisReset(false))
+	require.Error(t, err)
+
+	// The existing state is untouched
+	blockchain, exists, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, uint64(2), blockchain.LastBlockHeight())
+
+	// Explicitly allowed so starts again from the new genesis
+	blockchain, exists, err = LoadOrNewBlockchain(db, otherGenesisDoc, logging.NewNoopLogger(),
+		WithAllowGenesisReset(true))
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, uint64(0), blockchain.LastBlockHeight())
+	assert.Equal(t, otherGenesisDoc.Hash(), blockchain.GenesisHash())
+
+	// Once the new chain has saved state it loads without a reset
+	blockTime = otherGenesisDoc.GenesisTime
+	for i := byte(1); i <= 2; i++ {
+		blockTime = blockTime.Add(time.Second)
+		require.NoError(t, blockchain.CommitBlock(blockTime, sha3.Sha3([]byte{i}), sha3.Sha3([]byte{i})))
+	}
+	blockchain, exists, err = LoadOrNewBlockchain(db, otherGenesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, otherGenesisDoc.Hash(), blockchain.GenesisHash())
+	_, _, err = LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.Error(t, err)
+}
+
+func TestLoadOrNewBlockchainWithCodec(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	db := dbm.NewMemDB()
+	blockchain, exists, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger(), WithCodec(jsonCodec{}))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	blockTime1 := genesisDoc.GenesisTime.Add(time.Second * 10)
+	appHash1 := sha3.Sha3([]byte("appHash"))
+	err = blockchain.CommitBlock(blockTime1, sha3.Sha3([]byte("blockHash")), appHash1)
+	require.NoError(t, err)
+	err = blockchain.CommitBlock(blockTime1.Add(time.Second), sha3.Sha3([]byte("blockHash2")), appHash1)
+	require.NoError(t, err)
+
+	// State should be stored as JSON
+	persistedState := new(PersistedState)
+	require.NoError(t, json.Unmarshal(db.Get(stateKey), persistedState))
+	assert.Equal(t, uint64(1), persistedState.LastBlockHeight)
+
+	blockchain, exists, err = LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger(), WithCodec(jsonCodec{}))
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assertState(t, blockchain, 1, blockTime1, appHash1)
+
+	// Cannot read with the default codec
+	_, _, err = LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.Error(t, err)
+}
+
+func TestStateChecksum(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockTime := genesisDoc.GenesisTime.Add(time.Second * 10)
+	commit := func(blockchain *Blockchain, height int) {
+		for i := 0; i < height; i++ {
+			err := blockchain.CommitBlock(blockTime.Add(time.Duration(i)*time.Second),
+				sha3.Sha3([]byte(fmt.Sprintf("blockHash%d", i))), sha3.Sha3([]byte(fmt.Sprintf("appHash%d", i))))
+			require.NoError(t, err)
+		}
+	}
+
+	blockchainA := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	blockchainB := NewBlockchain(dbm.NewMemDB(), newGenesisDoc(), WithCodec(jsonCodec{}), WithSaveInterval(3))
+	assert.Equal(t, blockchainA.StateChecksum(), blockchainB.StateChecksum())
+	commit(blockchainA, 5)
+	commit(blockchainB, 5)
+	checksum := blockchainA.StateChecksum()
+	assert.Len(t, checksum, 32)
+	assert.Equal(t, checksum, blockchainB.StateChecksum())
+
+	// Stable across reloading the state, which is saved on the commit of the following block
+	db := dbm.NewMemDB()
+	blockchainC := NewBlockchain(db, genesisDoc)
+	commit(blockchainC, 6)
+	blockchainC, exists, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.Equal(t, checksum, blockchainC.StateChecksum())
+
+	// Diverges with the state
+	commit(blockchainB, 1)
+	assert.NotEqual(t, checksum, blockchainB.StateChecksum())
+	blockchainD := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	commit(blockchainD, 5)
+	require.NoError(t, blockchainD.CommitWithAppHash(sha3.Sha3([]byte("divergent"))))
+	assert.NotEqual(t, checksum, blockchainD.StateChecksum())
+}
+
+func TestConcurrentCommits(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	db := dbm.NewMemDB()
+	blockchain, _, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+
+	const commits = 50
+	appHashes := make(map[string]bool)
+	for i := 0; i < commits; i++ {
+		appHashes[string(sha3.Sha3([]byte(fmt.Sprintf("block%d", i))))] = true
+		appHashes[string(sha3.Sha3([]byte(fmt.Sprintf("app%d", i))))] = true
+	}
+
+	var wg sync.WaitGroup
+	errCh := make(chan error, 2*commits)
+	for i := 0; i < commits; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			blockTime := genesisDoc.GenesisTime.Add(time.Duration(i+1) * time.Second)
+			errCh <- blockchain.CommitBlock(blockTime, sha3.Sha3([]byte{byte(i)}),
+				sha3.Sha3([]byte(fmt.Sprintf("block%d", i))))
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			errCh <- blockchain.CommitWithAppHash(sha3.Sha3([]byte(fmt.Sprintf("app%d", i))))
+		}(i)
+	}
+	wg.Wait()
+	close(errCh)
+	for err := range errCh {
+		require.NoError(t, err)
+	}
+
+	// Every block commit must have been given its own height
+	assert.Equal(t, uint64(commits), blockchain.LastBlockHeight())
+	assert.True(t, appHashes[string(blockchain.AppHashAfterLastBlock())])
+
+	// Saved state must be consistent with the in-memory state once the final app hash is committed
+	finalAppHash := sha3.Sha3([]byte("final"))
+	require.NoError(t, blockchain.CommitWithAppHash(finalAppHash))
+	reloaded, exists, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.Equal(t, blockchain.LastBlockHeight(), reloaded.LastBlockHeight())
+	assert.Equal(t, blockchain.LastBlockTime(), reloaded.LastBlockTime())
+	assert.Equal(t, finalAppHash, []byte(reloaded.AppHashAfterLastBlock()))
+}
+
+// Counts synced and unsynced writes
+type syncCountingDB struct {
+	dbm.DB
+	sets     int
+	setSyncs int
+}
+
+func (db *syncCountingDB) Set(key, value []byte) {
+	db.sets++
+	db.DB.Set(key, value)
+}
+
+func (db *syncCountingDB) SetSync(key, value []byte) {
+	db.setSyncs++
+	db.DB.SetSync(key, value)
+}
+
+func TestSaveInterval(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	db := &syncCountingDB{DB: dbm.NewMemDB()}
+	blockchain, _, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger(), WithSaveInterval(3))
+	require.NoError(t, err)
+
+	for i := 1; i <= 7; i++ {
+		require.NoError(t, blockchain.CommitBlock(genesisDoc.GenesisTime.Add(time.Duration(i)*time.Second),
+			sha3.Sha3([]byte{byte(i)}), sha3.Sha3([]byte{byte(i)})))
+	}
+	assert.Equal(t, 2, db.setSyncs)
+	assert.Equal(t, 5, db.sets)
+
+	require.NoError(t, blockchain.Flush())
+	assert.Equal(t, 3, db.setSyncs)
+
+	// We checkpoint on the previous block
+	reloaded, exists, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.Equal(t, uint64(6), reloaded.LastBlockHeight())
+
+	// Default is to sync every commit
+	db = &syncCountingDB{DB: dbm.NewMemDB()}
+	blockchain, _, err = LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	db.sets, db.setSyncs = 0, 0
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, blockchain.CommitBlock(genesisDoc.GenesisTime.Add(time.Duration(i)*time.Second),
+			sha3.Sha3([]byte{byte(i)}), sha3.Sha3([]byte{byte(i)})))
+	}
+	assert.Equal(t, 0, db.sets)
+	assert.Equal(t, 3, db.setSyncs)
+}
+
+func TestCompact(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	dir, err := ioutil.TempDir("", "bcm-compact")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := dbm.NewDB("compact", dbm.GoLevelDBBackend, dir)
+	defer db.Close()
+
+	blockchain, _, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	require.True(t, blockchain.CanCompact())
+
+	// Compact continually while blocks are committed and read
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		blockchain.CompactEvery(ctx, time.Millisecond, logging.NewNoopLogger())
+		close(done)
+	}()
+	appHash := sha3.Sha3([]byte("app"))
+	for i := 1; i <= 100; i++ {
+		blockHash := sha3.Sha3([]byte(fmt.Sprintf("block%d", i)))
+		require.NoError(t, blockchain.CommitBlock(genesisDoc.GenesisTime.Add(time.Duration(i)*time.Second), blockHash,
+			appHash))
+		require.NoError(t, blockchain.Compact())
+		assert.Equal(t, uint64(i), blockchain.LastBlockHeight())
+	}
+	cancel()
+	<-done
+
+	// The state survives compaction, saved as of the commit of the last block (so checkpointed on the one before)
+	blockchain, exists, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	require.True(t, exists)
+	assert.Equal(t, uint64(99), blockchain.LastBlockHeight())
+	assert.Equal(t, appHash, blockchain.AppHashAfterLastBlock())
+	assert.Equal(t, genesisDoc.GenesisTime.Add(99*time.Second), blockchain.LastBlockTime())
+
+	// Other backends do nothing
+	blockchain = NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	assert.False(t, blockchain.CanCompact())
+	assert.NoError(t, blockchain.Compact())
+	blockchain.CompactEvery(context.Background(), time.Millisecond, logging.NewNoopLogger())
+	assert.NoError(t, NewEphemeralBlockchain(genesisDoc).Compact())
+}
+
+func TestCompactEveryInvalidInterval(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bcm-compact")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	db := dbm.NewDB("compact", dbm.GoLevelDBBackend, dir)
+	defer db.Close()
+
+	blockchain, _, err := LoadOrNewBlockchain(db, newGenesisDoc(), logging.NewNoopLogger())
+	require.NoError(t, err)
+	require.True(t, blockchain.CanCompact())
+
+	// Returns rather than running (or panicking in time.NewTicker) until the context is done
+	for _, interval := range []time.Duration{0, -time.Second} {
+		blockchain.CompactEvery(context.Background(), interval, logging.NewNoopLogger())
+	}
+}
+
+func BenchmarkCommitBlock(b *testing.B) {
+	for _, interval := range []uint64{1, 10, 100} {
+		b.Run(fmt.Sprintf("SaveInterval%d", interval), func(b *testing.B) {
+			dir, err := ioutil.TempDir("", "bcm-bench")
+			require.NoError(b, err)
+			defer os.RemoveAll(dir)
+			db := dbm.NewDB("bench", dbm.GoLevelDBBackend, dir)
+			defer db.Close()
+
+			genesisDoc := newGenesisDoc()
+			blockchain, _, err := LoadOrNewBlockchain(db, genesisDoc, logging.NewNoopLogger(), WithSaveInterval(interval))
+			require.NoError(b, err)
+			blockHash := sha3.Sha3([]byte("block"))
+			appHash := sha3.Sha3([]byte("app"))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err = blockchain.CommitBlock(genesisDoc.GenesisTime.Add(time.Duration(i)*time.Second), blockHash, appHash)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+			require.NoError(b, blockchain.Flush())
+		})
+	}
+}
+
+func TestGenesisHash(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain, _, err := LoadOrNewBlockchain(dbm.NewMemDB(), genesisDoc, logging.NewNoopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, genesisDoc.Hash(), blockchain.GenesisHash())
+	assert.Equal(t, blockchain.AppHashAfterLastBlock(), blockchain.GenesisHash())
+	assert.Equal(t, GenesisStats{
+		Validators: len(genesisDoc.Validators),
+		Accounts:   len(genesisDoc.Accounts),
+	}, blockchain.GenesisStats())
+
+	appHash := sha3.Sha3([]byte("appHash"))
+	err = blockchain.CommitBlock(genesisDoc.GenesisTime.Add(time.Second), sha3.Sha3([]byte("blockHash")), appHash)
+	require.NoError(t, err)
+	assert.Equal(t, appHash, blockchain.AppHashAfterLastBlock())
+	assert.Equal(t, genesisDoc.Hash(), blockchain.GenesisHash())
+}
+
+func TestGenesisAccounts(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	genesisDoc.Accounts[0].PublicKey.PublicKey = []byte{1, 2, 3}
+	genesisDoc.Accounts[0].Permissions.Roles = []string{"root"}
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+
+	accounts := blockchain.GenesisAccounts()
+	require.Len(t, accounts, len(genesisDoc.Accounts))
+	assert.Equal(t, genesisDoc.Accounts, accounts)
+	assert.Equal(t, accounts, blockchain.ReadOnly().GenesisAccounts())
+
+	// Mutating the returned accounts does not touch the genesis
+	accounts[0].Name = "mutated"
+	accounts[0].Amount++
+	accounts[0].PublicKey.PublicKey[0]++
+	accounts[0].Permissions.Roles[0] = "mutated"
+	assert.Equal(t, genesisDoc.Accounts, blockchain.GenesisDoc().Accounts)
+	assert.Equal(t, genesisDoc.Accounts, blockchain.GenesisAccounts())
+}
+
+func TestReadOnly(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	var info interface{} = blockchain.ReadOnly()
+
+	_, ok := info.(BlockchainInfo)
+	assert.True(t, ok)
+	_, ok = info.(*Blockchain)
+	assert.False(t, ok)
+	_, ok = info.(interface {
+		CommitBlock(blockTime time.Time, blockHash, appHash []byte) error
+	})
+	assert.False(t, ok)
+
+	// Reads follow the underlying Blockchain
+	readOnly := blockchain.ReadOnly()
+	assert.Equal(t, genesisDoc.ChainID(), readOnly.ChainID())
+	blockHash := sha3.Sha3([]byte("blockHash"))
+	err := blockchain.CommitBlock(genesisDoc.GenesisTime.Add(time.Second), blockHash, sha3.Sha3([]byte("appHash")))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), readOnly.LastBlockHeight())
+	assert.Equal(t, blockHash, readOnly.LastBlockHash())
+}
+
+func TestGetBlockHeader(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	blockStore := newMockBlockStore()
+	blockchain.SetBlockStore(NewBlockStore(blockStore))
+
+	blockTime := genesisDoc.GenesisTime
+	for height := int64(1); height <= 3; height++ {
+		blockTime = blockTime.Add(time.Second)
+		blockStore.addBlockMeta(height, blockTime)
+		err := blockchain.CommitBlock(blockTime, sha3.Sha3([]byte{byte(height)}), sha3.Sha3([]byte{byte(height)}))
+		require.NoError(t, err)
+	}
+
+	_, err := blockchain.GetBlockHeader(0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no such block")
+
+	header, err := blockchain.GetBlockHeader(2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), header.Height)
+
+	header, err = blockchain.GetBlockHeader(3)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), header.Height)
+
+	// The store knows about this block but it has not been committed to Blockchain
+	blockStore.addBlockMeta(4, blockTime.Add(time.Second))
+	_, err = blockchain.GetBlockHeader(4)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "above last committed height 3")
+
+	assert.Nil(t, blockchain.BlockHash(0))
+	assert.Nil(t, blockchain.BlockHash(4))
+	assert.NotNil(t, blockchain.BlockHash(1))
+
+	_, err = NewBlockchain(nil, genesisDoc).GetBlockHeader(1)
+	require.Error(t, err)
+}
+
+func TestBlockErrors(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	noStore := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	_, err := noStore.GetBlockHeader(1)
+	assert.Equal(t, ErrNoBlockStore, errors.Cause(err), "%v", err)
+	assert.NotEqual(t, ErrBlockNotFound, errors.Cause(err))
+	assert.Contains(t, err.Error(), "has not been given access to tendermint BlockStore")
+	_, err = noStore.GetBlockID(1)
+	assert.Equal(t, ErrNoBlockStore, errors.Cause(err), "%v", err)
+	_, err = noStore.BlockStats(1)
+	assert.Equal(t, ErrNoBlockStore, errors.Cause(err), "%v", err)
+
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	blockStore := newMockBlockStore()
+	blockchain.SetBlockStore(NewBlockStore(blockStore))
+	blockTime := genesisDoc.GenesisTime
+	for height := int64(1); height <= 2; height++ {
+		blockTime = blockTime.Add(time.Second)
+		// The BlockMeta of the second block is missing as if it had been pruned
+		if height == 1 {
+			blockStore.addBlockMeta(height, blockTime)
+		}
+		err := blockchain.CommitBlock(blockTime, sha3.Sha3([]byte{byte(height)}), sha3.Sha3([]byte{byte(height)}))
+		require.NoError(t, err)
+	}
+
+	for name, get := range map[string]func() error{
+		"genesis header": func() error {
+			_, err := blockchain.GetBlockHeader(0)
+			return err
+		},
+		"uncommitted header": func() error {
+			_, err := blockchain.GetBlockHeader(3)
+			return err
+		},
+		"pruned header": func() error {
+			_, err := blockchain.GetBlockHeader(2)
+			return err
+		},
+		"pruned block ID": func() error {
+			_, err := blockchain.GetBlockID(2)
+			return err
+		},
+		"uncommitted block time": func() error {
+			_, err := blockchain.BlockTime(3)
+			return err
+		},
+		"pruned block time": func() error {
+			_, err := blockchain.BlockTime(2)
+			return err
+		},
+		"first previous block hash": func() error {
+			_, err := blockchain.PreviousBlockHash(1)
+			return err
+		},
+		"pruned commit signers": func() error {
+			_, err := blockchain.GetCommitSigners(2)
+			return err
+		},
+		"pruned block stats": func() error {
+			_, err := blockchain.BlockStats(2)
+			return err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := get()
+			require.Error(t, err)
+			assert.Equal(t, ErrBlockNotFound, errors.Cause(err), "%v", err)
+			assert.NotEqual(t, ErrNoBlockStore, errors.Cause(err))
+		})
+	}
+
+	// Callers keep the sentinel and the description
+	_, err = blockchain.BlockTime(2)
+	assert.Equal(t, "BlockTime(): GetBlockHeader(): no such block: BlockMeta at height 2 not found in BlockStore",
+		err.Error())
+	assert.Nil(t, blockchain.BlockHash(2))
+}
+
+func TestBlockTime(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	blockStore := newMockBlockStore()
+	blockchain.SetBlockStore(NewBlockStore(blockStore))
+
+	blockTime := genesisDoc.GenesisTime
+	for height := int64(1); height <= 5; height++ {
+		blockTime = blockTime.Add(time.Duration(height) * time.Second)
+		blockStore.addBlockMeta(height, blockTime)
+		err := blockchain.CommitBlock(blockTime, sha3.Sha3([]byte{byte(height)}), sha3.Sha3([]byte{byte(height)}))
+		require.NoError(t, err)
+	}
+
+	for _, height := range []uint64{1, 3, 5} {
+		header, err := blockchain.GetBlockHeader(height)
+		require.NoError(t, err)
+		blockTime, err := blockchain.BlockTime(height)
+		require.NoError(t, err)
+		assert.Equal(t, header.Time, blockTime)
+		assert.Equal(t, blockStore.blockMetas[int64(height)].Header.Time, blockTime)
+	}
+	// Served from the header cache
+	assert.Equal(t, 3, blockStore.metaLoads)
+
+	_, err := blockchain.BlockTime(0)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of range")
+	_, err = blockchain.BlockTime(6)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of range")
+
+	// Overwriting a height drops cached headers
+	blockStore.addBlockMeta(3, blockTime.Add(time.Hour))
+	require.NoError(t, blockchain.CommitBlockAtHeight(blockTime.Add(time.Hour), sha3.Sha3([]byte("3b")),
+		sha3.Sha3([]byte("3b")), 3))
+	blockTime3, err := blockchain.BlockTime(3)
+	require.NoError(t, err)
+	assert.Equal(t, blockTime.Add(time.Hour), blockTime3)
+
+	// Without a cache every lookup goes to the store
+	blockchain = NewBlockchain(dbm.NewMemDB(), genesisDoc, WithHeaderCacheSize(0))
+	blockchain.SetBlockStore(NewBlockStore(blockStore))
+	require.NoError(t, blockchain.CommitBlockAtHeight(blockTime, nil, sha3.Sha3([]byte("app5")), 5))
+	blockStore.metaLoads = 0
+	for i := 0; i < 2; i++ {
+		_, err = blockchain.BlockTime(2)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, blockStore.metaLoads)
+}
+
+func TestGetBlockHeaderTimeout(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	newSlowBlockchain := func(delay, timeout time.Duration) *Blockchain {
+		blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc, WithBlockStoreTimeout(timeout))
+		blockStore := newMockBlockStore()
+		blockStore.delay = delay
+		blockTime := genesisDoc.GenesisTime.Add(time.Second)
+		blockStore.addBlockMeta(1, blockTime)
+		blockchain.SetBlockStore(NewBlockStore(blockStore))
+		require.NoError(t, blockchain.CommitBlock(blockTime, []byte("hash1"), sha3.Sha3([]byte("app1"))))
+		return blockchain
+	}
+
+	blockchain := newSlowBlockchain(time.Second, 10*time.Millisecond)
+	start := time.Now()
+	_, err := blockchain.GetBlockHeader(1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), context.DeadlineExceeded.Error())
+	assert.True(t, time.Since(start) < time.Second, "should not wait for the BlockStore")
+	assert.Nil(t, blockchain.BlockHash(1))
+
+	// The caller's context applies
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = blockchain.GetBlockHeaderContext(ctx, 1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), context.Canceled.Error())
+
+	// Reads within the deadline succeed
+	blockchain = newSlowBlockchain(time.Millisecond, time.Second)
+	header, err := blockchain.GetBlockHeader(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), header.Height)
+}
+
+func TestGetCommitSigners(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	blockStore := newMockBlockStore()
+	blockchain.SetBlockStore(NewBlockStore(blockStore))
+
+	blockTime := genesisDoc.GenesisTime.Add(time.Second)
+	blockStore.addBlockMeta(1, blockTime)
+	blockStore.addBlockMeta(2, blockTime.Add(time.Second))
+	proposer := genesisDoc.Validators[0].Address
+	blockStore.blockMetas[1].Header.ProposerAddress = proposer.Bytes()
+	require.NoError(t, blockchain.CommitBlock(blockTime, sha3.Sha3([]byte("block1")), sha3.Sha3([]byte("app1"))))
+	require.NoError(t, blockchain.CommitBlock(blockTime.Add(time.Second), sha3.Sha3([]byte("block2")),
+		sha3.Sha3([]byte("app2"))))
+
+	// Leave the last validator absent from the commit
+	commit := &types.Commit{}
+	for _, val := range genesisDoc.Validators[:len(genesisDoc.Validators)-1] {
+		commit.Precommits = append(commit.Precommits, &types.CommitSig{ValidatorAddress: val.Address.Bytes()})
+	}
+	commit.Precommits = append(commit.Precommits, nil)
+	blockStore.commits[1] = commit
+
+	signers, err := blockchain.GetCommitSigners(1)
+	require.NoError(t, err)
+	require.Len(t, signers, len(genesisDoc.Validators)-1)
+	for i, address := range signers {
+		assert.Equal(t, genesisDoc.Validators[i].Address, address)
+	}
+
+	address, err := blockchain.ProposerAddress(1)
+	require.NoError(t, err)
+	assert.Equal(t, proposer, address)
+
+	// No commit data for block 2
+	_, err = blockchain.GetCommitSigners(2)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no commit data")
+
+	_, err = blockchain.GetCommitSigners(3)
+	require.Error(t, err)
+}
+
 func assertState(t *testing.T, blockchain *Blockchain, height uint64, blockTime time.Time, appHash []byte) {
 	assert.Equal(t, height, blockchain.LastBlockHeight())
 	assert.Equal(t, blockTime, blockchain.LastBlockTime())
 	assert.Equal(t, appHash, blockchain.AppHashAfterLastBlock())
 }
 
+func TestArchiveStores(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	primary := newMockBlockStore()
+	archive := newMockBlockStore()
+	blockchain.SetBlockStore(NewBlockStore(primary))
+
+	blockTime := genesisDoc.GenesisTime
+	for height := int64(1); height <= 4; height++ {
+		blockTime = blockTime.Add(time.Second)
+		// The oldest blocks have been moved to the archive
+		if height <= 2 {
+			archive.addBlockMeta(height, blockTime)
+			archive.commits[height] = &types.Commit{}
+		} else {
+			primary.addBlockMeta(height, blockTime)
+		}
+		err := blockchain.CommitBlock(blockTime, sha3.Sha3([]byte{byte(height)}), sha3.Sha3([]byte{byte(height)}))
+		require.NoError(t, err)
+	}
+
+	_, err := blockchain.GetBlockHeader(1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found in BlockStore")
+
+	blockchain.AddArchiveStore(NewBlockStore(newMockBlockStore()))
+	blockchain.AddArchiveStore(NewBlockStore(archive))
+	// Neither store has it
+	delete(archive.blockMetas, 2)
+	for _, height := range []uint64{1, 3, 4} {
+		header, err := blockchain.GetBlockHeader(height)
+		require.NoError(t, err)
+		assert.Equal(t, int64(height), header.Height)
+	}
+	// The primary store is tried first so the archive is only read for its own block
+	assert.Equal(t, 1, archive.metaLoads)
+	_, err = blockchain.GetCommitSigners(1)
+	require.NoError(t, err)
+
+	_, err = blockchain.GetBlockHeader(2)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found in BlockStore or any of 2 archive stores")
+}
+
+func TestPreviousBlockHash(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	blockStore := newMockBlockStore()
+	blockchain.SetBlockStore(NewBlockStore(blockStore))
+
+	blockTime := genesisDoc.GenesisTime
+	for height := int64(1); height <= 4; height++ {
+		blockTime = blockTime.Add(time.Second)
+		blockStore.addBlockMeta(height, blockTime)
+		err := blockchain.CommitBlock(blockTime, sha3.Sha3([]byte{byte(height)}), sha3.Sha3([]byte{byte(height)}))
+		require.NoError(t, err)
+	}
+
+	for height := uint64(2); height <= 4; height++ {
+		previousHash, err := blockchain.PreviousBlockHash(height)
+		require.NoError(t, err)
+		require.NotEmpty(t, previousHash)
+		assert.Equal(t, blockchain.BlockHash(height-1), previousHash)
+	}
+
+	for _, height := range []uint64{0, 1} {
+		_, err := blockchain.PreviousBlockHash(height)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no previous block")
+	}
+	_, err := blockchain.PreviousBlockHash(5)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "above last committed height 4")
+}
+
+func TestGetBlockID(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	_, err := blockchain.GetBlockID(1)
+	require.Error(t, err)
+
+	blockStore := newMockBlockStore()
+	blockchain.SetBlockStore(NewBlockStore(blockStore))
+
+	blockTime := genesisDoc.GenesisTime
+	for height := int64(1); height <= 3; height++ {
+		blockTime = blockTime.Add(time.Second)
+		blockStore.addBlockMeta(height, blockTime)
+		err := blockchain.CommitBlock(blockTime, sha3.Sha3([]byte{byte(height)}), sha3.Sha3([]byte{byte(height)}))
+		require.NoError(t, err)
+	}
+
+	for height := uint64(1); height <= 3; height++ {
+		blockID, err := blockchain.GetBlockID(height)
+		require.NoError(t, err)
+		require.NotEmpty(t, blockID.Hash)
+		assert.Equal(t, blockchain.BlockHash(height), []byte(blockID.Hash))
+		assert.Equal(t, blockStore.blockMetas[int64(height)].BlockID, blockID)
+		assert.False(t, blockID.PartsHeader.IsZero())
+	}
+
+	for _, height := range []uint64{0, 4} {
+		_, err := blockchain.GetBlockID(height)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "out of range")
+	}
+}
+
 func newGenesisDoc() *genesis.GenesisDoc {
 	genesisDoc, _, _ := genesis.NewDeterministicGenesis(3450976).GenesisDoc(23, 10)
 	return genesisDoc
 }
+
+type mockBlockStore struct {
+	blockMetas map[int64]*types.BlockMeta
+	commits    map[int64]*types.Commit
+	txs        map[int64]types.Txs
+	height     int64
+	// Number of calls to LoadBlockMeta
+	metaLoads int
+	// Guards metaLoads against reads abandoned on timeout
+	mtx sync.Mutex
+	// How long LoadBlockMeta takes
+	delay time.Duration
+}
+
+var _ state.BlockStoreRPC = &mockBlockStore{}
+
+func newMockBlockStore() *mockBlockStore {
+	return &mockBlockStore{
+		blockMetas: make(map[int64]*types.BlockMeta),
+		commits:    make(map[int64]*types.Commit),
+		txs:        make(map[int64]types.Txs),
+	}
+}
+
+func (mbs *mockBlockStore) addBlockMeta(height int64, blockTime time.Time) {
+	mbs.blockMetas[height] = &types.BlockMeta{
+		Header: types.Header{
+			ChainID: "mock-chain",
+			Height:  height,
+			Time:    blockTime,
+			// Header.Hash() returns nil without a ValidatorsHash
+			ValidatorsHash: sha3.Sha3([]byte("validators")),
+		},
+	}
+	// Link to the previous block as tendermint does
+	if previous, ok := mbs.blockMetas[height-1]; ok {
+		mbs.blockMetas[height].Header.LastBlockID = previous.BlockID
+	}
+	mbs.blockMetas[height].BlockID = types.BlockID{
+		Hash:        mbs.blockMetas[height].Header.Hash(),
+		PartsHeader: types.PartSetHeader{Total: 1, Hash: sha3.Sha3([]byte("parts"))},
+	}
+	if height > mbs.height {
+		mbs.height = height
+	}
+}
+
+// Adds encoded transactions to the block at height, which must have been added, counting them in its header
+func (mbs *mockBlockStore) addBlockTxs(height int64, txs ...types.Tx) {
+	mbs.txs[height] = append(mbs.txs[height], txs...)
+	header := &mbs.blockMetas[height].Header
+	header.NumTxs = int64(len(mbs.txs[height]))
+	header.TotalTxs = header.NumTxs
+	if previous, ok := mbs.blockMetas[height-1]; ok {
+		header.TotalTxs += previous.Header.TotalTxs
+	}
+}
+
+func (mbs *mockBlockStore) Height() int64 {
+	return mbs.height
+}
+
+func (mbs *mockBlockStore) LoadBlockMeta(height int64) *types.BlockMeta {
+	mbs.mtx.Lock()
+	mbs.metaLoads++
+	mbs.mtx.Unlock()
+	time.Sleep(mbs.delay)
+	return mbs.blockMetas[height]
+}
+
+func (mbs *mockBlockStore) LoadBlock(height int64) *types.Block {
+	blockMeta := mbs.blockMetas[height]
+	if blockMeta == nil {
+		return nil
+	}
+	return &types.Block{Header: blockMeta.Header, Data: types.Data{Txs: mbs.txs[height]}}
+}
+
+func (mbs *mockBlockStore) LoadBlockPart(height int64, index int) *types.Part {
+	return nil
+}
+
+func (mbs *mockBlockStore) LoadBlockCommit(height int64) *types.Commit {
+	return mbs.commits[height]
+}
+
+func (mbs *mockBlockStore) LoadSeenCommit(height int64) *types.Commit {
+	return nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) MarshalBinaryBare(o interface{}) ([]byte, error) {
+	return json.Marshal(o)
+}
+
+func (jsonCodec) UnmarshalBinaryBare(bz []byte, ptr interface{}) error {
+	return json.Unmarshal(bz, ptr)
+}
+
+func TestCommitHooks(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc, WithAppHashLength(1))
+
+	var calls []string
+	hook := func(name string, err error) CommitHook {
+		return func(height uint64, appHash []byte) error {
+			// The block is visible to hooks as committed
+			assert.Equal(t, height, blockchain.persistedState.LastBlockHeight)
+			calls = append(calls, fmt.Sprintf("%s %d %X", name, height, appHash))
+			return err
+		}
+	}
+	blockchain.RegisterCommitHook(hook("first", nil))
+	blockchain.RegisterCommitHook(hook("second", nil))
+
+	blockTime := genesisDoc.GenesisTime.Add(time.Second)
+	require.NoError(t, blockchain.CommitBlock(blockTime, []byte{1}, []byte{0xA1}))
+	require.NoError(t, blockchain.CommitBlockAtHeight(blockTime.Add(time.Second), []byte{2}, []byte{0xA2}, 2))
+	assert.Equal(t, []string{"first 1 A1", "second 1 A1", "first 2 A2", "second 2 A2"}, calls)
+
+	// An error stops the later hooks and is returned but the block stays committed
+	calls = nil
+	blockchain.RegisterCommitHook(hook("failing", fmt.Errorf("index unavailable")))
+	blockchain.RegisterCommitHook(hook("last", nil))
+	err := blockchain.CommitBlock(blockTime.Add(2*time.Second), []byte{3}, []byte{0xA3})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "index unavailable")
+	assert.Equal(t, []string{"first 3 A3", "second 3 A3", "failing 3 A3"}, calls)
+	assert.Equal(t, uint64(3), blockchain.LastBlockHeight())
+}
+
+func TestBlockStats(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	_, err := blockchain.BlockStats(1)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not been given access")
+
+	blockStore := newMockBlockStore()
+	blockchain.SetBlockStore(NewBlockStore(blockStore))
+	codec := txs.NewAminoCodec()
+	encode := func(payload payload.Payload) types.Tx {
+		bs, err := codec.EncodeTx(txs.Enclose(genesisDoc.ChainID(), payload))
+		require.NoError(t, err)
+		return bs
+	}
+	input := &payload.TxInput{Address: genesisDoc.Accounts[0].Address, Amount: 1}
+	callee := genesisDoc.Accounts[1].Address
+
+	blockTime := genesisDoc.GenesisTime
+	for height := int64(1); height <= 3; height++ {
+		blockTime = blockTime.Add(time.Second)
+		blockStore.addBlockMeta(height, blockTime)
+		require.NoError(t, blockchain.CommitBlock(blockTime, []byte{byte(height)}, sha3.Sha3([]byte{byte(height)})))
+	}
+	blockStore.addBlockTxs(1,
+		encode(&payload.CallTx{Input: input, Address: &callee, GasLimit: 100}),
+		encode(&payload.SendTx{Inputs: []*payload.TxInput{input}}))
+	blockStore.addBlockTxs(3,
+		encode(&payload.CallTx{Input: input, Address: &callee, GasLimit: 250}),
+		encode(&payload.CallTx{Input: input, GasLimit: 7}),
+		encode(&payload.CallTx{Input: input, Address: &callee, GasLimit: 40}))
+
+	stats, err := blockchain.BlockStats(1)
+	require.NoError(t, err)
+	assert.Equal(t, BlockStats{Height: 1, NumTxs: 2, TotalTxs: 2, GasLimit: 100}, stats)
+
+	stats, err = blockchain.BlockStats(2)
+	require.NoError(t, err)
+	assert.Equal(t, BlockStats{Height: 2, NumTxs: 0, TotalTxs: 0}, stats)
+
+	stats, err = blockchain.BlockStats(3)
+	require.NoError(t, err)
+	assert.Equal(t, BlockStats{Height: 3, NumTxs: 3, TotalTxs: 3, GasLimit: 297}, stats)
+
+	_, err = blockchain.BlockStats(4)
+	require.Error(t, err)
+}
+
+func TestNilBlockchainInfo(t *testing.T) {
+	var bc *Blockchain
+	var info BlockchainInfo = bc
+	assert.Nil(t, info.GenesisHash())
+	assert.Equal(t, genesis.GenesisDoc{}, info.GenesisDoc())
+	assert.Nil(t, info.GenesisAccounts())
+	assert.Equal(t, "", info.ChainID())
+	assert.Equal(t, uint64(0), info.LastBlockHeight())
+	assert.True(t, info.LastBlockTime().IsZero())
+	assert.True(t, info.LastCommitTime().IsZero())
+	assert.Equal(t, time.Duration(0), info.LastCommitDuration())
+	assert.Nil(t, info.LastBlockHash())
+	assert.Nil(t, info.AppHashAfterLastBlock())
+	assert.Nil(t, info.BlockHash(1))
+	_, err := info.GetBlockHeader(1)
+	assert.Error(t, err)
+	_, err = info.BlockTime(1)
+	assert.Error(t, err)
+
+	assert.Nil(t, bc.GenesisHash())
+	assert.Equal(t, GenesisStats{}, bc.GenesisStats())
+}
+
+func TestBlockRate(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	commit := func(bc *Blockchain, blockTime time.Time) {
+		require.NoError(t, bc.CommitBlock(blockTime, []byte("hash"), sha3.Sha3([]byte("app"))))
+	}
+
+	bc := NewBlockchain(dbm.NewMemDB(), genesisDoc, WithBlockRateWindow(4, 0))
+	blockTime := genesisDoc.GenesisTime
+	assert.Equal(t, 0.0, bc.BlockRate())
+	commit(bc, blockTime)
+	// A single block has no rate
+	assert.Equal(t, 0.0, bc.BlockRate())
+
+	// Fewer than the window of blocks every half second
+	for i := 0; i < 2; i++ {
+		blockTime = blockTime.Add(500 * time.Millisecond)
+		commit(bc, blockTime)
+	}
+	assert.Equal(t, 2.0, bc.BlockRate())
+
+	// Once the window is full only the last 4 blocks count, 3 intervals of 2 seconds
+	for i := 0; i < 3; i++ {
+		blockTime = blockTime.Add(2 * time.Second)
+		commit(bc, blockTime)
+	}
+	assert.Equal(t, 0.5, bc.BlockRate())
+
+	// Recommitting an earlier height restarts the window
+	require.NoError(t, bc.CommitBlockAtHeight(blockTime.Add(time.Second), []byte("hash"), sha3.Sha3([]byte("app")), 2))
+	assert.Equal(t, 0.0, bc.BlockRate())
+
+	// Within a period only the blocks in the last 10 seconds count
+	bc = NewBlockchain(dbm.NewMemDB(), genesisDoc, WithBlockRateWindow(0, 10*time.Second))
+	blockTime = genesisDoc.GenesisTime
+	for i := 0; i < 5; i++ {
+		commit(bc, blockTime)
+		blockTime = blockTime.Add(10 * time.Second)
+	}
+	for i := 0; i < 5; i++ {
+		commit(bc, blockTime)
+		blockTime = blockTime.Add(2 * time.Second)
+	}
+	// Only the fast blocks from 50 to 58 seconds, 4 intervals in 8 seconds
+	assert.Equal(t, 0.5, bc.BlockRate())
+
+	var nilBlockchain *Blockchain
+	assert.Equal(t, 0.0, nilBlockchain.BlockRate())
+}
+
+func TestCommitBlockAppHashLength(t *testing.T) {
+	genesisDoc := newGenesisDoc()
+	blockchain := NewBlockchain(dbm.NewMemDB(), genesisDoc, WithAppHashLength(DefaultAppHashLength))
+	blockTime := genesisDoc.GenesisTime.Add(time.Second)
+
+	// Correct length
+	appHash := sha3.Sha3([]byte("app1"))
+	require.NoError(t, blockchain.CommitBlock(blockTime, sha3.Sha3([]byte("block1")), appHash))
+	assert.Equal(t, appHash, blockchain.AppHashAfterLastBlock())
+
+	// Wrong length
+	err := blockchain.CommitBlock(blockTime.Add(time.Second), sha3.Sha3([]byte("block2")), []byte("short"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has length 5 but app hashes should be 32 bytes long")
+	err = blockchain.CommitWithAppHash(append(appHash, 0))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "has length 33")
+
+	// Nil
+	err = blockchain.CommitBlockAtHeight(blockTime.Add(time.Second), sha3.Sha3([]byte("block2")), nil, 2)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "app hash is nil")
+	require.Error(t, blockchain.CommitWithAppHash(nil))
+
+	// Rejected commits leave the last block as it was
+	assert.Equal(t, uint64(1), blockchain.LastBlockHeight())
+	assert.Equal(t, appHash, blockchain.AppHashAfterLastBlock())
+
+	// The length is configurable
+	blockchain = NewBlockchain(dbm.NewMemDB(), genesisDoc, WithAppHashLength(5))
+	require.NoError(t, blockchain.CommitBlock(blockTime, sha3.Sha3([]byte("block1")), []byte("short")))
+	require.Error(t, blockchain.CommitBlock(blockTime.Add(time.Second), sha3.Sha3([]byte("block2")), appHash))
+
+	// By default the check is off
+	blockchain = NewBlockchain(dbm.NewMemDB(), genesisDoc)
+	require.NoError(t, blockchain.CommitBlock(blockTime, sha3.Sha3([]byte("block1")), []byte("any")))
+	require.NoError(t, blockchain.CommitBlock(blockTime.Add(time.Second), sha3.Sha3([]byte("block2")), nil))
+}
@@ -0,0 +1,66 @@
+package bcm
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/burrow/logging"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// levelDB is implemented by the GoLevelDB backend of dbm, the only backend whose compaction we can trigger
+type levelDB interface {
+	DB() *leveldb.DB
+}
+
+// CanCompact returns whether the database of the Blockchain supports Compact
+func (bc *Blockchain) CanCompact() bool {
+	if bc == nil {
+		return false
+	}
+	_, ok := bc.db.(levelDB)
+	return ok
+}
+
+// Compact compacts the whole database of the Blockchain to reclaim the space of superseded writes, such as the state
+// saved on every commit under the same key. It is safe to call while the Blockchain is read and committed to. For
+// databases that do not support compaction (see CanCompact), including ephemeral blockchains, it does nothing.
+func (bc *Blockchain) Compact() error {
+	if !bc.CanCompact() {
+		return nil
+	}
+	return bc.db.(levelDB).DB().CompactRange(util.Range{})
+}
+
+// CompactEvery compacts the database of the Blockchain every interval until ctx is done, logging any failure and
+// carrying on. If the database does not support compaction, or interval is not positive, it logs that and returns
+// immediately, so it can be run in a goroutine whatever the backend.
+func (bc *Blockchain) CompactEvery(ctx context.Context, interval time.Duration, logger *logging.Logger) {
+	logger = logger.WithScope("CompactEvery")
+	if !bc.CanCompact() {
+		logger.InfoMsg("Database backend does not support compaction so periodic compaction is disabled")
+		return
+	}
+	if interval <= 0 {
+		logger.InfoMsg("Compaction interval must be positive so periodic compaction is disabled",
+			"interval", interval.String())
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			start := time.Now()
+			err := bc.Compact()
+			if err != nil {
+				logger.InfoMsg("Could not compact database", "error", err)
+				continue
+			}
+			logger.TraceMsg("Compacted database", "duration", time.Since(start).String())
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package bcm
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoBlockStore identifies (as their github.com/pkg/errors Cause) the errors of reads of blocks from a
+	// Blockchain that has not been given access to the tendermint BlockStore
+	ErrNoBlockStore = errors.New("Blockchain has not been given access to tendermint BlockStore")
+	// ErrBlockNotFound identifies the errors of reads of blocks at heights without a block, that is genesis, above the
+	// last committed height, or missing from the BlockStore (as when pruned). Failures to read the BlockStore are not
+	// identified by it.
+	ErrBlockNotFound = errors.New("no such block")
+)
+
+// blockError has a descriptive message but is identified by its sentinel error
+type blockError struct {
+	sentinel error
+	message  string
+}
+
+func newBlockError(sentinel error, format string, args ...interface{}) error {
+	return &blockError{sentinel: sentinel, message: fmt.Sprintf(format, args...)}
+}
+
+func (be *blockError) Error() string {
+	return be.message
+}
+
+// Unwrap supports errors.Is and errors.As
+func (be *blockError) Unwrap() error {
+	return be.sentinel
+}
+
+// Cause supports github.com/pkg/errors.Cause
+func (be *blockError) Cause() error {
+	return be.sentinel
+}
+
+// wrapBlockError prefixes the message of err with errHeader, keeping the sentinel of a blockError
+func wrapBlockError(errHeader string, err error) error {
+	if be, ok := err.(*blockError); ok {
+		return newBlockError(be.sentinel, "%s %s", errHeader, be.message)
+	}
+	return fmt.Errorf("%s %v", errHeader, err)
+}
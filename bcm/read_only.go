@@ -0,0 +1,73 @@
+package bcm
+
+import (
+	"time"
+
+	"github.com/hyperledger/burrow/genesis"
+	"github.com/tendermint/tendermint/types"
+)
+
+// ReadOnly returns a view of the Blockchain exposing only the BlockchainInfo reads, so that components that should
+// not commit blocks (such as RPC handlers) cannot reach CommitBlock and friends by type-asserting back to *Blockchain
+func (bc *Blockchain) ReadOnly() BlockchainInfo {
+	return &readOnlyBlockchain{blockchain: bc}
+}
+
+// Forwards each method explicitly rather than embedding so no other method of Blockchain is promoted
+type readOnlyBlockchain struct {
+	blockchain *Blockchain
+}
+
+var _ BlockchainInfo = &readOnlyBlockchain{}
+
+func (ro *readOnlyBlockchain) GenesisHash() []byte {
+	return ro.blockchain.GenesisHash()
+}
+
+func (ro *readOnlyBlockchain) GenesisDoc() genesis.GenesisDoc {
+	return ro.blockchain.GenesisDoc()
+}
+
+func (ro *readOnlyBlockchain) GenesisAccounts() []genesis.Account {
+	return ro.blockchain.GenesisAccounts()
+}
+
+func (ro *readOnlyBlockchain) ChainID() string {
+	return ro.blockchain.ChainID()
+}
+
+func (ro *readOnlyBlockchain) LastBlockHeight() uint64 {
+	return ro.blockchain.LastBlockHeight()
+}
+
+func (ro *readOnlyBlockchain) LastBlockTime() time.Time {
+	return ro.blockchain.LastBlockTime()
+}
+
+func (ro *readOnlyBlockchain) LastCommitTime() time.Time {
+	return ro.blockchain.LastCommitTime()
+}
+
+func (ro *readOnlyBlockchain) LastCommitDuration() time.Duration {
+	return ro.blockchain.LastCommitDuration()
+}
+
+func (ro *readOnlyBlockchain) LastBlockHash() []byte {
+	return ro.blockchain.LastBlockHash()
+}
+
+func (ro *readOnlyBlockchain) AppHashAfterLastBlock() []byte {
+	return ro.blockchain.AppHashAfterLastBlock()
+}
+
+func (ro *readOnlyBlockchain) BlockHash(height uint64) []byte {
+	return ro.blockchain.BlockHash(height)
+}
+
+func (ro *readOnlyBlockchain) GetBlockHeader(height uint64) (*types.Header, error) {
+	return ro.blockchain.GetBlockHeader(height)
+}
+
+func (ro *readOnlyBlockchain) BlockTime(height uint64) (time.Time, error) {
+	return ro.blockchain.BlockTime(height)
+}
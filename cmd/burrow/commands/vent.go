@@ -37,6 +37,26 @@ func Vent(output Output) func(cmd *cli.Cmd) {
 				dbBlockOpt := cmd.BoolOpt("blocks", false, "Create block tables and persist related data")
 				dbTxOpt := cmd.BoolOpt("txs", false, "Create tx tables and persist related data")
 
+				startFromHeadOpt := cmd.BoolOpt("start-from-head", cfg.StartFromHead, "When no blocks have been committed to the DB start from the current chain head instead of genesis, skipping historical data")
+				fillGapsOpt := cmd.BoolOpt("fill-gaps", cfg.FillGaps, "Check that no blocks are missing from the block stream and request any that are before continuing")
+				backfillWindowOpt := cmd.IntOpt("backfill-window", int(cfg.BackfillWindow), "Request historical blocks up to the chain head in windows of this many blocks, checkpointing after each window (0 to request the whole range at once)")
+				sinkURLOpt := cmd.StringOpt("sink-url", cfg.SinkURL, "Send blocks as JSON to this http(s) URL (by POST) or ws(s) URL (over a WebSocket) instead of storing them in the SQL database")
+				protobufFileOpt := cmd.StringOpt("protobuf-file", cfg.ProtobufFile, "Also append each block with rows to this file (or named pipe) as a length-delimited protobuf BlockEvents message (see protobuf/vent.proto) for consumers not written in Go")
+				decodeWorkersOpt := cmd.IntOpt("decode-workers", cfg.DecodeWorkers, "Decode the transactions of each block with up to this many concurrent workers (0 or 1 to decode serially)")
+				maxConcurrentDecodesOpt := cmd.IntOpt("max-concurrent-decodes", cfg.MaxConcurrentDecodes, "Decode at most this many events at once across all workers and blocks to bound the CPU vent uses (0 for GOMAXPROCS)")
+				timeLayoutOpt := cmd.StringOpt("time-layout", cfg.TimeLayout, "Go reference time layout with which to write times to string columns (default RFC3339 with nanoseconds)")
+				timeZoneOpt := cmd.StringOpt("time-zone", cfg.TimeZone, "IANA time zone in which to write times to string columns (default UTC), timestamp columns are always UTC")
+				compressionOpt := cmd.StringOpt("compression", cfg.Compression, "Compress block streams from Burrow with this gRPC compressor, e.g. gzip (trades CPU on both ends for less bandwidth)")
+				maintenanceIntervalOpt := cmd.StringOpt("maintenance-interval", "", "Analyze the projection tables every period as a Go duration, e.g. 1h (by default tables are not maintained)")
+				maintenanceVacuumOpt := cmd.BoolOpt("maintenance-vacuum", cfg.MaintenanceVacuum, "Also vacuum the projection tables during maintenance")
+				rowErrorPolicyOpt := cmd.StringOpt("row-error-policy", string(cfg.RowErrorPolicy), "What to do with an event that cannot be decoded: fail-block (stop), skip-row (drop its row), or dead-letter (store it in the dead-letter table)")
+				heartbeatIntervalOpt := cmd.StringOpt("heartbeat-interval", "", "Commit a block without rows to advance the last committed height when none has been committed for this period as a Go duration, e.g. 1m (by default only blocks with rows are committed)")
+				maxInFlightBlocksOpt := cmd.IntOpt("max-in-flight-blocks", cfg.MaxInFlightBlocks, "Let decoding run up to this many blocks ahead of commits to the database to smooth bursts (0 to commit each block before decoding the next)")
+				orphanedColumnsOpt := cmd.StringOpt("db-orphaned-columns", string(cfg.DBOrphanedColumns), "What to do with columns of existing tables that are not in the projection: warn (log them), error (stop), or drop (drop them and their data)")
+				rowConflictsOpt := cmd.StringOpt("db-row-conflicts", string(cfg.DBRowConflicts), "What to do with a row that violates a constraint of the database (e.g. a unique index after a manual insert): fail (stop) or skip (log it and commit the rest of the block)")
+				maxRowsPerCommitOpt := cmd.IntOpt("db-max-rows-per-commit", cfg.DBMaxRowsPerCommit, "Commit blocks with more rows than this in several database transactions of at most this many rows, advancing the last committed height with the last (0 to commit each block in one transaction)")
+				checkpointFileOpt := cmd.StringOpt("checkpoint-file", cfg.CheckpointFile, "File in which to checkpoint the last committed height in addition to the SQL log table")
+
 				announceEveryOpt := cmd.StringOpt("announce-every", "5s", "Announce vent status every period as a Go duration, e.g. 1ms, 3s, 1h")
 
 				cmd.Before = func() {
@@ -44,11 +64,58 @@ func Vent(output Output) func(cmd *cli.Cmd) {
 					cfg.DBAdapter = *dbOpts.adapter
 					cfg.DBURL = *dbOpts.url
 					cfg.DBSchema = *dbOpts.schema
+					cfg.DBSkipCreateSchema = *dbOpts.skipCreateSchema
+					cfg.DBSetSearchPath = *dbOpts.setSearchPath
+					cfg.SQLTableNames = types.DefaultSQLTableNames.WithPrefix(*dbOpts.tablePrefix)
 					cfg.GRPCAddr = *grpcAddrOpt
 					cfg.HTTPAddr = *httpAddrOpt
 					cfg.LogLevel = *logLevelOpt
 					cfg.AbiFileOrDirs = *abiFileOpt
 					cfg.SpecFileOrDirs = *specFileOrDirOpt
+					cfg.CheckpointFile = *checkpointFileOpt
+					cfg.SinkURL = *sinkURLOpt
+					cfg.ProtobufFile = *protobufFileOpt
+					cfg.StartFromHead = *startFromHeadOpt
+					if *backfillWindowOpt < 0 {
+						output.Fatalf("backfill-window must not be negative")
+					}
+					cfg.BackfillWindow = uint64(*backfillWindowOpt)
+					cfg.FillGaps = *fillGapsOpt
+					cfg.DecodeWorkers = *decodeWorkersOpt
+					if *maxConcurrentDecodesOpt < 0 {
+						output.Fatalf("max-concurrent-decodes must not be negative")
+					}
+					cfg.MaxConcurrentDecodes = *maxConcurrentDecodesOpt
+					if *maxInFlightBlocksOpt < 0 {
+						output.Fatalf("max-in-flight-blocks must not be negative")
+					}
+					cfg.MaxInFlightBlocks = *maxInFlightBlocksOpt
+					if *maxRowsPerCommitOpt < 0 {
+						output.Fatalf("db-max-rows-per-commit must not be negative")
+					}
+					cfg.DBMaxRowsPerCommit = *maxRowsPerCommitOpt
+					cfg.TimeLayout = *timeLayoutOpt
+					cfg.TimeZone = *timeZoneOpt
+					cfg.Compression = *compressionOpt
+					cfg.MaintenanceVacuum = *maintenanceVacuumOpt
+					cfg.RowErrorPolicy = config.RowErrorPolicy(*rowErrorPolicyOpt)
+					switch cfg.RowErrorPolicy {
+					case config.FailBlockPolicy, config.SkipRowPolicy, config.DeadLetterRowPolicy:
+					default:
+						output.Fatalf("unknown row-error-policy %s", *rowErrorPolicyOpt)
+					}
+					cfg.DBOrphanedColumns = types.OrphanedColumnsPolicy(*orphanedColumnsOpt)
+					switch cfg.DBOrphanedColumns {
+					case types.WarnOrphanedColumns, types.ErrorOrphanedColumns, types.DropOrphanedColumns:
+					default:
+						output.Fatalf("unknown db-orphaned-columns %s", *orphanedColumnsOpt)
+					}
+					cfg.DBRowConflicts = types.RowConflictsPolicy(*rowConflictsOpt)
+					switch cfg.DBRowConflicts {
+					case types.FailRowConflicts, types.SkipRowConflicts:
+					default:
+						output.Fatalf("unknown db-row-conflicts %s", *rowConflictsOpt)
+					}
 					if *dbBlockOpt {
 						cfg.SpecOpt |= sqlsol.Block
 					}
@@ -56,6 +123,22 @@ func Vent(output Output) func(cmd *cli.Cmd) {
 						cfg.SpecOpt |= sqlsol.Tx
 					}
 
+					if *maintenanceIntervalOpt != "" {
+						var err error
+						cfg.MaintenanceInterval, err = time.ParseDuration(*maintenanceIntervalOpt)
+						if err != nil {
+							output.Fatalf("could not parse maintenance-interval duration %s: %v", *maintenanceIntervalOpt, err)
+						}
+					}
+
+					if *heartbeatIntervalOpt != "" {
+						var err error
+						cfg.HeartbeatInterval, err = time.ParseDuration(*heartbeatIntervalOpt)
+						if err != nil {
+							output.Fatalf("could not parse heartbeat-interval duration %s: %v", *heartbeatIntervalOpt, err)
+						}
+					}
+
 					if *announceEveryOpt != "" {
 						var err error
 						cfg.AnnounceEvery, err = time.ParseDuration(*announceEveryOpt)
@@ -65,8 +148,10 @@ func Vent(output Output) func(cmd *cli.Cmd) {
 					}
 				}
 
-				cmd.Spec = "--spec=<spec file or dir> --abi=<abi file or dir> [--db-adapter] [--db-url] [--db-schema] " +
-					"[--blocks] [--txs] [--grpc-addr] [--http-addr] [--log-level] [--announce-every=<duration>]"
+				cmd.Spec = "--spec=<spec file or dir> [--abi=<abi file or dir>] [--db-adapter] [--db-url] [--db-schema] [--table-prefix] [--db-skip-create-schema] [--db-search-path] " +
+					"[--blocks] [--txs] [--grpc-addr] [--http-addr] [--log-level] [--announce-every=<duration>] " +
+					"[--checkpoint-file] [--start-from-head] [--backfill-window] [--fill-gaps] [--sink-url] [--decode-workers] [--time-layout] [--time-zone] [--compression] " +
+					"[--maintenance-interval=<duration>] [--maintenance-vacuum] [--row-error-policy] [--heartbeat-interval=<duration>] [--max-in-flight-blocks] [--db-max-rows-per-commit]"
 
 				cmd.Action = func() {
 					log, err := lifecycle.NewStdErrLogger()
@@ -78,13 +163,17 @@ func Vent(output Output) func(cmd *cli.Cmd) {
 					consumer := service.NewConsumer(cfg, log, make(chan types.EventData))
 					server := service.NewServer(cfg, log, consumer)
 
-					projection, err := sqlsol.SpecLoader(cfg.SpecFileOrDirs, cfg.SpecOpt)
+					projection, err := sqlsol.SpecLoaderWithTableNames(cfg.SpecFileOrDirs, cfg.SpecOpt, cfg.SQLTableNames)
 					if err != nil {
 						output.Fatalf("Spec loader error: %v", err)
 					}
-					abiSpec, err := abi.LoadPath(cfg.AbiFileOrDirs...)
-					if err != nil {
-						output.Fatalf("ABI loader error: %v", err)
+					// A spec of only raw event classes needs no ABI
+					var abiSpec *abi.AbiSpec
+					if len(cfg.AbiFileOrDirs) > 0 {
+						abiSpec, err = abi.LoadPath(cfg.AbiFileOrDirs...)
+						if err != nil {
+							output.Fatalf("ABI loader error: %v", err)
+						}
 					}
 
 					var wg sync.WaitGroup
@@ -144,7 +233,8 @@ func Vent(output Output) func(cmd *cli.Cmd) {
 					timeLayout))
 				prefixOpt := cmd.StringOpt("p prefix", "", "")
 
-				cmd.Spec = "[--db-adapter] [--db-url] [--db-schema] [--time=<date/time to up to which to restore>] " +
+				cmd.Spec = "[--db-adapter] [--db-url] [--db-schema] [--table-prefix] [--db-skip-create-schema] [--db-search-path] " +
+					"[--time=<date/time to up to which to restore>] " +
 					"[--prefix=<destination table prefix>]"
 
 				var restoreTime time.Time
@@ -166,10 +256,14 @@ func Vent(output Output) func(cmd *cli.Cmd) {
 						output.Fatalf("failed to load logger: %v", err)
 					}
 					db, err := sqldb.NewSQLDB(types.SQLConnection{
-						DBAdapter: *dbOpts.adapter,
-						DBURL:     *dbOpts.url,
-						DBSchema:  *dbOpts.schema,
-						Log:       log.With("service", "vent"),
+						DBAdapter:  *dbOpts.adapter,
+						DBURL:      *dbOpts.url,
+						DBSchema:   *dbOpts.schema,
+						TableNames: types.DefaultSQLTableNames.WithPrefix(*dbOpts.tablePrefix),
+						Log:        log.With("service", "vent"),
+
+						SkipCreateSchema: *dbOpts.skipCreateSchema,
+						SetSearchPath:    *dbOpts.setSearchPath,
 					})
 					if err != nil {
 						output.Fatalf("Could not connect to SQL DB: %v", err)
@@ -198,9 +292,12 @@ func Vent(output Output) func(cmd *cli.Cmd) {
 }
 
 type dbOpts struct {
-	adapter *string
-	url     *string
-	schema  *string
+	adapter          *string
+	url              *string
+	schema           *string
+	tablePrefix      *string
+	skipCreateSchema *bool
+	setSearchPath    *bool
 }
 
 func sqlDBOpts(cmd *cli.Cmd, cfg *config.VentConfig) dbOpts {
@@ -208,5 +305,11 @@ func sqlDBOpts(cmd *cli.Cmd, cfg *config.VentConfig) dbOpts {
 		adapter: cmd.StringOpt("db-adapter", cfg.DBAdapter, "Database adapter, 'postgres' or 'sqlite' (if built with the sqlite tag) are supported"),
 		url:     cmd.StringOpt("db-url", cfg.DBURL, "PostgreSQL database URL or SQLite db file path"),
 		schema:  cmd.StringOpt("db-schema", cfg.DBSchema, "PostgreSQL database schema (empty for SQLite)"),
+		tablePrefix: cmd.StringOpt("table-prefix", "", "Prefix for the names of the vent log, dictionary, chain, "+
+			"block, and tx tables allowing independent vent instances to share a schema"),
+		skipCreateSchema: cmd.BoolOpt("db-skip-create-schema", cfg.DBSkipCreateSchema, "Require the PostgreSQL "+
+			"schema to exist rather than creating it if missing, for roles without the CREATE privilege on the database"),
+		setSearchPath: cmd.BoolOpt("db-search-path", cfg.DBSetSearchPath, "Set the search_path of each PostgreSQL "+
+			"connection to the schema so unqualified names (e.g. in triggers and ad hoc queries) resolve within it"),
 	}
 }
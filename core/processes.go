@@ -74,10 +74,14 @@ func DatabaseLauncher(kern *Kernel) process.Launcher {
 		Name:    DatabaseProcessName,
 		Enabled: true,
 		Launch: func() (process.Process, error) {
-			// Just close database
+			// Make sure any unsynced blockchain state is on disk and close database
 			return process.ShutdownFunc(func(ctx context.Context) error {
+				var err error
+				if kern.Blockchain != nil {
+					err = kern.Blockchain.Flush()
+				}
 				kern.database.Close()
-				return nil
+				return err
 			}), nil
 		},
 	}
@@ -91,7 +95,7 @@ func NoConsensusLauncher(kern *Kernel) process.Launcher {
 		Launch: func() (process.Process, error) {
 			accountState := kern.State
 			nameRegState := kern.State
-			kern.Service = rpc.NewService(accountState, nameRegState, kern.Blockchain, kern.State, nil, kern.Logger)
+			kern.Service = rpc.NewService(accountState, nameRegState, kern.Blockchain.ReadOnly(), kern.State, nil, kern.Logger)
 			// TimeoutFactor scales in units of seconds
 			blockDuration := time.Duration(kern.timeoutFactor * float64(time.Second))
 			//proc := abci.NewProcess(kern.checker, kern.committer, kern.Blockchain, kern.txCodec, blockDuration, kern.Panic)
@@ -119,7 +123,7 @@ func TendermintLauncher(kern *Kernel) process.Launcher {
 
 			accountState := kern.State
 			nameRegState := kern.State
-			kern.Service = rpc.NewService(accountState, nameRegState, kern.Blockchain, kern.State, nodeView, kern.Logger)
+			kern.Service = rpc.NewService(accountState, nameRegState, kern.Blockchain.ReadOnly(), kern.State, nodeView, kern.Logger)
 
 			kern.Blockchain.SetBlockStore(bcm.NewBlockStore(nodeView.BlockStore()))
 			// Provide execution accounts against checker state so that we can assign sequence numbers
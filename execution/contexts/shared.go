@@ -142,7 +142,7 @@ func HasPermission(accountGetter acmstate.AccountGetter, acc *acm.Account, perm
 		return false
 	}
 
-	v, err := acc.Permissions.Base.Compose(acmstate.GlobalAccountPermissions(accountGetter).Base).Get(perm)
+	v, err := acc.EffectivePermissions(acmstate.GlobalPermissionsAccount(accountGetter)).Get(perm)
 	if err != nil {
 		logger.TraceMsg("Error obtaining permission value (will default to false/deny)",
 			"perm_flag", perm.String(),
@@ -102,6 +102,26 @@ func TestSNativeContractDescription_Dispatch(t *testing.T) {
 	assert.Equal(t, retValue, LeftPadBytes([]byte{1}, 32))
 }
 
+func TestSNativeHasRoleMatchesExactly(t *testing.T) {
+	st := newAppState()
+	perms := permission.NewAccountPermissions()
+	perms.AddRole("Admin")
+	account := &acm.Account{
+		Address:     crypto.Address{3, 3, 3},
+		Permissions: perms,
+	}
+	require.NoError(t, st.UpdateAccount(account))
+	cache := NewState(st, blockHashGetter)
+	gas := uint64(1000)
+
+	// Roles are not normalised on chain so a role in another case is a different role
+	for role, expected := range map[string]bool{"Admin": true, "admin": false, "ADMIN": false} {
+		ret, err := hasRole(cache, account.Address, &gas, logger, &hasRoleArgs{Account: account.Address, Role: role})
+		require.NoError(t, err)
+		assert.Equal(t, hasRoleRets{Result: expected}, ret, "hasRole %s", role)
+	}
+}
+
 func TestSNativeContractDescription_Address(t *testing.T) {
 	contract := NewSNativeContract("A comment",
 		"CoolButVeryLongNamedContractOfDoom")
@@ -285,7 +285,7 @@ func validateInputs(tx *txs.Tx, getter acmstate.AccountGetter) error {
 			return errors.ErrorCodeInsufficientFunds
 		}
 		// Check for Input permission
-		v, err := acc.Permissions.Base.Compose(acmstate.GlobalAccountPermissions(getter).Base).Get(permission.Input)
+		v, err := acc.EffectivePermissions(acmstate.GlobalPermissionsAccount(getter)).Get(permission.Input)
 		if err != nil {
 			return err
 		}
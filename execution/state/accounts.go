@@ -24,7 +24,7 @@ func (s *ReadState) GetAccount(address crypto.Address) (*acm.Account, error) {
 
 func (ws *writeState) statsAddAccount(acc *acm.Account) {
 	if acc != nil {
-		if len(acc.EVMCode) > 0 || len(acc.WASMCode) > 0 {
+		if acc.IsContract() {
 			ws.accountStats.AccountsWithCode++
 		} else {
 			ws.accountStats.AccountsWithoutCode++
@@ -34,7 +34,7 @@ func (ws *writeState) statsAddAccount(acc *acm.Account) {
 
 func (ws *writeState) statsRemoveAccount(acc *acm.Account) {
 	if acc != nil {
-		if len(acc.EVMCode) > 0 || len(acc.WASMCode) > 0 {
+		if acc.IsContract() {
 			ws.accountStats.AccountsWithCode--
 		} else {
 			ws.accountStats.AccountsWithoutCode--
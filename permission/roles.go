@@ -0,0 +1,83 @@
+package permission
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RoleCase is how the case of role names is normalised. Roles are free-form strings so without normalisation roles
+// that differ only in case (such as "Admin" and "admin") are distinct, and checking for one misses the other.
+type RoleCase string
+
+const (
+	// Roles are kept as given, as is the empty RoleCase
+	RoleCaseAsIs RoleCase = "as-is"
+	// Roles are lower-cased
+	RoleCaseLower RoleCase = "lower"
+	// Roles are upper-cased
+	RoleCaseUpper RoleCase = "upper"
+)
+
+// Validate returns an error if the RoleCase is not one of the RoleCase constants (or empty)
+func (rc RoleCase) Validate() error {
+	switch rc {
+	case "", RoleCaseAsIs, RoleCaseLower, RoleCaseUpper:
+		return nil
+	default:
+		return fmt.Errorf("unknown role case '%s', expected %s, %s, or %s", rc, RoleCaseAsIs, RoleCaseLower,
+			RoleCaseUpper)
+	}
+}
+
+// Normalize returns role in the case of the RoleCase
+func (rc RoleCase) Normalize(role string) string {
+	switch rc {
+	case RoleCaseLower:
+		return strings.ToLower(role)
+	case RoleCaseUpper:
+		return strings.ToUpper(role)
+	default:
+		return role
+	}
+}
+
+// AddRoleWithCase is AddRole of role normalised with roleCase
+func (ap *AccountPermissions) AddRoleWithCase(role string, roleCase RoleCase) bool {
+	return ap.AddRole(roleCase.Normalize(role))
+}
+
+// HasRoleWithCase is HasRole of role normalised with roleCase
+func (ap AccountPermissions) HasRoleWithCase(role string, roleCase RoleCase) bool {
+	return ap.HasRole(roleCase.Normalize(role))
+}
+
+// RemoveRoleWithCase is RemoveRole of role normalised with roleCase
+func (ap *AccountPermissions) RemoveRoleWithCase(role string, roleCase RoleCase) bool {
+	return ap.RemoveRole(roleCase.Normalize(role))
+}
+
+// ValidateRoles returns an error if a role is not normalised with roleCase, or if two roles are the same but for case
+// (and the padding added by AddRole) since without normalisation they are most likely one role given inconsistently
+func (ap AccountPermissions) ValidateRoles(roleCase RoleCase) error {
+	err := roleCase.Validate()
+	if err != nil {
+		return err
+	}
+	for i, role := range ap.Roles {
+		if roleCase.Normalize(role) != role {
+			return fmt.Errorf("role '%s' should be %s case", trimRole(role), roleCase)
+		}
+		for _, other := range ap.Roles[:i] {
+			if strings.EqualFold(trimRole(role), trimRole(other)) {
+				return fmt.Errorf("role '%s' collides with role '%s', roles should differ by more than case",
+					trimRole(role), trimRole(other))
+			}
+		}
+	}
+	return nil
+}
+
+// trimRole removes the zero padding added to roles by AddRole
+func trimRole(role string) string {
+	return strings.TrimRight(role, "\x00")
+}
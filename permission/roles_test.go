@@ -0,0 +1,67 @@
+package permission
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleCase(t *testing.T) {
+	assert.Equal(t, "Admin", RoleCaseAsIs.Normalize("Admin"))
+	assert.Equal(t, "Admin", RoleCase("").Normalize("Admin"))
+	assert.Equal(t, "admin", RoleCaseLower.Normalize("Admin"))
+	assert.Equal(t, "ADMIN", RoleCaseUpper.Normalize("Admin"))
+	require.NoError(t, RoleCase("").Validate())
+	require.Error(t, RoleCase("title").Validate())
+
+	ap := NewAccountPermissions()
+	assert.True(t, ap.AddRoleWithCase("Admin", RoleCaseLower))
+	// The same role in any case
+	assert.False(t, ap.AddRoleWithCase("ADMIN", RoleCaseLower))
+	assert.True(t, ap.HasRoleWithCase("aDmIn", RoleCaseLower))
+	assert.True(t, ap.HasRole("admin"))
+	assert.False(t, ap.HasRole("Admin"))
+	require.NoError(t, ap.ValidateRoles(RoleCaseLower))
+	require.NoError(t, ap.ValidateRoles(RoleCaseAsIs))
+	require.Error(t, ap.ValidateRoles(RoleCaseUpper))
+	assert.True(t, ap.RemoveRoleWithCase("Admin", RoleCaseLower))
+	assert.Empty(t, ap.Roles)
+}
+
+func TestValidateRolesCollisions(t *testing.T) {
+	ap := NewAccountPermissions()
+	// Without normalisation these are distinct roles
+	assert.True(t, ap.AddRole("Admin"))
+	assert.True(t, ap.AddRole("admin"))
+	err := ap.ValidateRoles(RoleCaseAsIs)
+	require.Error(t, err)
+	assert.Equal(t, "role 'admin' collides with role 'Admin', roles should differ by more than case", err.Error())
+
+	// Whether or not the roles were padded by AddRole
+	ap = AccountPermissions{Roles: []string{"Admin", "minter"}}
+	require.NoError(t, ap.ValidateRoles(RoleCaseAsIs))
+	assert.True(t, ap.AddRole("MINTER"))
+	require.Error(t, ap.ValidateRoles(RoleCaseAsIs))
+
+	ap = AccountPermissions{Roles: []string{"root", "bums"}}
+	require.NoError(t, ap.ValidateRoles(RoleCaseAsIs))
+	require.NoError(t, ap.ValidateRoles(RoleCaseLower))
+	require.Error(t, ap.ValidateRoles("title"))
+}
+
+func TestRolesExactByDefault(t *testing.T) {
+	// Without a RoleCase roles match exactly as they always have, since hasRole, addRole, and removeRole are run on
+	// chain and every node must agree on them
+	ap := NewAccountPermissions()
+	assert.True(t, ap.AddRole("Admin"))
+	assert.True(t, ap.AddRole("admin"))
+	assert.True(t, ap.HasRole("Admin"))
+	assert.False(t, ap.HasRole("ADMIN"))
+	assert.False(t, ap.RemoveRole("ADMIN"))
+	assert.Len(t, ap.Roles, 2)
+
+	// Roles set directly without the padding added by AddRole do not match
+	ap = AccountPermissions{Roles: []string{"minter"}}
+	assert.False(t, ap.HasRole("minter"))
+}
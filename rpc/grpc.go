@@ -8,6 +8,8 @@ import (
 	"github.com/hyperledger/burrow/logging/structure"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	// Register the gzip compressor so that clients (such as vent) may request compressed streams
+	_ "google.golang.org/grpc/encoding/gzip"
 )
 
 func NewGRPCServer(logger *logging.Logger) *grpc.Server {
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/hyperledger/burrow/vent/sqlsol"
@@ -9,6 +10,31 @@ import (
 
 const DefaultPostgresDBURL = "postgres://postgres@localhost:5432/postgres?sslmode=disable"
 
+// ChannelDelivery determines how committed blocks are delivered to the external events channel
+type ChannelDelivery string
+
+const (
+	// Send each committed block only if the channel can receive it immediately (i.e. a receiver is waiting or there is
+	// buffer space), otherwise drop it. The consumer never waits on the channel but subscribers may miss blocks.
+	BestEffortDelivery ChannelDelivery = "best-effort"
+	// Send every committed block in commit order, blocking until the channel receives it. Subscribers never miss a
+	// committed block but a slow subscriber limits the throughput of the consumer to its own rate.
+	GuaranteedDelivery ChannelDelivery = "guaranteed"
+)
+
+// RowErrorPolicy determines what happens when an event matched by the projection cannot be decoded into a row
+type RowErrorPolicy string
+
+const (
+	// Fail the whole block so that the consumer stops with the error and no row of the block is committed
+	FailBlockPolicy RowErrorPolicy = "fail-block"
+	// Drop the row of the bad event and commit the rest of the block
+	SkipRowPolicy RowErrorPolicy = "skip-row"
+	// Commit the bad event (with the error) to the dead-letter table in place of its row along with the rest of the
+	// block, so that it can be inspected and replayed later
+	DeadLetterRowPolicy RowErrorPolicy = "dead-letter"
+)
+
 // VentConfig is a set of configuration parameters
 type VentConfig struct {
 	DBAdapter      string
@@ -20,20 +46,99 @@ type VentConfig struct {
 	SpecFileOrDirs []string
 	AbiFileOrDirs  []string
 	SpecOpt        sqlsol.SpecOpt
+	// Names of the log, dictionary, chain, block, and tx tables
+	SQLTableNames types.SQLTableNames
 	// Announce status every AnnouncePeriod
 	AnnounceEvery time.Duration
+	// How to deliver committed blocks to the external events channel passed to NewConsumer
+	ChannelDelivery ChannelDelivery
+	// If non-empty the last committed height is also checkpointed to this file
+	CheckpointFile string
+	// If there is no previously committed height start streaming from the current chain head rather than from
+	// genesis, skipping all historical blocks
+	StartFromHead bool
+	// If non-zero historical blocks up to the chain head are requested in windows of at most this many blocks, with
+	// the checkpoint saved once each window has been committed, before streaming continues as normal
+	BackfillWindow uint64
+	// If true each streamed block (after any backfill) is checked to follow on from the last block processed and
+	// missing blocks are requested before continuing, in case the stream skipped blocks
+	FillGaps bool
+	// If non-empty blocks are sent to this http(s) or ws(s) URL instead of being stored in the SQL database
+	SinkURL string
+	// If non-empty each block with rows is also appended to this file (which may be a named pipe) as a protobuf
+	// types.BlockEvents message prefixed by its length as a varint
+	ProtobufFile string
+	// If greater than one the transactions of each block are decoded by up to this many concurrent workers, rows are
+	// still committed in transaction order. Otherwise transactions are decoded one after another.
+	DecodeWorkers int
+	// The most events and calls decoded at once across all the blocks, transactions, and projections of the consumer,
+	// a hard ceiling on the CPU taken by decoding whatever the DecodeWorkers. If zero runtime.GOMAXPROCS.
+	MaxConcurrentDecodes int
+	// Go reference time layout with which times are written to string columns, if empty types.DefaultTimeLayout
+	TimeLayout string
+	// IANA name of the zone in which times are written to string columns, if empty UTC. Timestamp columns are always
+	// written in UTC.
+	TimeZone string
+	// Name of a gRPC compressor (e.g. "gzip") with which to compress block streams, if empty no compression
+	Compression string
+	// If non-zero the projection tables are analyzed every MaintenanceInterval in the background, if zero never
+	MaintenanceInterval time.Duration
+	// Whether maintenance also vacuums the projection tables
+	MaintenanceVacuum bool
+	// What to do with events that cannot be decoded, if empty FailBlockPolicy
+	RowErrorPolicy RowErrorPolicy
+	// If non-zero a block without any rows is committed (advancing the last committed height) when none has been for
+	// HeartbeatInterval, so the height keeps up with a quiet chain. If zero only blocks with rows are committed.
+	HeartbeatInterval time.Duration
+	// The number of decoded blocks that may wait to be committed, so decoding can run ahead of a slow database to
+	// smooth bursts. Beyond that decoding waits for commits. If zero each block is committed before the next is
+	// decoded.
+	MaxInFlightBlocks int
+	// If true the DBSchema must already exist, otherwise it is created if missing
+	DBSkipCreateSchema bool
+	// If true the search_path of each database connection is set to DBSchema so unqualified names resolve within it
+	DBSetSearchPath bool
+	// What to do with columns of existing tables that are not in the projection, if empty WarnOrphanedColumns
+	DBOrphanedColumns types.OrphanedColumnsPolicy
+	// What to do with a row that violates a constraint of the database, if empty FailRowConflicts
+	DBRowConflicts types.RowConflictsPolicy
+	// If greater than zero blocks with more rows are committed in several transactions of at most this many rows,
+	// the last committed height only advances with the last of them. Otherwise each block is one transaction.
+	DBMaxRowsPerCommit int
 }
 
 // DefaultFlags returns a configuration with default values
 func DefaultVentConfig() *VentConfig {
 	return &VentConfig{
-		DBAdapter:     types.PostgresDB,
-		DBURL:         DefaultPostgresDBURL,
-		DBSchema:      "vent",
-		GRPCAddr:      "localhost:10997",
-		HTTPAddr:      "0.0.0.0:8080",
-		LogLevel:      "debug",
-		SpecOpt:       sqlsol.None,
-		AnnounceEvery: time.Second * 5,
+		DBAdapter:       types.PostgresDB,
+		DBURL:           DefaultPostgresDBURL,
+		DBSchema:        "vent",
+		GRPCAddr:        "localhost:10997",
+		HTTPAddr:        "0.0.0.0:8080",
+		LogLevel:        "debug",
+		SpecOpt:         sqlsol.None,
+		SQLTableNames:   types.DefaultSQLTableNames,
+		AnnounceEvery:   time.Second * 5,
+		ChannelDelivery: BestEffortDelivery,
+		RowErrorPolicy:  FailBlockPolicy,
+
+		DBOrphanedColumns: types.WarnOrphanedColumns,
+		DBRowConflicts:    types.FailRowConflicts,
+	}
+}
+
+// TimeFormat returns the layout and zone with which to write times to columns
+func (cfg *VentConfig) TimeFormat() (types.TimeFormat, error) {
+	timeFormat := types.TimeFormat{
+		Layout:   cfg.TimeLayout,
+		Location: time.UTC,
+	}
+	if cfg.TimeZone != "" {
+		location, err := time.LoadLocation(cfg.TimeZone)
+		if err != nil {
+			return types.TimeFormat{}, fmt.Errorf("could not load time zone '%s': %v", cfg.TimeZone, err)
+		}
+		timeFormat.Location = location
 	}
+	return timeFormat, nil
 }
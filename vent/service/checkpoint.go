@@ -0,0 +1,124 @@
+package service
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Checkpointer records the last block height committed by the consumer in a store independent of the SQL database
+// so that progress can be tracked even if the target database has its own failure modes
+type Checkpointer interface {
+	// Save records height as the last committed block
+	Save(height uint64) error
+	// Load returns the last saved height, or false if no height has been saved
+	Load() (height uint64, ok bool, err error)
+}
+
+// FileCheckpointer stores the checkpoint height as decimal text in a single file
+type FileCheckpointer struct {
+	path string
+}
+
+var _ Checkpointer = &FileCheckpointer{}
+
+func NewFileCheckpointer(path string) *FileCheckpointer {
+	return &FileCheckpointer{
+		path: path,
+	}
+}
+
+func (fc *FileCheckpointer) Save(height uint64) error {
+	// Write to a temporary file and rename so that we never leave a partially written checkpoint
+	tmp, err := ioutil.TempFile(filepath.Dir(fc.path), filepath.Base(fc.path))
+	if err != nil {
+		return fmt.Errorf("FileCheckpointer could not create temporary checkpoint file: %v", err)
+	}
+	_, err = tmp.WriteString(strconv.FormatUint(height, 10))
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("FileCheckpointer could not write checkpoint: %v", err)
+	}
+	err = tmp.Close()
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("FileCheckpointer could not close checkpoint file: %v", err)
+	}
+	err = os.Rename(tmp.Name(), fc.path)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("FileCheckpointer could not move checkpoint file to %s: %v", fc.path, err)
+	}
+	return nil
+}
+
+func (fc *FileCheckpointer) Load() (uint64, bool, error) {
+	bs, err := ioutil.ReadFile(fc.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("FileCheckpointer could not read checkpoint file %s: %v", fc.path, err)
+	}
+	height, err := strconv.ParseUint(strings.TrimSpace(string(bs)), 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("FileCheckpointer could not parse checkpoint file %s: %v", fc.path, err)
+	}
+	return height, true, nil
+}
+
+// MemoryCheckpointer holds the checkpoint height in memory, it is useful when vent is used as a library and for tests
+type MemoryCheckpointer struct {
+	sync.Mutex
+	height uint64
+	saved  bool
+}
+
+var _ Checkpointer = &MemoryCheckpointer{}
+
+func NewMemoryCheckpointer() *MemoryCheckpointer {
+	return &MemoryCheckpointer{}
+}
+
+func (mc *MemoryCheckpointer) Save(height uint64) error {
+	mc.Lock()
+	defer mc.Unlock()
+	mc.height = height
+	mc.saved = true
+	return nil
+}
+
+func (mc *MemoryCheckpointer) Load() (uint64, bool, error) {
+	mc.Lock()
+	defer mc.Unlock()
+	return mc.height, mc.saved, nil
+}
+
+// reconcileCheckpoint returns the lower of the height stored in the SQL database and the height held by the
+// checkpointer (if any) so that we never skip a block that one of the stores has not recorded as committed. A
+// checkpointer that has no height yet, as when it is first enabled for an existing database, is seeded with the
+// height of the database rather than replaying the chain from the start.
+func reconcileCheckpoint(dbHeight uint64, checkpointer Checkpointer) (uint64, error) {
+	if checkpointer == nil {
+		return dbHeight, nil
+	}
+	checkpointHeight, ok, err := checkpointer.Load()
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		if err := checkpointer.Save(dbHeight); err != nil {
+			return 0, err
+		}
+		return dbHeight, nil
+	}
+	if checkpointHeight < dbHeight {
+		return checkpointHeight, nil
+	}
+	return dbHeight, nil
+}
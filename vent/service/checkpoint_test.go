@@ -0,0 +1,70 @@
+package service
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileCheckpointer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vent-checkpoint")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	checkpointer := NewFileCheckpointer(filepath.Join(dir, "checkpoint"))
+	_, ok, err := checkpointer.Load()
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, checkpointer.Save(34))
+	require.NoError(t, checkpointer.Save(35))
+
+	height, ok, err := NewFileCheckpointer(filepath.Join(dir, "checkpoint")).Load()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(35), height)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "bad"), []byte("not a height"), 0600))
+	_, _, err = NewFileCheckpointer(filepath.Join(dir, "bad")).Load()
+	require.Error(t, err)
+}
+
+func TestReconcileCheckpoint(t *testing.T) {
+	height, err := reconcileCheckpoint(10, nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), height)
+
+	// A checkpointer without a height is seeded with that of the database rather than starting from genesis
+	checkpointer := NewMemoryCheckpointer()
+	height, err = reconcileCheckpoint(10, checkpointer)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), height)
+	saved, ok, err := checkpointer.Load()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(10), saved)
+
+	require.NoError(t, checkpointer.Save(7))
+	height, err = reconcileCheckpoint(10, checkpointer)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), height)
+
+	require.NoError(t, checkpointer.Save(12))
+	height, err = reconcileCheckpoint(10, checkpointer)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), height)
+}
+
+func TestBackfillWindowEnd(t *testing.T) {
+	assert.Equal(t, uint64(2), backfillWindowEnd(0, 7, 3))
+	assert.Equal(t, uint64(7), backfillWindowEnd(6, 7, 3))
+	assert.Equal(t, uint64(4), backfillWindowEnd(4, 4, 10))
+	assert.Equal(t, uint64(1), backfillWindowEnd(1, 2, 1))
+	assert.Equal(t, uint64(4), backfillWindowEnd(0, 4, 0))
+	assert.Equal(t, uint64(math.MaxUint64), backfillWindowEnd(math.MaxUint64-1, math.MaxUint64, 5))
+}
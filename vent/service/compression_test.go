@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/hyperledger/burrow/logging"
+	"github.com/hyperledger/burrow/vent/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestStreamCallOptions(t *testing.T) {
+	cfg := config.DefaultVentConfig()
+	consumer := NewConsumer(cfg, logging.NewNoopLogger(), nil)
+
+	// No compression by default
+	opts, err := consumer.streamCallOptions()
+	require.NoError(t, err)
+	assert.Empty(t, opts)
+
+	cfg.Compression = "gzip"
+	opts, err = consumer.streamCallOptions()
+	require.NoError(t, err)
+	assert.Equal(t, []grpc.CallOption{grpc.CompressorCallOption{CompressorType: "gzip"}}, opts)
+
+	cfg.Compression = "brotli"
+	_, err = consumer.streamCallOptions()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "brotli")
+}
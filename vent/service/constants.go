@@ -2,5 +2,4 @@ package service
 
 import "github.com/hyperledger/burrow/vent/types"
 
-var tables = types.DefaultSQLTableNames
 var columns = types.DefaultSQLColumnNames
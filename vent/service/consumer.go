@@ -4,13 +4,14 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/hyperledger/burrow/rpc"
 
 	"github.com/hyperledger/burrow/execution/evm/abi"
 	"github.com/hyperledger/burrow/execution/exec"
-	"github.com/hyperledger/burrow/logging"
 	"github.com/hyperledger/burrow/rpc/rpcevents"
 	"github.com/hyperledger/burrow/rpc/rpcquery"
 	"github.com/hyperledger/burrow/vent/config"
@@ -20,18 +21,61 @@ import (
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/encoding"
+	// Provides the "gzip" compressor for Config.Compression
+	_ "google.golang.org/grpc/encoding/gzip"
 )
 
 // Consumer contains basic configuration for consumer to run
 type Consumer struct {
-	Config         *config.VentConfig
-	Log            *logging.Logger
+	Config *config.VentConfig
+	// Where the consumer logs, a *logging.Logger or an adapter of the embedder's own logger
+	Log            types.Logger
 	Closing        bool
 	DB             *sqldb.SQLDB
 	GRPCConnection *grpc.ClientConn
-	// external events channel used for when vent is leveraged as a library
+	// Optional clients of Burrow's query and execution events services used in place of those of a connection to
+	// Config.GRPCAddr, which is only dialled if one of them is nil. With both set, for example to the fakes of
+	// vent/test, the consumer can be run in-process without a Burrow node.
+	QueryClient           rpcquery.QueryClient
+	ExecutionEventsClient rpcevents.ExecutionEventsClient
+	// external events channel used for when vent is leveraged as a library, if nil the consumer runs in DB-only mode
 	EventsChannel chan types.EventData
+	// Optional store of the last committed height consulted alongside the SQL log table
+	Checkpointer Checkpointer
+	// Where blocks are committed, if nil Run connects to the sink given by the config (by default the SQL database).
+	// The sink is closed when Run returns.
+	Sink Sink
+	// Optional publisher of each block with rows encoded as protobuf, for consumers not written in Go. A block is
+	// published before it is committed to the sink and is not committed if publishing fails (which stops the
+	// consumer), so it is published again on restart and subscribers must tolerate blocks they have already seen.
+	// If nil and Config.ProtobufFile is set blocks are appended to that file.
+	PublishBlock BlockPublisher
+	// Optional channel on which to receive SyncStatus transitions, which is closed when Run returns. Sends block the
+	// consumer so the channel should be read promptly
+	StatusChannel chan SyncStatus
+	// Optional further projections each committed to its own SQL database in the same pass over the blocks. Each
+	// database resumes from its own log table so a database that was temporarily unavailable catches up on restart.
+	ProjectionDBs []ProjectionDB
+	// Optional callback for side effects (e.g. webhooks) made with the row of each event matched by an event class as
+	// soon as it has been decoded. It is called BEFORE the block is committed so the block may yet fail and be
+	// decoded again on restart, callers must tolerate rows that are never committed or are seen more than once. With
+	// DecodeWorkers greater than one it may be called concurrently. Errors (and panics) are logged and otherwise
+	// ignored.
+	OnEventMatched func(eventClass *types.EventClass, event *exec.Event, row types.EventDataRow) error
 	Status
+	// How time values are written to columns, from the config
+	timeFormat types.TimeFormat
+	// The connected ProjectionDBs
+	projectionTargets []*projectionTarget
+	// The projection being run, for HealthDeep
+	projection *sqlsol.Projection
+	// Non-nil while paused and closed to resume, see Pause
+	resumeCh  chan struct{}
+	pauseLock sync.Mutex
+	// Shared by every decode, see limitDecodes
+	decodes     decodeLimiter
+	decodesOnce sync.Once
 }
 
 // Status announcement
@@ -42,81 +86,189 @@ type Status struct {
 
 // NewConsumer constructs a new consumer configuration.
 // The event channel will be passed a collection of rows generated from all of the events in a single block
-// It will be closed by the consumer when it is finished
-func NewConsumer(cfg *config.VentConfig, log *logging.Logger, eventChannel chan types.EventData) *Consumer {
-	return &Consumer{
+// It will be closed by the consumer when it is finished. By default blocks are only sent when the channel is ready to
+// receive them, set cfg.ChannelDelivery to config.GuaranteedDelivery to receive every committed block at the cost of
+// blocking the consumer on the channel. The event channel may be nil for DB-only mode in which blocks are only
+// committed to the sink.
+func NewConsumer(cfg *config.VentConfig, log types.Logger, eventChannel chan types.EventData) *Consumer {
+	consumer := &Consumer{
 		Config:        cfg,
 		Log:           log,
 		Closing:       false,
 		EventsChannel: eventChannel,
 	}
+	if cfg.SQLTableNames == (types.SQLTableNames{}) {
+		cfg.SQLTableNames = types.DefaultSQLTableNames
+	}
+	if cfg.CheckpointFile != "" {
+		consumer.Checkpointer = NewFileCheckpointer(cfg.CheckpointFile)
+	}
+	return consumer
 }
 
 // Run connects to a grpc service and subscribes to log events,
 // then gets tables structures, maps them & parse event data.
-// Store data in SQL event tables, it runs forever
+// Store data in SQL event tables, it runs forever. Failures are returned as one of ErrDBConnection, ErrStream,
+// ErrDecode, or ErrSchemaSync where they can be classified
 func (c *Consumer) Run(projection *sqlsol.Projection, abiSpec *abi.AbiSpec, stream bool) error {
-	var err error
+	defer c.close()
+	return c.run(projection, abiSpec, stream)
+}
 
-	c.Log.InfoMsg("Connecting to Burrow gRPC server")
+// close closes the channels and any Sink given to the consumer once it has finished running
+func (c *Consumer) close() {
+	if c.EventsChannel != nil {
+		close(c.EventsChannel)
+	}
+	if c.StatusChannel != nil {
+		close(c.StatusChannel)
+	}
+	if c.Sink != nil {
+		c.Sink.Close()
+	}
+}
 
-	c.GRPCConnection, err = grpc.Dial(c.Config.GRPCAddr, grpc.WithInsecure())
+// run is a single Run that leaves the channels and any Sink given to the consumer open so it can be run again
+func (c *Consumer) run(projection *sqlsol.Projection, abiSpec *abi.AbiSpec, stream bool) error {
+	// fail fast on misconfiguration rather than when the first matching event arrives
+	err := projection.CheckAbi(abiSpec)
+	if err != nil {
+		return err
+	}
+	for _, pdb := range c.ProjectionDBs {
+		err = pdb.Projection.CheckAbi(abiSpec)
+		if err != nil {
+			return err
+		}
+	}
+	c.withDeadLetterTable(projection, c.Config.SQLTableNames)
+	c.projection = projection
+	c.timeFormat, err = c.Config.TimeFormat()
+	if err != nil {
+		return err
+	}
+	streamOptions, err := c.streamCallOptions()
 	if err != nil {
-		return errors.Wrapf(err, "Error connecting to Burrow gRPC server at %s", c.Config.GRPCAddr)
+		return err
+	}
+
+	qCli, eventsCli := c.QueryClient, c.ExecutionEventsClient
+	if qCli == nil || eventsCli == nil {
+		c.Log.InfoMsg("Connecting to Burrow gRPC server")
+
+		c.GRPCConnection, err = grpc.Dial(c.Config.GRPCAddr, grpc.WithInsecure())
+		if err != nil {
+			return newErrStream(err, "Error connecting to Burrow gRPC server at %s", c.Config.GRPCAddr)
+		}
+		defer c.GRPCConnection.Close()
+
+		if qCli == nil {
+			qCli = rpcquery.NewQueryClient(c.GRPCConnection)
+		}
+		if eventsCli == nil {
+			eventsCli = rpcevents.NewExecutionEventsClient(c.GRPCConnection)
+		}
 	}
-	defer c.GRPCConnection.Close()
-	defer close(c.EventsChannel)
 
 	// get the chain ID to compare with the one stored in the db
-	qCli := rpcquery.NewQueryClient(c.GRPCConnection)
 	c.Status.Burrow, err = qCli.Status(context.Background(), &rpcquery.StatusParam{})
 	if err != nil {
-		return errors.Wrapf(err, "Error getting chain status")
+		return newErrStream(err, "Error getting chain status")
 	}
 
-	if len(projection.EventSpec) == 0 {
+	if len(projection.EventSpec) == 0 && len(c.ProjectionDBs) == 0 {
 		c.Log.InfoMsg("No events specifications found")
 		return nil
 	}
 
-	c.Log.InfoMsg("Connecting to SQL database")
-
-	connection := types.SQLConnection{
-		DBAdapter: c.Config.DBAdapter,
-		DBURL:     c.Config.DBURL,
-		DBSchema:  c.Config.DBSchema,
-		Log:       c.Log,
+	if c.Sink == nil {
+		c.Sink, err = c.connectSink()
+		if err != nil {
+			return err
+		}
+		// Connected afresh by each run
+		defer func() {
+			c.Sink.Close()
+			c.Sink = nil
+		}()
 	}
 
-	c.DB, err = sqldb.NewSQLDB(connection)
-	if err != nil {
-		return fmt.Errorf("error connecting to SQL database: %v", err)
+	if c.PublishBlock == nil && c.Config.ProtobufFile != "" {
+		file, err := os.OpenFile(c.Config.ProtobufFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fmt.Errorf("could not open protobuf file %s: %v", c.Config.ProtobufFile, err)
+		}
+		c.PublishBlock = NewDelimitedPublisher(file)
+		// Opened afresh by each run
+		defer func() {
+			file.Close()
+			c.PublishBlock = nil
+		}()
 	}
-	defer c.DB.Close()
 
-	err = c.DB.Init(c.Burrow.ChainID, c.Burrow.BurrowVersion)
-	if err != nil {
-		return fmt.Errorf("could not clean tables after ChainID change: %v", err)
-	}
+	// Only the SQL database has a schema to maintain
+	if db, ok := c.Sink.(*sqldb.SQLDB); ok {
+		c.DB = db
+
+		err = c.DB.Init(c.Burrow.ChainID, c.Burrow.BurrowVersion)
+		if err != nil {
+			return newErrDBConnection(err, "could not clean tables after ChainID change")
+		}
+
+		c.Log.InfoMsg("Synchronizing config and database projection structures")
+
+		err = c.DB.SynchronizeDB(c.Burrow.ChainID, projection.Tables)
+		if err != nil {
+			return newErrSchemaSync(err, "Error trying to synchronize database")
+		}
 
-	c.Log.InfoMsg("Synchronizing config and database projection structures")
+		if c.Config.MaintenanceInterval > 0 {
+			stopMaintenance := c.DB.StartMaintenance(projection.Tables, c.Config.MaintenanceInterval,
+				c.Config.MaintenanceVacuum)
+			defer stopMaintenance()
+		}
+	}
 
-	err = c.DB.SynchronizeDB(c.Burrow.ChainID, projection.Tables)
+	c.projectionTargets, err = c.connectProjectionDBs()
 	if err != nil {
-		return errors.Wrap(err, "Error trying to synchronize database")
+		return err
+	}
+	defer func() {
+		for _, target := range c.projectionTargets {
+			target.db.Close()
+		}
+	}()
+	if c.Config.MaintenanceInterval > 0 {
+		for _, target := range c.projectionTargets {
+			stopMaintenance := target.db.StartMaintenance(target.Projection.Tables, c.Config.MaintenanceInterval,
+				c.Config.MaintenanceVacuum)
+			// Deferred after Close so runs first
+			defer stopMaintenance()
+		}
 	}
 
 	// doneCh is used for sending a "done" signal from each goroutine to the main thread
-	// eventCh is used for sending received events to the main thread to be stored in the db
+	// eventCh is used for sending received events to the main thread to be stored in the db, buffering up to
+	// MaxInFlightBlocks decoded blocks ahead of the commits
+	// windowCh is used for sending the last height of each completed backfill window to the main thread
 	doneCh := make(chan struct{})
 	errCh := make(chan error, 1)
-	eventCh := make(chan types.EventData)
+	eventCh := make(chan types.EventData, c.Config.MaxInFlightBlocks)
+	windowCh := make(chan uint64)
+	projectionCh := make(chan []projectionBlock)
+	// The height last committed to the sink before this run, set before anything is sent on windowCh
+	var sinkHeight uint64
+
+	// When backfilling in windows blocks up to this height are checkpointed per window rather than per block
+	backfillHeight := c.Burrow.SyncInfo.LatestBlockHeight
+	// Reports when committed blocks reach the chain head as of now
+	tracker := newSyncTracker(c.Burrow.SyncInfo.LatestBlockHeight)
 
 	go func() {
 		defer func() {
 			close(doneCh)
 		}()
-		go c.announceEvery(doneCh)
+		go c.announceEvery(qCli, doneCh)
 
 		c.Log.InfoMsg("Getting last processed block number from SQL log table")
 
@@ -130,21 +282,83 @@ func (c *Consumer) Run(projection *sqlsol.Projection, abiSpec *abi.AbiSpec, stre
 		// right now there is no way to know if the last block of events was completely read
 		// so we have to begin processing from the last block number stored in database
 		// and update event data if already present
-		fromBlock, err := c.DB.LastBlockHeight(c.Burrow.ChainID)
+		fromBlock, err := c.Sink.LastBlockHeight(c.Burrow.ChainID)
+		if err != nil {
+			errCh <- newErrDBConnection(err, "Error trying to get last processed block number")
+			return
+		}
+
+		checkpointBlock, err := reconcileCheckpoint(fromBlock, c.Checkpointer)
+		if err != nil {
+			errCh <- errors.Wrapf(err, "Error trying to load checkpoint")
+			return
+		}
+		if checkpointBlock != fromBlock {
+			c.Log.InfoMsg("Checkpoint is behind SQL log table, resuming from checkpoint",
+				"checkpoint_height", checkpointBlock, "sql_height", fromBlock)
+			fromBlock = checkpointBlock
+		}
+		sinkHeight = fromBlock
+
+		// Resume from the earliest height committed by any of the databases
+		err = c.loadLastHeights(c.projectionTargets)
 		if err != nil {
-			errCh <- errors.Wrapf(err, "Error trying to get last processed block number")
+			errCh <- err
 			return
 		}
+		for _, target := range c.projectionTargets {
+			if target.lastHeight < fromBlock {
+				fromBlock = target.lastHeight
+			}
+		}
+
+		c.sendSyncStatus(SyncStatus{Type: SyncStarted, Height: fromBlock, TargetHeight: tracker.targetHeight})
 
 		startingBlock := fromBlock
+		// The height from which to measure catching up with the chain head
+		caughtUpHeight := fromBlock
 		// Start the block after the last one successfully committed - apart from if this is the first block
 		// We include block 0 because it is where we currently place dump/restored transactions
 		if startingBlock > 0 {
 			startingBlock++
+		} else if c.Config.StartFromHead {
+			startingBlock = c.Burrow.SyncInfo.LatestBlockHeight
+			// Skipped history counts as caught up
+			caughtUpHeight = startingBlock
+			c.Log.InfoMsg("WARNING: no previously committed height and StartFromHead is set so skipping all "+
+				"historical blocks and starting from current chain head - data from earlier blocks will be missing",
+				"head_height", startingBlock)
+		}
+		// Nothing is sent on eventCh or windowCh until after this so the main loop does not yet share tracker
+		if status, ok := tracker.committed(caughtUpHeight); ok {
+			c.sendSyncStatus(status)
+		}
+
+		blockConsumer := c.makeBlockConsumer(projection, abiSpec, eventCh)
+		if len(c.projectionTargets) > 0 {
+			blockConsumer = c.withProjectionDBs(blockConsumer, sinkHeight, c.projectionTargets, abiSpec, projectionCh)
+		}
+
+		if c.Config.BackfillWindow > 0 {
+			startingBlock, err = c.backfill(eventsCli, startingBlock, backfillHeight, blockConsumer, windowCh, streamOptions...)
+			if err != nil {
+				if c.Closing {
+					c.Log.TraceMsg("GRPC connection closed")
+				} else {
+					errCh <- wrapStreamError(err, "Error backfilling blocks")
+				}
+				return
+			}
+			if !stream || c.Closing {
+				return
+			}
+		}
+
+		if c.Config.FillGaps {
+			blockConsumer = c.withGapFilling(eventsCli, startingBlock, blockConsumer, streamOptions...)
 		}
 
 		// setup block range to get needed blocks server side
-		cli := rpcevents.NewExecutionEventsClient(c.GRPCConnection)
 		var end *rpcevents.Bound
 		if stream {
 			end = rpcevents.StreamBound()
@@ -157,9 +371,9 @@ func (c *Consumer) Run(projection *sqlsol.Projection, abiSpec *abi.AbiSpec, stre
 		}
 
 		// gets blocks in given range based on last processed block taken from database
-		stream, err := cli.Stream(context.Background(), request)
+		stream, err := eventsCli.Stream(context.Background(), request, streamOptions...)
 		if err != nil {
-			errCh <- errors.Wrapf(err, "Error connecting to block stream")
+			errCh <- newErrStream(err, "Error connecting to block stream")
 			return
 		}
 
@@ -167,7 +381,7 @@ func (c *Consumer) Run(projection *sqlsol.Projection, abiSpec *abi.AbiSpec, stre
 
 		c.Log.TraceMsg("Waiting for blocks...")
 
-		err = rpcevents.ConsumeBlockExecutions(stream, c.makeBlockConsumer(projection, abiSpec, eventCh))
+		err = rpcevents.ConsumeBlockExecutions(stream, blockConsumer)
 
 		if err != nil {
 			if err == io.EOF {
@@ -176,25 +390,79 @@ func (c *Consumer) Run(projection *sqlsol.Projection, abiSpec *abi.AbiSpec, stre
 				if c.Closing {
 					c.Log.TraceMsg("GRPC connection closed")
 				} else {
-					errCh <- errors.Wrapf(err, "Error receiving blocks")
+					errCh <- wrapStreamError(err, "Error receiving blocks")
 					return
 				}
 			}
 		}
 	}()
 
+	commitEvents := func(blk types.EventData) error {
+		c.waitWhilePaused()
+		checkpoint := c.Config.BackfillWindow == 0 || blk.BlockHeight > backfillHeight
+		err := c.commitBlock(projection, blk, checkpoint)
+		if err != nil {
+			c.Log.InfoMsg("error committing block", "err", err)
+			return err
+		}
+		if status, ok := tracker.committed(blk.BlockHeight); ok {
+			c.sendSyncStatus(status)
+		}
+		return nil
+	}
+	// Commits the blocks buffered in eventCh, which were all sent before anything since sent on another channel
+	commitBuffered := func() error {
+		for len(eventCh) > 0 {
+			if err := commitEvents(<-eventCh); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
 	for {
 		select {
 		// Process block events
 		case blk := <-eventCh:
-			err := c.commitBlock(projection, blk)
-			if err != nil {
-				c.Log.InfoMsg("error committing block", "err", err)
+			if err := commitEvents(blk); err != nil {
+				return err
+			}
+
+		// Commit the other projections of the block to their own databases
+		case blocks := <-projectionCh:
+			c.waitWhilePaused()
+			for _, blk := range blocks {
+				err := c.commitProjectionBlock(blk)
+				if err != nil {
+					c.Log.InfoMsg("error committing block", "err", err)
+					return err
+				}
+			}
+
+		// Every block of the window has been received (and so committed once the buffer is) before the window end is
+		// sent
+		case height := <-windowCh:
+			if err := commitBuffered(); err != nil {
 				return err
 			}
+			// Windows resumed from behind the sink for the sake of other projections must not move its checkpoint back
+			if c.Checkpointer != nil && (sinkHeight == 0 || height > sinkHeight) {
+				if err := c.Checkpointer.Save(height); err != nil {
+					c.Log.InfoMsg("error saving checkpoint", "err", err)
+					return fmt.Errorf("error saving checkpoint: %v", err)
+				}
+			}
+			// Blocks without transactions are not streamed so the end of a window may be the only indication that
+			// the chain head has been reached
+			if status, ok := tracker.committed(height); ok && status.Type == SyncCaughtUp {
+				c.sendSyncStatus(status)
+			}
 
 		// Await completion
 		case <-doneCh:
+			if err := commitBuffered(); err != nil {
+				return err
+			}
 			select {
 
 			// Select possible error
@@ -211,9 +479,59 @@ func (c *Consumer) Run(projection *sqlsol.Projection, abiSpec *abi.AbiSpec, stre
 	}
 }
 
+// connectSink connects to the endpoint given by SinkURL or otherwise to the SQL database
+func (c *Consumer) connectSink() (Sink, error) {
+	if c.Config.SinkURL != "" {
+		c.Log.InfoMsg("Connecting to sink", "sink_url", c.Config.SinkURL)
+		// Endpoints do not tell us what they have already received so we resume from the checkpoint
+		var lastHeight uint64
+		if c.Checkpointer != nil {
+			var err error
+			lastHeight, _, err = c.Checkpointer.Load()
+			if err != nil {
+				return nil, errors.Wrapf(err, "Error trying to load checkpoint")
+			}
+		}
+		sink, err := NewURLSink(c.Config.SinkURL, lastHeight)
+		if err != nil {
+			return nil, newErrDBConnection(err, "error connecting to sink")
+		}
+		return sink, nil
+	}
+
+	c.Log.InfoMsg("Connecting to SQL database")
+
+	connection := types.SQLConnection{
+		DBAdapter:  c.Config.DBAdapter,
+		DBURL:      c.Config.DBURL,
+		DBSchema:   c.Config.DBSchema,
+		TableNames: c.Config.SQLTableNames,
+		Log:        c.Log,
+
+		SkipCreateSchema: c.Config.DBSkipCreateSchema,
+		SetSearchPath:    c.Config.DBSetSearchPath,
+		OrphanedColumns:  c.Config.DBOrphanedColumns,
+		RowConflicts:     c.Config.DBRowConflicts,
+		MaxRowsPerCommit: c.Config.DBMaxRowsPerCommit,
+	}
+
+	db, err := sqldb.NewSQLDB(connection)
+	if err != nil {
+		return nil, newErrDBConnection(err, "error connecting to SQL database")
+	}
+	return db, nil
+}
+
 func (c *Consumer) makeBlockConsumer(projection *sqlsol.Projection, abiSpec *abi.AbiSpec,
 	eventCh chan<- types.EventData) func(blockExecution *exec.BlockExecution) error {
 
+	if c.Config.DecodeWorkers > 1 {
+		memoiseProjection(projection)
+	}
+
+	// When a block was last sent to be committed, for heartbeats
+	lastSent := time.Now()
+
 	return func(blockExecution *exec.BlockExecution) error {
 		if c.Closing {
 			return io.EOF
@@ -228,104 +546,190 @@ func (c *Consumer) makeBlockConsumer(projection *sqlsol.Projection, abiSpec *abi
 
 		c.Log.TraceMsg("Block received", "height", blockExecution.Height, "num_txs", len(blockExecution.TxExecutions))
 
-		// create a fresh new structure to store block data at this height
-		blockData := sqlsol.NewBlockData(fromBlock)
-
-		if c.Config.SpecOpt&sqlsol.Block > 0 {
-			blkRawData, err := buildBlkData(projection.Tables, blockExecution)
-			if err != nil {
-				return errors.Wrapf(err, "Error building block raw data")
-			}
-			// set row in structure
-			blockData.AddRow(tables.Block, blkRawData)
+		blockData, err := c.decodeBlock(projection, abiSpec, c.Config.SQLTableNames, blockExecution)
+		if err != nil {
+			return err
 		}
 
-		// get transactions for a given block
-		for _, txe := range blockExecution.TxExecutions {
-			c.Log.TraceMsg("Getting transaction", "TxHash", txe.TxHash, "num_events", len(txe.Events))
+		// upsert rows in specific SQL event tables and update block number
+		// store block data in SQL tables (if any)
+		if blockData.PendingRows(fromBlock) {
+			// gets block data to upsert
+			blk := blockData.Data
 
-			if c.Config.SpecOpt&sqlsol.Tx > 0 {
-				txRawData, err := buildTxData(txe)
-				if err != nil {
-					return errors.Wrapf(err, "Error building tx raw data")
-				}
-				// set row in structure
-				blockData.AddRow(tables.Tx, txRawData)
-			}
+			c.Log.InfoMsg(fmt.Sprintf("Upserting rows in SQL tables %v", blk), "block", fromBlock)
+
+			eventCh <- blk
+			lastSent = time.Now()
+		} else if c.Config.HeartbeatInterval > 0 && time.Since(lastSent) >= c.Config.HeartbeatInterval {
+			// Blocks without transactions are not streamed so this can only advance the height to the last block
+			// with transactions
+			c.Log.TraceMsg("Committing block without rows to advance the last committed height", "block", fromBlock)
+
+			eventCh <- blockData.Data
+			lastSent = time.Now()
+		}
+		return nil
+	}
+}
 
-			// reverted transactions don't have to update event data tables
-			// so check that condition to filter them
-			if txe.Exception == nil {
+// decodeBlock returns the rows of blockExecution matched by the projection along with the block and tx rows (if
+// configured) in the tables named by tableNames
+func (c *Consumer) decodeBlock(projection *sqlsol.Projection, abiSpec *abi.AbiSpec, tableNames types.SQLTableNames,
+	blockExecution *exec.BlockExecution) (*sqlsol.BlockData, error) {
 
-				origin := txe.Origin
-				if origin == nil {
-					origin = &exec.Origin{
-						ChainID: c.Burrow.ChainID,
-						Height:  txe.Height,
-					}
-				}
+	// create a fresh new structure to store block data at this height
+	blockData := sqlsol.NewBlockData(blockExecution.Height)
 
-				// get events for a given transaction
-				for _, event := range txe.Events {
+	if c.Config.SpecOpt&sqlsol.Block > 0 {
+		blkRawData, err := buildBlkData(projection.Tables, tableNames.Block, blockExecution)
+		if err != nil {
+			return nil, newErrDecode(err, "Error building block raw data")
+		}
+		// set row in structure
+		blockData.AddRow(tableNames.Block, blkRawData)
+	}
 
-					taggedEvent := event.Tagged()
+	var blockTime time.Time
+	if blockExecution.Header != nil {
+		blockTime = blockExecution.Header.Time
+	}
 
-					// see which spec filter matches with the one in event data
-					for _, eventClass := range projection.EventSpec {
-						qry, err := eventClass.Query()
+	// decode the transactions of the block, possibly concurrently, and add their rows in transaction order
+	txRows, err := decodeTxs(flattenTxExecutions(nil, blockExecution.TxExecutions), c.Config.DecodeWorkers,
+		func(txe *exec.TxExecution) ([]txRow, error) {
+			return c.decodeTx(projection, abiSpec, tableNames, blockTime, txe)
+		})
+	if err != nil {
+		return nil, err
+	}
+	for _, rows := range txRows {
+		for _, r := range rows {
+			// set row in structure
+			blockData.AddRow(r.tableName, r.row)
+		}
+	}
 
-						if err != nil {
-							return errors.Wrapf(err, "Error parsing query from filter string")
-						}
+	// aggregate the rows of the block into the rollup tables so they are committed with them
+	rollupRows, err := buildRollupData(projection, blockData.Data, blockTime)
+	if err != nil {
+		return nil, newErrDecode(err, "Error building rollup data")
+	}
+	for _, r := range rollupRows {
+		blockData.AddRow(r.tableName, r.row)
+	}
+	return blockData, nil
+}
 
-						// there's a matching filter, add data to the rows
-						if qry.Matches(taggedEvent) {
+// flattenTxExecutions appends txes to flat, each followed by the transactions it contains (those of a proposal), so
+// that their events are decoded too. Events emitted by internal calls are already listed with those of their
+// transaction. The transactions contained by one that failed are skipped since their effects were reverted with it.
+func flattenTxExecutions(flat, txes []*exec.TxExecution) []*exec.TxExecution {
+	for _, txe := range txes {
+		flat = append(flat, txe)
+		if txe.Exception == nil {
+			flat = flattenTxExecutions(flat, txe.TxExecutions)
+		}
+	}
+	return flat
+}
 
-							c.Log.InfoMsg(fmt.Sprintf("Matched event header: %v", event.Header),
-								"filter", eventClass.Filter)
+// backfill requests blocks from startingBlock up to and including endHeight in windows of at most
+// Config.BackfillWindow blocks, sending the last height of each window on windowCh once all of the window's blocks
+// have been passed to the block consumer. It returns the height from which to continue.
+func (c *Consumer) backfill(cli rpcevents.ExecutionEventsClient, startingBlock, endHeight uint64,
+	blockConsumer func(*exec.BlockExecution) error, windowCh chan<- uint64, opts ...grpc.CallOption) (uint64, error) {
 
-							// unpack, decode & build event data
-							eventData, err := buildEventData(projection, eventClass, event, origin, abiSpec, c.Log)
-							if err != nil {
-								return errors.Wrapf(err, "Error building event data")
-							}
+	for startingBlock <= endHeight {
+		windowEnd := backfillWindowEnd(startingBlock, endHeight, c.Config.BackfillWindow)
+		c.Log.InfoMsg("Backfilling blocks", "window_start", startingBlock, "window_end", windowEnd)
 
-							// set row in structure
-							blockData.AddRow(eventClass.TableName, eventData)
-						}
-					}
-				}
-			}
+		stream, err := cli.Stream(context.Background(), &rpcevents.BlocksRequest{
+			BlockRange: rpcevents.NewBlockRange(rpcevents.AbsoluteBound(startingBlock), rpcevents.AbsoluteBound(windowEnd)),
+		}, opts...)
+		if err != nil {
+			return 0, newErrStream(err, "Error connecting to block stream")
 		}
 
-		// upsert rows in specific SQL event tables and update block number
-		// store block data in SQL tables (if any)
-		if blockData.PendingRows(fromBlock) {
-			// gets block data to upsert
-			blk := blockData.Data
+		err = rpcevents.ConsumeBlockExecutions(stream, blockConsumer)
+		if err != nil && err != io.EOF {
+			return 0, wrapStreamError(err, "Error receiving blocks")
+		}
+		if c.Closing {
+			return 0, nil
+		}
 
-			c.Log.InfoMsg(fmt.Sprintf("Upserting rows in SQL tables %v", blk), "block", fromBlock)
+		// Blocks without transactions are not stored so a window may legitimately produce no blocks
+		windowCh <- windowEnd
+		startingBlock = windowEnd + 1
+	}
+	return startingBlock, nil
+}
 
-			eventCh <- blk
-		}
-		return nil
+// streamCallOptions returns the options for block stream calls, requesting compression if configured. The server must
+// have the compressor registered (Burrow servers have gzip) otherwise the stream fails with Unimplemented.
+func (c *Consumer) streamCallOptions() ([]grpc.CallOption, error) {
+	if c.Config.Compression == "" {
+		return nil, nil
 	}
+	if encoding.GetCompressor(c.Config.Compression) == nil {
+		return nil, fmt.Errorf("unknown gRPC compressor '%s', the supported compressor is gzip", c.Config.Compression)
+	}
+	return []grpc.CallOption{grpc.UseCompressor(c.Config.Compression)}, nil
+}
+
+// backfillWindowEnd returns the inclusive end of the window of at most size blocks starting at start and not
+// extending beyond end
+func backfillWindowEnd(start, end, size uint64) uint64 {
+	windowEnd := start + size - 1
+	if size == 0 || windowEnd > end || windowEnd < start {
+		return end
+	}
+	return windowEnd
 }
 
-func (c *Consumer) commitBlock(projection *sqlsol.Projection, blockEvents types.EventData) error {
+func (c *Consumer) commitBlock(projection *sqlsol.Projection, blockEvents types.EventData, checkpoint bool) error {
+	if err := c.publishBlock(blockEvents); err != nil {
+		return err
+	}
+
 	// upsert rows in specific SQL event tables and update block number
-	if err := c.DB.SetBlock(c.Burrow.ChainID, projection.Tables, blockEvents); err != nil {
-		return fmt.Errorf("error upserting rows in database: %v", err)
+	if err := c.Sink.SetBlock(c.Burrow.ChainID, projection.Tables, blockEvents); err != nil {
+		return newErrDBConnection(err, "error upserting rows in database")
+	}
+
+	if checkpoint && c.Checkpointer != nil {
+		if err := c.Checkpointer.Save(blockEvents.BlockHeight); err != nil {
+			return fmt.Errorf("error saving checkpoint: %v", err)
+		}
 	}
 
-	// send to the external events channel in a non-blocking manner
-	select {
-	case c.EventsChannel <- blockEvents:
+	// Heartbeat blocks have no rows for subscribers
+	if c.EventsChannel == nil || len(blockEvents.Tables) == 0 {
+		return nil
+	}
+
+	switch c.Config.ChannelDelivery {
+	case config.GuaranteedDelivery:
+		// send to the external events channel after the DB commit and before the next block is committed so that
+		// subscribers see every committed block in order
+		c.EventsChannel <- blockEvents
 	default:
+		// send to the external events channel in a non-blocking manner
+		select {
+		case c.EventsChannel <- blockEvents:
+		default:
+		}
 	}
 	return nil
 }
 
+func (c *Consumer) sendSyncStatus(status SyncStatus) {
+	if c.StatusChannel != nil {
+		c.StatusChannel <- status
+	}
+}
+
 // Health returns the health status for the consumer
 func (c *Consumer) Health() error {
 	if c.Closing {
@@ -333,16 +737,32 @@ func (c *Consumer) Health() error {
 	}
 
 	// check db status
-	if c.DB == nil {
+	if c.Sink == nil {
 		return errors.New("database disconnected")
 	}
 
-	if err := c.DB.Ping(); err != nil {
-		return errors.New("database unavailable")
+	if c.DB != nil {
+		if err := c.DB.Ping(); err != nil {
+			return errors.New("database unavailable")
+		}
+	}
+
+	for i, target := range c.projectionTargets {
+		if err := target.db.Ping(); err != nil {
+			return fmt.Errorf("database of projection %d unavailable", i)
+		}
 	}
 
-	// check grpc connection status
+	// the block stream is idle while paused so the connection may be too
+	if c.Paused() {
+		return nil
+	}
+
+	// check grpc connection status, clients given in place of the connection are assumed to be up
 	if c.GRPCConnection == nil {
+		if c.QueryClient != nil && c.ExecutionEventsClient != nil {
+			return nil
+		}
 		return errors.New("grpc disconnected")
 	}
 
@@ -353,11 +773,78 @@ func (c *Consumer) Health() error {
 	return nil
 }
 
-// Shutdown gracefully shuts down the events consumer
+// HealthDeep returns the Health of the consumer and additionally checks that the tables of each projection in the
+// database still have all of the projection's columns, which catches tables altered out from under vent before
+// upserts start failing. It queries every table so it is more expensive than Health.
+func (c *Consumer) HealthDeep() error {
+	if err := c.Health(); err != nil {
+		return err
+	}
+
+	if c.DB != nil && c.projection != nil {
+		if err := c.DB.CheckSchema(c.projection.Tables); err != nil {
+			return fmt.Errorf("database schema does not match projection: %v", err)
+		}
+	}
+
+	for i, target := range c.projectionTargets {
+		if err := target.db.CheckSchema(target.Projection.Tables); err != nil {
+			return fmt.Errorf("database schema of projection %d does not match projection: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Pause stops the consumer committing blocks until Resume is called, for example during database maintenance,
+// without closing the connections to Burrow or the database. A block being committed when Pause is called is still
+// committed. While paused blocks continue to be received and decoded until MaxInFlightBlocks of them are waiting to be
+// committed, after which the block stream waits (buffering no more than gRPC flow control allows). Health reports a
+// paused consumer as healthy as long as its databases are.
+func (c *Consumer) Pause() {
+	c.pauseLock.Lock()
+	defer c.pauseLock.Unlock()
+	if c.resumeCh == nil {
+		c.Log.InfoMsg("Pausing vent consumer")
+		c.resumeCh = make(chan struct{})
+	}
+}
+
+// Resume resumes committing blocks after Pause, it does nothing if the consumer is not paused
+func (c *Consumer) Resume() {
+	c.pauseLock.Lock()
+	defer c.pauseLock.Unlock()
+	if c.resumeCh != nil {
+		c.Log.InfoMsg("Resuming vent consumer")
+		close(c.resumeCh)
+		c.resumeCh = nil
+	}
+}
+
+// Paused returns whether the consumer has been paused and not yet resumed
+func (c *Consumer) Paused() bool {
+	c.pauseLock.Lock()
+	defer c.pauseLock.Unlock()
+	return c.resumeCh != nil
+}
+
+func (c *Consumer) waitWhilePaused() {
+	c.pauseLock.Lock()
+	resumeCh := c.resumeCh
+	c.pauseLock.Unlock()
+	if resumeCh != nil {
+		<-resumeCh
+	}
+}
+
+// Shutdown gracefully shuts down the events consumer, resuming it if it is paused
 func (c *Consumer) Shutdown() {
 	c.Log.InfoMsg("Shutting down vent consumer...")
 	c.Closing = true
-	c.GRPCConnection.Close()
+	if c.GRPCConnection != nil {
+		c.GRPCConnection.Close()
+	}
+	c.Resume()
 }
 
 func (c *Consumer) updateStatus(qcli rpcquery.QueryClient) {
@@ -377,6 +864,7 @@ func (c *Consumer) statusMessage() []interface{} {
 	return []interface{}{
 		"msg", "status",
 		"last_processed_height", c.LastProcessedHeight,
+		"paused", c.Paused(),
 		"fraction_caught_up", catchUpRatio,
 		"burrow_latest_block_height", c.Burrow.SyncInfo.LatestBlockHeight,
 		"burrow_latest_block_duration", c.Burrow.SyncInfo.LatestBlockDuration,
@@ -389,9 +877,8 @@ func (c *Consumer) statusMessage() []interface{} {
 	}
 }
 
-func (c *Consumer) announceEvery(doneCh <-chan struct{}) {
+func (c *Consumer) announceEvery(qcli rpcquery.QueryClient, doneCh <-chan struct{}) {
 	if c.Config.AnnounceEvery != 0 {
-		qcli := rpcquery.NewQueryClient(c.GRPCConnection)
 		ticker := time.NewTicker(c.Config.AnnounceEvery)
 		for {
 			select {
@@ -48,6 +48,34 @@ func TestPostgresConsumer(t *testing.T) {
 			testResume(t, test.PostgresVentConfig(grpcAddress))
 		})
 
+		t.Run("PostgresGuaranteedDelivery", func(t *testing.T) {
+			testGuaranteedDelivery(t, test.PostgresVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("PostgresStartFromHead", func(t *testing.T) {
+			testStartFromHead(t, kern.Blockchain.ChainID(), test.PostgresVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("PostgresTablePrefixes", func(t *testing.T) {
+			testTablePrefixes(t, kern.Blockchain.ChainID(), test.PostgresVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("PostgresBackfillWindow", func(t *testing.T) {
+			testBackfillWindow(t, kern.Blockchain.ChainID(), test.PostgresVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("PostgresSyncStatus", func(t *testing.T) {
+			testSyncStatus(t, test.PostgresVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("PostgresHTTPSink", func(t *testing.T) {
+			testHTTPSink(t, test.PostgresVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("PostgresCompression", func(t *testing.T) {
+			testCompression(t, test.PostgresVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
 		t.Run("PostgresTriggers", func(t *testing.T) {
 			tCli := test.NewTransactClient(t, kern.GRPCListenAddress().String())
 			create := test.CreateContract(t, tCli, inputAddress)
@@ -112,5 +140,17 @@ func TestPostgresConsumer(t *testing.T) {
 			assert.Equal(t, `{"_action" : "INSERT", "testdescription" : "\\x5472696767657220697421000000000000000000000000000000000000000000", "testkey" : "\\x544553545f4556454e5453000000000000000000000000000000000000000000", "testname" : "TestTriggerEvent"}`,
 				notifications["keyed_meta"])
 		})
+
+		t.Run("PostgresNilEventsChannel", func(t *testing.T) {
+			testNilEventsChannel(t, kern.Blockchain.ChainID(), test.PostgresVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("PostgresMaxInFlightBlocks", func(t *testing.T) {
+			testMaxInFlightBlocks(t, test.PostgresVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("PostgresSubCallEvent", func(t *testing.T) {
+			testSubCallEvent(t, kern.Blockchain.ChainID(), test.PostgresVentConfig(grpcAddress), tcli, inputAddress)
+		})
 	})
 }
@@ -39,5 +39,49 @@ func TestSqliteConsumer(t *testing.T) {
 		t.Run("SqliteResume", func(t *testing.T) {
 			testResume(t, test.SqliteVentConfig(grpcAddress))
 		})
+
+		t.Run("SqliteGuaranteedDelivery", func(t *testing.T) {
+			testGuaranteedDelivery(t, test.SqliteVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("SqliteStartFromHead", func(t *testing.T) {
+			testStartFromHead(t, kern.Blockchain.ChainID(), test.SqliteVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("SqliteTablePrefixes", func(t *testing.T) {
+			testTablePrefixes(t, kern.Blockchain.ChainID(), test.SqliteVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("SqliteBackfillWindow", func(t *testing.T) {
+			testBackfillWindow(t, kern.Blockchain.ChainID(), test.SqliteVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("SqliteSyncStatus", func(t *testing.T) {
+			testSyncStatus(t, test.SqliteVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("SqliteHTTPSink", func(t *testing.T) {
+			testHTTPSink(t, test.SqliteVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("SqliteCompression", func(t *testing.T) {
+			testCompression(t, test.SqliteVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("SqliteProjectionDBs", func(t *testing.T) {
+			testProjectionDBs(t, kern.Blockchain.ChainID(), test.SqliteVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("SqliteNilEventsChannel", func(t *testing.T) {
+			testNilEventsChannel(t, kern.Blockchain.ChainID(), test.SqliteVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("SqliteMaxInFlightBlocks", func(t *testing.T) {
+			testMaxInFlightBlocks(t, test.SqliteVentConfig(grpcAddress), tcli, inputAddress)
+		})
+
+		t.Run("SqliteSubCallEvent", func(t *testing.T) {
+			testSubCallEvent(t, kern.Blockchain.ChainID(), test.SqliteVentConfig(grpcAddress), tcli, inputAddress)
+		})
 	})
 }
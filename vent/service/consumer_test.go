@@ -3,22 +3,33 @@
 package service_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"path"
 	"runtime"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/hyperledger/burrow/crypto"
 	"github.com/hyperledger/burrow/execution/evm/abi"
+	"github.com/hyperledger/burrow/execution/exec"
 	"github.com/hyperledger/burrow/logging"
 	"github.com/hyperledger/burrow/rpc/rpctransact"
 	"github.com/hyperledger/burrow/vent/config"
 	"github.com/hyperledger/burrow/vent/service"
+	"github.com/hyperledger/burrow/vent/sqldb"
 	"github.com/hyperledger/burrow/vent/sqlsol"
 	"github.com/hyperledger/burrow/vent/test"
 	"github.com/hyperledger/burrow/vent/types"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -184,7 +195,517 @@ func testInvalidUTF8(t *testing.T, cfg *config.VentConfig, tcli rpctransact.Tran
 	//require.Contains(t, err.Error(), "pq: invalid byte sequence for encoding \"UTF8\": 0xf3 0x6e")
 }
 
+func testGuaranteedDelivery(t *testing.T, cfg *config.VentConfig, tcli rpctransact.TransactClient, inputAddress crypto.Address) {
+	create := test.CreateContract(t, tcli, inputAddress)
+
+	// generate events in separate blocks
+	var heights []uint64
+	for i := 0; i < 4; i++ {
+		txe := test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress,
+			fmt.Sprintf("GuaranteedEvent%d", i), "Delivered")
+		heights = append(heights, txe.Height)
+	}
+
+	// create test db
+	db, closeDB := test.NewTestDB(t, cfg)
+	defer closeDB()
+
+	cfg.ChannelDelivery = config.GuaranteedDelivery
+	consumer := newConsumer(t, cfg)
+	// an unbuffered channel with a slow reader would drop blocks under best-effort delivery
+	consumer.EventsChannel = make(chan types.EventData)
+
+	received := make(map[uint64]bool)
+	var lastReceived uint64
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		for ed := range consumer.EventsChannel {
+			time.Sleep(10 * time.Millisecond)
+			assert.True(t, lastReceived == 0 || ed.BlockHeight > lastReceived,
+				"should receive blocks in commit order")
+			received[ed.BlockHeight] = true
+			lastReceived = ed.BlockHeight
+		}
+	}()
+
+	projection, err := sqlsol.SpecLoaderWithTableNames(cfg.SpecFileOrDirs, cfg.SpecOpt, cfg.SQLTableNames)
+	require.NoError(t, err)
+
+	abiSpec, err := abi.LoadPath(cfg.AbiFileOrDirs...)
+	require.NoError(t, err)
+
+	err = consumer.Run(projection, abiSpec, false)
+	require.NoError(t, err)
+	<-doneCh
+
+	for _, height := range heights {
+		require.True(t, received[height], "committed block %d should have been delivered", height)
+	}
+	lastCommitted, err := db.LastBlockHeight(consumer.Burrow.ChainID)
+	require.NoError(t, err)
+	require.Equal(t, lastCommitted, lastReceived)
+}
+
+func testStartFromHead(t *testing.T, chainID string, cfg *config.VentConfig, tcli rpctransact.TransactClient, inputAddress crypto.Address) {
+	create := test.CreateContract(t, tcli, inputAddress)
+
+	// generate an event in a historical block followed by a later block
+	txeHistorical := test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, "HistoricalEvent",
+		"Should be skipped")
+	txeLater := test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, "LaterEvent",
+		"Marks head")
+	require.True(t, txeLater.Height > txeHistorical.Height)
+
+	// create test db
+	db, closeDB := test.NewTestDB(t, cfg)
+	defer closeDB()
+
+	cfg.StartFromHead = true
+	runConsumer(t, cfg)
+
+	eventData, err := db.GetBlock(chainID, txeHistorical.Height)
+	require.NoError(t, err)
+	require.Len(t, eventData.Tables["EventTest"], 0)
+
+	height, err := db.LastBlockHeight(chainID)
+	require.NoError(t, err)
+	require.True(t, height >= txeLater.Height, "should have started from head")
+}
+
+func testTablePrefixes(t *testing.T, chainID string, cfg *config.VentConfig, tcli rpctransact.TransactClient, inputAddress crypto.Address) {
+	create := test.CreateContract(t, tcli, inputAddress)
+	test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, "PrefixedEventA", "First")
+
+	cfgA := *cfg
+	cfgA.SQLTableNames = types.DefaultSQLTableNames.WithPrefix("a")
+	cfgB := *cfg
+	cfgB.SQLTableNames = types.DefaultSQLTableNames.WithPrefix("b")
+
+	// create test dbs sharing a database (and schema)
+	dbA, closeDB := test.NewTestDB(t, &cfgA)
+	defer closeDB()
+	dbB, _ := test.NewTestDB(t, &cfgB)
+
+	runConsumer(t, &cfgA)
+	heightA, err := dbA.LastBlockHeight(chainID)
+	require.NoError(t, err)
+	require.True(t, heightA > 0)
+
+	txe := test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, "PrefixedEventB", "Second")
+	require.True(t, txe.Height > heightA)
+
+	// consumer B needs its own event tables as well as its own system tables
+	_, testFile, _, _ := runtime.Caller(0)
+	spec, err := ioutil.ReadFile(path.Join(path.Dir(testFile), "..", "test", "sqlsol_example.json"))
+	require.NoError(t, err)
+	specFile, err := ioutil.TempFile("", "sqlsol_b*.json")
+	require.NoError(t, err)
+	defer os.Remove(specFile.Name())
+	_, err = specFile.Write(bytes.Replace(spec, []byte(`"TableName": "`), []byte(`"TableName": "b_`), -1))
+	require.NoError(t, err)
+	require.NoError(t, specFile.Close())
+
+	consumer := newConsumer(t, &cfgB)
+	projection, err := sqlsol.SpecLoaderWithTableNames([]string{specFile.Name()}, cfgB.SpecOpt, cfgB.SQLTableNames)
+	require.NoError(t, err)
+	abiSpec, err := abi.LoadPath(cfgB.AbiFileOrDirs...)
+	require.NoError(t, err)
+	require.NoError(t, consumer.Run(projection, abiSpec, false))
+
+	heightB, err := dbB.LastBlockHeight(chainID)
+	require.NoError(t, err)
+	require.True(t, heightB >= txe.Height)
+
+	// consumer B should not have touched consumer A's checkpoint
+	height, err := dbA.LastBlockHeight(chainID)
+	require.NoError(t, err)
+	require.Equal(t, heightA, height)
+}
+
+// failingCheckpointer records every height saved and fails the save numbered failAt (counting from 1)
+type failingCheckpointer struct {
+	*service.MemoryCheckpointer
+	saves  []uint64
+	failAt int
+}
+
+func (fc *failingCheckpointer) Save(height uint64) error {
+	if len(fc.saves)+1 == fc.failAt {
+		fc.failAt = 0
+		return fmt.Errorf("injected checkpoint failure at height %d", height)
+	}
+	fc.saves = append(fc.saves, height)
+	return fc.MemoryCheckpointer.Save(height)
+}
+
+func testBackfillWindow(t *testing.T, chainID string, cfg *config.VentConfig, tcli rpctransact.TransactClient, inputAddress crypto.Address) {
+	const window = 3
+	create := test.CreateContract(t, tcli, inputAddress)
+	// make sure there are enough blocks for a few windows
+	for txe := create; txe.Height < 4*window; {
+		txe = test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, "BackfillEvent", "Window")
+	}
+
+	// create test db
+	db, closeDB := test.NewTestDB(t, cfg)
+	defer closeDB()
+
+	cfg.BackfillWindow = window
+	checkpointer := &failingCheckpointer{
+		MemoryCheckpointer: service.NewMemoryCheckpointer(),
+		failAt:             3,
+	}
+	// Checkpoint from the start so that the only saves are those of the backfill windows
+	require.NoError(t, checkpointer.MemoryCheckpointer.Save(0))
+
+	runBackfill := func() error {
+		consumer := newConsumer(t, cfg)
+		consumer.Checkpointer = checkpointer
+		projection, err := sqlsol.SpecLoaderWithTableNames(cfg.SpecFileOrDirs, cfg.SpecOpt, cfg.SQLTableNames)
+		require.NoError(t, err)
+		abiSpec, err := abi.LoadPath(cfg.AbiFileOrDirs...)
+		require.NoError(t, err)
+		return consumer.Run(projection, abiSpec, false)
+	}
+
+	err := runBackfill()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "injected checkpoint failure")
+	// The checkpoint only advances once per window
+	require.Equal(t, []uint64{window - 1, 2*window - 1}, checkpointer.saves)
+
+	// The SQL log table is ahead but we resume from the checkpoint, losing at most one window
+	height, err := db.LastBlockHeight(chainID)
+	require.NoError(t, err)
+	require.True(t, height > 2*window-1)
+
+	checkpointer.saves = nil
+	err = runBackfill()
+	require.NoError(t, err)
+	require.True(t, len(checkpointer.saves) > 0)
+	require.Equal(t, uint64(3*window-1), checkpointer.saves[0], "should resume from the window after the checkpoint")
+	for i := 1; i < len(checkpointer.saves); i++ {
+		require.True(t, checkpointer.saves[i]-checkpointer.saves[i-1] <= window)
+	}
+
+	checkpointHeight, _, err := checkpointer.Load()
+	require.NoError(t, err)
+	height, err = db.LastBlockHeight(chainID)
+	require.NoError(t, err)
+	// The checkpoint may be ahead of the SQL log table since blocks without transactions are not stored
+	require.True(t, checkpointHeight >= height)
+}
+
+func testSyncStatus(t *testing.T, cfg *config.VentConfig, tcli rpctransact.TransactClient, inputAddress crypto.Address) {
+	create := test.CreateContract(t, tcli, inputAddress)
+	for i := 0; i < 3; i++ {
+		test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, "SyncEvent", "Sync")
+	}
+
+	// create test db
+	_, closeDB := test.NewTestDB(t, cfg)
+	defer closeDB()
+
+	// The end of the last window reaches the head even when the head block has no transactions
+	cfg.BackfillWindow = 2
+	consumer := newConsumer(t, cfg)
+	consumer.StatusChannel = make(chan service.SyncStatus, 1000)
+	projection, err := sqlsol.SpecLoaderWithTableNames(cfg.SpecFileOrDirs, cfg.SpecOpt, cfg.SQLTableNames)
+	require.NoError(t, err)
+	abiSpec, err := abi.LoadPath(cfg.AbiFileOrDirs...)
+	require.NoError(t, err)
+	require.NoError(t, consumer.Run(projection, abiSpec, false))
+
+	var statuses []service.SyncStatus
+	for status := range consumer.StatusChannel {
+		statuses = append(statuses, status)
+	}
+	require.NotEmpty(t, statuses)
+	assert.Equal(t, service.SyncStarted, statuses[0].Type)
+	caughtUp := 0
+	for _, status := range statuses[1:] {
+		if status.Type == service.SyncCaughtUp {
+			caughtUp++
+			assert.True(t, status.Height >= status.TargetHeight)
+		}
+	}
+	assert.Equal(t, 1, caughtUp, "should catch up exactly once")
+}
+
+func testHTTPSink(t *testing.T, cfg *config.VentConfig, tcli rpctransact.TransactClient, inputAddress crypto.Address) {
+	create := test.CreateContract(t, tcli, inputAddress)
+	txe := test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, "SinkEvent", "Sink")
+
+	var heights []uint64
+	var mtx sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		block := service.SinkBlock{}
+		err := json.NewDecoder(r.Body).Decode(&block)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mtx.Lock()
+		heights = append(heights, block.BlockHeight)
+		mtx.Unlock()
+	}))
+	defer server.Close()
+
+	// No SQL database is needed
+	cfg.SinkURL = server.URL
+	consumer := newConsumer(t, cfg)
+	checkpointer := service.NewMemoryCheckpointer()
+	consumer.Checkpointer = checkpointer
+	projection, err := sqlsol.SpecLoaderWithTableNames(cfg.SpecFileOrDirs, cfg.SpecOpt, cfg.SQLTableNames)
+	require.NoError(t, err)
+	abiSpec, err := abi.LoadPath(cfg.AbiFileOrDirs...)
+	require.NoError(t, err)
+	require.NoError(t, consumer.Run(projection, abiSpec, false))
+	require.Nil(t, consumer.DB)
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	require.NotEmpty(t, heights)
+	for i := 1; i < len(heights); i++ {
+		require.True(t, heights[i] > heights[i-1])
+	}
+	lastHeight := heights[len(heights)-1]
+	require.True(t, lastHeight >= txe.Height)
+	// The checkpoint follows the acknowledged height
+	checkpointHeight, _, err := checkpointer.Load()
+	require.NoError(t, err)
+	require.Equal(t, lastHeight, checkpointHeight)
+}
+
+func testCompression(t *testing.T, cfg *config.VentConfig, tcli rpctransact.TransactClient, inputAddress crypto.Address) {
+	create := test.CreateContract(t, tcli, inputAddress)
+	txe := test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, "CompressedEvent", "Compressed")
+
+	// create test db
+	_, closeDB := test.NewTestDB(t, cfg)
+	defer closeDB()
+
+	// Both the backfill and the following stream are compressed
+	cfg.Compression = "gzip"
+	cfg.BackfillWindow = txe.Height
+	consumer := newConsumer(t, cfg)
+	projection, err := sqlsol.SpecLoaderWithTableNames(cfg.SpecFileOrDirs, cfg.SpecOpt, cfg.SQLTableNames)
+	require.NoError(t, err)
+	abiSpec, err := abi.LoadPath(cfg.AbiFileOrDirs...)
+	require.NoError(t, err)
+	require.NoError(t, consumer.Run(projection, abiSpec, false))
+
+	found := false
+	for blk := range consumer.EventsChannel {
+		if blk.BlockHeight == txe.Height {
+			found = true
+		}
+	}
+	require.True(t, found, "should receive the block containing the event over the compressed stream")
+}
+
+func testProjectionDBs(t *testing.T, chainID string, cfg *config.VentConfig, tcli rpctransact.TransactClient, inputAddress crypto.Address) {
+	create := test.CreateContract(t, tcli, inputAddress)
+	txeA := test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, "ProjectionEventA", "First")
+
+	// create test dbs in separate files
+	db, closeDB := test.NewTestDB(t, cfg)
+	defer closeDB()
+	cfgB := test.SqliteVentConfig(cfg.GRPCAddr)
+	dbB, closeDBB := test.NewTestDB(t, cfgB)
+	defer closeDBB()
+	connectionB := types.SQLConnection{
+		DBAdapter: cfgB.DBAdapter,
+		DBURL:     cfgB.DBURL,
+	}
+
+	runProjections := func(connections ...types.SQLConnection) error {
+		consumer := newConsumer(t, cfg)
+		projection, err := sqlsol.SpecLoaderWithTableNames(cfg.SpecFileOrDirs, cfg.SpecOpt, cfg.SQLTableNames)
+		require.NoError(t, err)
+		for _, connection := range connections {
+			projectionB, err := sqlsol.SpecLoaderWithTableNames(cfg.SpecFileOrDirs, cfg.SpecOpt, cfg.SQLTableNames)
+			require.NoError(t, err)
+			consumer.ProjectionDBs = append(consumer.ProjectionDBs, service.ProjectionDB{
+				Projection: projectionB,
+				Connection: connection,
+			})
+		}
+		abiSpec, err := abi.LoadPath(cfg.AbiFileOrDirs...)
+		require.NoError(t, err)
+		return consumer.Run(projection, abiSpec, false)
+	}
+	countLogRows := func(height uint64) int {
+		var count int
+		err := db.RawDB().QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM \"%s\" WHERE %s = %d", db.Tables.Log,
+			db.Columns.Height, height)).Scan(&count)
+		require.NoError(t, err)
+		return count
+	}
+
+	// The second database is unavailable
+	unavailable := connectionB
+	unavailable.DBURL = path.Join(os.TempDir(), "no-such-dir", "vent.sqlite")
+	err := runProjections(unavailable)
+	require.Error(t, err)
+	assert.IsType(t, &service.ErrDBConnection{}, err)
+
+	// So the first database is consumed alone for a while
+	require.NoError(t, runProjections())
+	heightA, err := db.LastBlockHeight(chainID)
+	require.NoError(t, err)
+	require.True(t, heightA >= txeA.Height)
+	logRowsA := countLogRows(heightA)
+	require.True(t, logRowsA > 0)
+
+	txeB := test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, "ProjectionEventB", "Second")
+	require.True(t, txeB.Height > heightA)
+
+	// Once available the second database catches up from its own checkpoint while the first resumes from its own
+	require.NoError(t, runProjections(connectionB))
+	for _, d := range []*sqldb.SQLDB{db, dbB} {
+		height, err := d.LastBlockHeight(chainID)
+		require.NoError(t, err)
+		require.True(t, height >= txeB.Height)
+	}
+	for _, txe := range []*exec.TxExecution{txeA, txeB} {
+		blk, err := dbB.GetBlock(chainID, txe.Height)
+		require.NoError(t, err)
+		require.NotEmpty(t, blk.Tables["EventTest"], "second database should have the rows of block %d", txe.Height)
+	}
+	require.Equal(t, logRowsA, countLogRows(heightA), "first database should not commit a block twice")
+}
+
+func testNilEventsChannel(t *testing.T, chainID string, cfg *config.VentConfig, tcli rpctransact.TransactClient, inputAddress crypto.Address) {
+	create := test.CreateContract(t, tcli, inputAddress)
+	txe := test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, "DBOnlyEvent", "DB only")
+
+	// create test db
+	db, closeDB := test.NewTestDB(t, cfg)
+	defer closeDB()
+
+	// Guaranteed delivery would block forever sending on a nil channel
+	cfg.ChannelDelivery = config.GuaranteedDelivery
+	configureTestSpec(cfg)
+	consumer := service.NewConsumer(cfg, logging.NewNoopLogger(), nil)
+	projection, err := sqlsol.SpecLoaderWithTableNames(cfg.SpecFileOrDirs, cfg.SpecOpt, cfg.SQLTableNames)
+	require.NoError(t, err)
+	abiSpec, err := abi.LoadPath(cfg.AbiFileOrDirs...)
+	require.NoError(t, err)
+	require.NoError(t, consumer.Run(projection, abiSpec, false))
+
+	height, err := db.LastBlockHeight(chainID)
+	require.NoError(t, err)
+	require.True(t, height >= txe.Height)
+}
+
+func testSubCallEvent(t *testing.T, chainID string, cfg *config.VentConfig, tcli rpctransact.TransactClient, inputAddress crypto.Address) {
+	create := test.CreateContract(t, tcli, inputAddress)
+	proxy := test.CreateProxyContract(t, tcli, inputAddress, create.Receipt.ContractAddress)
+	// The event is emitted by the EventsTest contract when called by the proxy
+	txe := test.CallAddEvent(t, tcli, inputAddress, proxy.Receipt.ContractAddress, "SubCallEvent", "From a sub-call")
+
+	var callDepths []uint64
+	for _, ev := range txe.Events {
+		if ev.Call != nil {
+			callDepths = append(callDepths, ev.Call.StackDepth)
+		}
+	}
+	require.Contains(t, callDepths, uint64(1), "event should be emitted by an internal call")
+
+	db, closeDB := test.NewTestDB(t, cfg)
+	defer closeDB()
+	runConsumer(t, cfg)
+
+	eventData, err := db.GetBlock(chainID, txe.Height)
+	require.NoError(t, err)
+	tblData := eventData.Tables["EventTest"]
+	require.Len(t, tblData, 1)
+	require.Equal(t, "UpdateTestEvents", tblData[0].RowData["_eventname"].(string))
+	require.Equal(t, "SubCallEvent", tblData[0].RowData["testname"].(string))
+}
+
+func testMaxInFlightBlocks(t *testing.T, cfg *config.VentConfig, tcli rpctransact.TransactClient, inputAddress crypto.Address) {
+	create := test.CreateContract(t, tcli, inputAddress)
+	for i := 0; i < 8; i++ {
+		test.CallAddEvent(t, tcli, inputAddress, create.Receipt.ContractAddress, fmt.Sprintf("InFlightEvent%d", i),
+			"In flight")
+	}
+
+	const maxInFlight = 2
+	cfg.MaxInFlightBlocks = maxInFlight
+	consumer := newConsumer(t, cfg)
+	// Only blocks with matched events have rows so every block sent to be committed is counted as decoded
+	cfg.SpecOpt = sqlsol.None
+	sink := &slowSink{delay: 20 * time.Millisecond, decoded: make(map[uint64]bool)}
+	consumer.Sink = sink
+	consumer.OnEventMatched = func(eventClass *types.EventClass, event *exec.Event, row types.EventDataRow) error {
+		sink.decode(event.Header.Height)
+		return nil
+	}
+	projection, err := sqlsol.SpecLoaderWithTableNames(cfg.SpecFileOrDirs, cfg.SpecOpt, cfg.SQLTableNames)
+	require.NoError(t, err)
+	abiSpec, err := abi.LoadPath(cfg.AbiFileOrDirs...)
+	require.NoError(t, err)
+	require.NoError(t, consumer.Run(projection, abiSpec, false))
+
+	sink.Lock()
+	defer sink.Unlock()
+	require.Equal(t, len(sink.decoded), sink.committed, "every decoded block should be committed")
+	// Beyond the buffer one block is being committed and the next waits to be sent
+	assert.True(t, sink.maxInFlight <= maxInFlight+2, "at most %d blocks should be in flight but %d were",
+		maxInFlight+2, sink.maxInFlight)
+	// In lockstep decoding could get no more than two blocks ahead
+	assert.True(t, sink.maxInFlight > 2, "decoding should run ahead of the slow sink but only %d blocks were in flight",
+		sink.maxInFlight)
+}
+
+// A sink that commits slowly and counts the blocks decoded but not yet committed
+type slowSink struct {
+	sync.Mutex
+	delay       time.Duration
+	decoded     map[uint64]bool
+	committed   int
+	maxInFlight int
+	height      uint64
+}
+
+func (ss *slowSink) decode(height uint64) {
+	ss.Lock()
+	defer ss.Unlock()
+	ss.decoded[height] = true
+	if inFlight := len(ss.decoded) - ss.committed; inFlight > ss.maxInFlight {
+		ss.maxInFlight = inFlight
+	}
+}
+
+func (ss *slowSink) SetBlock(chainID string, eventTables types.EventTables, eventData types.EventData) error {
+	time.Sleep(ss.delay)
+	ss.Lock()
+	defer ss.Unlock()
+	ss.committed++
+	ss.height = eventData.BlockHeight
+	return nil
+}
+
+func (ss *slowSink) LastBlockHeight(chainID string) (uint64, error) {
+	ss.Lock()
+	defer ss.Unlock()
+	return ss.height, nil
+}
+
+func (ss *slowSink) Close() {
+}
+
 func newConsumer(t *testing.T, cfg *config.VentConfig) *service.Consumer {
+	configureTestSpec(cfg)
+	ch := make(chan types.EventData, 100)
+	return service.NewConsumer(cfg, logging.NewNoopLogger(), ch)
+}
+
+// Sets the spec and ABI of the test dir
+func configureTestSpec(cfg *config.VentConfig) {
 	// Resolve relative path to test dir
 	_, testFile, _, _ := runtime.Caller(0)
 	testDir := path.Join(path.Dir(testFile), "..", "test")
@@ -192,16 +713,13 @@ func newConsumer(t *testing.T, cfg *config.VentConfig) *service.Consumer {
 	cfg.SpecFileOrDirs = []string{path.Join(testDir, "sqlsol_example.json")}
 	cfg.AbiFileOrDirs = []string{path.Join(testDir, "EventsTest.abi")}
 	cfg.SpecOpt = sqlsol.BlockTx
-
-	ch := make(chan types.EventData, 100)
-	return service.NewConsumer(cfg, logging.NewNoopLogger(), ch)
 }
 
 // Run consumer to listen to events
 func runConsumer(t *testing.T, cfg *config.VentConfig) chan types.EventData {
 	consumer := newConsumer(t, cfg)
 
-	projection, err := sqlsol.SpecLoader(cfg.SpecFileOrDirs, cfg.SpecOpt)
+	projection, err := sqlsol.SpecLoaderWithTableNames(cfg.SpecFileOrDirs, cfg.SpecOpt, cfg.SQLTableNames)
 	require.NoError(t, err)
 
 	abiSpec, err := abi.LoadPath(cfg.AbiFileOrDirs...)
@@ -1,20 +1,28 @@
 package service
 
 import (
+	"bytes"
 	"fmt"
 	"math/big"
+	"strings"
 
+	"github.com/hyperledger/burrow/binary"
 	"github.com/hyperledger/burrow/crypto"
 	"github.com/hyperledger/burrow/execution/evm/abi"
 	"github.com/hyperledger/burrow/execution/exec"
+	"github.com/hyperledger/burrow/txs/payload"
 	"github.com/hyperledger/burrow/vent/types"
 	"github.com/pkg/errors"
 )
 
 // decodeEvent unpacks & decodes event data
 func decodeEvent(header *exec.Header, log *exec.LogEvent, origin *exec.Origin, abiSpec *abi.AbiSpec) (map[string]interface{}, error) {
-	// to prepare decoded data and map to event item name
-	data := make(map[string]interface{})
+	if abiSpec == nil {
+		return nil, fmt.Errorf("no abi spec provided to decode event, use a raw event class to store it undecoded")
+	}
+	if len(log.Topics) == 0 {
+		return nil, fmt.Errorf("event has no signature topic to look up in abi spec")
+	}
 
 	var eventID abi.EventID
 	copy(eventID[:], log.Topics[0].Bytes())
@@ -24,12 +32,28 @@ func decodeEvent(header *exec.Header, log *exec.LogEvent, origin *exec.Origin, a
 		return nil, fmt.Errorf("abi spec not found for event %x", eventID)
 	}
 
-	// decode header to get context data for each event
+	return decodeEventWithSpec(header, log, origin, evAbi)
+}
+
+// decodeEventWithSpec unpacks & decodes event data according to the given event spec
+func decodeEventWithSpec(header *exec.Header, log *exec.LogEvent, origin *exec.Origin,
+	evAbi abi.EventSpec) (map[string]interface{}, error) {
+	topics := 0
+	if !evAbi.Anonymous {
+		topics++
+	}
+	for _, input := range evAbi.Inputs {
+		if input.Indexed {
+			topics++
+		}
+	}
+	if len(log.Topics) < topics {
+		return nil, fmt.Errorf("event %s expects %d topics but log has %d", evAbi.Name, topics, len(log.Topics))
+	}
+
+	// to prepare decoded data and map to event item name
+	data := contextData(header, origin)
 	data[types.EventNameLabel] = evAbi.Name
-	data[types.ChainIDLabel] = origin.ChainID
-	data[types.BlockHeightLabel] = fmt.Sprintf("%v", origin.GetHeight())
-	data[types.EventTypeLabel] = header.GetEventType().String()
-	data[types.TxTxHashLabel] = header.TxHash.String()
 
 	// build expected interface type array to get log event values
 	unpackedData := abi.GetPackingTypes(evAbi.Inputs)
@@ -41,17 +65,133 @@ func decodeEvent(header *exec.Header, log *exec.LogEvent, origin *exec.Origin, a
 
 	// for each decoded item value, stores it in given item name
 	for i, input := range evAbi.Inputs {
-		switch v := unpackedData[i].(type) {
-		case *crypto.Address:
-			data[input.Name] = v.String()
-		case *big.Int:
-			data[input.Name] = v.String()
-		case *string:
-			data[input.Name] = *v
-		default:
-			data[input.Name] = v
-		}
+		data[input.Name] = decodedValue(unpackedData[i])
 	}
 
 	return data, nil
 }
+
+// callInput returns the input data of txe if it is a call transaction to an existing contract
+func callInput(txe *exec.TxExecution) ([]byte, bool) {
+	if txe.Envelope == nil || txe.Envelope.Tx == nil {
+		return nil, false
+	}
+	callTx, ok := txe.Envelope.Tx.Payload.(*payload.CallTx)
+	if !ok || callTx.Address == nil {
+		return nil, false
+	}
+	return callTx.Data, true
+}
+
+// callsFunction returns whether input (as returned by callInput) is a call of the named function of abiSpec
+func callsFunction(input []byte, abiSpec *abi.AbiSpec, functionName string) (bool, error) {
+	if abiSpec == nil {
+		return false, fmt.Errorf("no abi spec provided to decode call of function %s", functionName)
+	}
+	funcSpec, ok := abiSpec.Functions[functionName]
+	if !ok {
+		return false, fmt.Errorf("abi spec not found for function %s", functionName)
+	}
+	return len(input) >= abi.FunctionIDSize && bytes.Equal(input[:abi.FunctionIDSize], funcSpec.FunctionID[:]), nil
+}
+
+// decodeCall unpacks & decodes the arguments of a call of the named function from input, which must call it
+func decodeCall(txe *exec.TxExecution, input []byte, origin *exec.Origin, abiSpec *abi.AbiSpec,
+	functionName string) (map[string]interface{}, error) {
+	funcSpec := abiSpec.Functions[functionName]
+
+	data := map[string]interface{}{
+		types.ChainIDLabel:     origin.ChainID,
+		types.BlockHeightLabel: fmt.Sprintf("%v", origin.GetHeight()),
+		types.BlockTimeLabel:   origin.GetTime(),
+		types.EventTypeLabel:   txe.EventType().String(),
+		types.TxTxHashLabel:    txe.TxHash.String(),
+		types.EventNameLabel:   functionName,
+	}
+
+	unpackedData := abi.GetPackingTypes(funcSpec.Inputs)
+	if err := abi.Unpack(funcSpec.Inputs, input[abi.FunctionIDSize:], unpackedData...); err != nil {
+		return nil, errors.Wrapf(err, "could not unpack arguments of call of function %s", functionName)
+	}
+
+	for i, input := range funcSpec.Inputs {
+		data[input.Name] = decodedValue(unpackedData[i])
+	}
+
+	return data, nil
+}
+
+// decodedValue returns the column value of a value unpacked by abi
+func decodedValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *crypto.Address:
+		return v.String()
+	case *big.Int:
+		return v.String()
+	case *string:
+		return *v
+	default:
+		return v
+	}
+}
+
+// decodeRawEvent returns the topics and data of the log event in hex without decoding them, absent topics are nil
+func decodeRawEvent(header *exec.Header, log *exec.LogEvent, origin *exec.Origin) (map[string]interface{}, error) {
+	if len(log.Topics) > len(types.TopicLabels) {
+		return nil, fmt.Errorf("raw event has %d topics but at most %d are supported", len(log.Topics),
+			len(types.TopicLabels))
+	}
+	data := contextData(header, origin)
+	data[types.EventIndexLabel] = fmt.Sprintf("%v", header.GetIndex())
+	for i, topic := range log.Topics {
+		data[types.TopicLabels[i]] = binary.HexBytes(topic.Bytes()).String()
+	}
+	data[types.DataLabel] = log.Data.String()
+	return data, nil
+}
+
+// decodeGovernanceEvent returns the account updated by a governance event, its name, public key, power, native
+// balance, permissions, and roles are nil unless the update sets them. Permissions and roles are comma-separated.
+func decodeGovernanceEvent(header *exec.Header, governAccount *exec.GovernAccountEvent,
+	origin *exec.Origin) (map[string]interface{}, error) {
+	if governAccount.GetAccountUpdate() == nil {
+		return nil, fmt.Errorf("%v event is not a governance event with an account update", header.GetEventType())
+	}
+	update := governAccount.AccountUpdate
+	data := contextData(header, origin)
+	data[types.EventIndexLabel] = fmt.Sprintf("%v", header.GetIndex())
+	if update.Address != nil {
+		data[types.AccountAddressLabel] = update.Address.String()
+	}
+	if update.Name != "" {
+		data[types.AccountNameLabel] = update.Name
+	}
+	if update.PublicKey != nil {
+		data[types.PublicKeyLabel] = update.PublicKey.String()
+	}
+	balances := update.Balances()
+	if balances.HasPower() {
+		data[types.PowerLabel] = balances.GetPower(0)
+	}
+	if balances.HasNative() {
+		data[types.BalanceLabel] = balances.GetNative(0)
+	}
+	if len(update.Permissions) > 0 {
+		data[types.PermissionsLabel] = strings.Join(update.Permissions, ",")
+	}
+	if len(update.Roles) > 0 {
+		data[types.RolesLabel] = strings.Join(update.Roles, ",")
+	}
+	return data, nil
+}
+
+// contextData returns the context of the event common to every row
+func contextData(header *exec.Header, origin *exec.Origin) map[string]interface{} {
+	return map[string]interface{}{
+		types.ChainIDLabel:     origin.ChainID,
+		types.BlockHeightLabel: fmt.Sprintf("%v", origin.GetHeight()),
+		types.BlockTimeLabel:   origin.GetTime(),
+		types.EventTypeLabel:   header.GetEventType().String(),
+		types.TxTxHashLabel:    header.TxHash.String(),
+	}
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"github.com/pkg/errors"
+)
+
+// The following error types are returned by Consumer.Run so that callers can distinguish the classes of failure with
+// a type assertion in order to decide whether to restart, alert, or ignore. Each wraps the underlying cause and has
+// the same message as the wrapped error.
+
+// ErrDBConnection is returned when the SQL database (or other Sink) cannot be connected to, read from, or written to
+type ErrDBConnection struct {
+	consumerError
+}
+
+// ErrStream is returned when the connection to Burrow over gRPC fails or the block stream ends in error
+type ErrStream struct {
+	consumerError
+}
+
+// ErrDecode is returned when a block, transaction, or event cannot be decoded into rows
+type ErrDecode struct {
+	consumerError
+}
+
+// ErrSchemaSync is returned when the database tables cannot be synchronised with the projection
+type ErrSchemaSync struct {
+	consumerError
+}
+
+type consumerError struct {
+	cause error
+}
+
+func (ce consumerError) Error() string {
+	return ce.cause.Error()
+}
+
+// Unwrap supports errors.Is and errors.As
+func (ce consumerError) Unwrap() error {
+	return ce.cause
+}
+
+// Cause supports github.com/pkg/errors.Cause
+func (ce consumerError) Cause() error {
+	return ce.cause
+}
+
+func newErrDBConnection(err error, format string, args ...interface{}) error {
+	return &ErrDBConnection{consumerError{errors.Wrapf(err, format, args...)}}
+}
+
+func newErrStream(err error, format string, args ...interface{}) error {
+	return &ErrStream{consumerError{errors.Wrapf(err, format, args...)}}
+}
+
+func newErrDecode(err error, format string, args ...interface{}) error {
+	return &ErrDecode{consumerError{errors.Wrapf(err, format, args...)}}
+}
+
+func newErrSchemaSync(err error, format string, args ...interface{}) error {
+	return &ErrSchemaSync{consumerError{errors.Wrapf(err, format, args...)}}
+}
+
+// wrapStreamError wraps an error returned while consuming the block stream. Errors already classified by the block
+// consumer keep their type (with message prepended), anything else is an ErrStream.
+func wrapStreamError(err error, message string) error {
+	switch e := err.(type) {
+	case *ErrDecode:
+		return newErrDecode(e.cause, message)
+	case *ErrDBConnection:
+		return newErrDBConnection(e.cause, message)
+	case *ErrStream:
+		return newErrStream(e.cause, message)
+	default:
+		return newErrStream(err, message)
+	}
+}
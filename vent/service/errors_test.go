@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"testing"
+
+	"github.com/hyperledger/burrow/logging"
+	"github.com/hyperledger/burrow/vent/config"
+	"github.com/hyperledger/burrow/vent/sqlsol"
+	"github.com/hyperledger/burrow/vent/types"
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumerErrors(t *testing.T) {
+	cause := fmt.Errorf("connection refused")
+	err := newErrDBConnection(cause, "error connecting to SQL database")
+	assert.Equal(t, "error connecting to SQL database: connection refused", err.Error())
+
+	require.IsType(t, &ErrDBConnection{}, err)
+	assert.Equal(t, cause, pkgerrors.Cause(err))
+}
+
+func TestWrapStreamError(t *testing.T) {
+	// Errors classified by the block consumer keep their type
+	err := wrapStreamError(newErrDecode(fmt.Errorf("bad bytes"), "Error building event data"), "Error receiving blocks")
+	assert.Equal(t, "Error receiving blocks: Error building event data: bad bytes", err.Error())
+	assert.IsType(t, &ErrDecode{}, err)
+
+	// Anything else is a stream error
+	err = wrapStreamError(fmt.Errorf("transport is closing"), "Error receiving blocks")
+	assert.Equal(t, "Error receiving blocks: transport is closing", err.Error())
+	assert.IsType(t, &ErrStream{}, err)
+}
+
+func TestRunReturnsErrStream(t *testing.T) {
+	// Grab a free port and close it so nothing is listening
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	require.NoError(t, listener.Close())
+
+	cfg := config.DefaultVentConfig()
+	cfg.GRPCAddr = listener.Addr().String()
+	consumer := NewConsumer(cfg, logging.NewNoopLogger(), make(chan types.EventData))
+
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{})
+	require.NoError(t, err)
+
+	err = consumer.Run(projection, nil, false)
+	require.Error(t, err)
+	assert.IsType(t, &ErrStream{}, err, "expected ErrStream but got %T: %v", err, err)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"context"
+	"io"
+
+	"github.com/hyperledger/burrow/execution/exec"
+	"github.com/hyperledger/burrow/rpc/rpcevents"
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+	"google.golang.org/grpc"
+)
+
+// withGapFilling wraps blockConsumer to check that each block follows on from the last block it consumed (starting at
+// next) and, if blocks are missing, to request them and consume them before the block itself. Burrow only streams
+// blocks with transactions so heights are not consecutive. Where the headers of both blocks are known the running
+// total of transactions tells whether a block with transactions was missed, otherwise any skipped height is requested.
+func (c *Consumer) withGapFilling(cli rpcevents.ExecutionEventsClient, next uint64,
+	blockConsumer func(*exec.BlockExecution) error, opts ...grpc.CallOption) func(*exec.BlockExecution) error {
+
+	var lastHeader *abciTypes.Header
+	consume := func(be *exec.BlockExecution) error {
+		err := blockConsumer(be)
+		if err != nil {
+			return err
+		}
+		lastHeader = be.Header
+		next = be.Height + 1
+		return nil
+	}
+
+	return func(be *exec.BlockExecution) error {
+		if be.Height < next {
+			// Already consumed, including any block resent after a gap was filled
+			return nil
+		}
+		if hasGap(lastHeader, be, next) {
+			c.Log.InfoMsg("WARNING: gap in block stream, requesting missing blocks before continuing",
+				"gap_start", next, "gap_end", be.Height-1)
+			stream, err := cli.Stream(context.Background(), &rpcevents.BlocksRequest{
+				BlockRange: rpcevents.NewBlockRange(rpcevents.AbsoluteBound(next), rpcevents.AbsoluteBound(be.Height-1)),
+			}, opts...)
+			if err != nil {
+				return newErrStream(err, "Error connecting to block stream to fill gap")
+			}
+			err = rpcevents.ConsumeBlockExecutions(stream, func(gapBlock *exec.BlockExecution) error {
+				if gapBlock.Height < next || gapBlock.Height >= be.Height {
+					return nil
+				}
+				return consume(gapBlock)
+			})
+			if err != nil && err != io.EOF {
+				return wrapStreamError(err, "Error receiving blocks to fill gap")
+			}
+		}
+		return consume(be)
+	}
+}
+
+// hasGap returns whether blocks with transactions may be missing between the block with lastHeader (nil if none has
+// been consumed) and be, the next expected height being next
+func hasGap(lastHeader *abciTypes.Header, be *exec.BlockExecution, next uint64) bool {
+	if be.Height <= next {
+		return false
+	}
+	if lastHeader != nil && be.Header != nil {
+		return lastHeader.TotalTxs+be.Header.NumTxs != be.Header.TotalTxs
+	}
+	return true
+}
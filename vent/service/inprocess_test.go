@@ -0,0 +1,453 @@
+package service_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	protoio "github.com/gogo/protobuf/io"
+	"github.com/hyperledger/burrow/acm"
+	"github.com/hyperledger/burrow/acm/balance"
+	"github.com/hyperledger/burrow/binary"
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/evm/abi"
+	"github.com/hyperledger/burrow/execution/exec"
+	"github.com/hyperledger/burrow/genesis/spec"
+	"github.com/hyperledger/burrow/logging"
+	"github.com/hyperledger/burrow/txs/payload"
+	"github.com/hyperledger/burrow/vent/config"
+	"github.com/hyperledger/burrow/vent/service"
+	"github.com/hyperledger/burrow/vent/sqlsol"
+	"github.com/hyperledger/burrow/vent/test"
+	"github.com/hyperledger/burrow/vent/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	abciTypes "github.com/tendermint/tendermint/abci/types"
+)
+
+// Runs the whole consumer pipeline in-process: events are matched by the filter, decoded with the ABI, and stored in
+// the sink
+func TestConsumerInProcess(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"Stored","anonymous":false,"inputs":[
+		{"name":"key","type":"uint256","indexed":true},{"name":"value","type":"string","indexed":false}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["Stored"]
+	address := crypto.Address{1, 2, 3}
+	other := crypto.Address{4, 5, 6}
+
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+		TableName: "Stored",
+		Filter:    "EventType = 'LogEvent' AND Address = '" + address.String() + "'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "key", ColumnName: "key", Type: "uint256", Primary: true},
+			{Field: "value", ColumnName: "value", Type: "string"},
+		},
+	}})
+	require.NoError(t, err)
+
+	newBlock := func(height uint64, address crypto.Address, key uint64, value string) *exec.BlockExecution {
+		data, err := abi.Pack(eventSpec.Inputs[1:], value)
+		require.NoError(t, err)
+		txe := &exec.TxExecution{
+			TxHeader: &exec.TxHeader{
+				TxType: payload.TypeCall,
+				TxHash: []byte{byte(height)},
+				Height: height,
+			},
+		}
+		require.NoError(t, txe.Log(&exec.LogEvent{
+			Address: address,
+			Data:    data,
+			Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes()),
+				binary.Uint64ToWord256(key)},
+		}))
+		return &exec.BlockExecution{Height: height, TxExecutions: []*exec.TxExecution{txe}}
+	}
+
+	burrow := test.NewFakeBurrow(test.ChainID,
+		newBlock(2, address, 1, "frogs"),
+		newBlock(3, other, 2, "not matched"),
+		newBlock(5, address, 1, "newts"),
+		newBlock(6, address, 7, "dogs"))
+	sink := test.NewMemorySink()
+	published := new(bytes.Buffer)
+
+	run := func() {
+		consumer := service.NewConsumer(config.DefaultVentConfig(), logging.NewNoopLogger(), nil)
+		consumer.Sink = sink
+		consumer.PublishBlock = service.NewDelimitedPublisher(published)
+		consumer.QueryClient = burrow.QueryClient()
+		consumer.ExecutionEventsClient = burrow.ExecutionEventsClient()
+		require.NoError(t, consumer.Run(projection, abiSpec, false))
+	}
+	run()
+	assert.True(t, sink.Closed())
+
+	var heights []uint64
+	for _, block := range sink.Blocks() {
+		heights = append(heights, block.BlockHeight)
+	}
+	assert.Equal(t, []uint64{2, 5, 6}, heights)
+	rows := sink.Rows("Stored")
+	require.Len(t, rows, 2)
+	assert.Equal(t, "1", rows[0]["key"])
+	assert.Equal(t, "newts", rows[0]["value"])
+	assert.Equal(t, "5", rows[0]["_height"])
+	assert.Equal(t, "7", rows[1]["key"])
+	assert.Equal(t, "dogs", rows[1]["value"])
+
+	// Every committed block is published with the same rows
+	reader := protoio.NewDelimitedReader(published, 1<<20)
+	for _, block := range sink.Blocks() {
+		blockEvents := new(types.BlockEvents)
+		require.NoError(t, reader.ReadMsg(blockEvents))
+		assert.Equal(t, test.ChainID, blockEvents.ChainID)
+		eventData := blockEvents.EventData()
+		assert.Equal(t, block.BlockHeight, eventData.BlockHeight)
+		require.Len(t, eventData.Tables["Stored"], len(block.Tables["Stored"]))
+		assert.Equal(t, block.Tables["Stored"][0].RowData["value"], eventData.Tables["Stored"][0].RowData["value"])
+	}
+	assert.Equal(t, 0, published.Len())
+
+	// Resumes after the last block committed to the sink
+	burrow.AddBlocks(newBlock(8, address, 7, "cats"))
+	run()
+	blocks := sink.Blocks()
+	require.Len(t, blocks, 4)
+	assert.Equal(t, uint64(8), blocks[3].BlockHeight)
+	height, err := sink.LastBlockHeight(test.ChainID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(8), height)
+	assert.Equal(t, "cats", sink.Rows("Stored")[1]["value"])
+}
+
+// pausingSink calls onSetBlock once each block has been committed
+type pausingSink struct {
+	*test.MemorySink
+	onSetBlock func(height uint64)
+}
+
+func (ps *pausingSink) SetBlock(chainID string, eventTables types.EventTables, eventData types.EventData) error {
+	err := ps.MemorySink.SetBlock(chainID, eventTables, eventData)
+	ps.onSetBlock(eventData.BlockHeight)
+	return err
+}
+
+func TestConsumerPauseResume(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"Stored","anonymous":false,"inputs":[
+		{"name":"key","type":"uint256","indexed":true},{"name":"value","type":"string","indexed":false}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["Stored"]
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+		TableName: "Stored",
+		Filter:    "EventType = 'LogEvent'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "key", ColumnName: "key", Type: "uint256", Primary: true},
+			{Field: "value", ColumnName: "value", Type: "string"},
+		},
+	}})
+	require.NoError(t, err)
+
+	var blocks []*exec.BlockExecution
+	for height := uint64(1); height <= 4; height++ {
+		data, err := abi.Pack(eventSpec.Inputs[1:], "value")
+		require.NoError(t, err)
+		txe := &exec.TxExecution{TxHeader: &exec.TxHeader{TxType: payload.TypeCall, TxHash: []byte{byte(height)},
+			Height: height}}
+		require.NoError(t, txe.Log(&exec.LogEvent{
+			Data:   data,
+			Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes()), binary.Uint64ToWord256(height)},
+		}))
+		blocks = append(blocks, &exec.BlockExecution{Height: height, TxExecutions: []*exec.TxExecution{txe}})
+	}
+	burrow := test.NewFakeBurrow(test.ChainID, blocks...)
+
+	consumer := service.NewConsumer(config.DefaultVentConfig(), logging.NewNoopLogger(), nil)
+	sink := &pausingSink{MemorySink: test.NewMemorySink()}
+	// Pause mid-stream once the first block has been committed
+	sink.onSetBlock = func(height uint64) {
+		if height == 1 {
+			consumer.Pause()
+		}
+	}
+	consumer.Sink = sink
+	consumer.QueryClient = burrow.QueryClient()
+	consumer.ExecutionEventsClient = burrow.ExecutionEventsClient()
+
+	errCh := make(chan error)
+	go func() {
+		errCh <- consumer.Run(projection, abiSpec, false)
+	}()
+
+	// Nothing more is committed while paused while the consumer remains healthy
+	time.Sleep(100 * time.Millisecond)
+	require.True(t, consumer.Paused())
+	require.Len(t, sink.Blocks(), 1)
+	require.NoError(t, consumer.Health())
+	select {
+	case err := <-errCh:
+		t.Fatalf("consumer returned while paused: %v", err)
+	default:
+	}
+
+	consumer.Resume()
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("consumer did not finish after resuming")
+	}
+	assert.False(t, consumer.Paused())
+	assert.Len(t, sink.Blocks(), 4)
+	assert.Len(t, sink.Rows("Stored"), 4)
+}
+
+func TestConsumerGovernance(t *testing.T) {
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+		TableName:  "ValidatorChanges",
+		Filter:     "EventType = 'GovernAccountEvent'",
+		Governance: true,
+	}})
+	require.NoError(t, err)
+
+	validator := acm.GeneratePrivateAccountFromSecret("validator")
+	publicKey := validator.GetPublicKey()
+	address := validator.GetAddress()
+	txe := &exec.TxExecution{
+		TxHeader: &exec.TxHeader{TxType: payload.TypeGovernance, TxHash: []byte{0xAB}, Height: 2},
+	}
+	txe.GovernAccount(&exec.GovernAccountEvent{
+		AccountUpdate: &spec.TemplateAccount{
+			Address:     &address,
+			PublicKey:   &publicKey,
+			Amounts:     balance.New().Power(100),
+			Permissions: []string{"bond", "unbond"},
+		},
+	}, nil)
+	burrow := test.NewFakeBurrow(test.ChainID, &exec.BlockExecution{Height: 2, TxExecutions: []*exec.TxExecution{txe}})
+
+	// Governance event classes need no ABI
+	sink := test.NewMemorySink()
+	consumer := service.NewConsumer(config.DefaultVentConfig(), logging.NewNoopLogger(), nil)
+	consumer.Sink = sink
+	consumer.QueryClient = burrow.QueryClient()
+	consumer.ExecutionEventsClient = burrow.ExecutionEventsClient()
+	require.NoError(t, consumer.Run(projection, nil, false))
+
+	rows := sink.Rows("ValidatorChanges")
+	require.Len(t, rows, 1)
+	columns := types.DefaultSQLColumnNames
+	assert.Equal(t, "2", rows[0][columns.Height])
+	assert.Equal(t, "AB", rows[0][columns.TxHash])
+	assert.Equal(t, exec.TypeGovernAccount.String(), rows[0][columns.EventType])
+	assert.Equal(t, address.String(), rows[0][columns.AccountAddress])
+	assert.Equal(t, publicKey.String(), rows[0][columns.PublicKey])
+	assert.Equal(t, uint64(100), rows[0][columns.Power])
+	assert.Equal(t, "bond,unbond", rows[0][columns.Permissions])
+	// Only what the update sets is stored
+	assert.NotContains(t, rows[0], columns.Balance)
+	assert.NotContains(t, rows[0], columns.Roles)
+
+	// Governance event classes do not project EVM events
+	_, err = sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+		TableName:  "ValidatorChanges",
+		Filter:     "EventType = 'GovernAccountEvent'",
+		Governance: true,
+		EventName:  "Stored",
+	}})
+	require.Error(t, err)
+}
+
+func TestConsumerRollup(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"Stored","anonymous":false,"inputs":[
+		{"name":"key","type":"uint256","indexed":true},{"name":"value","type":"string","indexed":false}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["Stored"]
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+		TableName: "Stored",
+		Filter:    "EventType = 'LogEvent'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "key", ColumnName: "key", Type: "uint256", Primary: true},
+			{Field: "value", ColumnName: "value", Type: "string"},
+		},
+		Rollups: []*types.Rollup{{
+			TableName:  "StoredHourly",
+			Bucket:     types.RollupBucketHour,
+			Aggregates: []*types.RollupAggregate{{Function: types.AggregateCount, As: "stores"}},
+		}},
+	}})
+	require.NoError(t, err)
+
+	hour := time.Date(2019, 6, 1, 12, 0, 0, 0, time.UTC)
+	newBlock := func(height uint64, blockTime time.Time) *exec.BlockExecution {
+		data, err := abi.Pack(eventSpec.Inputs[1:], "value")
+		require.NoError(t, err)
+		txe := &exec.TxExecution{TxHeader: &exec.TxHeader{TxType: payload.TypeCall, TxHash: []byte{byte(height)},
+			Height: height}}
+		require.NoError(t, txe.Log(&exec.LogEvent{
+			Data:   data,
+			Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes()), binary.Uint64ToWord256(height)},
+		}))
+		return &exec.BlockExecution{
+			Height:       height,
+			Header:       &abciTypes.Header{Height: int64(height), Time: blockTime},
+			TxExecutions: []*exec.TxExecution{txe},
+		}
+	}
+	// Two blocks in the same hour then one in the next
+	burrow := test.NewFakeBurrow(test.ChainID,
+		newBlock(1, hour.Add(5*time.Minute)),
+		newBlock(2, hour.Add(50*time.Minute)),
+		newBlock(3, hour.Add(70*time.Minute)))
+
+	sink := test.NewMemorySink()
+	consumer := service.NewConsumer(config.DefaultVentConfig(), logging.NewNoopLogger(), nil)
+	consumer.Sink = sink
+	consumer.QueryClient = burrow.QueryClient()
+	consumer.ExecutionEventsClient = burrow.ExecutionEventsClient()
+	require.NoError(t, consumer.Run(projection, abiSpec, false))
+
+	// Each block accumulates its rows into the rollup in the same commit
+	for _, block := range sink.Blocks() {
+		require.Len(t, block.Tables["StoredHourly"], 1)
+		assert.Equal(t, types.ActionAccumulate, block.Tables["StoredHourly"][0].Action)
+	}
+	rows := sink.Rows("StoredHourly")
+	require.Len(t, rows, 2)
+	columns := types.DefaultSQLColumnNames
+	assert.Equal(t, hour, rows[0][columns.Bucket])
+	assert.Equal(t, int64(2), rows[0]["stores"])
+	assert.Equal(t, hour.Add(time.Hour), rows[1][columns.Bucket])
+	assert.Equal(t, int64(1), rows[1]["stores"])
+
+	// A block committed again, as when it is replayed after a restart, is not accumulated again
+	require.NoError(t, sink.SetBlock(test.ChainID, projection.Tables, sink.Blocks()[1]))
+	assert.Equal(t, int64(2), sink.Rows("StoredHourly")[0]["stores"])
+
+	// Aggregated columns must be in the table
+	_, err = sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+		TableName: "Stored",
+		Filter:    "EventType = 'LogEvent'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "key", ColumnName: "key", Type: "uint256", Primary: true},
+		},
+		Rollups: []*types.Rollup{{
+			TableName:  "StoredHourly",
+			Bucket:     types.RollupBucketHour,
+			Aggregates: []*types.RollupAggregate{{Function: types.AggregateSum, ColumnName: "amount", As: "total"}},
+		}},
+	}})
+	require.Error(t, err)
+}
+
+func TestConsumerRowOrder(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"Stored","anonymous":false,"inputs":[
+		{"name":"key","type":"uint256","indexed":true},{"name":"value","type":"string","indexed":false}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["Stored"]
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+		TableName: "Stored",
+		Filter:    "EventType = 'LogEvent'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "key", ColumnName: "key", Type: "uint256", Primary: true},
+			{Field: "value", ColumnName: "value", Type: "string"},
+		},
+	}})
+	require.NoError(t, err)
+
+	// Two transactions in the same block store different values under the same key
+	newTx := func(txHash byte, value string) *exec.TxExecution {
+		data, err := abi.Pack(eventSpec.Inputs[1:], value)
+		require.NoError(t, err)
+		txe := &exec.TxExecution{TxHeader: &exec.TxHeader{TxType: payload.TypeCall, TxHash: []byte{txHash}, Height: 1}}
+		require.NoError(t, txe.Log(&exec.LogEvent{
+			Data:   data,
+			Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes()), binary.Uint64ToWord256(1)},
+		}))
+		return txe
+	}
+	burrow := test.NewFakeBurrow(test.ChainID, &exec.BlockExecution{
+		Height:       1,
+		TxExecutions: []*exec.TxExecution{newTx(1, "earlier"), newTx(2, "later")},
+	})
+
+	cfg := config.DefaultVentConfig()
+	// Decoding concurrently must not change the order rows are committed in
+	cfg.DecodeWorkers = 2
+	sink := test.NewMemorySink()
+	consumer := service.NewConsumer(cfg, logging.NewNoopLogger(), nil)
+	consumer.Sink = sink
+	consumer.QueryClient = burrow.QueryClient()
+	consumer.ExecutionEventsClient = burrow.ExecutionEventsClient()
+	require.NoError(t, consumer.Run(projection, abiSpec, false))
+
+	blocks := sink.Blocks()
+	require.Len(t, blocks, 1)
+	committed := blocks[0].Tables["Stored"]
+	require.Len(t, committed, 2)
+	assert.Equal(t, "earlier", committed[0].RowData["value"])
+	assert.Equal(t, "later", committed[1].RowData["value"])
+
+	// The upsert of the later transaction wins
+	rows := sink.Rows("Stored")
+	require.Len(t, rows, 1)
+	assert.Equal(t, "later", rows[0]["value"])
+}
+
+func TestConsumerFillGaps(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"Stored","anonymous":false,"inputs":[
+		{"name":"key","type":"uint256","indexed":true},{"name":"value","type":"string","indexed":false}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["Stored"]
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+		TableName: "Stored",
+		Filter:    "EventType = 'LogEvent'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "key", ColumnName: "key", Type: "uint256", Primary: true},
+			{Field: "value", ColumnName: "value", Type: "string"},
+		},
+	}})
+	require.NoError(t, err)
+
+	// Each block has one transaction, totalTxs counts the transactions of the chain up to and including the block
+	newBlock := func(height uint64, totalTxs int64) *exec.BlockExecution {
+		data, err := abi.Pack(eventSpec.Inputs[1:], "stored")
+		require.NoError(t, err)
+		txe := &exec.TxExecution{TxHeader: &exec.TxHeader{TxType: payload.TypeCall, TxHash: []byte{byte(height)},
+			Height: height}}
+		require.NoError(t, txe.Log(&exec.LogEvent{
+			Data:   data,
+			Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes()), binary.Uint64ToWord256(height)},
+		}))
+		return &exec.BlockExecution{
+			Height:       height,
+			Header:       &abciTypes.Header{Height: int64(height), NumTxs: 1, TotalTxs: totalTxs},
+			TxExecutions: []*exec.TxExecution{txe},
+		}
+	}
+	// Block 5 had no transactions so is not streamed
+	blocks := []*exec.BlockExecution{newBlock(1, 1), newBlock(2, 2), newBlock(3, 3), newBlock(4, 4), newBlock(6, 5)}
+
+	run := func(fillGaps bool) []uint64 {
+		burrow := test.NewFakeBurrow(test.ChainID, blocks...)
+		burrow.SkipInNextStream(3)
+		cfg := config.DefaultVentConfig()
+		cfg.FillGaps = fillGaps
+		sink := test.NewMemorySink()
+		consumer := service.NewConsumer(cfg, logging.NewNoopLogger(), nil)
+		consumer.Sink = sink
+		consumer.QueryClient = burrow.QueryClient()
+		consumer.ExecutionEventsClient = burrow.ExecutionEventsClient()
+		require.NoError(t, consumer.Run(projection, abiSpec, false))
+		var heights []uint64
+		for _, block := range sink.Blocks() {
+			heights = append(heights, block.BlockHeight)
+		}
+		return heights
+	}
+
+	// The missing block is lost
+	assert.Equal(t, []uint64{1, 2, 4, 6}, run(false))
+	// The missing block is requested and committed in order
+	assert.Equal(t, []uint64{1, 2, 3, 4, 6}, run(true))
+}
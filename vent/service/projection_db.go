@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hyperledger/burrow/execution/evm/abi"
+	"github.com/hyperledger/burrow/execution/exec"
+	"github.com/hyperledger/burrow/vent/sqldb"
+	"github.com/hyperledger/burrow/vent/sqlsol"
+	"github.com/hyperledger/burrow/vent/types"
+)
+
+// ProjectionDB is a projection committed to its own SQL database, independently of the projection passed to
+// Consumer.Run and its sink, so that for example hot and cold data can be kept on different servers
+type ProjectionDB struct {
+	Projection *sqlsol.Projection
+	// The database to which the projection is committed, the consumer's logger is used if Log is nil and
+	// DefaultSQLTableNames if TableNames is empty. The TableNames must match those the projection was loaded with.
+	Connection types.SQLConnection
+}
+
+// projectionTarget is a connected ProjectionDB
+type projectionTarget struct {
+	ProjectionDB
+	db         *sqldb.SQLDB
+	tableNames types.SQLTableNames
+	// The height of the last block committed to db before this run, earlier blocks are not committed again
+	lastHeight uint64
+}
+
+// projectionBlock is the block data for a single projectionTarget
+type projectionBlock struct {
+	target *projectionTarget
+	data   types.EventData
+}
+
+// connectProjectionDBs connects to, initialises, and synchronises the database of each of c.ProjectionDBs. On error
+// any databases already connected are closed.
+func (c *Consumer) connectProjectionDBs() ([]*projectionTarget, error) {
+	targets := make([]*projectionTarget, 0, len(c.ProjectionDBs))
+	closeAll := func() {
+		for _, target := range targets {
+			target.db.Close()
+		}
+	}
+	for i, pdb := range c.ProjectionDBs {
+		connection := pdb.Connection
+		if connection.Log == nil {
+			connection.Log = c.Log
+		}
+		if connection.TableNames == (types.SQLTableNames{}) {
+			connection.TableNames = types.DefaultSQLTableNames
+		}
+
+		c.Log.InfoMsg("Connecting to projection SQL database", "projection", i, "db_adapter", connection.DBAdapter)
+		db, err := sqldb.NewSQLDB(connection)
+		if err != nil {
+			closeAll()
+			return nil, newErrDBConnection(err, "error connecting to SQL database of projection %d", i)
+		}
+		targets = append(targets, &projectionTarget{
+			ProjectionDB: pdb,
+			db:           db,
+			tableNames:   connection.TableNames,
+		})
+
+		err = db.Init(c.Burrow.ChainID, c.Burrow.BurrowVersion)
+		if err != nil {
+			closeAll()
+			return nil, newErrDBConnection(err, "could not clean tables of projection %d after ChainID change", i)
+		}
+
+		c.withDeadLetterTable(pdb.Projection, connection.TableNames)
+		err = db.SynchronizeDB(c.Burrow.ChainID, pdb.Projection.Tables)
+		if err != nil {
+			closeAll()
+			return nil, newErrSchemaSync(err, "Error trying to synchronize database of projection %d", i)
+		}
+
+		if c.Config.DecodeWorkers > 1 {
+			memoiseProjection(pdb.Projection)
+		}
+	}
+	return targets, nil
+}
+
+// loadLastHeights sets the last committed height of each target from its own log table
+func (c *Consumer) loadLastHeights(targets []*projectionTarget) error {
+	for i, target := range targets {
+		height, err := target.db.LastBlockHeight(c.Burrow.ChainID)
+		if err != nil {
+			return newErrDBConnection(err, "Error trying to get last processed block number of projection %d", i)
+		}
+		target.lastHeight = height
+	}
+	return nil
+}
+
+// withProjectionDBs returns a block consumer that passes each block to blockConsumer, unless the block is no later
+// than lastHeight, and sends the block data of each target that has not yet committed the block on projectionCh.
+// Since the consumers may resume from different heights a block is only skipped by a consumer that has already
+// committed it, a lastHeight of zero cannot be distinguished from nothing committed so skips nothing.
+func (c *Consumer) withProjectionDBs(blockConsumer func(*exec.BlockExecution) error, lastHeight uint64,
+	targets []*projectionTarget, abiSpec *abi.AbiSpec,
+	projectionCh chan<- []projectionBlock) func(*exec.BlockExecution) error {
+
+	return func(blockExecution *exec.BlockExecution) error {
+		if c.Closing {
+			return io.EOF
+		}
+
+		height := blockExecution.Height
+		if lastHeight == 0 || height > lastHeight {
+			err := blockConsumer(blockExecution)
+			if err != nil {
+				return err
+			}
+		}
+
+		var blocks []projectionBlock
+		for _, target := range targets {
+			if target.lastHeight > 0 && height <= target.lastHeight {
+				continue
+			}
+			blockData, err := c.decodeBlock(target.Projection, abiSpec, target.tableNames, blockExecution)
+			if err != nil {
+				return err
+			}
+			if blockData.PendingRows(height) {
+				blocks = append(blocks, projectionBlock{target: target, data: blockData.Data})
+			}
+		}
+		if len(blocks) > 0 {
+			projectionCh <- blocks
+		}
+		return nil
+	}
+}
+
+// commitProjectionBlock commits the block data to the target's own database, which records the height in its own
+// log table
+func (c *Consumer) commitProjectionBlock(blk projectionBlock) error {
+	c.Log.InfoMsg(fmt.Sprintf("Upserting rows in projection SQL tables %v", blk.data), "block", blk.data.BlockHeight)
+	err := blk.target.db.SetBlock(c.Burrow.ChainID, blk.target.Projection.Tables, blk.data)
+	if err != nil {
+		return newErrDBConnection(err, "error upserting rows in projection database")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	bin "encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/hyperledger/burrow/vent/types"
+)
+
+// BlockPublisher is passed the protobuf encoding of a types.BlockEvents message of each block with rows as it is
+// committed, for example to write it to a file or pipe (see NewDelimitedPublisher) or produce it to a Kafka topic
+type BlockPublisher func(block []byte) error
+
+// NewDelimitedPublisher returns a BlockPublisher that writes each block to w prefixed by its length as a varint, which
+// is the framing read by parseDelimitedFrom (and its like) in the protobuf libraries of other languages
+func NewDelimitedPublisher(w io.Writer) BlockPublisher {
+	return func(block []byte) error {
+		var length [bin.MaxVarintLen64]byte
+		_, err := w.Write(length[:bin.PutUvarint(length[:], uint64(len(block)))])
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(block)
+		return err
+	}
+}
+
+// publishBlock passes blockEvents to the PublishBlock of the consumer if it has one
+func (c *Consumer) publishBlock(blockEvents types.EventData) error {
+	if c.PublishBlock == nil || len(blockEvents.Tables) == 0 {
+		return nil
+	}
+	msg, err := types.NewBlockEvents(c.Burrow.ChainID, blockEvents)
+	if err != nil {
+		return fmt.Errorf("could not encode block %d for publishing: %v", blockEvents.BlockHeight, err)
+	}
+	bs, err := msg.Marshal()
+	if err != nil {
+		return fmt.Errorf("could not encode block %d for publishing: %v", blockEvents.BlockHeight, err)
+	}
+	err = c.PublishBlock(bs)
+	if err != nil {
+		return fmt.Errorf("could not publish block %d: %v", blockEvents.BlockHeight, err)
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hyperledger/burrow/vent/sqlsol"
+	"github.com/hyperledger/burrow/vent/types"
+)
+
+// buildRollupData returns the rows accumulating the rows of eventData upserted by each event class into its rollup
+// tables, one for each group of the bucket of blockTime. Rows missing a value of a column they are grouped by cannot
+// be keyed so are not aggregated. Each row carries the height of the block so that it is only accumulated once.
+func buildRollupData(projection *sqlsol.Projection, eventData types.EventData, blockTime time.Time) ([]txRow, error) {
+	var rows []txRow
+	for _, eventClass := range projection.EventSpec {
+		for _, rollup := range eventClass.Rollups {
+			bucket, err := rollup.Bucket.Start(blockTime)
+			if err != nil {
+				return nil, fmt.Errorf("could not build rollup %s: %v", rollup.TableName, err)
+			}
+			// The accumulated row of each group by its group-by values, in the order the groups were first seen
+			groups := make(map[string]map[string]interface{})
+			var keys []string
+		rowLoop:
+			for _, row := range eventData.Tables[eventClass.TableName] {
+				if row.EventClass != eventClass || row.Action != types.ActionUpsert {
+					continue
+				}
+				group := map[string]interface{}{columns.Bucket: bucket, columns.Height: eventData.BlockHeight}
+				groupValues := make([]interface{}, len(rollup.GroupBy))
+				for i, columnName := range rollup.GroupBy {
+					value, ok := row.RowData[columnName]
+					if !ok || value == nil {
+						continue rowLoop
+					}
+					group[columnName] = value
+					groupValues[i] = value
+				}
+				// The JSON of the values dereferences any pointers and distinguishes their types
+				bs, err := json.Marshal(groupValues)
+				if err != nil {
+					return nil, fmt.Errorf("could not group row of table %s for rollup %s: %v", eventClass.TableName,
+						rollup.TableName, err)
+				}
+				key := string(bs)
+				accumulated, ok := groups[key]
+				if !ok {
+					accumulated = group
+					groups[key] = accumulated
+					keys = append(keys, key)
+				}
+				for _, aggregate := range rollup.Aggregates {
+					var value interface{} = int64(1)
+					if aggregate.Function != types.AggregateCount {
+						value = row.RowData[aggregate.ColumnName]
+					}
+					accumulated[aggregate.As], err = aggregate.Function.Combine(accumulated[aggregate.As], value)
+					if err != nil {
+						return nil, fmt.Errorf("could not %s column %s of table %s for rollup %s: %v",
+							aggregate.Function, aggregate.ColumnName, eventClass.TableName, rollup.TableName, err)
+					}
+				}
+			}
+			for _, key := range keys {
+				rows = append(rows, txRow{
+					tableName: rollup.TableName,
+					row: types.EventDataRow{
+						Action:     types.ActionAccumulate,
+						RowData:    groups[key],
+						EventClass: eventClass,
+					},
+				})
+			}
+		}
+	}
+	return rows, nil
+}
@@ -5,11 +5,12 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 	"unicode/utf8"
 
+	"github.com/hyperledger/burrow/binary"
 	"github.com/hyperledger/burrow/execution/evm/abi"
 	"github.com/hyperledger/burrow/execution/exec"
-	"github.com/hyperledger/burrow/logging"
 	"github.com/hyperledger/burrow/vent/sqlsol"
 	"github.com/hyperledger/burrow/vent/types"
 	"github.com/pkg/errors"
@@ -17,21 +18,55 @@ import (
 
 // buildEventData builds event data from transactions
 func buildEventData(projection *sqlsol.Projection, eventClass *types.EventClass, event *exec.Event, origin *exec.Origin, abiSpec *abi.AbiSpec,
-	l *logging.Logger) (types.EventDataRow, error) {
-
-	// a fresh new row to store column/value data
-	row := make(map[string]interface{})
+	timeFormat types.TimeFormat, l types.Logger) (types.EventDataRow, error) {
 
 	// get header & log data for the given event
 	eventHeader := event.GetHeader()
 	eventLog := event.GetLog()
 
-	// decode event data using the provided abi specification
-	decodedData, err := decodeEvent(eventHeader, eventLog, origin, abiSpec)
+	// decode event data using the provided abi specification or the declared layout of an anonymous event, or take
+	// the topics and data as they are for a raw event class or the account update of a governance event class
+	var decodedData map[string]interface{}
+	var err error
+	if eventClass.Raw {
+		decodedData, err = decodeRawEvent(eventHeader, eventLog, origin)
+	} else if eventClass.Governance {
+		decodedData, err = decodeGovernanceEvent(eventHeader, event.GetGovernAccount(), origin)
+	} else if eventClass.AnonymousEvent != nil {
+		var evAbi *abi.EventSpec
+		evAbi, err = eventClass.AnonymousEvent.EventSpec()
+		if err == nil {
+			decodedData, err = decodeEventWithSpec(eventHeader, eventLog, origin, *evAbi)
+		}
+	} else {
+		decodedData, err = decodeEvent(eventHeader, eventLog, origin, abiSpec)
+	}
 	if err != nil {
 		return types.EventDataRow{}, errors.Wrapf(err, "Error decoding event (filter: %s)", eventClass.Filter)
 	}
 
+	return buildRow(projection, eventClass, decodedData, timeFormat, l)
+}
+
+// buildCallData builds the row of a call of the function of eventClass from its input
+func buildCallData(projection *sqlsol.Projection, eventClass *types.EventClass, txe *exec.TxExecution, input []byte,
+	origin *exec.Origin, abiSpec *abi.AbiSpec, timeFormat types.TimeFormat, l types.Logger) (types.EventDataRow, error) {
+
+	decodedData, err := decodeCall(txe, input, origin, abiSpec, eventClass.FunctionName)
+	if err != nil {
+		return types.EventDataRow{}, errors.Wrapf(err, "Error decoding call (filter: %s)", eventClass.Filter)
+	}
+
+	return buildRow(projection, eventClass, decodedData, timeFormat, l)
+}
+
+// buildRow maps the fields of the decoded data of an event or call to the columns of eventClass's table
+func buildRow(projection *sqlsol.Projection, eventClass *types.EventClass, decodedData map[string]interface{},
+	timeFormat types.TimeFormat, l types.Logger) (types.EventDataRow, error) {
+
+	// a fresh new row to store column/value data
+	row := make(map[string]interface{})
+
 	l.InfoMsg(fmt.Sprintf("Unpacked data: %v", decodedData), "eventName", decodedData[types.EventNameLabel])
 
 	rowAction := types.ActionUpsert
@@ -50,6 +85,42 @@ func buildEventData(projection *sqlsol.Projection, eventClass *types.EventClass,
 		}
 		column, err := projection.GetColumn(eventClass.TableName, fieldMapping.ColumnName)
 		if err == nil {
+			// Amounts with looked up decimals are scaled by the SQL DB as it writes the row
+			if fieldMapping.Amount != nil && fieldMapping.Amount.DecimalsLookup == nil {
+				row[fieldMapping.Amount.ScaledColumnName], err = fieldMapping.Amount.Scaled(value,
+					fieldMapping.Amount.Decimals)
+				if err != nil {
+					return types.EventDataRow{}, errors.Wrapf(err, "Error scaling amount field %s", fieldName)
+				}
+			}
+			if t, ok := value.(time.Time); ok {
+				row[column.Name] = timeFormat.ColumnValue(t, column.Type)
+				continue
+			}
+			if fieldMapping.BigInt != nil {
+				row[column.Name], err = fieldMapping.BigInt.Value(value)
+				if err != nil {
+					return types.EventDataRow{}, errors.Wrapf(err, "Error converting field %s to big integer", fieldName)
+				}
+				continue
+			}
+			if fieldMapping.Enum != nil {
+				label, err := fieldMapping.Enum.Label(value)
+				if err != nil {
+					return types.EventDataRow{}, errors.Wrapf(err, "Error resolving enum label for field %s", fieldName)
+				}
+				if fieldMapping.Enum.LabelColumnName == "" {
+					row[column.Name] = label
+					continue
+				}
+				row[fieldMapping.Enum.LabelColumnName] = label
+			}
+			if fieldMapping.StringFromBytes32 {
+				if bs, ok := value.(*[]byte); ok {
+					row[column.Name] = stringFromBytes32(*bs, l)
+					continue
+				}
+			}
 			if fieldMapping.BytesToString {
 				if bs, ok := value.(*[]byte); ok {
 					str := sanitiseBytesForString(*bs, l)
@@ -63,16 +134,63 @@ func buildEventData(projection *sqlsol.Projection, eventClass *types.EventClass,
 		}
 	}
 
+	// the NOT NULL columns of fields missing from the event store their zero value
+	for _, fieldMapping := range eventClass.FieldMappings {
+		if !fieldMapping.NotNull {
+			continue
+		}
+		columnNames := []string{fieldMapping.ColumnName}
+		if fieldMapping.Enum != nil && fieldMapping.Enum.LabelColumnName != "" {
+			columnNames = append(columnNames, fieldMapping.Enum.LabelColumnName)
+		}
+		if fieldMapping.Amount != nil {
+			columnNames = append(columnNames, fieldMapping.Amount.ScaledColumnName)
+		}
+		for _, columnName := range columnNames {
+			if _, ok := row[columnName]; ok {
+				continue
+			}
+			column, err := projection.GetColumn(eventClass.TableName, columnName)
+			if err != nil {
+				l.TraceMsg("could not get column", "err", err)
+				continue
+			}
+			row[column.Name] = column.Type.ZeroValue()
+		}
+	}
+
 	return types.EventDataRow{Action: rowAction, RowData: row, EventClass: eventClass}, nil
 }
 
+// buildDeadLetterData builds the dead-letter row of the source of a row of eventClass (an event or for a call the
+// transaction envelope) that could not be decoded because of decodeErr
+func buildDeadLetterData(eventClass *types.EventClass, source rowSource, decodeErr error) (types.EventDataRow, error) {
+	eventJSON, err := json.Marshal(source.value)
+	if err != nil {
+		return types.EventDataRow{}, fmt.Errorf("couldn't marshal %v: %v", source.value, err)
+	}
+
+	return types.EventDataRow{
+		Action: types.ActionUpsert,
+		RowData: map[string]interface{}{
+			columns.Height:      fmt.Sprintf("%v", source.height),
+			columns.TxHash:      source.txHash.String(),
+			columns.EventIndex:  source.index,
+			columns.TableName:   eventClass.TableName,
+			columns.EventFilter: eventClass.Filter,
+			columns.Error:       decodeErr.Error(),
+			columns.Event:       string(eventJSON),
+		},
+	}, nil
+}
+
 // buildBlkData builds block data from block stream
-func buildBlkData(tbls types.EventTables, block *exec.BlockExecution) (types.EventDataRow, error) {
+func buildBlkData(tbls types.EventTables, blockTable string, block *exec.BlockExecution) (types.EventDataRow, error) {
 	// a fresh new row to store column/value data
 	row := make(map[string]interface{})
 
 	// block raw data
-	if _, ok := tbls[tables.Block]; ok {
+	if _, ok := tbls[blockTable]; ok {
 		blockHeader, err := json.Marshal(block.Header)
 		if err != nil {
 			return types.EventDataRow{}, fmt.Errorf("couldn not marshal BlockHeader in block %v", block)
@@ -81,7 +199,7 @@ func buildBlkData(tbls types.EventTables, block *exec.BlockExecution) (types.Eve
 		row[columns.Height] = fmt.Sprintf("%v", block.Height)
 		row[columns.BlockHeader] = string(blockHeader)
 	} else {
-		return types.EventDataRow{}, fmt.Errorf("table: %s not found in table structure %v", tables.Block, tbls)
+		return types.EventDataRow{}, fmt.Errorf("table: %s not found in table structure %v", blockTable, tbls)
 	}
 
 	return types.EventDataRow{Action: types.ActionUpsert, RowData: row}, nil
@@ -137,7 +255,7 @@ func buildTxData(txe *exec.TxExecution) (types.EventDataRow, error) {
 	}, nil
 }
 
-func sanitiseBytesForString(bs []byte, l *logging.Logger) string {
+func sanitiseBytesForString(bs []byte, l types.Logger) string {
 	str, err := UTF8StringFromBytes(bs)
 	if err != nil {
 		l.InfoMsg("buildEventData() received invalid bytes for utf8 string - proceeding with sanitised version",
@@ -147,6 +265,18 @@ func sanitiseBytesForString(bs []byte, l *logging.Logger) string {
 	return strings.Trim(str, "\x00")
 }
 
+// stringFromBytes32 returns the string held in a bytes32 value padded with trailing zero bytes, or the hex of the whole
+// value if it is not valid utf8
+func stringFromBytes32(bs []byte, l types.Logger) string {
+	trimmed := bytes.TrimRight(bs, "\x00")
+	if utf8.Valid(trimmed) {
+		return string(trimmed)
+	}
+	l.InfoMsg("buildEventData() received invalid utf8 bytes for bytes32 string - storing as hex",
+		"bytes", binary.HexBytes(bs).String())
+	return binary.HexBytes(bs).String()
+}
+
 // Checks whether the bytes passed are valid utf8 string bytes. If they are not returns a sanitised string version of the
 // bytes with offending sequences replaced by the utf8 replacement/error rune and an error indicating the offending
 // byte sequences and their position. Note: always returns a valid string regardless of error.
@@ -3,10 +3,20 @@ package service
 import (
 	"bytes"
 	"fmt"
+	"math/big"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/hyperledger/burrow/binary"
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/evm/abi"
+	"github.com/hyperledger/burrow/execution/exec"
 	"github.com/hyperledger/burrow/logging"
+	"github.com/hyperledger/burrow/vent/sqlsol"
+	"github.com/hyperledger/burrow/vent/types"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	hex "github.com/tmthrgd/go-hex"
 )
 
@@ -45,3 +55,555 @@ func BadStringToHexFunction(goodString string) string {
 	}
 	return string(hex.MustDecodeString(buf.String()))
 }
+
+func TestBuildEventDataEnum(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"StateChanged","anonymous":false,"inputs":[
+		{"name":"id","type":"uint256","indexed":false},{"name":"state","type":"uint8","indexed":false}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["StateChanged"]
+
+	newEvent := func(state uint8) *exec.Event {
+		data, err := abi.Pack(eventSpec.Inputs, 1, state)
+		require.NoError(t, err)
+		return &exec.Event{
+			Header: &exec.Header{EventType: exec.TypeLog, Height: 1},
+			Log: &exec.LogEvent{
+				Data:   data,
+				Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes())},
+			},
+		}
+	}
+
+	newProjection := func(enum *types.EventFieldEnum) (*sqlsol.Projection, *types.EventClass) {
+		eventClass := &types.EventClass{
+			TableName: "States",
+			Filter:    "EventType = 'LogEvent'",
+			FieldMappings: []*types.EventFieldMapping{
+				{Field: "id", ColumnName: "id", Type: "uint256", Primary: true},
+				{Field: "state", ColumnName: "state", Type: "uint8", Enum: enum},
+			},
+		}
+		projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{eventClass})
+		require.NoError(t, err)
+		return projection, eventClass
+	}
+	labels := map[string]string{"0": "Pending", "1": "Active", "2": "Closed"}
+	origin := &exec.Origin{ChainID: "test-chain", Height: 1}
+	logger := logging.NewNoopLogger()
+
+	t.Run("known code with label column", func(t *testing.T) {
+		projection, eventClass := newProjection(&types.EventFieldEnum{Labels: labels, LabelColumnName: "state_label"})
+		column, err := projection.GetColumn("States", "state_label")
+		require.NoError(t, err)
+		assert.Equal(t, types.SQLColumnTypeText, column.Type)
+
+		row, err := buildEventData(projection, eventClass, newEvent(1), origin, abiSpec, types.TimeFormat{}, logger)
+		require.NoError(t, err)
+		state, ok := row.RowData["state"].(*uint8)
+		require.True(t, ok)
+		assert.Equal(t, uint8(1), *state)
+		assert.Equal(t, "Active", row.RowData["state_label"])
+	})
+
+	t.Run("known code replaced by label", func(t *testing.T) {
+		projection, eventClass := newProjection(&types.EventFieldEnum{Labels: labels})
+		column, err := projection.GetColumn("States", "state")
+		require.NoError(t, err)
+		assert.Equal(t, types.SQLColumnTypeText, column.Type)
+
+		row, err := buildEventData(projection, eventClass, newEvent(2), origin, abiSpec, types.TimeFormat{}, logger)
+		require.NoError(t, err)
+		assert.Equal(t, "Closed", row.RowData["state"])
+	})
+
+	t.Run("unknown code is an error", func(t *testing.T) {
+		projection, eventClass := newProjection(&types.EventFieldEnum{Labels: labels, LabelColumnName: "state_label"})
+		_, err := buildEventData(projection, eventClass, newEvent(7), origin, abiSpec, types.TimeFormat{}, logger)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "code 7 is not one of the declared enum codes")
+	})
+
+	t.Run("unknown code falls back to sentinel", func(t *testing.T) {
+		projection, eventClass := newProjection(&types.EventFieldEnum{Labels: labels, UnknownLabel: "Unknown"})
+		row, err := buildEventData(projection, eventClass, newEvent(7), origin, abiSpec, types.TimeFormat{}, logger)
+		require.NoError(t, err)
+		assert.Equal(t, "Unknown", row.RowData["state"])
+	})
+}
+
+func TestBuildEventDataTime(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"Tick","anonymous":false,"inputs":[
+		{"name":"id","type":"uint256","indexed":false}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["Tick"]
+	data, err := abi.Pack(eventSpec.Inputs, 1)
+	require.NoError(t, err)
+	event := &exec.Event{
+		Header: &exec.Header{EventType: exec.TypeLog, Height: 1},
+		Log: &exec.LogEvent{
+			Data:   data,
+			Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes())},
+		},
+	}
+	eventClass := &types.EventClass{
+		TableName: "Ticks",
+		Filter:    "EventType = 'LogEvent'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "id", ColumnName: "id", Type: "uint256", Primary: true},
+			{Field: types.BlockTimeLabel, ColumnName: "block_time", Type: types.EventFieldTypeTimestamp},
+		},
+	}
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{eventClass})
+	require.NoError(t, err)
+	column, err := projection.GetColumn("Ticks", "block_time")
+	require.NoError(t, err)
+	assert.Equal(t, types.SQLColumnTypeTimeStamp, column.Type)
+
+	// Block time from a chain in UTC+2
+	blockTime := time.Date(2019, 3, 4, 12, 30, 15, 0, time.FixedZone("EET", 2*60*60))
+	origin := &exec.Origin{ChainID: "test-chain", Height: 1, Time: blockTime}
+
+	t.Run("timestamp column is UTC", func(t *testing.T) {
+		row, err := buildEventData(projection, eventClass, event, origin, abiSpec,
+			types.TimeFormat{Location: time.FixedZone("PST", -8*60*60)}, logging.NewNoopLogger())
+		require.NoError(t, err)
+		stored, ok := row.RowData["block_time"].(time.Time)
+		require.True(t, ok)
+		assert.Equal(t, time.UTC, stored.Location())
+		assert.Equal(t, "2019-03-04T10:30:15Z", stored.Format(time.RFC3339))
+	})
+
+	t.Run("string column uses layout and zone", func(t *testing.T) {
+		stringClass := &types.EventClass{
+			TableName: "TickStrings",
+			Filter:    "EventType = 'LogEvent'",
+			FieldMappings: []*types.EventFieldMapping{
+				{Field: "id", ColumnName: "id", Type: "uint256", Primary: true},
+				{Field: types.BlockTimeLabel, ColumnName: "block_time", Type: types.EventFieldTypeString},
+			},
+		}
+		projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{stringClass})
+		require.NoError(t, err)
+
+		row, err := buildEventData(projection, stringClass, event, origin, abiSpec, types.TimeFormat{},
+			logging.NewNoopLogger())
+		require.NoError(t, err)
+		assert.Equal(t, "2019-03-04T10:30:15Z", row.RowData["block_time"])
+
+		row, err = buildEventData(projection, stringClass, event, origin, abiSpec,
+			types.TimeFormat{Layout: "02/01/2006 15:04 MST", Location: time.FixedZone("PST", -8*60*60)},
+			logging.NewNoopLogger())
+		require.NoError(t, err)
+		assert.Equal(t, "04/03/2019 02:30 PST", row.RowData["block_time"])
+	})
+}
+
+func TestBuildEventDataBigInt(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+		{"name":"id","type":"uint256","indexed":false},{"name":"amount","type":"uint256","indexed":false}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["Transfer"]
+
+	newRow := func(t *testing.T, amount *big.Int, bigInt *types.EventFieldBigInt) (*sqlsol.Projection, types.EventDataRow) {
+		eventClass := &types.EventClass{
+			TableName: "Transfers",
+			Filter:    "EventType = 'LogEvent'",
+			FieldMappings: []*types.EventFieldMapping{
+				{Field: "id", ColumnName: "id", Type: "uint256", Primary: true},
+				{Field: "amount", ColumnName: "amount", Type: "uint256", BigInt: bigInt},
+			},
+		}
+		projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{eventClass})
+		require.NoError(t, err)
+		data, err := abi.Pack(eventSpec.Inputs, 1, amount.String())
+		require.NoError(t, err)
+		event := &exec.Event{
+			Header: &exec.Header{EventType: exec.TypeLog, Height: 1},
+			Log: &exec.LogEvent{
+				Data:   data,
+				Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes())},
+			},
+		}
+		row, err := buildEventData(projection, eventClass, event, &exec.Origin{ChainID: "test-chain", Height: 1},
+			abiSpec, types.TimeFormat{}, logging.NewNoopLogger())
+		require.NoError(t, err)
+		return projection, row
+	}
+
+	// Exceeds int64 (and uint64)
+	huge := new(big.Int).Lsh(big.NewInt(1), 200)
+	huge.Add(huge, big.NewInt(5))
+
+	t.Run("numeric", func(t *testing.T) {
+		projection, row := newRow(t, huge, &types.EventFieldBigInt{As: types.BigIntNumeric})
+		column, err := projection.GetColumn("Transfers", "amount")
+		require.NoError(t, err)
+		assert.Equal(t, types.SQLColumnTypeNumeric, column.Type)
+		assert.Equal(t, huge.String(), row.RowData["amount"])
+	})
+
+	t.Run("fixed width string", func(t *testing.T) {
+		projection, row := newRow(t, huge, &types.EventFieldBigInt{As: types.BigIntString})
+		column, err := projection.GetColumn("Transfers", "amount")
+		require.NoError(t, err)
+		assert.Equal(t, types.SQLColumnTypeVarchar, column.Type)
+		amount := row.RowData["amount"].(string)
+		assert.Len(t, amount, types.BigIntStringLength)
+		assert.Equal(t, huge.String(), strings.TrimLeft(amount, "0"))
+	})
+
+	t.Run("token amount with 18 decimals", func(t *testing.T) {
+		// 1234.5 tokens
+		amount, ok := new(big.Int).SetString("1234500000000000000000", 10)
+		require.True(t, ok)
+		projection, row := newRow(t, amount, &types.EventFieldBigInt{As: types.BigIntDecimal, Decimals: 18})
+		column, err := projection.GetColumn("Transfers", "amount")
+		require.NoError(t, err)
+		assert.Equal(t, types.SQLColumnTypeNumeric, column.Type)
+		assert.Equal(t, "1234.500000000000000000", row.RowData["amount"])
+	})
+
+	t.Run("non-integer field", func(t *testing.T) {
+		_, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+			TableName: "Transfers",
+			Filter:    "EventType = 'LogEvent'",
+			FieldMappings: []*types.EventFieldMapping{
+				{Field: "name", ColumnName: "name", Type: "string",
+					BigInt: &types.EventFieldBigInt{As: types.BigIntNumeric}},
+			},
+		}})
+		require.Error(t, err)
+	})
+}
+
+func TestBuildEventDataAmount(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+		{"name":"id","type":"uint256","indexed":false},{"name":"amount","type":"uint256","indexed":false}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["Transfer"]
+
+	newRow := func(t *testing.T, spec types.EventSpec) types.EventDataRow {
+		projection, err := sqlsol.NewProjectionFromEventSpec(spec)
+		require.NoError(t, err)
+		data, err := abi.Pack(eventSpec.Inputs, 1, "1234500000")
+		require.NoError(t, err)
+		event := &exec.Event{
+			Header: &exec.Header{EventType: exec.TypeLog, Height: 1},
+			Log: &exec.LogEvent{
+				Data:   data,
+				Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes())},
+			},
+		}
+		row, err := buildEventData(projection, spec[len(spec)-1], event,
+			&exec.Origin{ChainID: "test-chain", Height: 1}, abiSpec, types.TimeFormat{}, logging.NewNoopLogger())
+		require.NoError(t, err)
+		return row
+	}
+
+	t.Run("constant decimals", func(t *testing.T) {
+		row := newRow(t, types.EventSpec{{
+			TableName: "Transfers",
+			Filter:    "EventType = 'LogEvent'",
+			FieldMappings: []*types.EventFieldMapping{
+				{Field: "id", ColumnName: "id", Type: "uint256", Primary: true},
+				{Field: "amount", ColumnName: "amount", Type: "uint256",
+					Amount: &types.EventFieldAmount{ScaledColumnName: "amount_scaled", Decimals: 6}},
+			},
+		}})
+		assert.Equal(t, "1234500000", fmt.Sprint(row.RowData["amount"]))
+		assert.Equal(t, "1234.500000", row.RowData["amount_scaled"])
+	})
+
+	tokens := &types.EventClass{
+		TableName: "Tokens",
+		Filter:    "EventType = 'LogEvent'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "token", ColumnName: "token", Type: "address", Primary: true},
+			{Field: "decimals", ColumnName: "decimals", Type: "uint8"},
+		},
+	}
+	lookup := &types.EventFieldDecimalsLookup{
+		TableName:          "Tokens",
+		KeyColumnName:      "token",
+		DecimalsColumnName: "decimals",
+		TokenColumnName:    "token",
+	}
+
+	t.Run("looked up decimals", func(t *testing.T) {
+		row := newRow(t, types.EventSpec{tokens, {
+			TableName: "Transfers",
+			Filter:    "EventType = 'LogEvent'",
+			FieldMappings: []*types.EventFieldMapping{
+				{Field: "id", ColumnName: "id", Type: "uint256", Primary: true},
+				{Field: "token", ColumnName: "token", Type: "address"},
+				{Field: "amount", ColumnName: "amount", Type: "uint256",
+					Amount: &types.EventFieldAmount{ScaledColumnName: "amount_scaled", DecimalsLookup: lookup}},
+			},
+		}})
+		// Scaled by the SQL DB when it writes the row
+		assert.Equal(t, "1234500000", fmt.Sprint(row.RowData["amount"]))
+		assert.NotContains(t, row.RowData, "amount_scaled")
+	})
+
+	t.Run("lookup of missing table", func(t *testing.T) {
+		_, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+			TableName: "Transfers",
+			Filter:    "EventType = 'LogEvent'",
+			FieldMappings: []*types.EventFieldMapping{
+				{Field: "id", ColumnName: "id", Type: "uint256", Primary: true},
+				{Field: "token", ColumnName: "token", Type: "address"},
+				{Field: "amount", ColumnName: "amount", Type: "uint256",
+					Amount: &types.EventFieldAmount{ScaledColumnName: "amount_scaled", DecimalsLookup: lookup}},
+			},
+		}})
+		require.Error(t, err)
+	})
+
+	t.Run("non-integer field", func(t *testing.T) {
+		_, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+			TableName: "Transfers",
+			Filter:    "EventType = 'LogEvent'",
+			FieldMappings: []*types.EventFieldMapping{
+				{Field: "name", ColumnName: "name", Type: "string",
+					Amount: &types.EventFieldAmount{ScaledColumnName: "name_scaled", Decimals: 6}},
+			},
+		}})
+		require.Error(t, err)
+	})
+}
+
+func TestBuildEventDataStringFromBytes32(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"Named","anonymous":false,"inputs":[
+		{"name":"id","type":"uint256","indexed":false},{"name":"name","type":"bytes32","indexed":true}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["Named"]
+	eventClass := &types.EventClass{
+		TableName: "Names",
+		Filter:    "EventType = 'LogEvent'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "id", ColumnName: "id", Type: "uint256", Primary: true},
+			{Field: "name", ColumnName: "name", Type: "bytes32", StringFromBytes32: true},
+		},
+	}
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{eventClass})
+	require.NoError(t, err)
+	column, err := projection.GetColumn("Names", "name")
+	require.NoError(t, err)
+	assert.Equal(t, types.SQLColumnTypeText, column.Type)
+
+	newRow := func(t *testing.T, name binary.Word256) types.EventDataRow {
+		data, err := abi.Pack(eventSpec.Inputs[:1], 1)
+		require.NoError(t, err)
+		event := &exec.Event{
+			Header: &exec.Header{EventType: exec.TypeLog, Height: 1},
+			Log: &exec.LogEvent{
+				Data:   data,
+				Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes()), name},
+			},
+		}
+		row, err := buildEventData(projection, eventClass, event, &exec.Origin{ChainID: "test-chain", Height: 1},
+			abiSpec, types.TimeFormat{}, logging.NewNoopLogger())
+		require.NoError(t, err)
+		return row
+	}
+
+	t.Run("short string", func(t *testing.T) {
+		row := newRow(t, binary.RightPadWord256([]byte("héllo")))
+		assert.Equal(t, "héllo", row.RowData["name"])
+	})
+
+	t.Run("invalid utf8", func(t *testing.T) {
+		name := binary.RightPadWord256([]byte{0xff, 0xfe, 'a'})
+		row := newRow(t, name)
+		assert.Equal(t, binary.HexBytes(name.Bytes()).String(), row.RowData["name"])
+	})
+
+	t.Run("non-bytes32 field", func(t *testing.T) {
+		_, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+			TableName: "Names",
+			Filter:    "EventType = 'LogEvent'",
+			FieldMappings: []*types.EventFieldMapping{
+				{Field: "name", ColumnName: "name", Type: "string", StringFromBytes32: true},
+			},
+		}})
+		require.Error(t, err)
+	})
+}
+
+func TestBuildEventDataRenamedColumns(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"Sent","anonymous":false,"inputs":[
+		{"name":"_to","type":"address","indexed":true},{"name":"amount","type":"uint256","indexed":false}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["Sent"]
+	eventClass := &types.EventClass{
+		TableName: "Transfers",
+		Filter:    "EventType = 'LogEvent'",
+		EventName: "Sent",
+		FieldMappings: []*types.EventFieldMapping{
+			// The ABI argument _to is stored in the recipient column, amount in a column of its own name
+			{Field: "_to", ColumnName: "recipient", Type: "address", Primary: true},
+			{Field: "amount", ColumnName: "amount", Type: "uint256"},
+		},
+	}
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{eventClass})
+	require.NoError(t, err)
+	require.NoError(t, projection.CheckAbi(abiSpec))
+
+	recipient := crypto.Address{1, 2, 3}
+	data, err := abi.Pack(eventSpec.Inputs[1:], 42)
+	require.NoError(t, err)
+	event := &exec.Event{
+		Header: &exec.Header{EventType: exec.TypeLog, Height: 1},
+		Log: &exec.LogEvent{
+			Data:   data,
+			Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes()), recipient.Word256()},
+		},
+	}
+	row, err := buildEventData(projection, eventClass, event, &exec.Origin{ChainID: "test-chain", Height: 1},
+		abiSpec, types.TimeFormat{}, logging.NewNoopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, recipient.String(), row.RowData["recipient"])
+	assert.Equal(t, "42", row.RowData["amount"])
+	assert.NotContains(t, row.RowData, "_to")
+}
+
+func TestBuildEventDataAnonymous(t *testing.T) {
+	anonymousEvent := &types.AnonymousEvent{
+		Name: "Deposited",
+		Inputs: []abi.ArgumentJSON{
+			{Name: "from", Type: "address", Indexed: true},
+			{Name: "amount", Type: "uint256"},
+			{Name: "memo", Type: "string"},
+		},
+	}
+	eventClass := &types.EventClass{
+		TableName: "Deposits",
+		Filter:    "Log0 = '000000000000000000000000000000000000000000000000000000000000ABCD'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "from", ColumnName: "from_address", Type: "address", Primary: true},
+			{Field: "amount", ColumnName: "amount", Type: "uint256"},
+			{Field: "memo", ColumnName: "memo", Type: "string"},
+		},
+		AnonymousEvent: anonymousEvent,
+	}
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{eventClass})
+	require.NoError(t, err)
+
+	eventSpec, err := anonymousEvent.EventSpec()
+	require.NoError(t, err)
+	require.True(t, eventSpec.Anonymous)
+
+	from := crypto.Address{0xAB, 0xCD}
+	data, err := abi.Pack(eventSpec.Inputs[1:], 42, "first deposit")
+	require.NoError(t, err)
+	event := &exec.Event{
+		Header: &exec.Header{EventType: exec.TypeLog, Height: 3},
+		Log: &exec.LogEvent{
+			Data: data,
+			// No signature topic - the first topic is the indexed from address
+			Topics: []binary.Word256{binary.LeftPadWord256(from.Bytes())},
+		},
+	}
+
+	// The ABI does not need to know about the event
+	row, err := buildEventData(projection, eventClass, event, &exec.Origin{ChainID: "test-chain", Height: 3},
+		&abi.AbiSpec{}, types.TimeFormat{}, logging.NewNoopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, from.String(), row.RowData["from_address"])
+	assert.Equal(t, "42", row.RowData["amount"])
+	assert.Equal(t, "first deposit", row.RowData["memo"])
+	assert.Equal(t, "Deposited", row.RowData[columns.EventName])
+
+	// Too few topics for the declared layout
+	event.Log.Topics = nil
+	_, err = buildEventData(projection, eventClass, event, &exec.Origin{ChainID: "test-chain", Height: 3},
+		&abi.AbiSpec{}, types.TimeFormat{}, logging.NewNoopLogger())
+	require.Error(t, err)
+}
+
+func TestBuildEventDataRaw(t *testing.T) {
+	projection, err := sqlsol.NewProjectionFromBytes([]byte(`[{
+		"TableName": "RawLogs",
+		"Filter": "EventType = 'LogEvent'",
+		"Raw": true
+	}]`))
+	require.NoError(t, err)
+	eventClass := projection.EventSpec[0]
+
+	topics := []binary.Word256{binary.LeftPadWord256([]byte{0xAB, 0xCD}), binary.LeftPadWord256([]byte{0x01})}
+	event := &exec.Event{
+		Header: &exec.Header{EventType: exec.TypeLog, TxHash: []byte{0x12, 0x34}, Height: 3, Index: 2},
+		Log: &exec.LogEvent{
+			Data:   []byte{0xDE, 0xAD, 0xBE, 0xEF},
+			Topics: topics,
+		},
+	}
+
+	// No ABI is needed
+	row, err := buildEventData(projection, eventClass, event, &exec.Origin{ChainID: "test-chain", Height: 3},
+		nil, types.TimeFormat{}, logging.NewNoopLogger())
+	require.NoError(t, err)
+	assert.Equal(t, types.ActionUpsert, row.Action)
+	assert.Equal(t, "1234", row.RowData[columns.TxHash])
+	assert.Equal(t, "2", row.RowData[columns.EventIndex])
+	assert.Equal(t, "000000000000000000000000000000000000000000000000000000000000ABCD", row.RowData[columns.Topic0])
+	assert.Equal(t, "0000000000000000000000000000000000000000000000000000000000000001", row.RowData[columns.Topic1])
+	assert.NotContains(t, row.RowData, columns.Topic2)
+	assert.NotContains(t, row.RowData, columns.Topic3)
+	assert.Equal(t, "DEADBEEF", row.RowData[columns.Data])
+
+	// Rows are keyed by transaction and event index
+	var primary []string
+	for _, column := range projection.Tables["RawLogs"].Columns {
+		if column.Primary {
+			primary = append(primary, column.Name)
+		}
+	}
+	assert.Equal(t, []string{columns.TxHash, columns.EventIndex}, primary)
+
+	// Without an ABI a decoded event class cannot be built
+	_, err = buildEventData(projection, &types.EventClass{TableName: "RawLogs", Filter: eventClass.Filter}, event,
+		&exec.Origin{ChainID: "test-chain", Height: 3}, nil, types.TimeFormat{}, logging.NewNoopLogger())
+	require.Error(t, err)
+
+	// Raw event classes are not decoded so cannot be given a layout
+	_, err = sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+		TableName:      "RawLogs",
+		Filter:         eventClass.Filter,
+		Raw:            true,
+		AnonymousEvent: &types.AnonymousEvent{Name: "Deposited"},
+	}})
+	require.Error(t, err)
+}
+
+func TestBuildRowMissingFields(t *testing.T) {
+	eventClass := &types.EventClass{
+		TableName: "Transfers",
+		Filter:    "EventType = 'LogEvent'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "id", ColumnName: "id", Type: "uint256", Primary: true},
+			{Field: "memo", ColumnName: "memo", Type: "string"},
+			{Field: "amount", ColumnName: "amount", Type: "uint64", NotNull: true},
+			{Field: "note", ColumnName: "note", Type: "string", NotNull: true},
+			{Field: "settled", ColumnName: "settled", Type: "bool", NotNull: true},
+		},
+	}
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{eventClass})
+	require.NoError(t, err)
+	column, err := projection.GetColumn("Transfers", "amount")
+	require.NoError(t, err)
+	assert.True(t, column.NotNull)
+	column, err = projection.GetColumn("Transfers", "memo")
+	require.NoError(t, err)
+	assert.False(t, column.NotNull)
+
+	row, err := buildRow(projection, eventClass, map[string]interface{}{"id": "1", "note": "paid"},
+		types.TimeFormat{}, logging.NewNoopLogger())
+	require.NoError(t, err)
+	// A nullable column is left out so stores NULL
+	assert.NotContains(t, row.RowData, "memo")
+	assert.Equal(t, 0, row.RowData["amount"])
+	assert.Equal(t, false, row.RowData["settled"])
+	// Values that are present are kept
+	assert.Equal(t, "paid", row.RowData["note"])
+}
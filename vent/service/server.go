@@ -4,21 +4,21 @@ import (
 	"context"
 	"net/http"
 
-	"github.com/hyperledger/burrow/logging"
 	"github.com/hyperledger/burrow/vent/config"
+	"github.com/hyperledger/burrow/vent/types"
 )
 
 // Server exposes HTTP endpoints for the service
 type Server struct {
 	Config   *config.VentConfig
-	Log      *logging.Logger
+	Log      types.Logger
 	Consumer *Consumer
 	mux      *http.ServeMux
 	stopCh   chan bool
 }
 
 // NewServer returns a new HTTP server
-func NewServer(cfg *config.VentConfig, log *logging.Logger, consumer *Consumer) *Server {
+func NewServer(cfg *config.VentConfig, log types.Logger, consumer *Consumer) *Server {
 	// setup handlers
 	mux := http.NewServeMux()
 
@@ -65,11 +65,20 @@ func (s *Server) Shutdown() {
 
 func healthHandler(consumer *Consumer) func(resp http.ResponseWriter, req *http.Request) {
 	return func(resp http.ResponseWriter, req *http.Request) {
-		err := consumer.Health()
+		var err error
+		// The deep check is opt-in since it queries every table
+		if req.URL.Query().Get("deep") == "true" {
+			err = consumer.HealthDeep()
+		} else {
+			err = consumer.Health()
+		}
 		if err != nil {
 			resp.WriteHeader(http.StatusServiceUnavailable)
 		} else {
 			resp.WriteHeader(http.StatusOK)
+			if consumer.Paused() {
+				_, _ = resp.Write([]byte("paused"))
+			}
 		}
 	}
 }
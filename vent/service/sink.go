@@ -0,0 +1,202 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/burrow/vent/sqldb"
+	"github.com/hyperledger/burrow/vent/types"
+)
+
+// Sink receives the rows projected from each block. The SQL database (sqldb.SQLDB) is the default sink, see
+// NewURLSink for sending blocks to an HTTP or WebSocket endpoint instead.
+type Sink interface {
+	// SetBlock delivers the rows of a block, once it returns without error the block counts as committed
+	SetBlock(chainID string, eventTables types.EventTables, eventData types.EventData) error
+	// LastBlockHeight returns the height of the last block committed to the sink
+	LastBlockHeight(chainID string) (uint64, error)
+	Close()
+}
+
+var _ Sink = &sqldb.SQLDB{}
+var _ Sink = &HTTPSink{}
+var _ Sink = &WebSocketSink{}
+
+// How long to wait for an endpoint to acknowledge a block
+const DefaultSinkTimeout = 30 * time.Second
+
+// SinkBlock is the JSON body sent to an HTTP or WebSocket sink for each block
+type SinkBlock struct {
+	ChainID string
+	types.EventData
+}
+
+// SinkAck is the JSON message with which a WebSocket endpoint acknowledges a block
+type SinkAck struct {
+	BlockHeight uint64
+}
+
+// NewURLSink returns an HTTPSink for an http(s) URL or a WebSocketSink for a ws(s) URL. Endpoints do not report
+// what they have previously received so lastHeight (usually the checkpointed height) is reported by LastBlockHeight
+// until the first block is acknowledged.
+func NewURLSink(sinkURL string, lastHeight uint64) (Sink, error) {
+	u, err := url.Parse(sinkURL)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse sink URL '%s': %v", sinkURL, err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return NewHTTPSink(sinkURL, lastHeight), nil
+	case "ws", "wss":
+		return NewWebSocketSink(sinkURL, lastHeight)
+	default:
+		return nil, fmt.Errorf("sink URL '%s' must have scheme http, https, ws, or wss", sinkURL)
+	}
+}
+
+// HTTPSink POSTs each block as a JSON SinkBlock, any 2xx response acknowledges the block
+type HTTPSink struct {
+	url         string
+	client      *http.Client
+	ackedHeight uint64
+}
+
+func NewHTTPSink(url string, lastHeight uint64) *HTTPSink {
+	return &HTTPSink{
+		url:         url,
+		client:      &http.Client{Timeout: DefaultSinkTimeout},
+		ackedHeight: lastHeight,
+	}
+}
+
+func (hs *HTTPSink) SetBlock(chainID string, eventTables types.EventTables, eventData types.EventData) error {
+	bs, err := json.Marshal(SinkBlock{ChainID: chainID, EventData: eventData})
+	if err != nil {
+		return fmt.Errorf("HTTPSink could not encode block %d: %v", eventData.BlockHeight, err)
+	}
+	resp, err := hs.client.Post(hs.url, "application/json", bytes.NewReader(bs))
+	if err != nil {
+		return fmt.Errorf("HTTPSink could not send block %d: %v", eventData.BlockHeight, err)
+	}
+	defer resp.Body.Close()
+	// Drain so the connection can be reused
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTPSink endpoint %s did not acknowledge block %d: %s", hs.url, eventData.BlockHeight,
+			resp.Status)
+	}
+	atomic.StoreUint64(&hs.ackedHeight, eventData.BlockHeight)
+	return nil
+}
+
+// LastBlockHeight returns the height of the last block acknowledged by the endpoint
+func (hs *HTTPSink) LastBlockHeight(chainID string) (uint64, error) {
+	return atomic.LoadUint64(&hs.ackedHeight), nil
+}
+
+func (hs *HTTPSink) Close() {
+}
+
+// WebSocketSink sends each block as a JSON SinkBlock message over a single WebSocket connection and waits for the
+// endpoint to reply with a SinkAck for the same height before sending the next. If a block cannot be sent or is not
+// acknowledged the connection is dropped, since a late acknowledgement would otherwise be read as the reply to the
+// next block, and redialled on the next SetBlock so blocks can be resent from the acknowledged height.
+type WebSocketSink struct {
+	sync.Mutex
+	url         string
+	timeout     time.Duration
+	conn        *websocket.Conn
+	ackedHeight uint64
+}
+
+func NewWebSocketSink(url string, lastHeight uint64) (*WebSocketSink, error) {
+	ws := &WebSocketSink{
+		url:         url,
+		timeout:     DefaultSinkTimeout,
+		ackedHeight: lastHeight,
+	}
+	err := ws.dial()
+	if err != nil {
+		return nil, err
+	}
+	return ws, nil
+}
+
+func (ws *WebSocketSink) SetBlock(chainID string, eventTables types.EventTables, eventData types.EventData) error {
+	ws.Lock()
+	defer ws.Unlock()
+	if ws.conn == nil {
+		err := ws.dial()
+		if err != nil {
+			return err
+		}
+	}
+	err := ws.sendBlock(chainID, eventData)
+	if err != nil {
+		ws.conn.Close()
+		ws.conn = nil
+		return err
+	}
+	atomic.StoreUint64(&ws.ackedHeight, eventData.BlockHeight)
+	return nil
+}
+
+// LastBlockHeight returns the height of the last block acknowledged by the endpoint
+func (ws *WebSocketSink) LastBlockHeight(chainID string) (uint64, error) {
+	return atomic.LoadUint64(&ws.ackedHeight), nil
+}
+
+func (ws *WebSocketSink) Close() {
+	ws.Lock()
+	defer ws.Unlock()
+	if ws.conn == nil {
+		return
+	}
+	_ = ws.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""),
+		time.Now().Add(time.Second))
+	ws.conn.Close()
+	ws.conn = nil
+}
+
+func (ws *WebSocketSink) dial() error {
+	conn, _, err := websocket.DefaultDialer.Dial(ws.url, nil)
+	if err != nil {
+		return fmt.Errorf("WebSocketSink could not connect to %s: %v", ws.url, err)
+	}
+	ws.conn = conn
+	return nil
+}
+
+func (ws *WebSocketSink) sendBlock(chainID string, eventData types.EventData) error {
+	err := ws.conn.SetWriteDeadline(time.Now().Add(ws.timeout))
+	if err != nil {
+		return err
+	}
+	err = ws.conn.WriteJSON(SinkBlock{ChainID: chainID, EventData: eventData})
+	if err != nil {
+		return fmt.Errorf("WebSocketSink could not send block %d: %v", eventData.BlockHeight, err)
+	}
+	err = ws.conn.SetReadDeadline(time.Now().Add(ws.timeout))
+	if err != nil {
+		return err
+	}
+	ack := new(SinkAck)
+	err = ws.conn.ReadJSON(ack)
+	if err != nil {
+		return fmt.Errorf("WebSocketSink did not receive acknowledgement of block %d: %v", eventData.BlockHeight, err)
+	}
+	if ack.BlockHeight != eventData.BlockHeight {
+		return fmt.Errorf("WebSocketSink expected acknowledgement of block %d but endpoint acknowledged %d",
+			eventData.BlockHeight, ack.BlockHeight)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/hyperledger/burrow/vent/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSinkBlock(height uint64) types.EventData {
+	return types.EventData{
+		BlockHeight: height,
+		Tables: map[string]types.EventDataTable{
+			"Transfers": {{
+				Action:     types.ActionUpsert,
+				RowData:    map[string]interface{}{"id": 1, "amount": "42"},
+				EventClass: &types.EventClass{TableName: "Transfers"},
+			}},
+		},
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var received []SinkBlock
+	var fail bool
+	var mtx sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		block := SinkBlock{}
+		err := json.NewDecoder(r.Body).Decode(&block)
+		if err != nil || r.Method != http.MethodPost || fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = append(received, block)
+	}))
+	defer server.Close()
+
+	sink, err := NewURLSink(server.URL, 3)
+	require.NoError(t, err)
+	require.IsType(t, &HTTPSink{}, sink)
+	defer sink.Close()
+
+	// Reports the height it was started from until a block is acknowledged
+	height, err := sink.LastBlockHeight("test-chain")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), height)
+
+	require.NoError(t, sink.SetBlock("test-chain", nil, newSinkBlock(4)))
+	require.NoError(t, sink.SetBlock("test-chain", nil, newSinkBlock(7)))
+	mtx.Lock()
+	require.Len(t, received, 2)
+	assert.Equal(t, "test-chain", received[0].ChainID)
+	assert.Equal(t, uint64(4), received[0].BlockHeight)
+	assert.Equal(t, "42", received[1].Tables["Transfers"][0].RowData["amount"])
+	mtx.Unlock()
+
+	height, err = sink.LastBlockHeight("test-chain")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), height)
+
+	// Not acknowledged so the height does not advance
+	mtx.Lock()
+	fail = true
+	mtx.Unlock()
+	err = sink.SetBlock("test-chain", nil, newSinkBlock(8))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "503")
+	height, err = sink.LastBlockHeight("test-chain")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(7), height)
+}
+
+func TestWebSocketSink(t *testing.T) {
+	// Acknowledges with the height offset by skew
+	var skew uint64
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			block := SinkBlock{}
+			if err := conn.ReadJSON(&block); err != nil {
+				return
+			}
+			if err := conn.WriteJSON(SinkAck{BlockHeight: block.BlockHeight + atomic.LoadUint64(&skew)}); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	sink, err := NewURLSink("ws"+strings.TrimPrefix(server.URL, "http"), 0)
+	require.NoError(t, err)
+	require.IsType(t, &WebSocketSink{}, sink)
+	defer sink.Close()
+
+	require.NoError(t, sink.SetBlock("test-chain", nil, newSinkBlock(2)))
+	height, err := sink.LastBlockHeight("test-chain")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), height)
+
+	atomic.StoreUint64(&skew, 1)
+	err = sink.SetBlock("test-chain", nil, newSinkBlock(5))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expected acknowledgement of block 5")
+	height, err = sink.LastBlockHeight("test-chain")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), height)
+
+	// Redials and resends from the acknowledged height
+	atomic.StoreUint64(&skew, 0)
+	require.NoError(t, sink.SetBlock("test-chain", nil, newSinkBlock(5)))
+	height, err = sink.LastBlockHeight("test-chain")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), height)
+}
+
+func TestWebSocketSinkDelayedAck(t *testing.T) {
+	// Acknowledges the first block only after the sink has given up waiting
+	var conns int64
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		atomic.AddInt64(&conns, 1)
+		for {
+			block := SinkBlock{}
+			if err := conn.ReadJSON(&block); err != nil {
+				return
+			}
+			if block.BlockHeight == 3 {
+				time.Sleep(200 * time.Millisecond)
+			}
+			if err := conn.WriteJSON(SinkAck{BlockHeight: block.BlockHeight}); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	sink, err := NewWebSocketSink("ws"+strings.TrimPrefix(server.URL, "http"), 2)
+	require.NoError(t, err)
+	defer sink.Close()
+	sink.timeout = 50 * time.Millisecond
+
+	err = sink.SetBlock("test-chain", nil, newSinkBlock(3))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "did not receive acknowledgement of block 3")
+	height, err := sink.LastBlockHeight("test-chain")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), height)
+
+	// The late acknowledgement of block 3 must not be taken as an acknowledgement of block 4
+	sink.timeout = DefaultSinkTimeout
+	require.NoError(t, sink.SetBlock("test-chain", nil, newSinkBlock(4)))
+	height, err = sink.LastBlockHeight("test-chain")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), height)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&conns))
+}
+
+func TestNewURLSink(t *testing.T) {
+	_, err := NewURLSink("ftp://example.com/blocks", 0)
+	require.Error(t, err)
+	// Connection is made up front
+	_, err = NewURLSink("ws://127.0.0.1:1/blocks", 0)
+	require.Error(t, err)
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/hyperledger/burrow/execution/evm/abi"
+	"github.com/hyperledger/burrow/vent/sqlsol"
+	"github.com/pkg/errors"
+)
+
+// RestartPolicy controls how RunSupervised restarts a failed Run
+type RestartPolicy struct {
+	// The wait before the first restart, doubled for each further failure within Window
+	InitialBackoff time.Duration
+	// The longest wait between restarts, if unset the wait stops doubling once it exceeds an hour
+	MaxBackoff time.Duration
+	// The number of failures within Window after which RunSupervised gives up, zero to restart forever
+	MaxFailures int
+	// Failures older than this are forgotten, so a consumer that has run well for a while restarts quickly again. If
+	// zero failures are never forgotten.
+	Window time.Duration
+}
+
+// DefaultRestartPolicy gives up after 5 failures within 10 minutes, backing off from 1 second to 1 minute
+var DefaultRestartPolicy = RestartPolicy{
+	InitialBackoff: time.Second,
+	MaxBackoff:     time.Minute,
+	MaxFailures:    5,
+	Window:         10 * time.Minute,
+}
+
+// ErrRestartsExhausted is returned by RunSupervised when Run has failed RestartPolicy.MaxFailures times within
+// RestartPolicy.Window, it wraps the last failure
+type ErrRestartsExhausted struct {
+	consumerError
+	Failures int
+}
+
+// RunSupervised calls Run until it succeeds, restarting it after transient failures (ErrDBConnection and ErrStream)
+// with exponential backoff according to policy. Any other failure, such as an invalid projection or an ErrDecode or
+// ErrSchemaSync that would recur on restart, is returned immediately. Once the failures within the policy's window
+// reach its limit RunSupervised gives up with an ErrRestartsExhausted. Cancelling ctx shuts the consumer down (as
+// Shutdown does) and returns the context's error. The channels and any Sink given to the consumer stay open between
+// restarts and are closed when RunSupervised returns.
+func (c *Consumer) RunSupervised(ctx context.Context, projection *sqlsol.Projection, abiSpec *abi.AbiSpec, stream bool,
+	policy RestartPolicy) error {
+	defer c.close()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.Closing = true
+			if c.GRPCConnection != nil {
+				c.Shutdown()
+			}
+		case <-stop:
+		}
+	}()
+
+	s := &supervisor{
+		policy: policy,
+		now:    time.Now,
+		after:  time.After,
+	}
+	return s.supervise(ctx, func() error {
+		if c.Closing {
+			return nil
+		}
+		return c.run(projection, abiSpec, stream)
+	}, func(err error, failures int, backoff time.Duration) {
+		c.Log.InfoMsg("Vent consumer failed, restarting", "err", err, "failures", failures, "backoff", backoff)
+	})
+}
+
+// isTransientError reports whether a failure of Run may not recur when it is restarted
+func isTransientError(err error) bool {
+	switch err.(type) {
+	case *ErrDBConnection, *ErrStream:
+		return true
+	default:
+		return false
+	}
+}
+
+// Stops the backoff doubling before it overflows when RestartPolicy.MaxBackoff is unset
+const maxBackoff = time.Hour
+
+type supervisor struct {
+	policy RestartPolicy
+	// The clock, replaced in tests
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+	// The times of the failures within the policy's window
+	failures []time.Time
+}
+
+// supervise calls run until it succeeds, fails fatally, or fails too often, calling restarting before each backoff
+func (s *supervisor) supervise(ctx context.Context, run func() error,
+	restarting func(err error, failures int, backoff time.Duration)) error {
+	for {
+		err := run()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err == nil || !isTransientError(err) {
+			return err
+		}
+
+		failures := s.fail()
+		if s.policy.MaxFailures > 0 && failures >= s.policy.MaxFailures {
+			return &ErrRestartsExhausted{
+				consumerError: consumerError{errors.Wrapf(err, "giving up after %d failures within %v", failures,
+					s.policy.Window)},
+				Failures: failures,
+			}
+		}
+
+		backoff := s.backoff(failures)
+		restarting(err, failures, backoff)
+		select {
+		case <-s.after(backoff):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// fail records a failure and returns the number of failures within the window
+func (s *supervisor) fail() int {
+	now := s.now()
+	recent := s.failures[:0]
+	for _, failure := range s.failures {
+		if s.policy.Window <= 0 || now.Sub(failure) < s.policy.Window {
+			recent = append(recent, failure)
+		}
+	}
+	s.failures = append(recent, now)
+	return len(s.failures)
+}
+
+// backoff returns the wait before restarting after the given number of failures within the window
+func (s *supervisor) backoff(failures int) time.Duration {
+	backoff := s.policy.InitialBackoff
+	if backoff <= 0 {
+		return 0
+	}
+	for i := 1; i < failures && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if s.policy.MaxBackoff > 0 && backoff > s.policy.MaxBackoff {
+		return s.policy.MaxBackoff
+	}
+	return backoff
+}
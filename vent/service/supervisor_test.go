@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pkgerrors "github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// A clock that only moves when waited on or advanced, recording the waits
+type testClock struct {
+	now   time.Time
+	waits []time.Duration
+}
+
+func newTestSupervisor(policy RestartPolicy, clock *testClock) *supervisor {
+	return &supervisor{
+		policy: policy,
+		now:    func() time.Time { return clock.now },
+		after: func(d time.Duration) <-chan time.Time {
+			clock.waits = append(clock.waits, d)
+			clock.now = clock.now.Add(d)
+			ch := make(chan time.Time, 1)
+			ch <- clock.now
+			return ch
+		},
+	}
+}
+
+func noopRestarting(err error, failures int, backoff time.Duration) {}
+
+func TestSuperviseBackoff(t *testing.T) {
+	clock := new(testClock)
+	s := newTestSupervisor(RestartPolicy{
+		InitialBackoff: time.Second,
+		MaxBackoff:     5 * time.Second,
+		Window:         time.Hour,
+	}, clock)
+
+	runs := 0
+	err := s.supervise(context.Background(), func() error {
+		runs++
+		if runs <= 5 {
+			return newErrStream(fmt.Errorf("transport is closing"), "Error receiving blocks")
+		}
+		return nil
+	}, noopRestarting)
+	require.NoError(t, err)
+	assert.Equal(t, 6, runs)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second},
+		clock.waits)
+}
+
+func TestSuperviseGivesUp(t *testing.T) {
+	clock := new(testClock)
+	s := newTestSupervisor(RestartPolicy{
+		InitialBackoff: time.Second,
+		MaxFailures:    3,
+		Window:         time.Minute,
+	}, clock)
+
+	cause := fmt.Errorf("connection refused")
+	runs := 0
+	err := s.supervise(context.Background(), func() error {
+		runs++
+		return newErrDBConnection(cause, "error upserting rows in database")
+	}, noopRestarting)
+	require.Error(t, err)
+	assert.Equal(t, 3, runs)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, clock.waits)
+
+	errExhausted, ok := err.(*ErrRestartsExhausted)
+	require.True(t, ok, "expected ErrRestartsExhausted but got %T: %v", err, err)
+	assert.Equal(t, 3, errExhausted.Failures)
+	assert.Equal(t, cause, pkgerrors.Cause(err))
+}
+
+func TestSuperviseForgetsOldFailures(t *testing.T) {
+	clock := new(testClock)
+	s := newTestSupervisor(RestartPolicy{
+		InitialBackoff: time.Second,
+		MaxFailures:    2,
+		Window:         time.Minute,
+	}, clock)
+
+	runs := 0
+	err := s.supervise(context.Background(), func() error {
+		runs++
+		switch runs {
+		case 2:
+			// A long healthy run before failing again
+			clock.now = clock.now.Add(time.Hour)
+		case 3:
+			return nil
+		}
+		return newErrStream(fmt.Errorf("transport is closing"), "Error receiving blocks")
+	}, noopRestarting)
+	require.NoError(t, err)
+	assert.Equal(t, 3, runs)
+	// The earlier failure no longer counts towards the backoff or the limit
+	assert.Equal(t, []time.Duration{time.Second, time.Second}, clock.waits)
+}
+
+func TestSuperviseFatalError(t *testing.T) {
+	for _, err := range []error{
+		fmt.Errorf("projection references events not found in ABI"),
+		newErrDecode(fmt.Errorf("bad bytes"), "Error building event data"),
+		newErrSchemaSync(fmt.Errorf("column type mismatch"), "Error trying to synchronize database"),
+	} {
+		clock := new(testClock)
+		s := newTestSupervisor(DefaultRestartPolicy, clock)
+		runs := 0
+		returned := s.supervise(context.Background(), func() error {
+			runs++
+			return err
+		}, noopRestarting)
+		assert.Equal(t, err, returned)
+		assert.Equal(t, 1, runs)
+		assert.Empty(t, clock.waits)
+	}
+}
+
+func TestSuperviseCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &supervisor{
+		policy: DefaultRestartPolicy,
+		now:    time.Now,
+		after: func(d time.Duration) <-chan time.Time {
+			cancel()
+			return nil
+		},
+	}
+	err := s.supervise(ctx, func() error {
+		return newErrStream(fmt.Errorf("transport is closing"), "Error receiving blocks")
+	}, noopRestarting)
+	assert.Equal(t, context.Canceled, err)
+}
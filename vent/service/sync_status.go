@@ -0,0 +1,57 @@
+package service
+
+// SyncStatusType identifies a transition in the consumer's progress towards the chain head
+type SyncStatusType string
+
+const (
+	// The consumer has connected and is about to request blocks after Height, the last height committed to the DB
+	SyncStarted SyncStatusType = "Started"
+	// The block at Height was committed and reached the chain head as it was when the consumer started - sent once
+	SyncCaughtUp SyncStatusType = "CaughtUp"
+	// A block at Height was committed after the consumer caught up
+	SyncLive SyncStatusType = "Live"
+)
+
+// SyncStatus is sent on the consumer's StatusChannel
+type SyncStatus struct {
+	Type SyncStatusType
+	// The committed height to which the status refers
+	Height uint64
+	// The chain head when the consumer started, against which catching up is measured
+	TargetHeight uint64
+}
+
+// Fraction of the TargetHeight reached at Height
+func (ss SyncStatus) Fraction() float64 {
+	if ss.TargetHeight == 0 || ss.Height >= ss.TargetHeight {
+		return 1
+	}
+	return float64(ss.Height) / float64(ss.TargetHeight)
+}
+
+// syncTracker determines when the committed height first crosses the target height
+type syncTracker struct {
+	targetHeight uint64
+	caughtUp     bool
+}
+
+func newSyncTracker(targetHeight uint64) *syncTracker {
+	return &syncTracker{targetHeight: targetHeight}
+}
+
+// Returns the status to report once height has been committed, if any
+func (st *syncTracker) committed(height uint64) (SyncStatus, bool) {
+	status := SyncStatus{
+		Type:         SyncLive,
+		Height:       height,
+		TargetHeight: st.targetHeight,
+	}
+	if !st.caughtUp {
+		if height < st.targetHeight {
+			return status, false
+		}
+		st.caughtUp = true
+		status.Type = SyncCaughtUp
+	}
+	return status, true
+}
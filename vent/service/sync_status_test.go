@@ -0,0 +1,38 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncTracker(t *testing.T) {
+	tracker := newSyncTracker(10)
+	var statuses []SyncStatus
+	// Empty blocks are not streamed so the head itself may be skipped
+	for _, height := range []uint64{3, 7, 9, 11, 12, 15} {
+		if status, ok := tracker.committed(height); ok {
+			statuses = append(statuses, status)
+		}
+	}
+	assert.Equal(t, []SyncStatus{
+		{Type: SyncCaughtUp, Height: 11, TargetHeight: 10},
+		{Type: SyncLive, Height: 12, TargetHeight: 10},
+		{Type: SyncLive, Height: 15, TargetHeight: 10},
+	}, statuses)
+
+	// Already at head
+	tracker = newSyncTracker(10)
+	status, ok := tracker.committed(10)
+	assert.True(t, ok)
+	assert.Equal(t, SyncCaughtUp, status.Type)
+	status, ok = tracker.committed(10)
+	assert.True(t, ok)
+	assert.Equal(t, SyncLive, status.Type)
+}
+
+func TestSyncStatusFraction(t *testing.T) {
+	assert.Equal(t, 0.72, SyncStatus{Height: 72, TargetHeight: 100}.Fraction())
+	assert.Equal(t, float64(1), SyncStatus{Height: 120, TargetHeight: 100}.Fraction())
+	assert.Equal(t, float64(1), SyncStatus{}.Fraction())
+}
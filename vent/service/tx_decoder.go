@@ -0,0 +1,327 @@
+package service
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/burrow/binary"
+	"github.com/hyperledger/burrow/execution/evm/abi"
+	"github.com/hyperledger/burrow/execution/exec"
+	"github.com/hyperledger/burrow/vent/config"
+	"github.com/hyperledger/burrow/vent/sqlsol"
+	"github.com/hyperledger/burrow/vent/types"
+)
+
+// txRow is a row decoded from a transaction along with the table it belongs to
+type txRow struct {
+	tableName string
+	row       types.EventDataRow
+}
+
+// rowSource identifies what a row is decoded from
+type rowSource struct {
+	height uint64
+	txHash binary.HexBytes
+	// The index of the event within its transaction, or callIndex for the call made by the transaction
+	index string
+	// The event, or for a call the transaction envelope
+	value interface{}
+}
+
+const callIndex = "call"
+
+func eventSource(event *exec.Event) rowSource {
+	return rowSource{
+		height: event.Header.Height,
+		txHash: event.Header.TxHash,
+		index:  fmt.Sprintf("%v", event.Header.Index),
+		value:  event,
+	}
+}
+
+func callSource(txe *exec.TxExecution) rowSource {
+	return rowSource{
+		height: txe.Height,
+		txHash: txe.TxHash,
+		index:  callIndex,
+		value:  txe.Envelope,
+	}
+}
+
+// decodeTxs decodes each of txes with decode, returning the rows of each transaction at the transaction's index. If
+// workers is greater than one up to that many transactions are decoded concurrently, otherwise they are decoded in
+// turn. The error (if any) of the earliest failing transaction is returned so the result does not depend on workers.
+func decodeTxs(txes []*exec.TxExecution, workers int,
+	decode func(txe *exec.TxExecution) ([]txRow, error)) ([][]txRow, error) {
+
+	rows := make([][]txRow, len(txes))
+	if workers <= 1 || len(txes) <= 1 {
+		for i, txe := range txes {
+			txRows, err := decode(txe)
+			if err != nil {
+				return nil, err
+			}
+			rows[i] = txRows
+		}
+		return rows, nil
+	}
+
+	if workers > len(txes) {
+		workers = len(txes)
+	}
+	errs := make([]error, len(txes))
+	indexCh := make(chan int)
+	wg := new(sync.WaitGroup)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexCh {
+				rows[i], errs[i] = decode(txes[i])
+			}
+		}()
+	}
+	for i := range txes {
+		indexCh <- i
+	}
+	close(indexCh)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// decodeLimiter caps the number of decodes in progress at once at its capacity
+type decodeLimiter chan struct{}
+
+// newDecodeLimiter returns a decodeLimiter allowing limit decodes at once, or runtime.GOMAXPROCS if limit is not
+// positive
+func newDecodeLimiter(limit int) decodeLimiter {
+	if limit <= 0 {
+		limit = runtime.GOMAXPROCS(0)
+	}
+	return make(decodeLimiter, limit)
+}
+
+// do calls decode once fewer than the limit of other decodes are in progress
+func (dl decodeLimiter) do(decode func()) {
+	dl <- struct{}{}
+	defer func() { <-dl }()
+	decode()
+}
+
+// limitDecodes returns the limiter of Config.MaxConcurrentDecodes shared by all decodes of the consumer, so the cap
+// holds across concurrently decoded transactions, blocks, and projections
+func (c *Consumer) limitDecodes() decodeLimiter {
+	c.decodesOnce.Do(func() {
+		c.decodes = newDecodeLimiter(c.Config.MaxConcurrentDecodes)
+	})
+	return c.decodes
+}
+
+// decodeTx returns the tx row (if configured) in the table named by tableNames.Tx and the rows of the call and each event of txe matched by the projection
+func (c *Consumer) decodeTx(projection *sqlsol.Projection, abiSpec *abi.AbiSpec, tableNames types.SQLTableNames,
+	blockTime time.Time, txe *exec.TxExecution) ([]txRow, error) {
+	c.Log.TraceMsg("Getting transaction", "TxHash", txe.TxHash, "num_events", len(txe.Events))
+
+	var rows []txRow
+	if c.Config.SpecOpt&sqlsol.Tx > 0 {
+		txRawData, err := buildTxData(txe)
+		if err != nil {
+			return nil, newErrDecode(err, "Error building tx raw data")
+		}
+		rows = append(rows, txRow{tableName: tableNames.Tx, row: txRawData})
+	}
+
+	// reverted transactions don't have to update event data tables
+	// so check that condition to filter them
+	if txe.Exception != nil {
+		return rows, nil
+	}
+
+	origin := txe.Origin
+	if origin == nil {
+		origin = &exec.Origin{
+			ChainID: c.Burrow.ChainID,
+			Height:  txe.Height,
+			Time:    blockTime,
+		}
+	}
+
+	// decode the call made by the transaction for the classes that project calls
+	callRows, err := c.decodeCall(projection, abiSpec, tableNames, origin, txe)
+	if err != nil {
+		return nil, err
+	}
+	rows = append(rows, callRows...)
+
+	// get events for a given transaction
+	for _, event := range txe.Events {
+
+		taggedEvent := event.Tagged()
+
+		// see which spec filter matches with the one in event data
+		for _, eventClass := range projection.EventSpec {
+			if eventClass.FunctionName != "" {
+				continue
+			}
+			qry, err := eventClass.Query()
+
+			if err != nil {
+				return nil, newErrDecode(err, "Error parsing query from filter string")
+			}
+
+			// there's a matching filter, add data to the rows
+			if qry.Matches(taggedEvent) {
+
+				c.Log.InfoMsg(fmt.Sprintf("Matched event header: %v", event.Header),
+					"filter", eventClass.Filter)
+
+				// unpack, decode & build event data
+				var eventData types.EventDataRow
+				c.limitDecodes().do(func() {
+					eventData, err = buildEventData(projection, eventClass, event, origin, abiSpec, c.timeFormat,
+						c.Log)
+				})
+				if err != nil {
+					row, err := c.handleRowError(eventClass, eventSource(event), tableNames, err)
+					if err != nil {
+						return nil, err
+					}
+					if row != nil {
+						rows = append(rows, *row)
+					}
+					continue
+				}
+
+				c.eventMatched(eventClass, event, eventData)
+				rows = append(rows, txRow{tableName: eventClass.TableName, row: eventData})
+			}
+		}
+	}
+	return rows, nil
+}
+
+// decodeCall returns the rows of each class of the projection that projects calls whose filter matches txe and whose
+// function txe calls
+func (c *Consumer) decodeCall(projection *sqlsol.Projection, abiSpec *abi.AbiSpec, tableNames types.SQLTableNames,
+	origin *exec.Origin, txe *exec.TxExecution) ([]txRow, error) {
+
+	input, ok := callInput(txe)
+	if !ok {
+		return nil, nil
+	}
+	taggedTx := txe.Tagged()
+
+	var rows []txRow
+	for _, eventClass := range projection.EventSpec {
+		if eventClass.FunctionName == "" {
+			continue
+		}
+		qry, err := eventClass.Query()
+		if err != nil {
+			return nil, newErrDecode(err, "Error parsing query from filter string")
+		}
+		if !qry.Matches(taggedTx) {
+			continue
+		}
+		calls, err := callsFunction(input, abiSpec, eventClass.FunctionName)
+		if err == nil && !calls {
+			continue
+		}
+		var callData types.EventDataRow
+		if err == nil {
+			c.Log.InfoMsg(fmt.Sprintf("Matched call of %s", eventClass.FunctionName), "tx_hash", txe.TxHash,
+				"filter", eventClass.Filter)
+			c.limitDecodes().do(func() {
+				callData, err = buildCallData(projection, eventClass, txe, input, origin, abiSpec, c.timeFormat,
+					c.Log)
+			})
+		}
+		if err != nil {
+			row, err := c.handleRowError(eventClass, callSource(txe), tableNames, err)
+			if err != nil {
+				return nil, err
+			}
+			if row != nil {
+				rows = append(rows, *row)
+			}
+			continue
+		}
+		rows = append(rows, txRow{tableName: eventClass.TableName, row: callData})
+	}
+	return rows, nil
+}
+
+// eventMatched calls OnEventMatched (if set) without letting it fail the block
+func (c *Consumer) eventMatched(eventClass *types.EventClass, event *exec.Event, row types.EventDataRow) {
+	if c.OnEventMatched == nil {
+		return
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			c.Log.InfoMsg("OnEventMatched panicked", "filter", eventClass.Filter, "panic", r)
+		}
+	}()
+	err := c.OnEventMatched(eventClass, event, row)
+	if err != nil {
+		c.Log.InfoMsg("Error from OnEventMatched", "filter", eventClass.Filter, "err", err)
+	}
+}
+
+// handleRowError applies the configured RowErrorPolicy to an event or call that could not be decoded because of
+// decodeErr, returning the error to fail the block with or the row (if any) to commit instead
+func (c *Consumer) handleRowError(eventClass *types.EventClass, source rowSource, tableNames types.SQLTableNames,
+	decodeErr error) (*txRow, error) {
+
+	switch c.Config.RowErrorPolicy {
+	case config.SkipRowPolicy:
+		c.Log.InfoMsg("Skipping row that could not be decoded", "filter", eventClass.Filter,
+			"height", source.height, "tx_hash", source.txHash, "index", source.index, "err", decodeErr)
+		return nil, nil
+	case config.DeadLetterRowPolicy:
+		c.Log.InfoMsg("Dead-lettering row that could not be decoded", "filter", eventClass.Filter,
+			"height", source.height, "tx_hash", source.txHash, "index", source.index, "err", decodeErr)
+		deadLetterData, err := buildDeadLetterData(eventClass, source, decodeErr)
+		if err != nil {
+			return nil, newErrDecode(err, "Error building dead-letter data")
+		}
+		return &txRow{tableName: tableNames.DeadLetter, row: deadLetterData}, nil
+	default:
+		return nil, newErrDecode(decodeErr, "Error building event data")
+	}
+}
+
+// withDeadLetterTable adds the dead-letter table to the projection's tables if events may be dead-lettered
+func (c *Consumer) withDeadLetterTable(projection *sqlsol.Projection, tableNames types.SQLTableNames) {
+	if c.Config.RowErrorPolicy != config.DeadLetterRowPolicy {
+		return
+	}
+	for name, table := range sqlsol.DeadLetterTables(tableNames.DeadLetter) {
+		if _, ok := projection.Tables[name]; !ok {
+			projection.Tables[name] = table
+		}
+	}
+}
+
+// The projection lazily memoises queries, field mappings, columns, and anonymous event specs on first use, which
+// would race between concurrent decoders, so populate them all up front
+func memoiseProjection(projection *sqlsol.Projection) {
+	for _, table := range projection.Tables {
+		table.GetColumn("")
+	}
+	for _, eventClass := range projection.EventSpec {
+		// Errors are reported when the query is used
+		_, _ = eventClass.Query()
+		eventClass.GetFieldMapping("")
+		if eventClass.AnonymousEvent != nil {
+			_, _ = eventClass.AnonymousEvent.EventSpec()
+		}
+	}
+}
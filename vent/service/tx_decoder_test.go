@@ -0,0 +1,445 @@
+package service
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/burrow/binary"
+	"github.com/hyperledger/burrow/crypto"
+	"github.com/hyperledger/burrow/execution/errors"
+	"github.com/hyperledger/burrow/execution/evm/abi"
+	"github.com/hyperledger/burrow/execution/exec"
+	"github.com/hyperledger/burrow/logging"
+	"github.com/hyperledger/burrow/rpc"
+	"github.com/hyperledger/burrow/txs"
+	"github.com/hyperledger/burrow/txs/payload"
+	"github.com/hyperledger/burrow/vent/config"
+	"github.com/hyperledger/burrow/vent/sqlsol"
+	"github.com/hyperledger/burrow/vent/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeTxs(t *testing.T) {
+	txes := make([]*exec.TxExecution, 20)
+	for i := range txes {
+		txes[i] = &exec.TxExecution{TxHeader: &exec.TxHeader{Index: uint64(i)}}
+	}
+	decode := func(txe *exec.TxExecution) ([]txRow, error) {
+		if txe.Index%7 == 6 {
+			return nil, fmt.Errorf("bad tx %d", txe.Index)
+		}
+		return []txRow{{tableName: fmt.Sprintf("table%d", txe.Index)}}, nil
+	}
+
+	for _, workers := range []int{0, 1, 4, 100} {
+		rows, err := decodeTxs(txes[:6], workers, decode)
+		require.NoError(t, err)
+		require.Len(t, rows, 6)
+		for i, txRows := range rows {
+			assert.Equal(t, fmt.Sprintf("table%d", i), txRows[0].tableName)
+		}
+
+		// The earliest error is returned however many workers there are
+		_, err = decodeTxs(txes, workers, decode)
+		require.Error(t, err)
+		assert.Equal(t, "bad tx 6", err.Error())
+	}
+}
+
+func TestBlockConsumerDecodeWorkers(t *testing.T) {
+	projection, abiSpec, block := newSyntheticBlock(t, 50, 4)
+
+	consumeBlock := func(workers int) types.EventData {
+		eventCh := make(chan types.EventData, 1)
+		consumer := newDecodeConsumer(workers)
+		err := consumer.makeBlockConsumer(projection, abiSpec, eventCh)(block)
+		require.NoError(t, err)
+		return <-eventCh
+	}
+
+	serial := consumeBlock(0)
+	require.Len(t, serial.Tables["Transfers"], 200)
+	for _, workers := range []int{2, 8} {
+		assert.Equal(t, serial, consumeBlock(workers))
+	}
+}
+
+func TestDecodeLimiter(t *testing.T) {
+	const limit = 3
+	limiter := newDecodeLimiter(limit)
+	var lock sync.Mutex
+	var current, peak int
+	wg := new(sync.WaitGroup)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.do(func() {
+				lock.Lock()
+				current++
+				if current > peak {
+					peak = current
+				}
+				lock.Unlock()
+				time.Sleep(time.Millisecond)
+				lock.Lock()
+				current--
+				lock.Unlock()
+			})
+		}()
+	}
+	wg.Wait()
+	assert.True(t, peak <= limit, "%d decodes at once exceeds the limit of %d", peak, limit)
+	assert.Equal(t, 0, current)
+
+	assert.Equal(t, runtime.GOMAXPROCS(0), cap(newDecodeLimiter(0)))
+}
+
+func TestBlockConsumerMaxConcurrentDecodes(t *testing.T) {
+	projection, abiSpec, block := newSyntheticBlock(t, 50, 4)
+
+	consumeBlock := func(consumer *Consumer) types.EventData {
+		eventCh := make(chan types.EventData, 1)
+		err := consumer.makeBlockConsumer(projection, abiSpec, eventCh)(block)
+		require.NoError(t, err)
+		return <-eventCh
+	}
+
+	serial := consumeBlock(newDecodeConsumer(0))
+	// More workers than may decode at once still decode every event
+	consumer := newDecodeConsumer(8)
+	consumer.Config.MaxConcurrentDecodes = 1
+	assert.Equal(t, serial, consumeBlock(consumer))
+	assert.Equal(t, 1, cap(consumer.limitDecodes()))
+	assert.Len(t, consumer.limitDecodes(), 0)
+}
+
+func TestBlockConsumerHeartbeat(t *testing.T) {
+	// Blocks whose transaction has no events so has no rows
+	projection, abiSpec, block := newSyntheticBlock(t, 1, 0)
+	consumeBlocks := func(consumer *Consumer, heights ...uint64) []types.EventData {
+		eventCh := make(chan types.EventData, len(heights))
+		blockConsumer := consumer.makeBlockConsumer(projection, abiSpec, eventCh)
+		for _, height := range heights {
+			block.Height = height
+			require.NoError(t, blockConsumer(block))
+		}
+		close(eventCh)
+		var blocks []types.EventData
+		for blk := range eventCh {
+			blocks = append(blocks, blk)
+		}
+		return blocks
+	}
+
+	consumer := newDecodeConsumer(0)
+	consumer.Config.SpecOpt = sqlsol.None
+	assert.Len(t, consumeBlocks(consumer, 1, 2, 3), 0)
+
+	consumer.Config.HeartbeatInterval = time.Hour
+	assert.Len(t, consumeBlocks(consumer, 1, 2, 3), 0)
+
+	consumer.Config.HeartbeatInterval = time.Nanosecond
+	sink := &heightSink{}
+	consumer.Sink = sink
+	consumer.Checkpointer = NewMemoryCheckpointer()
+	eventsCh := make(chan types.EventData, 1)
+	consumer.EventsChannel = eventsCh
+	blocks := consumeBlocks(consumer, 4, 5, 6)
+	require.Len(t, blocks, 3)
+	for _, blk := range blocks {
+		assert.Len(t, blk.Tables, 0)
+		require.NoError(t, consumer.commitBlock(projection, blk, true))
+	}
+
+	// The committed and checkpointed heights advance through the empty blocks without publishing them
+	height, err := sink.LastBlockHeight("test-chain")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), height)
+	checkpoint, _, err := consumer.Checkpointer.Load()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), checkpoint)
+	assert.Len(t, eventsCh, 0)
+}
+
+func TestDecodeBlockContainedTxs(t *testing.T) {
+	projection, abiSpec, block := newSyntheticBlock(t, 3, 2)
+	// A proposal containing the other transactions
+	proposal := block.TxExecutions[0]
+	proposal.TxExecutions = block.TxExecutions[1:]
+	block.TxExecutions = block.TxExecutions[:1]
+
+	consumer := newDecodeConsumer(0)
+	blockData, err := consumer.decodeBlock(projection, abiSpec, consumer.Config.SQLTableNames, block)
+	require.NoError(t, err)
+	assert.Len(t, blockData.Data.Tables["Transfers"], 6)
+	assert.Len(t, blockData.Data.Tables[consumer.Config.SQLTableNames.Tx], 3)
+
+	// Nothing a failed proposal contains took effect
+	proposal.Exception = errors.NewException(errors.ErrorCodeExecutionReverted, "proposal failed")
+	blockData, err = consumer.decodeBlock(projection, abiSpec, consumer.Config.SQLTableNames, block)
+	require.NoError(t, err)
+	assert.Len(t, blockData.Data.Tables["Transfers"], 0)
+	assert.Len(t, blockData.Data.Tables[consumer.Config.SQLTableNames.Tx], 1)
+}
+
+// Records the height of the last block it was given
+type heightSink struct {
+	height uint64
+}
+
+func (hs *heightSink) SetBlock(chainID string, eventTables types.EventTables, eventData types.EventData) error {
+	hs.height = eventData.BlockHeight
+	return nil
+}
+
+func (hs *heightSink) LastBlockHeight(chainID string) (uint64, error) {
+	return hs.height, nil
+}
+
+func (hs *heightSink) Close() {
+}
+
+func TestBlockConsumerLogger(t *testing.T) {
+	projection, abiSpec, block := newSyntheticBlock(t, 2, 1)
+	logger := &capturingLogger{}
+	consumer := newDecodeConsumer(0)
+	consumer.Log = logger
+	eventCh := make(chan types.EventData, 1)
+	require.NoError(t, consumer.makeBlockConsumer(projection, abiSpec, eventCh)(block))
+	require.Len(t, eventCh, 1)
+
+	assert.Contains(t, logger.trace, "Block received")
+	assert.NotEmpty(t, logger.info)
+	assert.Contains(t, logger.keyvals, []interface{}{"height", block.Height, "num_txs", 2})
+}
+
+// Records the messages logged at each level and the key/values logged with them
+type capturingLogger struct {
+	sync.Mutex
+	info    []string
+	trace   []string
+	keyvals [][]interface{}
+}
+
+func (cl *capturingLogger) InfoMsg(message string, keyvals ...interface{}) error {
+	cl.Lock()
+	defer cl.Unlock()
+	cl.info = append(cl.info, message)
+	cl.keyvals = append(cl.keyvals, keyvals)
+	return nil
+}
+
+func (cl *capturingLogger) TraceMsg(message string, keyvals ...interface{}) error {
+	cl.Lock()
+	defer cl.Unlock()
+	cl.trace = append(cl.trace, message)
+	cl.keyvals = append(cl.keyvals, keyvals)
+	return nil
+}
+
+func TestDecodeTxRowErrorPolicy(t *testing.T) {
+	projection, abiSpec, block := newSyntheticBlock(t, 1, 4)
+	txe := block.TxExecutions[0]
+	// Events with a signature missing from the ABI cannot be decoded
+	for _, i := range []int{1, 3} {
+		txe.Events[i].Log.Topics[0] = binary.LeftPadWord256([]byte("unknown"))
+	}
+
+	decode := func(policy config.RowErrorPolicy) ([]txRow, error) {
+		consumer := newDecodeConsumer(0)
+		consumer.Config.SpecOpt = sqlsol.None
+		consumer.Config.RowErrorPolicy = policy
+		return consumer.decodeTx(projection, abiSpec, types.DefaultSQLTableNames, block.Header.GetTime(), txe)
+	}
+
+	for _, policy := range []config.RowErrorPolicy{"", config.FailBlockPolicy} {
+		_, err := decode(policy)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "abi spec not found")
+	}
+
+	rows, err := decode(config.SkipRowPolicy)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	for _, row := range rows {
+		assert.Equal(t, "Transfers", row.tableName)
+	}
+
+	rows, err = decode(config.DeadLetterRowPolicy)
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	columns := types.DefaultSQLColumnNames
+	for i, row := range rows {
+		if i%2 == 0 {
+			assert.Equal(t, "Transfers", row.tableName)
+			continue
+		}
+		assert.Equal(t, types.DefaultSQLTableNames.DeadLetter, row.tableName)
+		assert.Equal(t, fmt.Sprintf("%d", i), row.row.RowData[columns.EventIndex])
+		assert.Equal(t, "Transfers", row.row.RowData[columns.TableName])
+		assert.Contains(t, row.row.RowData[columns.Error], "abi spec not found")
+		assert.Contains(t, row.row.RowData[columns.Event], `"Log"`)
+	}
+}
+
+func TestOnEventMatched(t *testing.T) {
+	projection, abiSpec, block := newSyntheticBlock(t, 5, 3)
+	for _, workers := range []int{0, 4} {
+		consumer := newDecodeConsumer(workers)
+		var mtx sync.Mutex
+		matched := make(map[string]int)
+		consumer.OnEventMatched = func(eventClass *types.EventClass, event *exec.Event, row types.EventDataRow) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			matched[eventClass.TableName]++
+			// Neither errors nor panics fail the block
+			switch fmt.Sprint(row.RowData["id"]) {
+			case "1":
+				return fmt.Errorf("webhook failed")
+			case "2":
+				panic("webhook panicked")
+			}
+			return nil
+		}
+		eventCh := make(chan types.EventData, 1)
+		err := consumer.makeBlockConsumer(projection, abiSpec, eventCh)(block)
+		require.NoError(t, err)
+		assert.Len(t, (<-eventCh).Tables["Transfers"], 15)
+		assert.Equal(t, map[string]int{"Transfers": 15}, matched)
+	}
+}
+
+func TestDecodeTxCall(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"function","name":"transfer","inputs":[
+		{"name":"to","type":"address"},{"name":"amount","type":"uint256"},{"name":"memo","type":"string"}],
+		"outputs":[]},{"type":"function","name":"other","inputs":[],"outputs":[]}]`))
+	require.NoError(t, err)
+	contract := crypto.Address{1, 2, 3}
+	recipient := crypto.Address{4, 5, 6}
+
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+		TableName:    "Transfers",
+		Filter:       fmt.Sprintf("Address = '%v'", contract),
+		FunctionName: "transfer",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "to", ColumnName: "recipient", Type: "address", Primary: true},
+			{Field: "amount", ColumnName: "amount", Type: "uint256"},
+			{Field: "memo", ColumnName: "memo", Type: "string"},
+		},
+	}})
+	require.NoError(t, err)
+
+	newCallTx := func(address crypto.Address, data []byte) *exec.TxExecution {
+		return &exec.TxExecution{
+			TxHeader: &exec.TxHeader{TxHash: binary.HexBytes{1}, Height: 1},
+			Envelope: txs.Enclose("test-chain", &payload.CallTx{
+				Input:   &payload.TxInput{Address: crypto.Address{9}},
+				Address: &address,
+				Data:    data,
+			}),
+		}
+	}
+	decode := func(txe *exec.TxExecution) ([]txRow, error) {
+		consumer := newDecodeConsumer(0)
+		consumer.Config.SpecOpt = sqlsol.None
+		return consumer.decodeTx(projection, abiSpec, types.DefaultSQLTableNames, time.Now(), txe)
+	}
+
+	input, _, err := abiSpec.Pack("transfer", recipient.String(), 42, "rent")
+	require.NoError(t, err)
+	rows, err := decode(newCallTx(contract, input))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "Transfers", rows[0].tableName)
+	assert.Equal(t, types.ActionUpsert, rows[0].row.Action)
+	assert.Equal(t, recipient.String(), rows[0].row.RowData["recipient"])
+	assert.Equal(t, "42", rows[0].row.RowData["amount"])
+	assert.Equal(t, "rent", rows[0].row.RowData["memo"])
+	assert.Equal(t, "transfer", rows[0].row.RowData[types.DefaultSQLColumnNames.EventName])
+
+	// Calls of other functions or to other contracts are not matched
+	other, _, err := abiSpec.Pack("other")
+	require.NoError(t, err)
+	rows, err = decode(newCallTx(contract, other))
+	require.NoError(t, err)
+	assert.Len(t, rows, 0)
+	rows, err = decode(newCallTx(crypto.Address{7}, input))
+	require.NoError(t, err)
+	assert.Len(t, rows, 0)
+
+	// A call of the function that cannot be unpacked is a row error
+	_, err = decode(newCallTx(contract, input[:abi.FunctionIDSize+10]))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "could not unpack arguments")
+}
+
+// Decoding is CPU bound so the speedup over a single worker is limited by GOMAXPROCS, compare with -cpu 1,4
+func BenchmarkBlockConsumerDecodeWorkers(b *testing.B) {
+	projection, abiSpec, block := newSyntheticBlock(b, 2000, 5)
+	for _, workers := range []int{1, 4} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			eventCh := make(chan types.EventData, 1)
+			blockConsumer := newDecodeConsumer(workers).makeBlockConsumer(projection, abiSpec, eventCh)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := blockConsumer(block)
+				if err != nil {
+					b.Fatal(err)
+				}
+				<-eventCh
+			}
+		})
+	}
+}
+
+func newDecodeConsumer(workers int) *Consumer {
+	cfg := config.DefaultVentConfig()
+	cfg.SpecOpt = sqlsol.Tx
+	cfg.DecodeWorkers = workers
+	consumer := NewConsumer(cfg, logging.NewNoopLogger(), nil)
+	consumer.Burrow = &rpc.ResultStatus{ChainID: "test-chain"}
+	return consumer
+}
+
+// Returns a block of numTxs transactions each with numEvents matching Transfer events
+func newSyntheticBlock(t require.TestingT, numTxs, numEvents int) (*sqlsol.Projection, *abi.AbiSpec, *exec.BlockExecution) {
+	abiSpec, err := abi.ReadAbiSpec([]byte(`[{"type":"event","name":"Transfer","anonymous":false,"inputs":[
+		{"name":"id","type":"uint256","indexed":false},{"name":"memo","type":"string","indexed":false}]}]`))
+	require.NoError(t, err)
+	eventSpec := abiSpec.Events["Transfer"]
+
+	projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+		TableName: "Transfers",
+		Filter:    "EventType = 'LogEvent'",
+		FieldMappings: []*types.EventFieldMapping{
+			{Field: "id", ColumnName: "id", Type: "uint256", Primary: true},
+			{Field: "memo", ColumnName: "memo", Type: "string"},
+		},
+	}})
+	require.NoError(t, err)
+
+	block := &exec.BlockExecution{Height: 1}
+	for i := 0; i < numTxs; i++ {
+		txe := &exec.TxExecution{
+			TxHeader: &exec.TxHeader{TxHash: binary.HexBytes{byte(i >> 8), byte(i)}, Height: 1, Index: uint64(i)},
+		}
+		for j := 0; j < numEvents; j++ {
+			data, err := abi.Pack(eventSpec.Inputs, i*numEvents+j, fmt.Sprintf("transfer %d of tx %d", j, i))
+			require.NoError(t, err)
+			txe.Events = append(txe.Events, &exec.Event{
+				Header: &exec.Header{EventType: exec.TypeLog, Height: 1, Index: uint64(j)},
+				Log: &exec.LogEvent{
+					Data:   data,
+					Topics: []binary.Word256{binary.LeftPadWord256(eventSpec.EventID.Bytes())},
+				},
+			})
+		}
+		block.TxExecutions = append(block.TxExecutions, txe)
+	}
+	return projection, abiSpec, block
+}
@@ -25,8 +25,14 @@ type DBAdapter interface {
 	FindTableQuery() string
 	// TableDefinitionQuery builds a SELECT query to get a table structure from the Dictionary table
 	TableDefinitionQuery() string
-	// AlterColumnQuery builds an ALTER COLUMN query to alter a table structure (only adding columns is supported)
-	AlterColumnQuery(tableName, columnName string, sqlColumnType types.SQLColumnType, length, order int) (string, string)
+	// AlterColumnQuery builds an ALTER COLUMN query to alter a table structure (only adding columns is supported), a
+	// NOT NULL column is given its type's zero value as default for the existing rows
+	AlterColumnQuery(tableName, columnName string, sqlColumnType types.SQLColumnType, length, order int,
+		notNull bool) (string, string)
+	// DropColumnsQuery builds a query to drop the named columns from a table, leaving the columns of table, and a
+	// query to remove them from the dictionary. The first query may comprise several statements and should be run in
+	// a transaction.
+	DropColumnsQuery(table *types.SQLTable, columnNames []string) (string, string)
 	// SelectRowQuery builds a SELECT query to get row values
 	SelectRowQuery(tableName, fields, indexValue string) string
 	// SelectLogQuery builds a SELECT query to get all tables involved in a given block transaction
@@ -43,6 +49,13 @@ type DBAdapter interface {
 	CleanDBQueries() types.SQLCleanDBQuery
 	// DropTableQuery builds a DROP TABLE query to delete a table
 	DropTableQuery(tableName string) string
+	// MaintenanceQuery builds a query to refresh the planner statistics of a table, and if vacuum is set to reclaim
+	// the space of its dead rows. It must be run outside a transaction.
+	MaintenanceQuery(tableName string, vacuum bool) string
+	// VacuumDatabaseQuery builds a query to reclaim the space of dead rows in the whole database, run once per
+	// maintenance pass when vacuuming, or returns an empty string if MaintenanceQuery vacuums each table instead.
+	// It must be run outside a transaction.
+	VacuumDatabaseQuery() string
 	// Get the schema qualified name of the given table
 	SchemaName(tableName string) string
 }
@@ -56,6 +69,32 @@ type DBNotifyTriggerAdapter interface {
 	CreateTriggerQuery(triggerName, tableName, functionName string) string
 }
 
+// notNullDefinition returns the constraint of a NOT NULL column that is not part of the primary key
+func notNullDefinition(sqlColumnType types.SQLColumnType) string {
+	return " NOT NULL DEFAULT " + sqlColumnType.ZeroLiteral()
+}
+
+// dropDictionaryColumnsQuery builds a DELETE query removing the named columns of a table from the dictionary
+func dropDictionaryColumnsQuery(dictionary string, columns types.SQLColumnNames, tableName string,
+	columnNames []string) string {
+	quoted := make([]string, len(columnNames))
+	for i, columnName := range columnNames {
+		quoted[i] = "'" + columnName + "'"
+	}
+	return Cleanf("DELETE FROM %s WHERE %s = '%s' AND %s IN (%s);", dictionary, columns.TableName, tableName,
+		columns.ColumnName, strings.Join(quoted, ", "))
+}
+
+// accumulatesHeight returns whether row accumulates into a row of table that records the height of the last block
+// accumulated into it, in which case the accumulation only applies if the row is from a later block
+func accumulatesHeight(table *types.SQLTable, row types.EventDataRow, columns types.SQLColumnNames) bool {
+	if row.Action != types.ActionAccumulate || table.GetColumn(columns.Height) == nil {
+		return false
+	}
+	height, ok := row.RowData[columns.Height]
+	return ok && height != nil
+}
+
 // clean queries from tabs, spaces  and returns
 func clean(parameter string) string {
 	replacer := strings.NewReplacer("\n", " ", "\t", "")
@@ -2,11 +2,11 @@ package adapters
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 
 	"github.com/lib/pq"
 
-	"github.com/hyperledger/burrow/logging"
 	"github.com/hyperledger/burrow/vent/types"
 	"github.com/jmoiron/sqlx"
 	"github.com/prometheus/common/log"
@@ -29,13 +29,17 @@ var pgDataTypes = map[types.SQLColumnType]string{
 type PostgresAdapter struct {
 	Schema string
 	types.SQLNames
-	Log *logging.Logger
+	Log types.Logger
+	// If true the schema must already exist, otherwise it is created if missing
+	SkipCreateSchema bool
+	// If true the search_path of every connection is set to the schema so that unqualified names resolve within it
+	SetSearchPath bool
 }
 
 var _ DBAdapter = &PostgresAdapter{}
 
 // NewPostgresAdapter constructs a new db adapter
-func NewPostgresAdapter(schema string, sqlNames types.SQLNames, log *logging.Logger) *PostgresAdapter {
+func NewPostgresAdapter(schema string, sqlNames types.SQLNames, log types.Logger) *PostgresAdapter {
 	return &PostgresAdapter{
 		Schema:   schema,
 		SQLNames: sqlNames,
@@ -44,6 +48,14 @@ func NewPostgresAdapter(schema string, sqlNames types.SQLNames, log *logging.Log
 }
 
 func (pa *PostgresAdapter) Open(dbURL string) (*sqlx.DB, error) {
+	if pa.SetSearchPath && pa.Schema != "" {
+		var err error
+		dbURL, err = withSearchPath(dbURL, pa.Schema)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	db, err := sqlx.Open("postgres", dbURL)
 	if err != nil {
 		log.Info("msg", "Error creating database connection", "err", err)
@@ -56,7 +68,7 @@ func (pa *PostgresAdapter) Open(dbURL string) (*sqlx.DB, error) {
 	}
 
 	if pa.Schema != "" {
-		err = ensureSchema(db, pa.Schema, pa.Log)
+		err = ensureSchema(db, pa.Schema, !pa.SkipCreateSchema, pa.Log)
 		if err != nil {
 			return nil, err
 		}
@@ -67,30 +79,60 @@ func (pa *PostgresAdapter) Open(dbURL string) (*sqlx.DB, error) {
 	return db, nil
 }
 
-func ensureSchema(db sqlx.Ext, schema string, log *logging.Logger) error {
+// withSearchPath adds the search_path run-time parameter to a connection string given either as a URL or as
+// key/value pairs, lib/pq passes it to the server when each connection starts so it applies to the whole pool
+func withSearchPath(dbURL, schema string) (string, error) {
+	if strings.HasPrefix(dbURL, "postgres://") || strings.HasPrefix(dbURL, "postgresql://") {
+		u, err := url.Parse(dbURL)
+		if err != nil {
+			return "", fmt.Errorf("could not parse database URL to set search_path: %v", err)
+		}
+		query := u.Query()
+		query.Set("search_path", schema)
+		u.RawQuery = query.Encode()
+		return u.String(), nil
+	}
+	value := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(schema)
+	return strings.TrimSpace(fmt.Sprintf("%s search_path='%s'", dbURL, value)), nil
+}
+
+// ensureSchema creates the schema if it does not exist and create is set, otherwise it fails if it does not exist
+func ensureSchema(db sqlx.Ext, schema string, create bool, log types.Logger) error {
 	query := Cleanf(`SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_namespace n WHERE n.nspname = '%s');`, schema)
 	log.InfoMsg("FIND SCHEMA", "query", query)
 
 	var found bool
-	if err := db.QueryRowx(query).Scan(&found); err == nil {
-		if !found {
-			log.InfoMsg("Schema not found")
-		}
-		log.InfoMsg("Creating schema")
-
-		query = Cleanf("CREATE SCHEMA %s;", schema)
-		log.InfoMsg("CREATE SCHEMA", "query", query)
-
-		if _, err = db.Exec(query); err != nil {
-			if errorEquals(err, types.SQLErrorTypeDuplicatedSchema) {
-				log.InfoMsg("Duplicated schema")
-				return nil
-			}
-		}
-	} else {
+	if err := db.QueryRowx(query).Scan(&found); err != nil {
 		log.InfoMsg("Error searching schema", "err", err)
 		return err
 	}
+	if found {
+		return nil
+	}
+	log.InfoMsg("Schema not found")
+	if !create {
+		return fmt.Errorf("schema %s does not exist and schema creation is disabled, it must be created before "+
+			"starting vent", schema)
+	}
+
+	log.InfoMsg("Creating schema")
+	query = Cleanf("CREATE SCHEMA IF NOT EXISTS %s;", schema)
+	log.InfoMsg("CREATE SCHEMA", "query", query)
+
+	if _, err := db.Exec(query); err != nil {
+		switch {
+		case errorEquals(err, types.SQLErrorTypeDuplicatedSchema):
+			// Created concurrently
+			log.InfoMsg("Duplicated schema")
+			return nil
+		case errorEquals(err, types.SQLErrorTypeInsufficientPrivilege):
+			return fmt.Errorf("could not create schema %s because the database role lacks the CREATE privilege on "+
+				"the database, either grant it or have a privileged role create the schema (for example with "+
+				"CREATE SCHEMA %s AUTHORIZATION <vent role>): %v", schema, schema, err)
+		default:
+			return fmt.Errorf("could not create schema %s: %v", schema, err)
+		}
+	}
 	return nil
 }
 
@@ -131,6 +173,10 @@ func (pa *PostgresAdapter) CreateTableQuery(tableName string, columns []*types.S
 			columnsDef += Cleanf("(%v)", column.Length)
 		}
 
+		if column.NotNull && !column.Primary {
+			columnsDef += notNullDefinition(column.Type)
+		}
+
 		if column.Primary {
 			pKey = 1
 			columnsDef += " NOT NULL"
@@ -196,11 +242,15 @@ func (pa *PostgresAdapter) TableDefinitionQuery() string {
 }
 
 // AlterColumnQuery returns a query for adding a new column to a table
-func (pa *PostgresAdapter) AlterColumnQuery(tableName, columnName string, sqlColumnType types.SQLColumnType, length, order int) (string, string) {
+func (pa *PostgresAdapter) AlterColumnQuery(tableName, columnName string, sqlColumnType types.SQLColumnType, length, order int,
+	notNull bool) (string, string) {
 	sqlType, _ := pa.TypeMapping(sqlColumnType)
 	if length > 0 {
 		sqlType = Cleanf("%s(%d)", sqlType, length)
 	}
+	if notNull {
+		sqlType += notNullDefinition(sqlColumnType)
+	}
 
 	query := Cleanf("ALTER TABLE %s.%s ADD COLUMN %s %s;",
 		pa.Schema,
@@ -223,6 +273,18 @@ func (pa *PostgresAdapter) AlterColumnQuery(tableName, columnName string, sqlCol
 	return query, dictionaryQuery
 }
 
+// DropColumnsQuery returns a query that drops the named columns from a table and a query that removes them from the
+// dictionary
+func (pa *PostgresAdapter) DropColumnsQuery(table *types.SQLTable, columnNames []string) (string, string) {
+	drops := make([]string, len(columnNames))
+	for i, columnName := range columnNames {
+		drops[i] = "DROP COLUMN " + pa.SecureName(columnName)
+	}
+	query := Cleanf("ALTER TABLE %s.%s %s;", pa.Schema, pa.SecureName(table.Name), strings.Join(drops, ", "))
+
+	return query, dropDictionaryColumnsQuery(pa.Schema+"."+pa.Tables.Dictionary, pa.Columns, table.Name, columnNames)
+}
+
 // SelectRowQuery returns a query for selecting row values
 func (pa *PostgresAdapter) SelectRowQuery(tableName, fields, indexValue string) string {
 	return Cleanf("SELECT %s FROM %s.%s WHERE %s = '%s';",
@@ -281,6 +343,11 @@ func errorEquals(err error, sqlErrorType types.SQLErrorType) bool {
 			return err.Code == "42703"
 		case types.SQLErrorTypeInvalidType:
 			return err.Code == "42704"
+		case types.SQLErrorTypeInsufficientPrivilege:
+			return err.Code == "42501"
+		case types.SQLErrorTypeConstraintViolation:
+			// Any integrity constraint violation, such as of a unique index or NOT NULL
+			return err.Code.Class() == "23"
 		}
 	}
 
@@ -333,11 +400,24 @@ func (pa *PostgresAdapter) UpsertQuery(table *types.SQLTable, row types.EventDat
 				if updValues != "" {
 					updValues += ", "
 				}
-				updValues += secureColumn + " = $" + Cleanf("%d", i)
+				if row.Action == types.ActionAccumulate && column.Aggregate != "" {
+					aggregate, err := pa.accumulateExpression(table, column)
+					if err != nil {
+						return types.UpsertDeleteQuery{}, nil, err
+					}
+					updValues += secureColumn + " = " + aggregate
+				} else {
+					updValues += secureColumn + " = $" + Cleanf("%d", i)
+				}
 			}
 		} else if column.Primary {
 			// column NOT found (is null) and is PK
 			return types.UpsertDeleteQuery{}, nil, fmt.Errorf("error null primary key for column %s", secureColumn)
+		} else if column.NotNull {
+			// column NOT found and is NOT NULL so store the zero value, which is not added to the update list
+			value := column.Type.ZeroValue()
+			pointers = append(pointers, value)
+			values += fmt.Sprint(value)
 		} else {
 			// column NOT found (is null) and is NOT PK
 			//pointers = append(pointers, &null)
@@ -351,12 +431,36 @@ func (pa *PostgresAdapter) UpsertQuery(table *types.SQLTable, row types.EventDat
 
 	if updValues != "" {
 		query += Cleanf("ON CONFLICT ON CONSTRAINT %s_pkey DO UPDATE SET %s", table.Name, updValues)
+		if accumulatesHeight(table, row, pa.Columns) {
+			// A block committed again must not be accumulated again
+			existing := pa.SecureName(table.Name) + "." + pa.SecureName(pa.Columns.Height)
+			query += Cleanf(" WHERE %s IS NULL OR %s < EXCLUDED.%s", existing, existing,
+				pa.SecureName(pa.Columns.Height))
+		}
 	} else {
 		query += Cleanf("ON CONFLICT ON CONSTRAINT %s_pkey DO NOTHING", table.Name)
 	}
-	query += ";"
+	// xmax is only zero for a freshly inserted row version
+	query += " RETURNING (xmax = 0) AS inserted;"
 
-	return types.UpsertDeleteQuery{Query: query, Values: values, Pointers: pointers}, txHash, nil
+	return types.UpsertDeleteQuery{Query: query, Values: values, Pointers: pointers, ReturnsInserted: true}, txHash, nil
+}
+
+// accumulateExpression returns the expression with which an accumulated row combines the value of an aggregate column
+// with that of the existing row of table
+func (pa *PostgresAdapter) accumulateExpression(table *types.SQLTable, column *types.SQLTableColumn) (string, error) {
+	existing := pa.SecureName(table.Name) + "." + pa.SecureName(column.Name)
+	accumulated := "EXCLUDED." + pa.SecureName(column.Name)
+	switch column.Aggregate {
+	case types.AggregateCount, types.AggregateSum:
+		return Cleanf("COALESCE(%s, 0) + COALESCE(%s, 0)", existing, accumulated), nil
+	case types.AggregateMin:
+		return Cleanf("LEAST(%s, %s)", existing, accumulated), nil
+	case types.AggregateMax:
+		return Cleanf("GREATEST(%s, %s)", existing, accumulated), nil
+	default:
+		return "", fmt.Errorf("unknown aggregate function '%s' of column %s", column.Aggregate, column.Name)
+	}
 }
 
 func (pa *PostgresAdapter) DeleteQuery(table *types.SQLTable, row types.EventDataRow) (types.UpsertDeleteQuery, error) {
@@ -481,6 +585,18 @@ func (pa *PostgresAdapter) DropTableQuery(tableName string) string {
 	return Cleanf(`DROP TABLE IF EXISTS %s CASCADE;`, pa.SchemaName(tableName))
 }
 
+func (pa *PostgresAdapter) MaintenanceQuery(tableName string, vacuum bool) string {
+	if vacuum {
+		return Cleanf(`VACUUM ANALYZE %s;`, pa.SchemaName(tableName))
+	}
+	return Cleanf(`ANALYZE %s;`, pa.SchemaName(tableName))
+}
+
+func (pa *PostgresAdapter) VacuumDatabaseQuery() string {
+	// Tables are vacuumed one at a time by MaintenanceQuery
+	return ""
+}
+
 func (pa *PostgresAdapter) CreateNotifyFunctionQuery(function, channel string, columns ...string) string {
 	return Cleanf(`CREATE OR REPLACE FUNCTION %s() RETURNS trigger AS
 		$trigger$
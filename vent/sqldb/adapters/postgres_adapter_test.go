@@ -4,9 +4,20 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestPostgresAdapter_CreateTriggerQuery(t *testing.T) {
 	assert.Equal(t, `'Address', NEW."Address", 'Name', NEW."Name", 'Index', NEW."Index"`,
 		jsonBuildObjectArgs("NEW", []string{"Address", "Name", "Index"}))
 }
+
+func TestWithSearchPath(t *testing.T) {
+	dbURL, err := withSearchPath("postgres://postgres@localhost:5432/postgres?sslmode=disable", "vent")
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://postgres@localhost:5432/postgres?search_path=vent&sslmode=disable", dbURL)
+
+	dbURL, err = withSearchPath("host=localhost user=postgres", `it's`)
+	require.NoError(t, err)
+	assert.Equal(t, `host=localhost user=postgres search_path='it\'s'`, dbURL)
+}
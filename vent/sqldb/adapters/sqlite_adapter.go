@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/hyperledger/burrow/logging"
 	"github.com/hyperledger/burrow/vent/types"
 	"github.com/jmoiron/sqlx"
 	sqlite3 "github.com/mattn/go-sqlite3"
@@ -29,13 +28,13 @@ var sqliteDataTypes = map[types.SQLColumnType]string{
 // SQLiteAdapter implements DBAdapter for SQLiteDB
 type SQLiteAdapter struct {
 	types.SQLNames
-	Log *logging.Logger
+	Log types.Logger
 }
 
 var _ DBAdapter = &SQLiteAdapter{}
 
 // NewSQLiteAdapter constructs a new db adapter
-func NewSQLiteAdapter(sqlNames types.SQLNames, log *logging.Logger) *SQLiteAdapter {
+func NewSQLiteAdapter(sqlNames types.SQLNames, log types.Logger) *SQLiteAdapter {
 	return &SQLiteAdapter{
 		SQLNames: sqlNames,
 		Log:      log,
@@ -95,6 +94,10 @@ func (sla *SQLiteAdapter) CreateTableQuery(tableName string, columns []*types.SQ
 			columnsDef += Cleanf("(%v)", column.Length)
 		}
 
+		if column.NotNull && !column.Primary {
+			columnsDef += notNullDefinition(column.Type)
+		}
+
 		if column.Primary {
 			pKey = 1
 			columnsDef += " NOT NULL"
@@ -164,11 +167,15 @@ func (sla *SQLiteAdapter) TableDefinitionQuery() string {
 }
 
 // AlterColumnQuery returns a query for adding a new column to a table
-func (sla *SQLiteAdapter) AlterColumnQuery(tableName, columnName string, sqlColumnType types.SQLColumnType, length, order int) (string, string) {
+func (sla *SQLiteAdapter) AlterColumnQuery(tableName, columnName string, sqlColumnType types.SQLColumnType, length, order int,
+	notNull bool) (string, string) {
 	sqlType, _ := sla.TypeMapping(sqlColumnType)
 	if length > 0 {
 		sqlType = Cleanf("%s(%d)", sqlType, length)
 	}
+	if notNull {
+		sqlType += notNullDefinition(sqlColumnType)
+	}
 
 	query := Cleanf("ALTER TABLE %s ADD COLUMN %s %s;",
 		sla.SecureName(tableName),
@@ -190,6 +197,25 @@ func (sla *SQLiteAdapter) AlterColumnQuery(tableName, columnName string, sqlColu
 	return query, dictionaryQuery
 }
 
+// DropColumnsQuery returns a query that rebuilds a table without the named columns, since SQLite before 3.35 cannot
+// drop columns, and a query that removes them from the dictionary
+func (sla *SQLiteAdapter) DropColumnsQuery(table *types.SQLTable, columnNames []string) (string, string) {
+	rebuildTable := table.Name + "_vent_rebuild"
+	createQuery, _ := sla.CreateTableQuery(rebuildTable, table.Columns)
+	columns := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		columns[i] = sla.SecureName(column.Name)
+	}
+	columnList := strings.Join(columns, ", ")
+
+	query := createQuery + Cleanf(" INSERT INTO %s (%s) SELECT %s FROM %s; DROP TABLE %s; ALTER TABLE %s RENAME TO %s;",
+		sla.SecureName(rebuildTable), columnList, columnList, sla.SecureName(table.Name),
+		sla.SecureName(table.Name),
+		sla.SecureName(rebuildTable), sla.SecureName(table.Name))
+
+	return query, dropDictionaryColumnsQuery(sla.Tables.Dictionary, sla.Columns, table.Name, columnNames)
+}
+
 // SelectRowQuery returns a query for selecting row values
 func (sla *SQLiteAdapter) SelectRowQuery(tableName, fields, indexValue string) string {
 	return Cleanf("SELECT %s FROM %s WHERE %s = '%s';", fields, sla.SecureName(tableName), sla.Columns.Height, indexValue)
@@ -239,7 +265,9 @@ func (sla *SQLiteAdapter) ErrorEquals(err error, sqlErrorType types.SQLErrorType
 			return err.Code == 1 && strings.Contains(errDescription, "no such table")
 		case types.SQLErrorTypeUndefinedColumn:
 			return err.Code == 1 && strings.Contains(errDescription, "table") && strings.Contains(errDescription, "has no column named")
-		case types.SQLErrorTypeInvalidType:
+		case types.SQLErrorTypeConstraintViolation:
+			return err.Code == sqlite3.ErrConstraint
+		case types.SQLErrorTypeInvalidType, types.SQLErrorTypeInsufficientPrivilege:
 			// NOT SUPPORTED
 			return false
 		}
@@ -293,11 +321,24 @@ func (sla *SQLiteAdapter) UpsertQuery(table *types.SQLTable, row types.EventData
 				if updValues != "" {
 					updValues += ", "
 				}
-				updValues += secureColumn + " = $" + Cleanf("%d", i)
+				if row.Action == types.ActionAccumulate && column.Aggregate != "" {
+					aggregate, err := sla.accumulateExpression(column)
+					if err != nil {
+						return types.UpsertDeleteQuery{}, nil, err
+					}
+					updValues += secureColumn + " = " + aggregate
+				} else {
+					updValues += secureColumn + " = $" + Cleanf("%d", i)
+				}
 			}
 		} else if column.Primary {
 			// column NOT found (is null) and is PK
 			return types.UpsertDeleteQuery{}, nil, fmt.Errorf("error null primary key for column %s", secureColumn)
+		} else if column.NotNull {
+			// column NOT found and is NOT NULL so store the zero value, which is not added to the update list
+			value := column.Type.ZeroValue()
+			pointers = append(pointers, value)
+			values += fmt.Sprint(value)
 		} else {
 			// column NOT found (is null) and is NOT PK
 			pointers = append(pointers, nil)
@@ -319,6 +360,11 @@ func (sla *SQLiteAdapter) UpsertQuery(table *types.SQLTable, row types.EventData
 	if pkColumns != "" {
 		if updValues != "" {
 			query += Cleanf("ON CONFLICT (%s) DO UPDATE SET %s", pkColumns, updValues)
+			if accumulatesHeight(table, row, sla.Columns) {
+				// A block committed again must not be accumulated again
+				existing := sla.SecureName(sla.Columns.Height)
+				query += Cleanf(" WHERE %s IS NULL OR %s < excluded.%s", existing, existing, existing)
+			}
 		} else {
 			query += Cleanf("ON CONFLICT (%s) DO NOTHING", pkColumns)
 		}
@@ -328,6 +374,23 @@ func (sla *SQLiteAdapter) UpsertQuery(table *types.SQLTable, row types.EventData
 	return types.UpsertDeleteQuery{Query: query, Values: values, Pointers: pointers}, txHash, nil
 }
 
+// accumulateExpression returns the expression with which an accumulated row combines the value of an aggregate column
+// with that of the existing row, which bare column names refer to in an upsert
+func (sla *SQLiteAdapter) accumulateExpression(column *types.SQLTableColumn) (string, error) {
+	existing := sla.SecureName(column.Name)
+	accumulated := "excluded." + existing
+	switch column.Aggregate {
+	case types.AggregateCount, types.AggregateSum:
+		return Cleanf("coalesce(%s, 0) + coalesce(%s, 0)", existing, accumulated), nil
+	case types.AggregateMin, types.AggregateMax:
+		// The scalar min and max are null if any argument is
+		return Cleanf("%s(coalesce(%s, %s), coalesce(%s, %s))", column.Aggregate, existing, accumulated,
+			accumulated, existing), nil
+	default:
+		return "", fmt.Errorf("unknown aggregate function '%s' of column %s", column.Aggregate, column.Name)
+	}
+}
+
 func (sla *SQLiteAdapter) DeleteQuery(table *types.SQLTable, row types.EventDataRow) (types.UpsertDeleteQuery, error) {
 
 	pointers := make([]interface{}, 0)
@@ -453,6 +516,15 @@ func (sla *SQLiteAdapter) DropTableQuery(tableName string) string {
 	return Cleanf(`DROP TABLE IF EXISTS %s;`, sla.SecureName(tableName))
 }
 
+func (sla *SQLiteAdapter) MaintenanceQuery(tableName string, vacuum bool) string {
+	// SQLite can only vacuum the whole database, see VacuumDatabaseQuery
+	return Cleanf(`ANALYZE %s;`, sla.SecureName(tableName))
+}
+
+func (sla *SQLiteAdapter) VacuumDatabaseQuery() string {
+	return `VACUUM;`
+}
+
 func (sla *SQLiteAdapter) SchemaName(tableName string) string {
 	return secureName(tableName)
 }
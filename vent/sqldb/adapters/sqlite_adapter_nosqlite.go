@@ -6,19 +6,18 @@ package adapters
 import (
 	"fmt"
 
-	"github.com/hyperledger/burrow/logging"
 	"github.com/hyperledger/burrow/vent/types"
 	"github.com/jmoiron/sqlx"
 )
 
 // This is a no-op version of SQLiteAdapter
 type SQLiteAdapter struct {
-	Log *logging.Logger
+	Log types.Logger
 }
 
 var _ DBAdapter = &SQLiteAdapter{}
 
-func NewSQLiteAdapter(names types.SQLNames, log *logging.Logger) *SQLiteAdapter {
+func NewSQLiteAdapter(names types.SQLNames, log types.Logger) *SQLiteAdapter {
 	panic(fmt.Errorf("vent has been built without sqlite support. To use the sqlite DBAdapter build with the 'sqlite' build tag enabled"))
 }
 
@@ -50,7 +49,12 @@ func (*SQLiteAdapter) TableDefinitionQuery() string {
 	panic("implement me")
 }
 
-func (*SQLiteAdapter) AlterColumnQuery(tableName, columnName string, sqlColumnType types.SQLColumnType, length, order int) (string, string) {
+func (*SQLiteAdapter) AlterColumnQuery(tableName, columnName string, sqlColumnType types.SQLColumnType, length, order int,
+	notNull bool) (string, string) {
+	panic("implement me")
+}
+
+func (*SQLiteAdapter) DropColumnsQuery(table *types.SQLTable, columnNames []string) (string, string) {
 	panic("implement me")
 }
 
@@ -86,6 +90,14 @@ func (*SQLiteAdapter) DropTableQuery(tableName string) string {
 	panic("implement me")
 }
 
+func (*SQLiteAdapter) MaintenanceQuery(tableName string, vacuum bool) string {
+	panic("implement me")
+}
+
+func (*SQLiteAdapter) VacuumDatabaseQuery() string {
+	panic("implement me")
+}
+
 func (*SQLiteAdapter) SchemaName(tableName string) string {
 	panic("implement me")
 }
@@ -0,0 +1,278 @@
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hyperledger/burrow/vent/types"
+	"github.com/xitongsys/parquet-go/source"
+	parquetwriter "github.com/xitongsys/parquet-go/writer"
+)
+
+// ExportFormat is the file format ExportTable writes
+type ExportFormat string
+
+const (
+	// ExportCSV writes a header of the column names followed by a record per row
+	ExportCSV ExportFormat = "csv"
+	// ExportParquet writes a Parquet file with an optional column per table column and a row group per
+	// exportFlushRows rows
+	ExportParquet ExportFormat = "parquet"
+)
+
+// Rows are flushed to the writer this often so a slow or large export does not buffer the whole table, for Parquet
+// this is the size of each row group
+const exportFlushRows = 1000
+
+// ExportTable streams every row of tableName to w in the given format, ordered by the primary key of the table.
+// For CSV values are written as text: numbers (including big integers, which would lose precision as floats) in
+// decimal, byte arrays as upper-case hex, timestamps in RFC 3339 and NULL as an empty field. For Parquet integers and
+// booleans are written as such, byte arrays as raw bytes, timestamps as microseconds since the epoch, NULL as a
+// missing value, and everything else (including big integers) as UTF8 text as for CSV. Rows are read from the
+// database and written one at a time, or a row group at a time for Parquet, so the table is never held in memory.
+func (db *SQLDB) ExportTable(ctx context.Context, tableName string, w io.Writer, format ExportFormat) error {
+	if format != ExportCSV && format != ExportParquet {
+		return fmt.Errorf("unsupported export format '%s', the supported formats are '%s' and '%s'", format,
+			ExportCSV, ExportParquet)
+	}
+
+	table, err := db.getTableDef(tableName)
+	if err != nil {
+		return err
+	}
+	if len(table.Columns) == 0 {
+		return fmt.Errorf("table %s does not contain any fields", tableName)
+	}
+
+	names := make([]string, len(table.Columns))
+	fields := make([]string, len(table.Columns))
+	var primary []string
+	for i, column := range table.Columns {
+		names[i] = column.Name
+		fields[i] = db.DBAdapter.SecureName(column.Name)
+		if column.Primary {
+			primary = append(primary, fields[i])
+		}
+	}
+
+	// language=SQL
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(fields, ", "), db.DBAdapter.SchemaName(table.Name))
+	if len(primary) > 0 {
+		query += " ORDER BY " + strings.Join(primary, ", ")
+	}
+	query += ";"
+
+	db.Log.InfoMsg("Exporting table", "query", query, "format", format)
+	rows, err := db.DB.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("could not query table %s for export: %v", tableName, err)
+	}
+	defer rows.Close()
+
+	if format == ExportParquet {
+		return exportParquet(rows, table, w)
+	}
+	return exportCSV(rows, table, names, w)
+}
+
+// exportCSV writes the rows of table to w as CSV with a header of the column names
+func exportCSV(rows *sql.Rows, table *types.SQLTable, names []string, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write(names)
+	if err != nil {
+		return err
+	}
+
+	values, pointers := exportScanValues(len(table.Columns))
+	record := make([]string, len(table.Columns))
+
+	for n := 1; rows.Next(); n++ {
+		if err = rows.Scan(pointers...); err != nil {
+			return fmt.Errorf("could not scan row %d of table %s for export: %v", n, table.Name, err)
+		}
+		for i, column := range table.Columns {
+			record[i] = exportValue(column.Type, values[i])
+		}
+		if err = writer.Write(record); err != nil {
+			return err
+		}
+		if n%exportFlushRows == 0 {
+			writer.Flush()
+			if err = writer.Error(); err != nil {
+				return err
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("could not read table %s for export: %v", table.Name, err)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportParquet writes the rows of table to w as a Parquet file
+func exportParquet(rows *sql.Rows, table *types.SQLTable, w io.Writer) error {
+	schema := make([]string, len(table.Columns))
+	for i, column := range table.Columns {
+		schema[i] = parquetSchema(column)
+	}
+	pw, err := parquetwriter.NewCSVWriter(schema, parquetWriterFile{w}, 1)
+	if err != nil {
+		return err
+	}
+
+	values, pointers := exportScanValues(len(table.Columns))
+
+	for n := 1; rows.Next(); n++ {
+		if err = rows.Scan(pointers...); err != nil {
+			return fmt.Errorf("could not scan row %d of table %s for export: %v", n, table.Name, err)
+		}
+		record := make([]interface{}, len(table.Columns))
+		for i, column := range table.Columns {
+			record[i], err = parquetValue(column.Type, values[i])
+			if err != nil {
+				return fmt.Errorf("could not export column %s of row %d of table %s: %v", column.Name, n,
+					table.Name, err)
+			}
+		}
+		if err = pw.Write(record); err != nil {
+			return err
+		}
+		if n%exportFlushRows == 0 {
+			// Write out the rows so far as a row group
+			if err = pw.Flush(true); err != nil {
+				return err
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("could not read table %s for export: %v", table.Name, err)
+	}
+
+	return pw.WriteStop()
+}
+
+// exportScanValues returns values for the columns of a row and pointers to them to scan into
+func exportScanValues(n int) ([]interface{}, []interface{}) {
+	values := make([]interface{}, n)
+	pointers := make([]interface{}, n)
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	return values, pointers
+}
+
+// exportValue formats a value scanned from a column of the given type as text
+func exportValue(columnType types.SQLColumnType, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		// Drivers return most types other than integers as bytes, only binary columns need encoding
+		if columnType == types.SQLColumnTypeByteA {
+			return strings.ToUpper(hex.EncodeToString(v))
+		}
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parquetSchema returns the Parquet schema metadata of the optional column that column is exported to
+func parquetSchema(column *types.SQLTableColumn) string {
+	var parquetType string
+	switch column.Type {
+	case types.SQLColumnTypeBool:
+		parquetType = "BOOLEAN"
+	case types.SQLColumnTypeInt, types.SQLColumnTypeSerial, types.SQLColumnTypeBigInt:
+		parquetType = "INT64"
+	case types.SQLColumnTypeByteA:
+		parquetType = "BYTE_ARRAY"
+	case types.SQLColumnTypeTimeStamp:
+		parquetType = "TIMESTAMP_MICROS"
+	default:
+		// Including numeric columns so that big integers keep their precision
+		parquetType = "UTF8"
+	}
+	return fmt.Sprintf("name=%s, type=%s, repetitiontype=OPTIONAL", column.Name, parquetType)
+}
+
+// parquetValue converts a value scanned from a column of the given type to the value of its Parquet column
+func parquetValue(columnType types.SQLColumnType, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch columnType {
+	case types.SQLColumnTypeBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case int64:
+			return v != 0, nil
+		}
+		return strconv.ParseBool(exportValue(columnType, value))
+	case types.SQLColumnTypeInt, types.SQLColumnTypeSerial, types.SQLColumnTypeBigInt:
+		if v, ok := value.(int64); ok {
+			return v, nil
+		}
+		return strconv.ParseInt(exportValue(columnType, value), 10, 64)
+	case types.SQLColumnTypeByteA:
+		if v, ok := value.([]byte); ok {
+			return string(v), nil
+		}
+		return exportValue(columnType, value), nil
+	case types.SQLColumnTypeTimeStamp:
+		if v, ok := value.(time.Time); ok {
+			return v.UnixNano() / int64(time.Microsecond), nil
+		}
+		return nil, fmt.Errorf("expected a timestamp but got %v", value)
+	default:
+		return exportValue(columnType, value), nil
+	}
+}
+
+// parquetWriterFile adapts an io.Writer to the file the Parquet writer expects, which it only writes to in order
+type parquetWriterFile struct {
+	io.Writer
+}
+
+var _ source.ParquetFile = parquetWriterFile{}
+
+func (parquetWriterFile) Seek(offset int64, whence int) (int64, error) {
+	return 0, errors.New("cannot seek in a Parquet export")
+}
+
+func (parquetWriterFile) Read(p []byte) (int, error) {
+	return 0, errors.New("cannot read from a Parquet export")
+}
+
+func (parquetWriterFile) Close() error {
+	return nil
+}
+
+func (parquetWriterFile) Open(name string) (source.ParquetFile, error) {
+	return nil, errors.New("cannot open a file from a Parquet export")
+}
+
+func (parquetWriterFile) Create(name string) (source.ParquetFile, error) {
+	return nil, errors.New("cannot create a file from a Parquet export")
+}
@@ -0,0 +1,51 @@
+package sqldb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/burrow/vent/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportValue(t *testing.T) {
+	assert.Equal(t, "", exportValue(types.SQLColumnTypeText, nil))
+	assert.Equal(t, "text", exportValue(types.SQLColumnTypeText, []byte("text")))
+	assert.Equal(t, "00FF10", exportValue(types.SQLColumnTypeByteA, []byte{0x00, 0xff, 0x10}))
+	// Big integers come back from the drivers as text and are kept exact
+	assert.Equal(t, "115792089237316195423570985008687907853269984665640564039457584007913129639935",
+		exportValue(types.SQLColumnTypeNumeric,
+			[]byte("115792089237316195423570985008687907853269984665640564039457584007913129639935")))
+	assert.Equal(t, "-42", exportValue(types.SQLColumnTypeInt, int64(-42)))
+	assert.Equal(t, "0.5", exportValue(types.SQLColumnTypeNumeric, 0.5))
+	assert.Equal(t, "true", exportValue(types.SQLColumnTypeBool, true))
+	assert.Equal(t, "2006-01-02T15:04:05Z",
+		exportValue(types.SQLColumnTypeTimeStamp, time.Date(2006, 1, 2, 15, 4, 5, 0, time.UTC)))
+}
+
+func TestParquetValue(t *testing.T) {
+	value := func(columnType types.SQLColumnType, v interface{}) interface{} {
+		pv, err := parquetValue(columnType, v)
+		require.NoError(t, err)
+		return pv
+	}
+	assert.Nil(t, value(types.SQLColumnTypeInt, nil))
+	assert.Equal(t, int64(-42), value(types.SQLColumnTypeInt, int64(-42)))
+	assert.Equal(t, int64(42), value(types.SQLColumnTypeBigInt, []byte("42")))
+	assert.Equal(t, true, value(types.SQLColumnTypeBool, int64(1)))
+	assert.Equal(t, "\x00\xff", value(types.SQLColumnTypeByteA, []byte{0x00, 0xff}))
+	assert.Equal(t, "115792089237316195423570985008687907853269984665640564039457584007913129639935",
+		value(types.SQLColumnTypeNumeric,
+			[]byte("115792089237316195423570985008687907853269984665640564039457584007913129639935")))
+	assert.Equal(t, int64(1136214245000001),
+		value(types.SQLColumnTypeTimeStamp, time.Date(2006, 1, 2, 15, 4, 5, 1000, time.UTC)))
+
+	_, err := parquetValue(types.SQLColumnTypeTimeStamp, "yesterday")
+	require.Error(t, err)
+	_, err = parquetValue(types.SQLColumnTypeInt, "forty two")
+	require.Error(t, err)
+
+	assert.Equal(t, "name=amount, type=UTF8, repetitiontype=OPTIONAL",
+		parquetSchema(&types.SQLTableColumn{Name: "amount", Type: types.SQLColumnTypeNumeric}))
+}
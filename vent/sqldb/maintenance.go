@@ -0,0 +1,128 @@
+package sqldb
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/burrow/vent/types"
+)
+
+// StartMaintenance runs Maintain on eventTables every interval in the background until the returned function is
+// called, which waits for any maintenance in progress to finish
+func (db *SQLDB) StartMaintenance(eventTables types.EventTables, interval time.Duration, vacuum bool) (stop func()) {
+	ticker := time.NewTicker(interval)
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		runMaintenance(ticker.C, stopCh, func() {
+			err := db.Maintain(eventTables, vacuum)
+			if err != nil {
+				db.Log.InfoMsg("Error maintaining tables", "err", err)
+			}
+		})
+	}()
+	return func() {
+		ticker.Stop()
+		close(stopCh)
+		<-doneCh
+	}
+}
+
+// Maintain refreshes the planner statistics of each of eventTables (ANALYZE) and if vacuum is set also reclaims the
+// space of dead rows (VACUUM), which keeps queries on high-churn tables fast. Maintenance runs outside any transaction
+// and each table (or the database, if the adapter can only vacuum the whole database) is maintained while no block is
+// being committed. If a block is being committed by SetBlock when the next table is due the rest of the tables are
+// skipped and tried again on the next call. Commits that begin while a table is being maintained wait for it.
+func (db *SQLDB) Maintain(eventTables types.EventTables, vacuum bool) error {
+	tableNames := make([]string, 0, len(eventTables))
+	for _, table := range eventTables {
+		tableNames = append(tableNames, table.Name)
+	}
+	sort.Strings(tableNames)
+
+	var queries []string
+	if vacuum {
+		if query := db.DBAdapter.VacuumDatabaseQuery(); query != "" {
+			queries = append(queries, query)
+		}
+	}
+	for _, tableName := range tableNames {
+		queries = append(queries, db.DBAdapter.MaintenanceQuery(tableName, vacuum))
+	}
+	for _, query := range queries {
+		if !db.commits.tryMaintain() {
+			db.Log.InfoMsg("Skipping table maintenance while a block is being committed")
+			return nil
+		}
+		db.Log.InfoMsg("Maintaining table", "query", query)
+		_, err := db.DB.Exec(query)
+		db.commits.endMaintain()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// commitGate lets table maintenance run only while no block is being committed, without making commits wait for
+// maintenance that has not yet started
+type commitGate struct {
+	sync.Mutex
+	// Number of commits in progress
+	commits int
+	// Closed when the maintenance in progress is done, nil if there is none
+	maintained chan struct{}
+}
+
+// begin waits for any maintenance in progress then counts a commit in until end is called
+func (cg *commitGate) begin() {
+	cg.Lock()
+	for cg.maintained != nil {
+		maintained := cg.maintained
+		cg.Unlock()
+		<-maintained
+		cg.Lock()
+	}
+	cg.commits++
+	cg.Unlock()
+}
+
+func (cg *commitGate) end() {
+	cg.Lock()
+	cg.commits--
+	cg.Unlock()
+}
+
+// tryMaintain returns false without waiting if a block is being committed, otherwise it returns true and commits wait
+// until endMaintain is called
+func (cg *commitGate) tryMaintain() bool {
+	cg.Lock()
+	defer cg.Unlock()
+	if cg.commits > 0 || cg.maintained != nil {
+		return false
+	}
+	cg.maintained = make(chan struct{})
+	return true
+}
+
+func (cg *commitGate) endMaintain() {
+	cg.Lock()
+	close(cg.maintained)
+	cg.maintained = nil
+	cg.Unlock()
+}
+
+// runMaintenance calls maintain for each tick until stopCh is closed, ticks that arrive while maintain is running
+// are dropped by the ticker
+func runMaintenance(ticks <-chan time.Time, stopCh <-chan struct{}, maintain func()) {
+	for {
+		select {
+		case <-ticks:
+			maintain()
+		case <-stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package sqldb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/burrow/logging"
+	"github.com/hyperledger/burrow/vent/sqldb/adapters"
+	"github.com/hyperledger/burrow/vent/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunMaintenance(t *testing.T) {
+	ticks := make(chan time.Time)
+	stopCh := make(chan struct{})
+	doneCh := make(chan struct{})
+	maintained := make(chan struct{})
+	go func() {
+		defer close(doneCh)
+		runMaintenance(ticks, stopCh, func() {
+			maintained <- struct{}{}
+		})
+	}()
+
+	for i := 0; i < 3; i++ {
+		ticks <- time.Now()
+		select {
+		case <-maintained:
+		case <-time.After(time.Second):
+			t.Fatalf("maintenance did not run on tick %d", i)
+		}
+	}
+
+	close(stopCh)
+	select {
+	case <-doneCh:
+	case <-time.After(time.Second):
+		t.Fatal("maintenance did not stop")
+	}
+	select {
+	case <-maintained:
+		t.Fatal("maintenance ran without a tick")
+	default:
+	}
+}
+
+func TestMaintainSkipsDuringCommit(t *testing.T) {
+	// Without a connection any attempt to maintain the table would panic
+	log := logging.NewNoopLogger()
+	db := &SQLDB{Log: log, DBAdapter: adapters.NewPostgresAdapter("test", types.DefaultSQLNames, log)}
+	db.commits.begin()
+	err := db.Maintain(types.EventTables{"test": {Name: "test"}}, true)
+	require.NoError(t, err)
+}
+
+func TestCommitGate(t *testing.T) {
+	cg := new(commitGate)
+	cg.begin()
+	cg.begin()
+	assert.False(t, cg.tryMaintain())
+	cg.end()
+	assert.False(t, cg.tryMaintain())
+	cg.end()
+	require.True(t, cg.tryMaintain())
+	assert.False(t, cg.tryMaintain())
+
+	// Commits wait for the maintenance in progress
+	begun := make(chan struct{})
+	go func() {
+		cg.begin()
+		close(begun)
+	}()
+	select {
+	case <-begun:
+		t.Fatal("commit began during maintenance")
+	case <-time.After(50 * time.Millisecond):
+	}
+	cg.endMaintain()
+	select {
+	case <-begun:
+	case <-time.After(time.Second):
+		t.Fatal("commit did not begin after maintenance")
+	}
+	assert.False(t, cg.tryMaintain())
+	cg.end()
+	assert.True(t, cg.tryMaintain())
+}
+
+// CommitForTest counts a block as being committed until the returned function is called
+func (db *SQLDB) CommitForTest() (done func()) {
+	db.commits.begin()
+	return db.commits.end
+}
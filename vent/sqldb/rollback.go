@@ -0,0 +1,72 @@
+package sqldb
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hyperledger/burrow/vent/types"
+)
+
+// RollbackToHeight undoes the blocks committed above height, for example after a reorg of the chain below the last
+// height processed. In a single transaction it deletes the rows of each of eventTables and the log entries whose
+// height is above height and resets the chain's checkpoint to height, so the consumer will resume from the block after
+// it. It does nothing if the checkpoint is not above height.
+//
+// Event tables keep only the latest version of each row, so a row that was last written above height is removed
+// along with any earlier version of it. Projections that update rows across blocks should be rebuilt instead.
+func (db *SQLDB) RollbackToHeight(chainID string, eventTables types.EventTables, height uint64) error {
+	const errHeader = "RollbackToHeight()"
+	db.commits.begin()
+	defer db.commits.end()
+
+	lastHeight, err := db.LastBlockHeight(chainID)
+	if err != nil {
+		return fmt.Errorf("%s: %v", errHeader, err)
+	}
+	if lastHeight <= height {
+		db.Log.InfoMsg("Nothing to roll back", "height", height, "last_height", lastHeight)
+		return nil
+	}
+
+	tableNames := make([]string, 0, len(eventTables))
+	for _, table := range eventTables {
+		if table.GetColumn(db.Columns.Height) == nil {
+			return fmt.Errorf("%s: table %s has no %s column so cannot be rolled back", errHeader, table.Name,
+				db.Columns.Height)
+		}
+		tableNames = append(tableNames, table.Name)
+	}
+	sort.Strings(tableNames)
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		db.Log.InfoMsg("Error beginning transaction", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	// Heights are stored as text so compare them as numbers
+	// language=SQL
+	where := fmt.Sprintf("CAST(%s AS NUMERIC) > ?", db.DBAdapter.SecureName(db.Columns.Height))
+	for _, tableName := range tableNames {
+		query := tx.Rebind(fmt.Sprintf("DELETE FROM %s WHERE %s;", db.DBAdapter.SchemaName(tableName), where))
+		db.Log.InfoMsg("Rolling back table", "query", query, "height", height)
+		if _, err = tx.Exec(query, height); err != nil {
+			return fmt.Errorf("%s: could not roll back table %s: %v", errHeader, tableName, err)
+		}
+	}
+
+	// language=SQL
+	query := tx.Rebind(fmt.Sprintf("DELETE FROM %s WHERE %s = ? AND %s;", db.DBAdapter.SchemaName(db.Tables.Log),
+		db.DBAdapter.SecureName(db.Columns.ChainID), where))
+	db.Log.InfoMsg("Rolling back log", "query", query, "height", height)
+	if _, err = tx.Exec(query, chainID, height); err != nil {
+		return fmt.Errorf("%s: could not roll back log: %v", errHeader, err)
+	}
+
+	if err = db.SetBlockHeight(tx, chainID, height); err != nil {
+		return fmt.Errorf("%s: could not reset block height: %v", errHeader, err)
+	}
+
+	return tx.Commit()
+}
@@ -4,15 +4,22 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
-	"github.com/hyperledger/burrow/logging"
 	"github.com/hyperledger/burrow/vent/sqldb/adapters"
 	"github.com/hyperledger/burrow/vent/types"
 	"github.com/jmoiron/sqlx"
 )
 
+const (
+	// How many times SynchronizeDB will attempt to synchronize the schema before giving up on a transient error
+	SynchronizeAttempts = 5
+	// Initial wait between SynchronizeDB attempts, doubled after each failure
+	SynchronizeBackoff = 100 * time.Millisecond
+)
+
 // SQLDB implements the access to a sql database
 type SQLDB struct {
 	DB *sqlx.DB
@@ -20,7 +27,15 @@ type SQLDB struct {
 	Schema  string
 	Queries Queries
 	types.SQLNames
-	Log *logging.Logger
+	Log types.Logger
+	// What SynchronizeDB does with columns that are not in the projection
+	OrphanedColumns types.OrphanedColumnsPolicy
+	// What SetBlock does with rows that violate a constraint of the database
+	RowConflicts types.RowConflictsPolicy
+	// If greater than zero the most rows SetBlock commits in one transaction
+	MaxRowsPerCommit int
+	// Keeps table maintenance and block commits from overlapping
+	commits commitGate
 }
 
 // NewSQLDB delegates work to a specific database adapter implementation,
@@ -30,11 +45,22 @@ func NewSQLDB(connection types.SQLConnection) (*SQLDB, error) {
 		Schema:   connection.DBSchema,
 		SQLNames: types.DefaultSQLNames,
 		Log:      connection.Log,
+
+		OrphanedColumns: connection.OrphanedColumns,
+		RowConflicts:    connection.RowConflicts,
+
+		MaxRowsPerCommit: connection.MaxRowsPerCommit,
+	}
+	if connection.TableNames != (types.SQLTableNames{}) {
+		db.Tables = connection.TableNames
 	}
 
 	switch connection.DBAdapter {
 	case types.PostgresDB:
-		db.DBAdapter = adapters.NewPostgresAdapter(safe(connection.DBSchema), db.SQLNames, connection.Log)
+		pa := adapters.NewPostgresAdapter(safe(connection.DBSchema), db.SQLNames, connection.Log)
+		pa.SkipCreateSchema = connection.SkipCreateSchema
+		pa.SetSearchPath = connection.SetSearchPath
+		db.DBAdapter = pa
 
 	case types.SQLiteDB:
 		db.DBAdapter = adapters.NewSQLiteAdapter(db.SQLNames, connection.Log)
@@ -254,6 +280,16 @@ func (db *SQLDB) Close() {
 	}
 }
 
+// RawDB returns the underlying connection pool so that embedders can run their own statements (for example to create
+// a materialized view after sync) without opening a second pool to the same database. The pool remains owned
+// by SQLDB so it must not be closed (use Close) and its pool limits should not be lowered. Statements run against
+// it are not part of vent's per-block transactions, so writing to vent's projection or system tables may be
+// overwritten or break restores, and holding locks on those tables will stall vent (in SQLite any open write
+// transaction blocks vent entirely).
+func (db *SQLDB) RawDB() *sql.DB {
+	return db.DB.DB
+}
+
 // Ping database
 func (db *SQLDB) Ping() error {
 	if err := db.DB.Ping(); err != nil {
@@ -264,11 +300,36 @@ func (db *SQLDB) Ping() error {
 	return nil
 }
 
-// SynchronizeDB synchronize db tables structures from given tables specifications
+// SynchronizeDB synchronize db tables structures from given tables specifications. Each table (or added column) is
+// created along with its dictionary and log entries in a single transaction and the dictionary is consulted before
+// each DDL statement, so a synchronization interrupted part way through can be re-run and will only perform the
+// remaining steps. Transient failures are retried up to SynchronizeAttempts times with exponential backoff. Columns of
+// existing tables that are not in the projection are handled according to the OrphanedColumns policy.
 func (db *SQLDB) SynchronizeDB(chainID string, eventTables types.EventTables) error {
 	db.Log.InfoMsg("Synchronizing DB")
 
-	for _, table := range eventTables {
+	backoff := SynchronizeBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.synchronizeTables(chainID, eventTables)
+		if err == nil || attempt >= SynchronizeAttempts || !db.isTransientError(err) {
+			return err
+		}
+		db.Log.InfoMsg("Error synchronizing DB, retrying", "err", err, "attempt", attempt, "backoff", backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (db *SQLDB) synchronizeTables(chainID string, eventTables types.EventTables) error {
+	// Synchronize in a stable order so that a resumed synchronization picks up where the last one stopped
+	tableNames := make([]string, 0, len(eventTables))
+	for name := range eventTables {
+		tableNames = append(tableNames, name)
+	}
+	sort.Strings(tableNames)
+
+	for _, name := range tableNames {
+		table := eventTables[name]
 		found, err := db.findTable(table.Name)
 		if err != nil {
 			return err
@@ -287,109 +348,109 @@ func (db *SQLDB) SynchronizeDB(chainID string, eventTables types.EventTables) er
 	return nil
 }
 
-// SetBlock inserts or updates multiple rows and stores log info in SQL tables
-func (db *SQLDB) SetBlock(chainID string, eventTables types.EventTables, eventData types.EventData) error {
-	db.Log.InfoMsg("Synchronize Block..........")
+// CheckSchema compares the live columns of each of the tables in the database with the given tables specifications,
+// returning an error describing the first table that is missing or is missing columns (for example because it was
+// altered out from under vent). Extra columns are allowed. This queries every table so is more expensive than Ping.
+func (db *SQLDB) CheckSchema(eventTables types.EventTables) error {
+	tableNames := make([]string, 0, len(eventTables))
+	for name := range eventTables {
+		tableNames = append(tableNames, name)
+	}
+	sort.Strings(tableNames)
 
-	// Begin tx
-	tx, err := db.DB.Beginx()
-	if err != nil {
-		db.Log.InfoMsg("Error beginning transaction", "err", err)
-		return err
+	for _, name := range tableNames {
+		table := eventTables[name]
+		// The dictionary may still list a table that has been dropped so look at the table itself
+		liveColumns, err := db.liveColumns(table.Name)
+		if err != nil {
+			return err
+		}
+		if liveColumns == nil {
+			return fmt.Errorf("table %s of the projection is missing from the database", table.Name)
+		}
+		var missing []string
+		for _, column := range table.Columns {
+			if !liveColumns[column.Name] {
+				missing = append(missing, column.Name)
+			}
+		}
+		if len(missing) > 0 {
+			return fmt.Errorf("table %s is missing column(s) of the projection: %s", table.Name,
+				strings.Join(missing, ", "))
+		}
 	}
-	defer tx.Rollback()
+	return nil
+}
 
-	// Prepare log statement
-	logQuery := db.DBAdapter.InsertLogQuery()
-	logStmt, err := tx.Prepare(logQuery)
+// liveColumns returns the set of the names of the columns the table actually has, or nil if there is no such table
+func (db *SQLDB) liveColumns(tableName string) (map[string]bool, error) {
+	// language=SQL
+	query := fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", db.DBAdapter.SchemaName(tableName))
+	rows, err := db.DB.Query(query)
+	if db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeUndefinedTable) {
+		return nil, nil
+	}
 	if err != nil {
-		db.Log.InfoMsg("Error preparing log stmt", "err", err)
-		return err
+		return nil, fmt.Errorf("could not query columns of table %s: %v", tableName, err)
 	}
-	defer logStmt.Close()
-
-	var safeTable string
-loop:
-	// for each table in the block
-	for en, table := range eventTables {
-		safeTable = safe(table.Name)
-		dataRows := eventData.Tables[table.Name]
-		// for Each Row
-		for _, row := range dataRows {
-			var queryVal types.UpsertDeleteQuery
-			var txHash interface{}
-			var errQuery error
-
-			switch row.Action {
-			case types.ActionUpsert:
-				//Prepare Upsert
-				if queryVal, txHash, errQuery = db.DBAdapter.UpsertQuery(table, row); errQuery != nil {
-					db.Log.InfoMsg("Error building upsert query", "err", errQuery, "value", fmt.Sprintf("%v %v", table, row))
-					break loop // exits from all loops -> continue in close log stmt
-				}
-
-			case types.ActionDelete:
-				//Prepare Delete
-				if queryVal, errQuery = db.DBAdapter.DeleteQuery(table, row); errQuery != nil {
-					db.Log.InfoMsg("Error building delete query", "err", errQuery, "value", fmt.Sprintf("%v %v", table, row))
-					break loop // exits from all loops -> continue in close log stmt
-				}
-			default:
-				//Invalid Action
-				db.Log.InfoMsg("invalid action", "value", row.Action)
-				err = fmt.Errorf("invalid row action %s", row.Action)
-				break loop // exits from all loops -> continue in close log stmt
-			}
-
-			query := queryVal.Query
-
-			// Perform row action
-			db.Log.InfoMsg("msg", "action", row.Action, "query", query, "value", queryVal.Values)
-			if _, err = tx.Exec(query, queryVal.Pointers...); err != nil {
-				db.Log.InfoMsg(fmt.Sprintf("error performing %s on row", row.Action), "err", err, "value", queryVal.Values)
-				break loop // exits from all loops -> continue in close log stmt
-			}
-
-			// Marshal the rowData map
-			jsonData, err := getJSON(row.RowData)
-			if err != nil {
-				db.Log.InfoMsg("error marshaling rowData", "err", err, "value", fmt.Sprintf("%v", row.RowData))
-				break loop // exits from all loops -> continue in close log stmt
-			}
-
-			// Marshal sql values
-			sqlValues, err := getJSONFromValues(queryVal.Pointers)
-			if err != nil {
-				db.Log.InfoMsg("error marshaling rowdata", "err", err, "value", fmt.Sprintf("%v", row.RowData))
-				break loop // exits from all loops -> continue in close log stmt
-			}
-
-			eventName, _ := row.RowData[db.Columns.EventName].(string)
-			// Insert in log
-			db.Log.InfoMsg("INSERT LOG", "query", logQuery, "value",
-				fmt.Sprintf("chainid = %s tableName = %s eventName = %s block = %d", chainID, safeTable, en, eventData.BlockHeight))
-
-			if _, err = logStmt.Exec(chainID, safeTable, eventName, row.EventClass.GetFilter(), eventData.BlockHeight, txHash,
-				row.Action, jsonData, query, sqlValues); err != nil {
-				db.Log.InfoMsg("Error inserting into log", "err", err)
-				break loop // exits from all loops -> continue in close log stmt
-			}
-		}
+	defer rows.Close()
+	names, err := rows.Columns()
+	if err != nil {
+		return nil, fmt.Errorf("could not read columns of table %s: %v", tableName, err)
+	}
+	live := make(map[string]bool, len(names))
+	for _, name := range names {
+		live[name] = true
 	}
+	return live, nil
+}
 
-	// Close log statement
-	if err == nil {
-		if err = logStmt.Close(); err != nil {
-			db.Log.InfoMsg("Error closing log stmt", "err", err)
+// Errors that indicate the table definitions conflict with the database will not go away by retrying
+func (db *SQLDB) isTransientError(err error) bool {
+	if _, ok := err.(*ErrOrphanedColumns); ok {
+		return false
+	}
+	for _, errorType := range []types.SQLErrorType{
+		types.SQLErrorTypeDuplicatedTable,
+		types.SQLErrorTypeDuplicatedColumn,
+		types.SQLErrorTypeInvalidType,
+		types.SQLErrorTypeUndefinedTable,
+		types.SQLErrorTypeUndefinedColumn,
+	} {
+		if db.DBAdapter.ErrorEquals(err, errorType) {
+			return false
 		}
 	}
+	return true
+}
 
-	// Error handling
-	if err != nil {
-		// Rollback error
-		if errRb := tx.Rollback(); errRb != nil {
-			db.Log.InfoMsg("Error on rollback", "err", errRb)
-			return errRb
+// The savepoint before each row under SkipRowConflicts
+const rowSavepoint = "vent_row"
+
+// SetBlock inserts or updates multiple rows and stores log info in SQL tables. A row that violates a constraint of the
+// database fails the whole block unless RowConflicts is SkipRowConflicts, in which case the row is skipped (and
+// marked RowOperationSkipped) and the rest of the block is committed.
+//
+// If MaxRowsPerCommit is greater than zero a block with more rows is committed in several transactions of at most that
+// many rows, only the last of which sets the block height. So if one of them fails the block is not recorded as
+// committed and is committed again in full when it is next processed, which is safe since upserts and deletes are
+// idempotent (though the log records the rows of the transactions that did commit twice). Accumulated rows are all
+// committed in the last transaction, which may exceed MaxRowsPerCommit by their number. An accumulated row carrying the
+// height of its block is only accumulated into a row last accumulated from an earlier block, so blocks committed again
+// are not accumulated twice.
+func (db *SQLDB) SetBlock(chainID string, eventTables types.EventTables, eventData types.EventData) error {
+	db.Log.InfoMsg("Synchronize Block..........")
+	db.commits.begin()
+	defer db.commits.end()
+	return db.setBlock(chainID, eventTables, eventData)
+}
+
+func (db *SQLDB) setBlock(chainID string, eventTables types.EventTables, eventData types.EventData) error {
+	batches := db.commitBatches(eventTables, eventData, db.MaxRowsPerCommit)
+	for i, batch := range batches {
+		safeTable, err := db.setRows(chainID, eventData.BlockHeight, batch, i == len(batches)-1)
+		if err == nil {
+			continue
 		}
 
 		//Is a SQL error
@@ -403,7 +464,7 @@ loop:
 					return err
 				}
 				//Retry
-				return db.SetBlock(chainID, eventTables, eventData)
+				return db.setBlock(chainID, eventTables, eventData)
 			}
 
 			// Columns do not match
@@ -414,28 +475,262 @@ loop:
 					return err
 				}
 				//Retry
-				return db.SetBlock(chainID, eventTables, eventData)
+				return db.setBlock(chainID, eventTables, eventData)
 			}
 			return err
 		}
 		return err
 	}
 
-	db.Log.InfoMsg("COMMIT")
+	return nil
+}
 
-	err = db.SetBlockHeight(tx, chainID, eventData.BlockHeight)
+// SetBlockTx writes the rows of a block, their log entries, and the block height in tx, a transaction the caller has
+// begun on DB (for example with DB.Beginx), so that the caller can make writes of its own in the same transaction and
+// commit them atomically with the block. The caller owns tx: SetBlockTx neither commits nor rolls it back, and nothing
+// of the block (including the last committed height) is visible until the caller commits. If SetBlockTx returns an
+// error part of the block may have been written so the caller should roll back. Unlike SetBlock all the rows are
+// written to tx whatever MaxRowsPerCommit, and missing tables or columns are not synchronized and retried (since a
+// failed statement can abort the whole transaction) so SynchronizeDB should be called first.
+func (db *SQLDB) SetBlockTx(tx *sqlx.Tx, chainID string, eventTables types.EventTables,
+	eventData types.EventData) error {
+	db.Log.InfoMsg("Synchronize Block in caller's transaction..........")
+	db.commits.begin()
+	defer db.commits.end()
+
+	for _, batch := range db.commitBatches(eventTables, eventData, 0) {
+		if _, err := db.writeRows(tx, chainID, eventData.BlockHeight, batch); err != nil {
+			return err
+		}
+	}
+	err := db.SetBlockHeight(tx, chainID, eventData.BlockHeight)
 	if err != nil {
-		db.Log.InfoMsg("Could not commit block height", "err", err)
+		db.Log.InfoMsg("Could not set block height", "err", err)
 		return err
 	}
+	return nil
+}
+
+// blockRow is a row of a block along with the table it is committed to
+type blockRow struct {
+	table *types.SQLTable
+	// A reference into the caller's EventData so we can record the row operation there
+	row *types.EventDataRow
+}
+
+// commitBatches returns the rows of eventData in the order they are committed, by table name and then in the order
+// they were added to the block, but with the accumulated rows last. If maxRows is greater than zero they are split
+// into batches of at most that many rows, except that the accumulated rows all go in the last batch. There is
+// always at least one batch so that the block height is set for a block without rows.
+func (db *SQLDB) commitBatches(eventTables types.EventTables, eventData types.EventData, maxRows int) [][]blockRow {
+	// Commit tables in a stable order so that the log of each block is written in the same order, the rows of each
+	// table are committed in the order they were added to the block
+	tableNames := make([]string, 0, len(eventTables))
+	for name := range eventTables {
+		tableNames = append(tableNames, name)
+	}
+	sort.Strings(tableNames)
+
+	var rows, accumulated []blockRow
+	for _, name := range tableNames {
+		table := eventTables[name]
+		dataRows := eventData.Tables[table.Name]
+		for i := range dataRows {
+			row := blockRow{table: table, row: &dataRows[i]}
+			if row.row.Action == types.ActionAccumulate {
+				accumulated = append(accumulated, row)
+			} else {
+				rows = append(rows, row)
+			}
+		}
+	}
+
+	if maxRows <= 0 || len(rows)+len(accumulated) <= maxRows {
+		return [][]blockRow{append(rows, accumulated...)}
+	}
+	var batches [][]blockRow
+	for len(rows) > maxRows {
+		batches = append(batches, rows[:maxRows])
+		rows = rows[maxRows:]
+	}
+	return append(batches, append(rows, accumulated...))
+}
+
+// setRows commits rows of the block at height in one transaction along with their log entries, and if setHeight also
+// sets the block height. On error it returns the table of the row it failed on.
+func (db *SQLDB) setRows(chainID string, height uint64, rows []blockRow, setHeight bool) (string, error) {
+	// Begin tx
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		db.Log.InfoMsg("Error beginning transaction", "err", err)
+		return "", err
+	}
+	defer tx.Rollback()
+
+	safeTable, err := db.writeRows(tx, chainID, height, rows)
+
+	// Error handling
+	if err != nil {
+		// Rollback error
+		if errRb := tx.Rollback(); errRb != nil {
+			db.Log.InfoMsg("Error on rollback", "err", errRb)
+			return safeTable, errRb
+		}
+		return safeTable, err
+	}
+
+	db.Log.InfoMsg("COMMIT")
+
+	if setHeight {
+		err = db.SetBlockHeight(tx, chainID, height)
+		if err != nil {
+			db.Log.InfoMsg("Could not commit block height", "err", err)
+			return "", err
+		}
+	}
 
 	err = tx.Commit()
 	if err != nil {
 		db.Log.InfoMsg("Error on commit", "err", err)
-		return err
+		return "", err
 	}
 
-	return nil
+	return "", nil
+}
+
+// writeRows writes rows of the block at height along with their log entries in tx. On error it returns the table of
+// the row it failed on.
+func (db *SQLDB) writeRows(tx *sqlx.Tx, chainID string, height uint64, rows []blockRow) (string, error) {
+	// Prepare log statement
+	logQuery := db.DBAdapter.InsertLogQuery()
+	logStmt, err := tx.Prepare(logQuery)
+	if err != nil {
+		db.Log.InfoMsg("Error preparing log stmt", "err", err)
+		return "", err
+	}
+	defer logStmt.Close()
+
+	var safeTable string
+loop:
+	// for each row of the batch
+	for _, br := range rows {
+		table, row := br.table, br.row
+		safeTable = safe(table.Name)
+		var queryVal types.UpsertDeleteQuery
+		var txHash interface{}
+		var errQuery error
+
+		switch row.Action {
+		case types.ActionUpsert, types.ActionAccumulate:
+			if err = db.scaleAmounts(tx, row); err != nil {
+				db.Log.InfoMsg("Error scaling amounts", "err", err, "value", fmt.Sprintf("%v %v", table, row))
+				break loop // exits from all loops -> continue in close log stmt
+			}
+			//Prepare Upsert
+			if queryVal, txHash, errQuery = db.DBAdapter.UpsertQuery(table, *row); errQuery != nil {
+				db.Log.InfoMsg("Error building upsert query", "err", errQuery, "value", fmt.Sprintf("%v %v", table, row))
+				break loop // exits from all loops -> continue in close log stmt
+			}
+
+		case types.ActionDelete:
+			//Prepare Delete
+			if queryVal, errQuery = db.DBAdapter.DeleteQuery(table, *row); errQuery != nil {
+				db.Log.InfoMsg("Error building delete query", "err", errQuery, "value", fmt.Sprintf("%v %v", table, row))
+				break loop // exits from all loops -> continue in close log stmt
+			}
+		default:
+			//Invalid Action
+			db.Log.InfoMsg("invalid action", "value", row.Action)
+			err = fmt.Errorf("invalid row action %s", row.Action)
+			break loop // exits from all loops -> continue in close log stmt
+		}
+
+		query := queryVal.Query
+
+		// Under SkipRowConflicts each row can be rolled back on its own
+		skipConflicts := db.RowConflicts == types.SkipRowConflicts
+		if skipConflicts {
+			if _, err = tx.Exec("SAVEPOINT " + rowSavepoint); err != nil {
+				db.Log.InfoMsg("Error creating row savepoint", "err", err)
+				break loop // exits from all loops -> continue in close log stmt
+			}
+		}
+
+		// Perform row action
+		db.Log.InfoMsg("msg", "action", row.Action, "query", query, "value", queryVal.Values)
+		if queryVal.ReturnsInserted {
+			var inserted bool
+			err = tx.QueryRow(query, queryVal.Pointers...).Scan(&inserted)
+			switch {
+			case err == sql.ErrNoRows:
+				// Conflicting row existed and was left as is
+				err = nil
+				row.Operation = types.RowOperationSkipped
+			case inserted:
+				row.Operation = types.RowOperationInsert
+			default:
+				row.Operation = types.RowOperationUpdate
+			}
+		} else {
+			_, err = tx.Exec(query, queryVal.Pointers...)
+		}
+		if err != nil && skipConflicts && db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeConstraintViolation) {
+			db.Log.InfoMsg(fmt.Sprintf("Skipping %s of row that violates a constraint", row.Action), "err", err,
+				"table", safeTable, "value", queryVal.Values)
+			if _, err = tx.Exec("ROLLBACK TO SAVEPOINT " + rowSavepoint); err != nil {
+				db.Log.InfoMsg("Error rolling back to row savepoint", "err", err)
+				break loop // exits from all loops -> continue in close log stmt
+			}
+			row.Operation = types.RowOperationSkipped
+			continue
+		}
+		if err != nil {
+			db.Log.InfoMsg(fmt.Sprintf("error performing %s on row", row.Action), "err", err, "value", queryVal.Values)
+			break loop // exits from all loops -> continue in close log stmt
+		}
+		if skipConflicts {
+			if _, err = tx.Exec("RELEASE SAVEPOINT " + rowSavepoint); err != nil {
+				db.Log.InfoMsg("Error releasing row savepoint", "err", err)
+				break loop // exits from all loops -> continue in close log stmt
+			}
+		}
+
+		// Marshal the rowData map
+		jsonData, err := getJSON(row.RowData)
+		if err != nil {
+			db.Log.InfoMsg("error marshaling rowData", "err", err, "value", fmt.Sprintf("%v", row.RowData))
+			break loop // exits from all loops -> continue in close log stmt
+		}
+
+		// Marshal sql values
+		sqlValues, err := getJSONFromValues(queryVal.Pointers)
+		if err != nil {
+			db.Log.InfoMsg("error marshaling rowdata", "err", err, "value", fmt.Sprintf("%v", row.RowData))
+			break loop // exits from all loops -> continue in close log stmt
+		}
+
+		eventName, _ := row.RowData[db.Columns.EventName].(string)
+		// Insert in log
+		db.Log.InfoMsg("INSERT LOG", "query", logQuery, "value",
+			fmt.Sprintf("chainid = %s tableName = %s eventName = %s block = %d", chainID, safeTable, eventName, height))
+
+		if _, err = logStmt.Exec(chainID, safeTable, eventName, row.EventClass.GetFilter(), height, txHash,
+			row.Action, jsonData, query, sqlValues); err != nil {
+			db.Log.InfoMsg("Error inserting into log", "err", err)
+			break loop // exits from all loops -> continue in close log stmt
+		}
+	}
+
+	// Close log statement
+	if err == nil {
+		if err = logStmt.Close(); err != nil {
+			db.Log.InfoMsg("Error closing log stmt", "err", err)
+		}
+	}
+	if err != nil {
+		return safeTable, err
+	}
+	return "", nil
 }
 
 // GetBlock returns all tables structures and row data for given block
@@ -608,7 +903,7 @@ func (db *SQLDB) RestoreDB(restoreTime time.Time, prefix string) error {
 		}
 
 		switch action {
-		case types.ActionUpsert, types.ActionDelete:
+		case types.ActionUpsert, types.ActionAccumulate, types.ActionDelete:
 			// get row values
 			if pointers, err = getValuesFromJSON(sqlValues); err != nil {
 				db.Log.InfoMsg("error unmarshaling json", "err", err, "value", sqlValues)
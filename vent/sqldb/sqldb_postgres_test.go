@@ -9,6 +9,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hyperledger/burrow/logging"
+	"github.com/hyperledger/burrow/vent/sqldb"
 	"github.com/hyperledger/burrow/vent/sqldb/adapters"
 	"github.com/hyperledger/burrow/vent/test"
 	"github.com/hyperledger/burrow/vent/types"
@@ -23,6 +25,18 @@ func TestPostgresSynchronizeDB(t *testing.T) {
 	testSynchronizeDB(t, test.PostgresVentConfig(""))
 }
 
+func TestPostgresSynchronizeDBResume(t *testing.T) {
+	testSynchronizeDBResume(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresSynchronizeDBDeterministic(t *testing.T) {
+	testSynchronizeDBDeterministic(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresRawDB(t *testing.T) {
+	testRawDB(t, test.PostgresVentConfig(""))
+}
+
 func TestPostgresCleanDB(t *testing.T) {
 	testCleanDB(t, test.PostgresVentConfig(""))
 }
@@ -31,6 +45,38 @@ func TestPostgresSetBlock(t *testing.T) {
 	testSetBlock(t, test.PostgresVentConfig(""))
 }
 
+func TestPostgresSetBlockRowOperation(t *testing.T) {
+	testSetBlockRowOperation(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresSetBlockBigInt(t *testing.T) {
+	testSetBlockBigInt(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresSetBlockAmount(t *testing.T) {
+	testSetBlockAmount(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresSetBlockRollup(t *testing.T) {
+	testSetBlockRollup(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresSetBlockRaw(t *testing.T) {
+	testSetBlockRaw(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresSetBlockDeadLetter(t *testing.T) {
+	testSetBlockDeadLetter(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresCheckSchema(t *testing.T) {
+	testCheckSchema(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresMaintain(t *testing.T) {
+	testMaintain(t, test.PostgresVentConfig(""))
+}
+
 func TestRestore(t *testing.T) {
 	testRestore(t, test.PostgresVentConfig(""))
 }
@@ -102,3 +148,67 @@ func TestPostgresBlockNotification(t *testing.T) {
 
 	require.NoError(t, <-errCh)
 }
+
+func TestPostgresExportTable(t *testing.T) {
+	testExportTable(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresExportTableParquet(t *testing.T) {
+	testExportTableParquet(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresRollbackToHeight(t *testing.T) {
+	testRollbackToHeight(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresCreateSchema(t *testing.T) {
+	cfg := test.PostgresVentConfig("")
+	cfg.DBSetSearchPath = true
+	db, closeDB := test.NewTestDB(t, cfg)
+	defer closeDB()
+
+	// The schema did not exist so was created with the system tables inside it
+	var schema string
+	//language=SQL
+	err := db.DB.QueryRow("SELECT table_schema FROM information_schema.tables WHERE table_name = $1;",
+		tables.Log).Scan(&schema)
+	require.NoError(t, err)
+	require.Equal(t, cfg.DBSchema, schema)
+
+	// Unqualified names resolve within the schema
+	var count int
+	err = db.DB.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s;", tables.ChainInfo)).Scan(&count)
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	// A missing schema is an error when it must not be created
+	_, err = sqldb.NewSQLDB(types.SQLConnection{
+		DBAdapter:        cfg.DBAdapter,
+		DBURL:            cfg.DBURL,
+		DBSchema:         cfg.DBSchema + "_missing",
+		Log:              logging.NewNoopLogger(),
+		SkipCreateSchema: true,
+	})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "does not exist")
+}
+
+func TestPostgresOrphanedColumns(t *testing.T) {
+	testOrphanedColumns(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresNotNullColumns(t *testing.T) {
+	testNotNullColumns(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresSetBlockRowConflicts(t *testing.T) {
+	testSetBlockRowConflicts(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresSetBlockMaxRowsPerCommit(t *testing.T) {
+	testSetBlockMaxRowsPerCommit(t, test.PostgresVentConfig(""))
+}
+
+func TestPostgresSetBlockTx(t *testing.T) {
+	testSetBlockTx(t, test.PostgresVentConfig(""))
+}
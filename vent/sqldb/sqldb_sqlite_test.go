@@ -12,6 +12,18 @@ func TestSqliteSynchronizeDB(t *testing.T) {
 	testSynchronizeDB(t, test.SqliteVentConfig(""))
 }
 
+func TestSqliteSynchronizeDBResume(t *testing.T) {
+	testSynchronizeDBResume(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteSynchronizeDBDeterministic(t *testing.T) {
+	testSynchronizeDBDeterministic(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteRawDB(t *testing.T) {
+	testRawDB(t, test.SqliteVentConfig(""))
+}
+
 func TestSqliteCleanDB(t *testing.T) {
 	testCleanDB(t, test.SqliteVentConfig(""))
 }
@@ -20,6 +32,70 @@ func TestSqliteSetBlock(t *testing.T) {
 	testSetBlock(t, test.SqliteVentConfig(""))
 }
 
+func TestSqliteSetBlockRowOperation(t *testing.T) {
+	testSetBlockRowOperation(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteSetBlockBigInt(t *testing.T) {
+	testSetBlockBigInt(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteSetBlockAmount(t *testing.T) {
+	testSetBlockAmount(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteSetBlockRollup(t *testing.T) {
+	testSetBlockRollup(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteSetBlockRaw(t *testing.T) {
+	testSetBlockRaw(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteSetBlockDeadLetter(t *testing.T) {
+	testSetBlockDeadLetter(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteCheckSchema(t *testing.T) {
+	testCheckSchema(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteMaintain(t *testing.T) {
+	testMaintain(t, test.SqliteVentConfig(""))
+}
+
 func TestSqliteRestore(t *testing.T) {
 	testRestore(t, test.SqliteVentConfig(""))
 }
+
+func TestSqliteExportTable(t *testing.T) {
+	testExportTable(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteExportTableParquet(t *testing.T) {
+	testExportTableParquet(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteRollbackToHeight(t *testing.T) {
+	testRollbackToHeight(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteOrphanedColumns(t *testing.T) {
+	testOrphanedColumns(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteNotNullColumns(t *testing.T) {
+	testNotNullColumns(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteSetBlockRowConflicts(t *testing.T) {
+	testSetBlockRowConflicts(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteSetBlockMaxRowsPerCommit(t *testing.T) {
+	testSetBlockMaxRowsPerCommit(t, test.SqliteVentConfig(""))
+}
+
+func TestSqliteSetBlockTx(t *testing.T) {
+	testSetBlockTx(t, test.SqliteVentConfig(""))
+}
@@ -3,8 +3,13 @@
 package sqldb_test
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
+	"encoding/csv"
 	"fmt"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
@@ -14,8 +19,11 @@ import (
 	"github.com/hyperledger/burrow/vent/sqlsol"
 	"github.com/hyperledger/burrow/vent/test"
 	"github.com/hyperledger/burrow/vent/types"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
 )
 
 func testSynchronizeDB(t *testing.T, cfg *config.VentConfig) {
@@ -38,6 +46,164 @@ func testSynchronizeDB(t *testing.T, cfg *config.VentConfig) {
 		})
 }
 
+func testSynchronizeDBResume(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: resumes an interrupted synchronization", cfg.DBAdapter),
+		func(t *testing.T) {
+			tableStructure, err := sqlsol.NewProjectionFromBytes([]byte(test.GoodJSONConfFile(t)))
+			require.NoError(t, err)
+
+			db, cleanUpDB := test.NewTestDB(t, cfg)
+			defer cleanUpDB()
+
+			qualify := func(tableName string) string {
+				if cfg.DBAdapter == types.PostgresDB {
+					return fmt.Sprintf("%s.\"%s\"", cfg.DBSchema, tableName)
+				}
+				return fmt.Sprintf("\"%s\"", tableName)
+			}
+			countCreated := func() int {
+				var count int
+				err := db.RawDB().QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = '%s'",
+					qualify(db.Tables.Log), db.Columns.Action, types.ActionCreateTable)).Scan(&count)
+				require.NoError(t, err)
+				return count
+			}
+
+			// Tables are synchronized in name order so occupying the name of the last table makes synchronization
+			// fail after the others have been created
+			var tableNames []string
+			for name := range tableStructure.Tables {
+				tableNames = append(tableNames, name)
+			}
+			sort.Strings(tableNames)
+			require.True(t, len(tableNames) > 1)
+			blocked := qualify(tableNames[len(tableNames)-1])
+
+			_, err = db.RawDB().Exec(fmt.Sprintf("CREATE TABLE %s (blocker INTEGER)", blocked))
+			require.NoError(t, err)
+			err = db.SynchronizeDB(test.ChainID, tableStructure.Tables)
+			require.Error(t, err)
+			require.True(t, db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeDuplicatedTable))
+			require.Equal(t, len(tableNames)-1, countCreated())
+
+			// Clear the failure and resume, only the remaining table is created
+			_, err = db.RawDB().Exec(fmt.Sprintf("DROP TABLE %s", blocked))
+			require.NoError(t, err)
+			err = db.SynchronizeDB(test.ChainID, tableStructure.Tables)
+			require.NoError(t, err)
+			require.Equal(t, len(tableNames), countCreated())
+
+			// Synchronizing again is a no-op
+			err = db.SynchronizeDB(test.ChainID, tableStructure.Tables)
+			require.NoError(t, err)
+			require.Equal(t, len(tableNames), countCreated())
+
+			for _, name := range tableNames {
+				_, err = db.RawDB().Exec(fmt.Sprintf("SELECT COUNT(*) FROM %s", qualify(name)))
+				require.NoError(t, err)
+			}
+		})
+}
+
+func testSynchronizeDBDeterministic(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: generates the same DDL on every run", cfg.DBAdapter),
+		func(t *testing.T) {
+			// Projections modify their event spec so build a fresh one each time
+			newProjection := func(extraColumns ...string) *sqlsol.Projection {
+				itemMappings := []*types.EventFieldMapping{
+					{Field: "id", ColumnName: "id", Type: "uint256", Primary: true, Notify: []string{"items"}},
+					{Field: "name", ColumnName: "name", Type: "string", Notify: []string{"items", "names"}},
+				}
+				for _, column := range extraColumns {
+					itemMappings = append(itemMappings, &types.EventFieldMapping{Field: column, ColumnName: column,
+						Type: "string", Notify: []string{"extras"}})
+				}
+				projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{
+					{TableName: "Items", Filter: "EventName = 'Item'", FieldMappings: itemMappings},
+					{TableName: "Items", Filter: "EventName = 'Label'", FieldMappings: []*types.EventFieldMapping{
+						{Field: "id", ColumnName: "id", Type: "uint256", Primary: true},
+						{Field: "label", ColumnName: "label", Type: "string"},
+					}},
+					{TableName: "Owners", Filter: "EventName = 'Owner'", FieldMappings: []*types.EventFieldMapping{
+						{Field: "owner", ColumnName: "owner", Type: "address", Primary: true},
+					}},
+				})
+				require.NoError(t, err)
+				return projection
+			}
+
+			var position func(statement, column string) int
+			// The CREATE and ALTER statements run in order
+			runDDL := func() []string {
+				db, cleanUpDB := test.NewTestDB(t, cfg)
+				defer cleanUpDB()
+				position = func(statement, column string) int {
+					return strings.Index(statement, db.DBAdapter.SecureName(column))
+				}
+
+				require.NoError(t, db.SynchronizeDB(test.ChainID, newProjection().Tables))
+				require.NoError(t, db.SynchronizeDB(test.ChainID, newProjection("colour", "size").Tables))
+
+				rows, err := db.RawDB().Query(fmt.Sprintf("SELECT %s FROM %s WHERE %s IN ('%s', '%s') ORDER BY %s",
+					db.Columns.SqlStmt, db.DBAdapter.SchemaName(db.Tables.Log), db.Columns.Action,
+					types.ActionCreateTable, types.ActionAlterTable, db.Columns.Id))
+				require.NoError(t, err)
+				defer rows.Close()
+				var statements []string
+				for rows.Next() {
+					var statement string
+					require.NoError(t, rows.Scan(&statement))
+					statements = append(statements, statement)
+				}
+				require.NoError(t, rows.Err())
+				return statements
+			}
+
+			statements := runDDL()
+			// Two tables created and two columns added in the order declared
+			require.Len(t, statements, 4)
+			// Columns are created in the order declared, with those of later event classes after earlier ones
+			items := statements[0]
+			require.Contains(t, items, "Items")
+			assert.True(t, position(items, "id") < position(items, "name"))
+			assert.True(t, position(items, "name") < position(items, "label"))
+			assert.Contains(t, statements[2], "colour")
+			assert.Contains(t, statements[3], "size")
+			for i := 0; i < 3; i++ {
+				assert.Equal(t, statements, runDDL())
+			}
+		})
+}
+
+func testRawDB(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: runs custom statements on the vent connection pool", cfg.DBAdapter),
+		func(t *testing.T) {
+			tableStructure, err := sqlsol.NewProjectionFromBytes([]byte(test.GoodJSONConfFile(t)))
+			require.NoError(t, err)
+
+			db, cleanUpDB := test.NewTestDB(t, cfg)
+			defer cleanUpDB()
+
+			err = db.SynchronizeDB(test.ChainID, tableStructure.Tables)
+			require.NoError(t, err)
+
+			rawDB := db.RawDB()
+			require.NotNil(t, rawDB)
+
+			var count int
+			query := fmt.Sprintf("SELECT COUNT(*) FROM %s", db.Tables.Dictionary)
+			if cfg.DBAdapter == types.PostgresDB {
+				query = fmt.Sprintf("SELECT COUNT(*) FROM %s.%s", cfg.DBSchema, db.Tables.Dictionary)
+			}
+			err = rawDB.QueryRow(query).Scan(&count)
+			require.NoError(t, err)
+			require.True(t, count > 0)
+
+			// vent can still use the pool
+			require.NoError(t, db.Ping())
+		})
+}
+
 func testCleanDB(t *testing.T, cfg *config.VentConfig) {
 	t.Run(fmt.Sprintf("%s: successfully creates tables, updates test.ChainID and drops all tables", cfg.DBAdapter),
 		func(t *testing.T) {
@@ -158,6 +324,1027 @@ func testSetBlock(t *testing.T, cfg *config.VentConfig) {
 	})
 }
 
+func testSetBlockRowOperation(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: reports whether upserts inserted or updated rows", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			tables := types.EventTables{
+				"entities": {
+					Name: "entities",
+					Columns: []*types.SQLTableColumn{
+						{Name: "id", Type: types.SQLColumnTypeInt, Primary: true},
+						{Name: "name", Type: types.SQLColumnTypeText},
+					},
+				},
+			}
+			require.NoError(t, db.SynchronizeDB(test.ChainID, tables))
+
+			newBlock := func(height uint64, ids ...int) types.EventData {
+				var rows types.EventDataTable
+				for _, id := range ids {
+					rows = append(rows, types.EventDataRow{
+						Action:  types.ActionUpsert,
+						RowData: map[string]interface{}{"id": id, "name": fmt.Sprintf("entity%d at %d", id, height)},
+					})
+				}
+				return types.EventData{
+					BlockHeight: height,
+					Tables:      map[string]types.EventDataTable{"entities": rows},
+				}
+			}
+
+			first := newBlock(1, 1)
+			require.NoError(t, db.SetBlock(test.ChainID, tables, first))
+			second := newBlock(2, 1, 2)
+			require.NoError(t, db.SetBlock(test.ChainID, tables, second))
+
+			if cfg.DBAdapter != types.PostgresDB {
+				// Not supported so left unknown
+				assert.Equal(t, types.RowOperationUnknown, first.Tables["entities"][0].Operation)
+				return
+			}
+			assert.Equal(t, types.RowOperationInsert, first.Tables["entities"][0].Operation)
+			assert.Equal(t, types.RowOperationUpdate, second.Tables["entities"][0].Operation)
+			assert.Equal(t, types.RowOperationInsert, second.Tables["entities"][1].Operation)
+		})
+
+	t.Run(fmt.Sprintf("%s: reports upserts that left a conflicting row as is as skipped", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+			db.RowConflicts = types.SkipRowConflicts
+
+			// With no columns to update an upsert of an existing key does nothing
+			tables := types.EventTables{
+				"members": {
+					Name: "members",
+					Columns: []*types.SQLTableColumn{
+						{Name: "id", Type: types.SQLColumnTypeInt, Primary: true},
+					},
+				},
+			}
+			require.NoError(t, db.SynchronizeDB(test.ChainID, tables))
+
+			newBlock := func(height uint64) types.EventData {
+				return types.EventData{
+					BlockHeight: height,
+					Tables: map[string]types.EventDataTable{"members": {{
+						Action:  types.ActionUpsert,
+						RowData: map[string]interface{}{"id": 1},
+					}}},
+				}
+			}
+
+			first := newBlock(1)
+			require.NoError(t, db.SetBlock(test.ChainID, tables, first))
+			second := newBlock(2)
+			require.NoError(t, db.SetBlock(test.ChainID, tables, second))
+			_, rows := selectAll(t, db, "members")
+			require.Len(t, rows, 1)
+
+			if cfg.DBAdapter != types.PostgresDB {
+				// Not supported so left unknown
+				assert.Equal(t, types.RowOperationUnknown, second.Tables["members"][0].Operation)
+				return
+			}
+			assert.Equal(t, types.RowOperationInsert, first.Tables["members"][0].Operation)
+			assert.Equal(t, types.RowOperationSkipped, second.Tables["members"][0].Operation)
+		})
+}
+
+func testSetBlockBigInt(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: stores integers wider than 64 bits", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			eventClass := &types.EventClass{
+				TableName: "Balances",
+				Filter:    "EventType = 'LogEvent'",
+				FieldMappings: []*types.EventFieldMapping{
+					{Field: "id", ColumnName: "id", Type: "uint64", Primary: true},
+					{Field: "raw", ColumnName: "raw", Type: "uint256",
+						BigInt: &types.EventFieldBigInt{As: types.BigIntNumeric}},
+					{Field: "padded", ColumnName: "padded", Type: "uint256",
+						BigInt: &types.EventFieldBigInt{As: types.BigIntString}},
+					{Field: "balance", ColumnName: "balance", Type: "uint256",
+						BigInt: &types.EventFieldBigInt{As: types.BigIntDecimal, Decimals: 18}},
+				},
+			}
+			projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{eventClass})
+			require.NoError(t, err)
+			require.NoError(t, db.SynchronizeDB(test.ChainID, projection.Tables))
+
+			// 2^64 + 1 does not fit in a bigint
+			raw := "18446744073709551617"
+			row := map[string]interface{}{"id": 1}
+			for _, fm := range eventClass.FieldMappings {
+				if fm.BigInt != nil {
+					row[fm.ColumnName], err = fm.BigInt.Value(raw)
+					require.NoError(t, err)
+				}
+			}
+			err = db.SetBlock(test.ChainID, projection.Tables, types.EventData{
+				BlockHeight: 1,
+				Tables: map[string]types.EventDataTable{
+					"Balances": {{Action: types.ActionUpsert, RowData: row}},
+				},
+			})
+			require.NoError(t, err)
+
+			_, rows := selectAll(t, db, "Balances")
+			require.Len(t, rows, 1)
+			assert.Equal(t, row["padded"], rows[0]["padded"])
+			if cfg.DBAdapter == types.PostgresDB {
+				// SQLite can only store these approximately
+				assert.Equal(t, raw, rows[0]["raw"])
+				assert.Equal(t, "18.446744073709551617", rows[0]["balance"])
+			}
+		})
+}
+
+func testSetBlockAmount(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: scales amounts by decimals looked up in the same transaction", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			tokens := &types.EventClass{
+				TableName: "Tokens",
+				Filter:    "EventType = 'LogEvent'",
+				FieldMappings: []*types.EventFieldMapping{
+					{Field: "token", ColumnName: "token", Type: "address", Primary: true},
+					{Field: "decimals", ColumnName: "decimals", Type: "uint8"},
+				},
+			}
+			transfers := &types.EventClass{
+				TableName: "Transfers",
+				Filter:    "EventType = 'LogEvent'",
+				FieldMappings: []*types.EventFieldMapping{
+					{Field: "id", ColumnName: "id", Type: "uint64", Primary: true},
+					{Field: "token", ColumnName: "token", Type: "address"},
+					{Field: "amount", ColumnName: "amount", Type: "uint64",
+						Amount: &types.EventFieldAmount{
+							ScaledColumnName: "amount_scaled",
+							DecimalsLookup: &types.EventFieldDecimalsLookup{
+								TableName:          "Tokens",
+								KeyColumnName:      "token",
+								DecimalsColumnName: "decimals",
+								TokenColumnName:    "token",
+							},
+						}},
+				},
+			}
+			projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{tokens, transfers})
+			require.NoError(t, err)
+			require.NoError(t, db.SynchronizeDB(test.ChainID, projection.Tables))
+
+			const token = "0A1B2C3D4E5F60718293A4B5C6D7E8F901234567"
+			const unknownToken = "1A1B2C3D4E5F60718293A4B5C6D7E8F901234567"
+			// The token is registered in the same block as the transfers
+			err = db.SetBlock(test.ChainID, projection.Tables, types.EventData{
+				BlockHeight: 1,
+				Tables: map[string]types.EventDataTable{
+					"Tokens": {{Action: types.ActionUpsert, RowData: map[string]interface{}{
+						"token": token, "decimals": 6}}},
+					"Transfers": {
+						{Action: types.ActionUpsert, EventClass: transfers, RowData: map[string]interface{}{
+							"id": 1, "token": token, "amount": 1234500000}},
+						{Action: types.ActionUpsert, EventClass: transfers, RowData: map[string]interface{}{
+							"id": 2, "token": unknownToken, "amount": 1}},
+					},
+				},
+			})
+			require.NoError(t, err)
+
+			_, rows := selectAll(t, db, "Transfers")
+			require.Len(t, rows, 2)
+			scaled := make(map[string]string)
+			for _, row := range rows {
+				scaled[fmt.Sprint(row["id"])] = fmt.Sprint(row["amount_scaled"])
+			}
+			if cfg.DBAdapter == types.PostgresDB {
+				assert.Equal(t, "1234.500000", scaled["1"])
+			} else {
+				// SQLite stores the scaled amount as a float
+				assert.Equal(t, "1234.5", scaled["1"])
+			}
+			assert.Equal(t, "<nil>", scaled["2"])
+		})
+}
+
+func testSetBlockRollup(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: accumulates rollup rows of the same bucket", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+				TableName: "Transfers",
+				Filter:    "EventType = 'LogEvent'",
+				FieldMappings: []*types.EventFieldMapping{
+					{Field: "id", ColumnName: "id", Type: "uint64", Primary: true},
+					{Field: "token", ColumnName: "token", Type: "string"},
+					{Field: "amount", ColumnName: "amount", Type: "uint64"},
+				},
+				Rollups: []*types.Rollup{{
+					TableName: "TransfersDaily",
+					Bucket:    types.RollupBucketDay,
+					GroupBy:   []string{"token"},
+					Aggregates: []*types.RollupAggregate{
+						{Function: types.AggregateCount, As: "transfers"},
+						{Function: types.AggregateSum, ColumnName: "amount", As: "total"},
+						{Function: types.AggregateMin, ColumnName: "amount", As: "smallest"},
+						{Function: types.AggregateMax, ColumnName: "amount", As: "largest"},
+					},
+				}},
+			}})
+			require.NoError(t, err)
+			require.NoError(t, db.SynchronizeDB(test.ChainID, projection.Tables))
+
+			bucket := time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+			accumulate := func(height uint64, count int64, total, smallest, largest string) {
+				err := db.SetBlock(test.ChainID, projection.Tables, types.EventData{
+					BlockHeight: height,
+					Tables: map[string]types.EventDataTable{
+						"TransfersDaily": {{
+							Action: types.ActionAccumulate,
+							RowData: map[string]interface{}{
+								columns.Bucket: bucket,
+								columns.Height: height,
+								"token":        "frogs",
+								"transfers":    count,
+								"total":        total,
+								"smallest":     smallest,
+								"largest":      largest,
+							},
+						}},
+					},
+				})
+				require.NoError(t, err)
+			}
+			accumulate(1, 2, "30", "10", "20")
+			accumulate(2, 1, "5", "5", "5")
+
+			assertRollup := func() {
+				_, rows := selectAll(t, db, "TransfersDaily")
+				require.Len(t, rows, 1)
+				assert.Equal(t, "frogs", rows[0]["token"])
+				assert.Equal(t, "3", fmt.Sprint(rows[0]["transfers"]))
+				assert.Equal(t, "35", fmt.Sprint(rows[0]["total"]))
+				assert.Equal(t, "5", fmt.Sprint(rows[0]["smallest"]))
+				assert.Equal(t, "20", fmt.Sprint(rows[0]["largest"]))
+				assert.Equal(t, "2", fmt.Sprint(rows[0][columns.Height]))
+			}
+			assertRollup()
+
+			// Blocks committed again, as when they are replayed after a restart, are not accumulated again
+			accumulate(2, 1, "5", "5", "5")
+			accumulate(1, 2, "30", "10", "20")
+			assertRollup()
+		})
+}
+
+func testSetBlockRaw(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: stores raw events", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			projection, err := sqlsol.NewProjectionFromEventSpec(types.EventSpec{{
+				TableName: "RawLogs",
+				Filter:    "EventType = 'LogEvent'",
+				Raw:       true,
+			}})
+			require.NoError(t, err)
+			require.NoError(t, db.SynchronizeDB(test.ChainID, projection.Tables))
+
+			columns := types.DefaultSQLColumnNames
+			cols, _ := selectAll(t, db, "RawLogs")
+			for _, col := range []string{columns.TxHash, columns.EventIndex, columns.Topic0, columns.Topic1,
+				columns.Topic2, columns.Topic3, columns.Data} {
+				assert.Contains(t, cols, col)
+			}
+
+			// Events of the same transaction are told apart by their index
+			rawRow := func(index string, topic0 string) types.EventDataRow {
+				return types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{
+					columns.TxHash:     "ABCD",
+					columns.EventIndex: index,
+					columns.Topic0:     topic0,
+					columns.Data:       "0102",
+				}}
+			}
+			err = db.SetBlock(test.ChainID, projection.Tables, types.EventData{
+				BlockHeight: 1,
+				Tables: map[string]types.EventDataTable{
+					"RawLogs": {rawRow("0", "AA"), rawRow("1", "BB")},
+				},
+			})
+			require.NoError(t, err)
+
+			_, rows := selectAll(t, db, "RawLogs")
+			require.Len(t, rows, 2)
+			var topics []interface{}
+			for _, row := range rows {
+				topics = append(topics, row[columns.Topic0])
+				assert.Equal(t, "0102", row[columns.Data])
+				assert.Nil(t, row[columns.Topic1])
+			}
+			assert.ElementsMatch(t, []interface{}{"AA", "BB"}, topics)
+		})
+}
+
+func testSetBlockDeadLetter(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: stores dead-lettered events", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			tableName := types.DefaultSQLTableNames.DeadLetter
+			eventTables := sqlsol.DeadLetterTables(tableName)
+			require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+
+			columns := types.DefaultSQLColumnNames
+			err := db.SetBlock(test.ChainID, eventTables, types.EventData{
+				BlockHeight: 3,
+				Tables: map[string]types.EventDataTable{
+					tableName: {{Action: types.ActionUpsert, RowData: map[string]interface{}{
+						columns.Height:      "3",
+						columns.TxHash:      "ABCD",
+						columns.EventIndex:  "1",
+						columns.TableName:   "Transfers",
+						columns.EventFilter: "EventType = 'LogEvent'",
+						columns.Error:       "abi spec not found",
+						columns.Event:       `{"Log":{}}`,
+					}}},
+				},
+			})
+			require.NoError(t, err)
+
+			_, rows := selectAll(t, db, tableName)
+			require.Len(t, rows, 1)
+			assert.Equal(t, "Transfers", rows[0][columns.TableName])
+			assert.Equal(t, "abi spec not found", rows[0][columns.Error])
+		})
+}
+
+func testCheckSchema(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: detects tables that no longer match the projection", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			eventTables, _ := getBlock()
+			require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+			require.NoError(t, db.CheckSchema(eventTables))
+
+			// Drop a column behind vent's back
+			if cfg.DBAdapter == types.PostgresDB {
+				_, err := db.RawDB().Exec(fmt.Sprintf("ALTER TABLE %s.\"test_table1\" DROP COLUMN \"col2\"", cfg.DBSchema))
+				require.NoError(t, err)
+			} else {
+				// SQLite cannot drop columns so rebuild the table without it
+				for _, query := range []string{
+					"CREATE TABLE \"test_table1_new\" AS SELECT \"test_id\", \"col1\", \"_height\", \"col4\", \"colV\", \"colT\" FROM \"test_table1\"",
+					"DROP TABLE \"test_table1\"",
+					"ALTER TABLE \"test_table1_new\" RENAME TO \"test_table1\"",
+				} {
+					_, err := db.RawDB().Exec(query)
+					require.NoError(t, err)
+				}
+			}
+
+			err := db.CheckSchema(eventTables)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "test_table1")
+			assert.Contains(t, err.Error(), "col2")
+
+			_, err = db.RawDB().Exec(fmt.Sprintf("DROP TABLE %s", db.DBAdapter.SchemaName("test_table1")))
+			require.NoError(t, err)
+			err = db.CheckSchema(eventTables)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "missing from the database")
+		})
+}
+
+func testMaintain(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: maintains projection tables", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			eventTables, eventData := getBlock()
+			require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+			require.NoError(t, db.SetBlock(test.ChainID, eventTables, eventData))
+
+			require.NoError(t, db.Maintain(eventTables, false))
+			require.NoError(t, db.Maintain(eventTables, true))
+
+			// Skipped rather than waiting for a block being committed
+			committed := db.CommitForTest()
+			maintained := make(chan error, 1)
+			go func() {
+				maintained <- db.Maintain(eventTables, true)
+			}()
+			select {
+			case err := <-maintained:
+				require.NoError(t, err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("maintenance waited for the block being committed")
+			}
+			committed()
+
+			// Maintenance in the background does not get in the way of commits
+			stop := db.StartMaintenance(eventTables, time.Millisecond, true)
+			for height := uint64(2); height < 5; height++ {
+				eventData.BlockHeight = height
+				require.NoError(t, db.SetBlock(test.ChainID, eventTables, eventData))
+			}
+			stop()
+			height, err := db.LastBlockHeight(test.ChainID)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(4), height)
+		})
+}
+
+func testExportTable(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: exports a table as CSV", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			eventTables, eventData := getBlock()
+			require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+			require.NoError(t, db.SetBlock(test.ChainID, eventTables, eventData))
+
+			buf := new(bytes.Buffer)
+			require.NoError(t, db.ExportTable(context.Background(), "test_table1", buf, sqldb.ExportCSV))
+
+			records, err := csv.NewReader(buf).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, records, 5)
+			assert.Equal(t, []string{"test_id", "col1", "col2", "_height", "col4", "colV", "colT"}, records[0])
+
+			longtext := records[1][5]
+			assert.Equal(t, eventData.Tables["test_table1"][0].RowData["colV"], longtext)
+			height := fmt.Sprint(eventData.BlockHeight)
+			assert.Equal(t, []string{"1", "upd", "upd", height, "upd", longtext, longtext}, records[1])
+			assert.Equal(t, []string{"2", "text21", "text22", height, "24", longtext, longtext}, records[2])
+			assert.Equal(t, []string{"3", "text31", "text32", height, "34", longtext, longtext}, records[3])
+			// Missing values are exported as empty fields
+			assert.Equal(t, []string{"4", "text41", "", height, "", longtext, longtext}, records[4])
+
+			err = db.ExportTable(context.Background(), "test_table1", buf, sqldb.ExportFormat("xml"))
+			require.Error(t, err)
+			err = db.ExportTable(context.Background(), "no_such_table", buf, sqldb.ExportCSV)
+			require.Error(t, err)
+		})
+}
+
+func testExportTableParquet(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: exports a table as Parquet", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			at := time.Date(2019, 3, 4, 10, 30, 15, 0, time.UTC)
+			table := &types.SQLTable{
+				Name: "test_export",
+				Columns: []*types.SQLTableColumn{
+					{Name: "id", Type: types.SQLColumnTypeInt, Primary: true},
+					{Name: "flag", Type: types.SQLColumnTypeBool},
+					{Name: "data", Type: types.SQLColumnTypeByteA},
+					{Name: "amount", Type: types.SQLColumnTypeNumeric},
+					{Name: "at", Type: types.SQLColumnTypeTimeStamp},
+					{Name: "note", Type: types.SQLColumnTypeText},
+				},
+			}
+			eventTables := types.EventTables{table.Name: table}
+			require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+			require.NoError(t, db.SetBlock(test.ChainID, eventTables, types.EventData{
+				BlockHeight: 1,
+				Tables: map[string]types.EventDataTable{
+					table.Name: {
+						{Action: types.ActionUpsert, RowData: map[string]interface{}{"id": 1, "flag": true,
+							"data": []byte{0x00, 0xff}, "amount": "123456789", "at": at, "note": "first"}},
+						// Missing values are exported as nulls
+						{Action: types.ActionUpsert, RowData: map[string]interface{}{"id": 2}},
+					},
+				},
+			}))
+
+			buf := new(bytes.Buffer)
+			require.NoError(t, db.ExportTable(context.Background(), table.Name, buf, sqldb.ExportParquet))
+
+			pr, err := reader.NewParquetColumnReader(newParquetBuffer(buf.Bytes()), 1)
+			require.NoError(t, err)
+			defer pr.ReadStop()
+			require.Equal(t, int64(2), pr.GetNumRows())
+
+			expected := [][]interface{}{
+				{int64(1), int64(2)},
+				{true, nil},
+				{string([]byte{0x00, 0xff}), nil},
+				{"123456789", nil},
+				{at.UnixNano() / int64(time.Microsecond), nil},
+				{"first", nil},
+			}
+			for i, column := range table.Columns {
+				values, _, _, err := pr.ReadColumnByIndex(int64(i), 2)
+				require.NoError(t, err)
+				assert.Equal(t, expected[i], values, "column %s", column.Name)
+			}
+		})
+}
+
+func testRollbackToHeight(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: rolls back rows and checkpoint to a height", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			eventTables := types.EventTables{
+				"rollback": {
+					Name: "test_rollback",
+					Columns: []*types.SQLTableColumn{
+						{Name: "id", Type: types.SQLColumnTypeInt, Primary: true},
+						{Name: "val", Type: types.SQLColumnTypeVarchar, Length: 100},
+						{Name: "_height", Type: types.SQLColumnTypeVarchar, Length: 100},
+					},
+				},
+			}
+			require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+
+			// Heights that order differently as text and as numbers
+			for _, height := range []uint64{9, 10, 11} {
+				eventData := types.EventData{
+					BlockHeight: height,
+					Tables: map[string]types.EventDataTable{
+						"test_rollback": {{Action: types.ActionUpsert, RowData: map[string]interface{}{
+							"id": height, "val": fmt.Sprintf("block %d", height), "_height": fmt.Sprint(height)}}},
+					},
+				}
+				require.NoError(t, db.SetBlock(test.ChainID, eventTables, eventData))
+			}
+
+			require.NoError(t, db.RollbackToHeight(test.ChainID, eventTables, 9))
+
+			_, rows := selectAll(t, db, "test_rollback")
+			require.Len(t, rows, 1)
+			assert.Equal(t, "block 9", rows[0]["val"])
+
+			height, err := db.LastBlockHeight(test.ChainID)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(9), height)
+
+			// The log entries of table creation have no height and are kept
+			_, logRows := selectAll(t, db, db.Tables.Log)
+			var logHeights []string
+			for _, row := range logRows {
+				if row["_height"] != nil {
+					logHeights = append(logHeights, fmt.Sprint(row["_height"]))
+				}
+			}
+			assert.Equal(t, []string{"9"}, logHeights)
+
+			// Rolling back to or above the checkpoint does nothing
+			require.NoError(t, db.RollbackToHeight(test.ChainID, eventTables, 10))
+			height, err = db.LastBlockHeight(test.ChainID)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(9), height)
+
+			// Tables must have a height to be rolled back
+			err = db.RollbackToHeight(test.ChainID, types.EventTables{"noheight": {Name: "test_rollback",
+				Columns: []*types.SQLTableColumn{{Name: "id", Type: types.SQLColumnTypeInt, Primary: true}}}}, 0)
+			require.Error(t, err)
+		})
+}
+
+func testOrphanedColumns(t *testing.T, cfg *config.VentConfig) {
+	legacyTables := types.EventTables{
+		"orphaned": {
+			Name: "test_orphaned",
+			Columns: []*types.SQLTableColumn{
+				{Name: "id", Type: types.SQLColumnTypeInt, Primary: true},
+				{Name: "val", Type: types.SQLColumnTypeVarchar, Length: 100},
+				{Name: "legacy", Type: types.SQLColumnTypeVarchar, Length: 100},
+				{Name: "_height", Type: types.SQLColumnTypeVarchar, Length: 100},
+			},
+		},
+	}
+	// The current projection no longer has the legacy column
+	eventTables := types.EventTables{
+		"orphaned": {
+			Name: "test_orphaned",
+			Columns: []*types.SQLTableColumn{
+				legacyTables["orphaned"].Columns[0],
+				legacyTables["orphaned"].Columns[1],
+				legacyTables["orphaned"].Columns[3],
+			},
+		},
+	}
+
+	setUp := func(t *testing.T, policy types.OrphanedColumnsPolicy) (*sqldb.SQLDB, func()) {
+		db, closeDB := test.NewTestDB(t, cfg)
+		db.OrphanedColumns = policy
+		require.NoError(t, db.SynchronizeDB(test.ChainID, legacyTables))
+		require.NoError(t, db.SetBlock(test.ChainID, legacyTables, types.EventData{
+			BlockHeight: 1,
+			Tables: map[string]types.EventDataTable{
+				"test_orphaned": {{Action: types.ActionUpsert, RowData: map[string]interface{}{
+					"id": 1, "val": "current", "legacy": "old", "_height": "1"}}},
+			},
+		}))
+		return db, closeDB
+	}
+
+	dictionaryColumns := func(t *testing.T, db *sqldb.SQLDB) []string {
+		// language=SQL
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE %s = 'test_orphaned' ORDER BY %s", db.Columns.ColumnName,
+			db.DBAdapter.SchemaName(db.Tables.Dictionary), db.Columns.TableName, db.Columns.ColumnName)
+		rows, err := db.RawDB().Query(query)
+		require.NoError(t, err)
+		defer rows.Close()
+		var names []string
+		for rows.Next() {
+			var name string
+			require.NoError(t, rows.Scan(&name))
+			names = append(names, name)
+		}
+		require.NoError(t, rows.Err())
+		return names
+	}
+
+	t.Run(fmt.Sprintf("%s: warns of orphaned columns and leaves them", cfg.DBAdapter), func(t *testing.T) {
+		db, closeDB := setUp(t, types.WarnOrphanedColumns)
+		defer closeDB()
+
+		require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+		columns, rows := selectAll(t, db, "test_orphaned")
+		assert.Contains(t, columns, "legacy")
+		require.Len(t, rows, 1)
+	})
+
+	t.Run(fmt.Sprintf("%s: errors on orphaned columns", cfg.DBAdapter), func(t *testing.T) {
+		db, closeDB := setUp(t, types.ErrorOrphanedColumns)
+		defer closeDB()
+
+		start := time.Now()
+		err := db.SynchronizeDB(test.ChainID, eventTables)
+		require.Error(t, err)
+		orphanedErr, ok := err.(*sqldb.ErrOrphanedColumns)
+		require.True(t, ok, "expected ErrOrphanedColumns but got %v", err)
+		assert.Equal(t, "test_orphaned", orphanedErr.Table)
+		assert.Equal(t, []string{"legacy"}, orphanedErr.Columns)
+		// The error would recur so is not retried
+		assert.True(t, time.Since(start) < sqldb.SynchronizeBackoff)
+
+		columns, _ := selectAll(t, db, "test_orphaned")
+		assert.Contains(t, columns, "legacy")
+	})
+
+	t.Run(fmt.Sprintf("%s: drops orphaned columns", cfg.DBAdapter), func(t *testing.T) {
+		db, closeDB := setUp(t, types.DropOrphanedColumns)
+		defer closeDB()
+
+		require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+		columns, rows := selectAll(t, db, "test_orphaned")
+		assert.NotContains(t, columns, "legacy")
+		require.Len(t, rows, 1)
+		assert.Equal(t, "current", rows[0]["val"])
+		assert.Equal(t, []string{"_height", "id", "val"}, dictionaryColumns(t, db))
+
+		// The rebuilt table still has its primary key
+		require.NoError(t, db.SetBlock(test.ChainID, eventTables, types.EventData{
+			BlockHeight: 2,
+			Tables: map[string]types.EventDataTable{
+				"test_orphaned": {{Action: types.ActionUpsert, RowData: map[string]interface{}{
+					"id": 1, "val": "updated", "_height": "2"}}},
+			},
+		}))
+		_, rows = selectAll(t, db, "test_orphaned")
+		require.Len(t, rows, 1)
+		assert.Equal(t, "updated", rows[0]["val"])
+
+		// And there is nothing left to drop
+		require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+	})
+}
+
+func testNotNullColumns(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: stores NULL or the zero value for missing values", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+
+			eventTables := types.EventTables{
+				"nullable": {
+					Name: "test_nullable",
+					Columns: []*types.SQLTableColumn{
+						{Name: "id", Type: types.SQLColumnTypeInt, Primary: true},
+						{Name: "nullable", Type: types.SQLColumnTypeInt},
+						{Name: "not_null", Type: types.SQLColumnTypeInt, NotNull: true},
+						{Name: "not_null_text", Type: types.SQLColumnTypeVarchar, Length: 100, NotNull: true},
+					},
+				},
+			}
+			require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+
+			row := func(height uint64, rowData map[string]interface{}) types.EventData {
+				return types.EventData{
+					BlockHeight: height,
+					Tables: map[string]types.EventDataTable{
+						"test_nullable": {{Action: types.ActionUpsert, RowData: rowData}},
+					},
+				}
+			}
+			require.NoError(t, db.SetBlock(test.ChainID, eventTables, row(1, map[string]interface{}{"id": 1})))
+
+			// language=SQL
+			query := fmt.Sprintf("SELECT nullable, not_null, not_null_text FROM %s WHERE id = 1",
+				db.DBAdapter.SchemaName("test_nullable"))
+			var nullable sql.NullInt64
+			var notNull sql.NullInt64
+			var notNullText sql.NullString
+			require.NoError(t, db.RawDB().QueryRow(query).Scan(&nullable, &notNull, &notNullText))
+			assert.False(t, nullable.Valid)
+			assert.Equal(t, sql.NullInt64{Int64: 0, Valid: true}, notNull)
+			assert.Equal(t, sql.NullString{String: "", Valid: true}, notNullText)
+
+			// A later row missing the value does not overwrite it with the zero value
+			require.NoError(t, db.SetBlock(test.ChainID, eventTables, row(2, map[string]interface{}{"id": 1,
+				"nullable": 4, "not_null": 5})))
+			require.NoError(t, db.SetBlock(test.ChainID, eventTables, row(3, map[string]interface{}{"id": 1})))
+			require.NoError(t, db.RawDB().QueryRow(query).Scan(&nullable, &notNull, &notNullText))
+			assert.Equal(t, sql.NullInt64{Int64: 4, Valid: true}, nullable)
+			assert.Equal(t, sql.NullInt64{Int64: 5, Valid: true}, notNull)
+
+			// The database enforces the constraint
+			// language=SQL
+			_, err := db.RawDB().Exec(fmt.Sprintf("INSERT INTO %s (id, not_null) VALUES (2, NULL)",
+				db.DBAdapter.SchemaName("test_nullable")))
+			require.Error(t, err)
+
+			// A NOT NULL column added to a table with rows gives them the zero value
+			eventTables["nullable"].Columns = append(eventTables["nullable"].Columns,
+				&types.SQLTableColumn{Name: "added", Type: types.SQLColumnTypeBool, NotNull: true})
+			require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+			var added bool
+			// language=SQL
+			require.NoError(t, db.RawDB().QueryRow(fmt.Sprintf("SELECT added FROM %s WHERE id = 1",
+				db.DBAdapter.SchemaName("test_nullable"))).Scan(&added))
+			assert.False(t, added)
+		})
+}
+
+func testSetBlockRowConflicts(t *testing.T, cfg *config.VentConfig) {
+	eventTables := types.EventTables{
+		"conflicts": {
+			Name: "test_conflicts",
+			Columns: []*types.SQLTableColumn{
+				{Name: "id", Type: types.SQLColumnTypeInt, Primary: true},
+				{Name: "val", Type: types.SQLColumnTypeVarchar, Length: 100},
+				{Name: "_height", Type: types.SQLColumnTypeVarchar, Length: 100},
+			},
+		},
+	}
+	newRow := func(id int, val string) types.EventDataRow {
+		return types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{
+			"id": id, "val": val, "_height": "7"}}
+	}
+
+	setUp := func(t *testing.T, policy types.RowConflictsPolicy) (*sqldb.SQLDB, types.EventData, func()) {
+		db, closeDB := test.NewTestDB(t, cfg)
+		db.RowConflicts = policy
+		require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+		// A unique index vent does not know about, as might be added by hand
+		// language=SQL
+		_, err := db.RawDB().Exec(fmt.Sprintf("CREATE UNIQUE INDEX test_conflicts_val ON %s (val)",
+			db.DBAdapter.SchemaName("test_conflicts")))
+		require.NoError(t, err)
+
+		eventData := types.EventData{
+			BlockHeight: 7,
+			Tables: map[string]types.EventDataTable{
+				// The second row violates the index
+				"test_conflicts": {newRow(1, "a"), newRow(2, "a"), newRow(3, "b")},
+			},
+		}
+		return db, eventData, closeDB
+	}
+
+	t.Run(fmt.Sprintf("%s: fails a block with a conflicting row", cfg.DBAdapter), func(t *testing.T) {
+		db, eventData, closeDB := setUp(t, types.FailRowConflicts)
+		defer closeDB()
+
+		err := db.SetBlock(test.ChainID, eventTables, eventData)
+		require.Error(t, err)
+		assert.True(t, db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeConstraintViolation), "%v", err)
+
+		_, rows := selectAll(t, db, "test_conflicts")
+		assert.Empty(t, rows)
+		height, err := db.LastBlockHeight(test.ChainID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), height)
+	})
+
+	t.Run(fmt.Sprintf("%s: skips a conflicting row", cfg.DBAdapter), func(t *testing.T) {
+		db, eventData, closeDB := setUp(t, types.SkipRowConflicts)
+		defer closeDB()
+
+		require.NoError(t, db.SetBlock(test.ChainID, eventTables, eventData))
+
+		_, rows := selectAll(t, db, "test_conflicts")
+		require.Len(t, rows, 2)
+		vals := []string{fmt.Sprint(rows[0]["val"]), fmt.Sprint(rows[1]["val"])}
+		sort.Strings(vals)
+		assert.Equal(t, []string{"a", "b"}, vals)
+		assert.Equal(t, types.RowOperationSkipped, eventData.Tables["test_conflicts"][1].Operation)
+		assert.NotEqual(t, types.RowOperationSkipped, eventData.Tables["test_conflicts"][2].Operation)
+
+		height, err := db.LastBlockHeight(test.ChainID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(7), height)
+	})
+}
+
+func testSetBlockMaxRowsPerCommit(t *testing.T, cfg *config.VentConfig) {
+	t.Run(fmt.Sprintf("%s: only advances the height with the last transaction of a split block", cfg.DBAdapter),
+		func(t *testing.T) {
+			db, closeDB := test.NewTestDB(t, cfg)
+			defer closeDB()
+			db.MaxRowsPerCommit = 2
+
+			eventTables := types.EventTables{
+				"batches": {
+					Name: "test_batches",
+					Columns: []*types.SQLTableColumn{
+						{Name: "id", Type: types.SQLColumnTypeInt, Primary: true},
+						{Name: "val", Type: types.SQLColumnTypeVarchar, Length: 100},
+						{Name: "_height", Type: types.SQLColumnTypeVarchar, Length: 100},
+					},
+				},
+				"batch_totals": {
+					Name: "test_batch_totals",
+					Columns: []*types.SQLTableColumn{
+						{Name: "id", Type: types.SQLColumnTypeInt, Primary: true},
+						{Name: "rows", Type: types.SQLColumnTypeBigInt, NotNull: true, Aggregate: types.AggregateCount},
+						{Name: "_height", Type: types.SQLColumnTypeVarchar, Length: 100},
+					},
+				},
+			}
+			require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+			// language=SQL
+			_, err := db.RawDB().Exec(fmt.Sprintf("CREATE UNIQUE INDEX test_batches_val ON %s (val)",
+				db.DBAdapter.SchemaName("test_batches")))
+			require.NoError(t, err)
+
+			// Committed in transactions of rows 1-2, 3-4, 5-6, and 7 with the accumulated total, the fifth row
+			// violates the index so the third transaction fails
+			vals := []string{"a", "b", "c", "d", "a", "e", "f"}
+			batches := make(types.EventDataTable, len(vals))
+			for i, val := range vals {
+				batches[i] = types.EventDataRow{Action: types.ActionUpsert, RowData: map[string]interface{}{
+					"id": i + 1, "val": val, "_height": "7"}}
+			}
+			eventData := types.EventData{
+				BlockHeight: 7,
+				Tables: map[string]types.EventDataTable{
+					"test_batches": batches,
+					"test_batch_totals": {{Action: types.ActionAccumulate, RowData: map[string]interface{}{
+						"id": 1, "rows": int64(len(vals)), "_height": "7"}}},
+				},
+			}
+
+			require.Error(t, db.SetBlock(test.ChainID, eventTables, eventData))
+			// The transactions before the failure are committed but the block is not
+			_, rows := selectAll(t, db, "test_batches")
+			assert.Len(t, rows, 4)
+			_, rows = selectAll(t, db, "test_batch_totals")
+			assert.Empty(t, rows)
+			height, err := db.LastBlockHeight(test.ChainID)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(0), height)
+
+			// Once the cause is fixed the whole block is committed again
+			// language=SQL
+			_, err = db.RawDB().Exec("DROP INDEX " + db.DBAdapter.SchemaName("test_batches_val"))
+			require.NoError(t, err)
+			require.NoError(t, db.SetBlock(test.ChainID, eventTables, eventData))
+
+			_, rows = selectAll(t, db, "test_batches")
+			require.Len(t, rows, len(vals))
+			_, rows = selectAll(t, db, "test_batch_totals")
+			require.Len(t, rows, 1)
+			assert.Equal(t, fmt.Sprint(len(vals)), fmt.Sprint(rows[0]["rows"]))
+			height, err = db.LastBlockHeight(test.ChainID)
+			require.NoError(t, err)
+			assert.Equal(t, uint64(7), height)
+		})
+}
+
+func testSetBlockTx(t *testing.T, cfg *config.VentConfig) {
+	eventTables := types.EventTables{
+		"test_tx_rows": {
+			Name: "test_tx_rows",
+			Columns: []*types.SQLTableColumn{
+				{Name: "id", Type: types.SQLColumnTypeInt, Primary: true},
+				{Name: "_height", Type: types.SQLColumnTypeVarchar, Length: 100},
+			},
+		},
+	}
+	eventData := types.EventData{
+		BlockHeight: 7,
+		Tables: map[string]types.EventDataTable{
+			"test_tx_rows": {
+				{Action: types.ActionUpsert, RowData: map[string]interface{}{"id": 1, "_height": "7"}},
+				{Action: types.ActionUpsert, RowData: map[string]interface{}{"id": 2, "_height": "7"}},
+			},
+		},
+	}
+	setUp := func(t *testing.T) (*sqldb.SQLDB, func()) {
+		db, closeDB := test.NewTestDB(t, cfg)
+		require.NoError(t, db.SynchronizeDB(test.ChainID, eventTables))
+		// A table of the caller's own side effects
+		// language=SQL
+		_, err := db.RawDB().Exec(fmt.Sprintf("CREATE TABLE %s (height INTEGER)",
+			db.DBAdapter.SchemaName("test_side_effects")))
+		require.NoError(t, err)
+		return db, closeDB
+	}
+	// Writes the block and a side effect in a transaction of the caller's
+	setBlockTx := func(t *testing.T, db *sqldb.SQLDB) *sqlx.Tx {
+		tx, err := db.DB.Beginx()
+		require.NoError(t, err)
+		require.NoError(t, db.SetBlockTx(tx, test.ChainID, eventTables, eventData))
+		// language=SQL
+		_, err = tx.Exec(db.DB.Rebind(fmt.Sprintf("INSERT INTO %s (height) VALUES (?)",
+			db.DBAdapter.SchemaName("test_side_effects"))), eventData.BlockHeight)
+		require.NoError(t, err)
+		return tx
+	}
+
+	t.Run(fmt.Sprintf("%s: writes nothing when the caller rolls back", cfg.DBAdapter), func(t *testing.T) {
+		db, closeDB := setUp(t)
+		defer closeDB()
+
+		require.NoError(t, setBlockTx(t, db).Rollback())
+
+		_, rows := selectAll(t, db, "test_tx_rows")
+		assert.Empty(t, rows)
+		_, rows = selectAll(t, db, "test_side_effects")
+		assert.Empty(t, rows)
+		height, err := db.LastBlockHeight(test.ChainID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), height)
+	})
+
+	t.Run(fmt.Sprintf("%s: commits the block with the caller's writes", cfg.DBAdapter), func(t *testing.T) {
+		db, closeDB := setUp(t)
+		defer closeDB()
+
+		require.NoError(t, setBlockTx(t, db).Commit())
+
+		_, rows := selectAll(t, db, "test_tx_rows")
+		assert.Len(t, rows, 2)
+		_, rows = selectAll(t, db, "test_side_effects")
+		require.Len(t, rows, 1)
+		assert.Equal(t, "7", fmt.Sprint(rows[0]["height"]))
+		height, err := db.LastBlockHeight(test.ChainID)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(7), height)
+	})
+}
+
+// parquetBuffer is a Parquet file held in memory for reading back an export
+type parquetBuffer struct {
+	*bytes.Reader
+	data []byte
+}
+
+func newParquetBuffer(data []byte) *parquetBuffer {
+	return &parquetBuffer{Reader: bytes.NewReader(data), data: data}
+}
+
+func (pb *parquetBuffer) Write(p []byte) (int, error) {
+	return 0, fmt.Errorf("parquetBuffer is read only")
+}
+
+func (pb *parquetBuffer) Close() error {
+	return nil
+}
+
+func (pb *parquetBuffer) Open(name string) (source.ParquetFile, error) {
+	return newParquetBuffer(pb.data), nil
+}
+
+func (pb *parquetBuffer) Create(name string) (source.ParquetFile, error) {
+	return nil, fmt.Errorf("parquetBuffer is read only")
+}
+
 func getBlock() (types.EventTables, types.EventData) {
 	longtext := "qwertyuiopasdfghjklzxcvbnm1234567890QWERTYUIOPASDFGHJKLZXCVBNM"
 	longtext = fmt.Sprintf("%s %s %s %s %s", longtext, longtext, longtext, longtext, longtext)
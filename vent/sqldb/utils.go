@@ -1,9 +1,11 @@
 package sqldb
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -14,11 +16,11 @@ import (
 	"encoding/json"
 
 	"github.com/hyperledger/burrow/vent/types"
+	"github.com/jmoiron/sqlx"
 )
 
 const maxUint64 uint64 = (1 << 64) - 1
 
-var tables = types.DefaultSQLTableNames
 var columns = types.DefaultSQLColumnNames
 
 // findTable checks if a table exists in the default schema
@@ -45,8 +47,8 @@ func (db *SQLDB) findTable(tableName string) (bool, error) {
 // getSysTablesDefinition returns log, chain info & dictionary structures
 func (db *SQLDB) getSysTablesDefinition() types.EventTables {
 	return types.EventTables{
-		tables.Log: {
-			Name: tables.Log,
+		db.Tables.Log: {
+			Name: db.Tables.Log,
 			Columns: []*types.SQLTableColumn{
 				{
 					Name:    columns.Id,
@@ -112,8 +114,8 @@ func (db *SQLDB) getSysTablesDefinition() types.EventTables {
 			},
 			NotifyChannels: map[string][]string{types.BlockHeightLabel: {columns.Height}},
 		},
-		tables.Dictionary: {
-			Name: tables.Dictionary,
+		db.Tables.Dictionary: {
+			Name: db.Tables.Dictionary,
 			Columns: []*types.SQLTableColumn{
 				{
 					Name:    columns.TableName,
@@ -149,8 +151,8 @@ func (db *SQLDB) getSysTablesDefinition() types.EventTables {
 				},
 			},
 		},
-		tables.ChainInfo: {
-			Name: tables.ChainInfo,
+		db.Tables.ChainInfo: {
+			Name: db.Tables.ChainInfo,
 			Columns: []*types.SQLTableColumn{
 				{
 					Name:    columns.ChainID,
@@ -233,13 +235,11 @@ func (db *SQLDB) getTableDef(tableName string) (*types.SQLTable, error) {
 	return table, nil
 }
 
-// alterTable alters the structure of a SQL table & add info to the dictionary
+// alterTable alters the structure of a SQL table & add info to the dictionary. Columns missing from the table are
+// added in the order they are declared in the spec.
 func (db *SQLDB) alterTable(chainID string, table *types.SQLTable) error {
 	db.Log.InfoMsg("Altering table", "value", table.Name)
 
-	// prepare log query
-	logQuery := db.DBAdapter.InsertLogQuery()
-
 	// current table structure
 	safeTable := safe(table.Name)
 	currentTable, err := db.getTableDef(safeTable)
@@ -247,8 +247,6 @@ func (db *SQLDB) alterTable(chainID string, table *types.SQLTable) error {
 		return err
 	}
 
-	sqlValues, _ := getJSON(nil)
-
 	// for each column in the new table structure
 	for order, newColumn := range table.Columns {
 		found := false
@@ -263,46 +261,18 @@ func (db *SQLDB) alterTable(chainID string, table *types.SQLTable) error {
 		}
 
 		if !found {
-			safeCol := safe(newColumn.Name)
-			query, dictionary := db.DBAdapter.AlterColumnQuery(safeTable, safeCol, newColumn.Type, newColumn.Length, order)
-
-			//alter column
-			db.Log.InfoMsg("ALTER TABLE", "query", safe(query))
-			_, err = db.DB.Exec(safe(query))
-
+			err = db.alterColumn(chainID, table, newColumn, order)
 			if err != nil {
-				if db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeDuplicatedColumn) {
-					db.Log.InfoMsg("Duplicate column", "value", safeCol)
-				} else {
-					db.Log.InfoMsg("Error altering table", "err", err)
-					return err
-				}
-			} else {
-				//store dictionary
-				db.Log.InfoMsg("STORE DICTIONARY", "query", dictionary)
-				_, err = db.DB.Exec(dictionary)
-				if err != nil {
-					db.Log.InfoMsg("Error storing  dictionary", "err", err)
-					return err
-				}
-
-				// Marshal the table into a JSON string.
-				var jsonData []byte
-				jsonData, err = getJSON(newColumn)
-				if err != nil {
-					db.Log.InfoMsg("error marshaling column", "err", err, "value", fmt.Sprintf("%v", newColumn))
-					return err
-				}
-				//insert log
-				_, err = db.DB.Exec(logQuery, chainID, table.Name, "", "", nil, nil, types.ActionAlterTable, jsonData, query, sqlValues)
-				if err != nil {
-					db.Log.InfoMsg("Error inserting log", "err", err)
-					return err
-				}
+				return err
 			}
 		}
 	}
 
+	err = db.handleOrphanedColumns(chainID, table)
+	if err != nil {
+		return err
+	}
+
 	// Ensure triggers are defined
 	err = db.createTableTriggers(table)
 	if err != nil {
@@ -312,7 +282,162 @@ func (db *SQLDB) alterTable(chainID string, table *types.SQLTable) error {
 	return nil
 }
 
-// createTable creates a new table
+// alterColumn adds a column to a SQL table along with its dictionary and log entries in a single transaction
+func (db *SQLDB) alterColumn(chainID string, table *types.SQLTable, newColumn *types.SQLTableColumn, order int) error {
+	safeTable := safe(table.Name)
+	safeCol := safe(newColumn.Name)
+	query, dictionary := db.DBAdapter.AlterColumnQuery(safeTable, safeCol, newColumn.Type, newColumn.Length, order,
+		newColumn.NotNull)
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		db.Log.InfoMsg("Error beginning transaction", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	//alter column
+	db.Log.InfoMsg("ALTER TABLE", "query", safe(query))
+	_, err = tx.Exec(safe(query))
+	if err != nil {
+		if !db.DBAdapter.ErrorEquals(err, types.SQLErrorTypeDuplicatedColumn) {
+			db.Log.InfoMsg("Error altering table", "err", err)
+			return err
+		}
+		// The column exists but is missing from the dictionary (left by a synchronization that predates the
+		// transaction above) so back-fill the dictionary outside the aborted transaction
+		db.Log.InfoMsg("Duplicate column", "value", safeCol)
+		err = tx.Rollback()
+		if err != nil {
+			return err
+		}
+		db.Log.InfoMsg("STORE DICTIONARY", "query", dictionary)
+		_, err = db.DB.Exec(dictionary)
+		if err != nil {
+			db.Log.InfoMsg("Error storing  dictionary", "err", err)
+			return err
+		}
+		return nil
+	}
+
+	//store dictionary
+	db.Log.InfoMsg("STORE DICTIONARY", "query", dictionary)
+	_, err = tx.Exec(dictionary)
+	if err != nil {
+		db.Log.InfoMsg("Error storing  dictionary", "err", err)
+		return err
+	}
+
+	// Marshal the table into a JSON string.
+	jsonData, err := getJSON(newColumn)
+	if err != nil {
+		db.Log.InfoMsg("error marshaling column", "err", err, "value", fmt.Sprintf("%v", newColumn))
+		return err
+	}
+	sqlValues, _ := getJSON(nil)
+
+	//insert log
+	_, err = tx.Exec(db.DBAdapter.InsertLogQuery(), chainID, table.Name, "", "", nil, nil, types.ActionAlterTable,
+		jsonData, query, sqlValues)
+	if err != nil {
+		db.Log.InfoMsg("Error inserting log", "err", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// ErrOrphanedColumns is returned by SynchronizeDB under ErrorOrphanedColumns when a table has columns that are not in
+// the projection
+type ErrOrphanedColumns struct {
+	Table   string
+	Columns []string
+}
+
+func (e *ErrOrphanedColumns) Error() string {
+	return fmt.Sprintf("table %s has column(s) not in the projection: %s", e.Table, strings.Join(e.Columns, ", "))
+}
+
+// handleOrphanedColumns applies the OrphanedColumns policy to the columns of the table in the database that are not
+// in its spec
+func (db *SQLDB) handleOrphanedColumns(chainID string, table *types.SQLTable) error {
+	// Look at the table itself since columns may have been added behind vent's back
+	liveColumns, err := db.liveColumns(table.Name)
+	if err != nil {
+		return err
+	}
+	for _, column := range table.Columns {
+		delete(liveColumns, column.Name)
+	}
+	if len(liveColumns) == 0 {
+		return nil
+	}
+	orphaned := make([]string, 0, len(liveColumns))
+	for name := range liveColumns {
+		orphaned = append(orphaned, name)
+	}
+	sort.Strings(orphaned)
+
+	switch db.OrphanedColumns {
+	case types.WarnOrphanedColumns, "":
+		db.Log.InfoMsg("Table has columns not in the projection", "value", table.Name,
+			"columns", strings.Join(orphaned, ", "))
+		return nil
+	case types.ErrorOrphanedColumns:
+		return &ErrOrphanedColumns{Table: table.Name, Columns: orphaned}
+	case types.DropOrphanedColumns:
+		return db.dropColumns(chainID, table, orphaned)
+	default:
+		return fmt.Errorf("unknown orphaned columns policy %s", db.OrphanedColumns)
+	}
+}
+
+// dropColumns drops columns from a SQL table along with their dictionary entries and logs the change in a single
+// transaction
+func (db *SQLDB) dropColumns(chainID string, table *types.SQLTable, columnNames []string) error {
+	query, dictionary := db.DBAdapter.DropColumnsQuery(table, columnNames)
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		db.Log.InfoMsg("Error beginning transaction", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
+	db.Log.InfoMsg("DROP COLUMNS", "query", query)
+	_, err = tx.Exec(query)
+	if err != nil {
+		db.Log.InfoMsg("Error dropping columns", "err", err)
+		return fmt.Errorf("could not drop column(s) %s of table %s: %v", strings.Join(columnNames, ", "),
+			table.Name, err)
+	}
+
+	db.Log.InfoMsg("DELETE DICTIONARY", "query", dictionary)
+	_, err = tx.Exec(dictionary)
+	if err != nil {
+		db.Log.InfoMsg("Error deleting dictionary", "err", err)
+		return err
+	}
+
+	jsonData, err := getJSON(columnNames)
+	if err != nil {
+		db.Log.InfoMsg("error marshaling columns", "err", err, "value", fmt.Sprintf("%v", columnNames))
+		return err
+	}
+	sqlValues, _ := getJSON(nil)
+
+	//insert log
+	_, err = tx.Exec(db.DBAdapter.InsertLogQuery(), chainID, table.Name, "", "", nil, nil, types.ActionAlterTable,
+		jsonData, query, sqlValues)
+	if err != nil {
+		db.Log.InfoMsg("Error inserting log", "err", err)
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// createTable creates a new table with columns in the order they are declared in the spec
 func (db *SQLDB) createTable(chainID string, table *types.SQLTable, isInitialise bool) error {
 	db.Log.InfoMsg("Creating Table", "value", table.Name)
 
@@ -327,27 +452,30 @@ func (db *SQLDB) createTable(chainID string, table *types.SQLTable, isInitialise
 		return errors.New("empty CREATE TABLE query")
 	}
 
+	// The table is only found (via the dictionary) once the table, its dictionary and log entries are all stored so
+	// that an interrupted synchronization is not left with a table it will try to create again
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		db.Log.InfoMsg("Error beginning transaction", "err", err)
+		return err
+	}
+	defer tx.Rollback()
+
 	// create table
 	db.Log.InfoMsg("CREATE TABLE", "query", query)
-	_, err := db.DB.Exec(query)
+	_, err = tx.Exec(query)
 	if err != nil {
 		return err
 	}
 
 	//store dictionary
 	db.Log.InfoMsg("STORE DICTIONARY", "query", dictionary)
-	_, err = db.DB.Exec(dictionary)
+	_, err = tx.Exec(dictionary)
 	if err != nil {
 		db.Log.InfoMsg("Error storing  dictionary", "err", err)
 		return err
 	}
 
-	err = db.createTableTriggers(table)
-	if err != nil {
-		db.Log.InfoMsg("error creating notification triggers", "err", err, "value", fmt.Sprintf("%v", table))
-		return fmt.Errorf("could not create table notification triggers: %v", err)
-	}
-
 	//insert log (if action is not database initialization)
 	if !isInitialise {
 		// Marshal the table into a JSON string.
@@ -360,12 +488,25 @@ func (db *SQLDB) createTable(chainID string, table *types.SQLTable, isInitialise
 		sqlValues, _ := getJSON(nil)
 
 		//insert log
-		_, err = db.DB.Exec(logQuery, chainID, table.Name, "", "", nil, nil, types.ActionCreateTable, jsonData, query, sqlValues)
+		_, err = tx.Exec(logQuery, chainID, table.Name, "", "", nil, nil, types.ActionCreateTable, jsonData, query, sqlValues)
 		if err != nil {
 			db.Log.InfoMsg("Error inserting log", "err", err)
 			return err
 		}
 	}
+
+	err = tx.Commit()
+	if err != nil {
+		db.Log.InfoMsg("Error committing transaction", "err", err)
+		return err
+	}
+
+	// Triggers are replaced each time a table is synchronized so need not be part of the transaction
+	err = db.createTableTriggers(table)
+	if err != nil {
+		db.Log.InfoMsg("error creating notification triggers", "err", err, "value", fmt.Sprintf("%v", table))
+		return fmt.Errorf("could not create table notification triggers: %v", err)
+	}
 	return nil
 }
 
@@ -374,7 +515,14 @@ func (db *SQLDB) createTableTriggers(table *types.SQLTable) error {
 	// If the adapter supports notification triggers
 	dbNotify, ok := db.DBAdapter.(adapters.DBNotifyTriggerAdapter)
 	if ok {
-		for channel, columns := range table.NotifyChannels {
+		// In a stable order so that the same spec always produces the same DDL
+		channels := make([]string, 0, len(table.NotifyChannels))
+		for channel := range table.NotifyChannels {
+			channels = append(channels, channel)
+		}
+		sort.Strings(channels)
+		for _, channel := range channels {
+			columns := table.NotifyChannels[channel]
 			function := fmt.Sprintf("%s_%s_notify_function", table.Name, channel)
 
 			query := dbNotify.CreateNotifyFunctionQuery(function, channel, columns...)
@@ -459,6 +607,47 @@ func (db *SQLDB) getBlockTables(chainid string, height uint64) (types.EventTable
 	return tables, nil
 }
 
+// scaleAmounts sets the scaled column of each amount of row whose decimals are looked up in another table, reading
+// the decimals in tx so that those written earlier in the same block are seen. An amount whose token has no decimals
+// recorded keeps the value given to its scaled column by the row builder.
+func (db *SQLDB) scaleAmounts(tx *sqlx.Tx, row *types.EventDataRow) error {
+	if row.EventClass == nil {
+		return nil
+	}
+	for _, mapping := range row.EventClass.FieldMappings {
+		if mapping.Amount == nil || mapping.Amount.DecimalsLookup == nil {
+			continue
+		}
+		value, ok := row.RowData[mapping.ColumnName]
+		if !ok || value == nil {
+			continue
+		}
+		lookup := mapping.Amount.DecimalsLookup
+		query := adapters.Cleanf("SELECT %s FROM %s WHERE %s = $1;",
+			db.DBAdapter.SecureName(lookup.DecimalsColumnName), db.DBAdapter.SchemaName(lookup.TableName),
+			db.DBAdapter.SecureName(lookup.KeyColumnName))
+		var decimals sql.NullString
+		err := tx.QueryRow(query, row.RowData[lookup.TokenColumnName]).Scan(&decimals)
+		if err == sql.ErrNoRows || (err == nil && !decimals.Valid) {
+			db.Log.InfoMsg("No decimals found for amount", "table", lookup.TableName,
+				"token", row.RowData[lookup.TokenColumnName])
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("could not look up decimals of field %s: %v", mapping.Field, err)
+		}
+		n, err := strconv.Atoi(decimals.String)
+		if err != nil {
+			return fmt.Errorf("decimals %s of field %s are not an integer: %v", decimals.String, mapping.Field, err)
+		}
+		row.RowData[mapping.Amount.ScaledColumnName], err = mapping.Amount.Scaled(value, n)
+		if err != nil {
+			return fmt.Errorf("could not scale field %s: %v", mapping.Field, err)
+		}
+	}
+	return nil
+}
+
 // safe sanitizes a parameter
 func safe(parameter string) string {
 	replacer := strings.NewReplacer(";", "", ",", "")
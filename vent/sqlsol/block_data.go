@@ -6,7 +6,11 @@ import (
 	"github.com/hyperledger/burrow/vent/types"
 )
 
-// BlockData contains EventData definition
+// BlockData contains EventData definition. The rows of each table are committed in the order they are added, which
+// for a block is: the block row, then for each transaction in turn (in the order of the block, each followed by the
+// transactions of its proposal, if any) its tx row, the rows of its call, and the rows of its events in event index
+// order, then the rows of the rollups. So where several rows upsert the same key the row of the later transaction or
+// event is the one left in the table.
 type BlockData struct {
 	Data types.EventData
 }
@@ -23,7 +27,7 @@ func NewBlockData(height uint64) *BlockData {
 	}
 }
 
-// AddRow appends a row to a specific table name in structure
+// AddRow appends a row to a specific table name in structure, after any rows already added to that table
 func (b *BlockData) AddRow(tableName string, row types.EventDataRow) {
 	if _, ok := b.Data.Tables[tableName]; !ok {
 		b.Data.Tables[tableName] = types.EventDataTable{}
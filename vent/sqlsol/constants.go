@@ -2,5 +2,4 @@ package sqlsol
 
 import "github.com/hyperledger/burrow/vent/types"
 
-var tables = types.DefaultSQLTableNames
 var columns = types.DefaultSQLColumnNames
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/hyperledger/burrow/execution/evm/abi"
 	"github.com/hyperledger/burrow/vent/types"
 	"github.com/pkg/errors"
 	"github.com/xeipuuv/gojsonschema"
@@ -102,7 +103,13 @@ func NewProjectionFromEventSpec(eventSpec types.EventSpec) (*Projection, error)
 		channels := make(map[string][]string)
 
 		// Add the global mappings
-		eventClass.FieldMappings = append(globalFieldMappings, eventClass.FieldMappings...)
+		if eventClass.Raw {
+			eventClass.FieldMappings = append(getRawFieldMappings(), eventClass.FieldMappings...)
+		} else if eventClass.Governance {
+			eventClass.FieldMappings = append(getGovernanceFieldMappings(), eventClass.FieldMappings...)
+		} else {
+			eventClass.FieldMappings = append(globalFieldMappings, eventClass.FieldMappings...)
+		}
 
 		i := 0
 		for _, mapping := range eventClass.FieldMappings {
@@ -110,6 +117,40 @@ func NewProjectionFromEventSpec(eventSpec types.EventSpec) (*Projection, error)
 			if err != nil {
 				return nil, err
 			}
+			if mapping.BigInt != nil {
+				sqlType, sqlTypeLength, err = getBigIntSQLType(mapping)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if mapping.StringFromBytes32 {
+				sqlType, sqlTypeLength, err = getStringFromBytes32SQLType(mapping)
+				if err != nil {
+					return nil, err
+				}
+			}
+			if mapping.Amount != nil {
+				if err := checkAmount(mapping); err != nil {
+					return nil, err
+				}
+				columns = append(columns, &types.SQLTableColumn{
+					Name:    mapping.Amount.ScaledColumnName,
+					Type:    types.SQLColumnTypeNumeric,
+					NotNull: mapping.NotNull,
+				})
+			}
+			if mapping.Enum != nil {
+				if mapping.Enum.LabelColumnName == "" {
+					// The label replaces the code
+					sqlType, sqlTypeLength = types.SQLColumnTypeText, 0
+				} else {
+					columns = append(columns, &types.SQLTableColumn{
+						Name:    mapping.Enum.LabelColumnName,
+						Type:    types.SQLColumnTypeText,
+						NotNull: mapping.NotNull,
+					})
+				}
+			}
 
 			i++
 
@@ -123,6 +164,7 @@ func NewProjectionFromEventSpec(eventSpec types.EventSpec) (*Projection, error)
 				Type:    sqlType,
 				Primary: mapping.Primary,
 				Length:  sqlTypeLength,
+				NotNull: mapping.NotNull,
 			})
 		}
 
@@ -140,6 +182,34 @@ func NewProjectionFromEventSpec(eventSpec types.EventSpec) (*Projection, error)
 
 	}
 
+	// add the rollup tables of each event class now that the tables they aggregate are complete
+	for _, eventClass := range eventSpec {
+		for _, rollup := range eventClass.Rollups {
+			if _, ok := tables[rollup.TableName]; ok {
+				return nil, fmt.Errorf("rollup table %s of table %s has the name of another table", rollup.TableName,
+					eventClass.TableName)
+			}
+			table, err := newRollupTable(tables[eventClass.TableName], rollup)
+			if err != nil {
+				return nil, err
+			}
+			tables[rollup.TableName] = table
+		}
+	}
+
+	// check the decimals lookups now that the tables they read are complete
+	for _, eventClass := range eventSpec {
+		for _, mapping := range eventClass.FieldMappings {
+			if mapping.Amount == nil || mapping.Amount.DecimalsLookup == nil {
+				continue
+			}
+			err := checkDecimalsLookup(tables, eventClass.TableName, mapping)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	// check if there are duplicated duplicated column names (for a given table)
 	colName := make(map[string]int)
 
@@ -158,6 +228,62 @@ func NewProjectionFromEventSpec(eventSpec types.EventSpec) (*Projection, error)
 	}, nil
 }
 
+// newRollupTable returns the table of rollup of the rows of table, which is keyed by the bucket and the columns the
+// rows are grouped by and records the height of the last block accumulated into each row
+func newRollupTable(table *types.SQLTable, rollup *types.Rollup) (*types.SQLTable, error) {
+	rollupColumns := []*types.SQLTableColumn{{
+		Name:    columns.Bucket,
+		Type:    types.SQLColumnTypeTimeStamp,
+		Primary: true,
+	}}
+	for _, columnName := range rollup.GroupBy {
+		column := table.GetColumn(columnName)
+		if column == nil {
+			return nil, fmt.Errorf("rollup %s groups by column %s that table %s does not have", rollup.TableName,
+				columnName, table.Name)
+		}
+		rollupColumns = append(rollupColumns, &types.SQLTableColumn{
+			Name:    column.Name,
+			Type:    column.Type,
+			Primary: true,
+			Length:  column.Length,
+		})
+	}
+	for _, aggregate := range rollup.Aggregates {
+		column := &types.SQLTableColumn{
+			Name:      aggregate.As,
+			Type:      types.SQLColumnTypeNumeric,
+			Aggregate: aggregate.Function,
+		}
+		if aggregate.Function == types.AggregateCount {
+			column.Type = types.SQLColumnTypeBigInt
+			column.NotNull = true
+		} else {
+			aggregated := table.GetColumn(aggregate.ColumnName)
+			if aggregated == nil {
+				return nil, fmt.Errorf("rollup %s aggregates column %s that table %s does not have",
+					rollup.TableName, aggregate.ColumnName, table.Name)
+			}
+			if !aggregated.Type.IsNumeric() {
+				return nil, fmt.Errorf("rollup %s cannot %s column %s of table %s since it is not numeric",
+					rollup.TableName, aggregate.Function, aggregated.Name, table.Name)
+			}
+		}
+		rollupColumns = append(rollupColumns, column)
+	}
+	// The height of the last block accumulated into each row so that a block committed again (for example when it is
+	// replayed after a restart) is not accumulated twice
+	rollupColumns = append(rollupColumns, &types.SQLTableColumn{
+		Name: columns.Height,
+		Type: types.SQLColumnTypeBigInt,
+	})
+	return &types.SQLTable{
+		Name:           rollup.TableName,
+		NotifyChannels: make(map[string][]string),
+		Columns:        rollupColumns,
+	}, nil
+}
+
 // Get the column for a particular table and column name
 func (p *Projection) GetColumn(tableName, columnName string) (*types.SQLTableColumn, error) {
 	if table, ok := p.Tables[tableName]; ok {
@@ -172,6 +298,96 @@ func (p *Projection) GetColumn(tableName, columnName string) (*types.SQLTableCol
 	return nil, fmt.Errorf("GetColumn: table does not exist projection: %s ", tableName)
 }
 
+// CheckAbi returns an error listing the names of any events or functions referenced by the projection's event
+// classes that are not contained in abiSpec
+func (p *Projection) CheckAbi(abiSpec *abi.AbiSpec) error {
+	var unresolved, unresolvedFunctions []string
+	seen := make(map[string]bool)
+	seenFunctions := make(map[string]bool)
+	for _, eventClass := range p.EventSpec {
+		if name := eventClass.FunctionName; name != "" && !seenFunctions[name] {
+			seenFunctions[name] = true
+			if abiSpec == nil {
+				unresolvedFunctions = append(unresolvedFunctions, name)
+			} else if _, ok := abiSpec.Functions[name]; !ok {
+				unresolvedFunctions = append(unresolvedFunctions, name)
+			}
+		}
+		name := eventClass.EventName
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		if abiSpec == nil {
+			unresolved = append(unresolved, name)
+			continue
+		}
+		if _, ok := abiSpec.Events[name]; !ok {
+			unresolved = append(unresolved, name)
+		}
+	}
+	if len(unresolved) > 0 {
+		return fmt.Errorf("projection references events not found in ABI: %s", strings.Join(unresolved, ", "))
+	}
+	if len(unresolvedFunctions) > 0 {
+		return fmt.Errorf("projection references functions not found in ABI: %s",
+			strings.Join(unresolvedFunctions, ", "))
+	}
+
+	// The Field of each mapping names the argument whose value is stored in its column so must be one of them
+	globalFields := make(map[string]bool)
+	for _, mapping := range getGlobalFieldMappings() {
+		globalFields[mapping.Field] = true
+	}
+	var unmapped []string
+	for _, eventClass := range p.EventSpec {
+		source, args, err := decodedArguments(eventClass, abiSpec)
+		if err != nil {
+			return err
+		}
+		if source == "" {
+			continue
+		}
+		argNames := make(map[string]bool, len(args))
+		names := make([]string, len(args))
+		for i, arg := range args {
+			argNames[arg.Name] = true
+			names[i] = arg.Name
+		}
+		for _, mapping := range eventClass.FieldMappings {
+			if !globalFields[mapping.Field] && !argNames[mapping.Field] {
+				unmapped = append(unmapped, fmt.Sprintf("field %s of table %s is not an argument of %s (which has "+
+					"arguments: %s)", mapping.Field, eventClass.TableName, source, strings.Join(names, ", ")))
+			}
+		}
+	}
+	if len(unmapped) > 0 {
+		return fmt.Errorf("projection maps fields not found in ABI: %s", strings.Join(unmapped, "; "))
+	}
+	return nil
+}
+
+// decodedArguments returns a description of the event or function whose arguments are decoded for eventClass along
+// with the arguments, or an empty description if they are not known until an event is matched (because the class
+// matches events by its filter alone or is raw)
+func decodedArguments(eventClass *types.EventClass, abiSpec *abi.AbiSpec) (string, []abi.Argument, error) {
+	switch {
+	case eventClass.FunctionName != "":
+		return "function " + eventClass.FunctionName, abiSpec.Functions[eventClass.FunctionName].Inputs, nil
+	case eventClass.AnonymousEvent != nil:
+		eventSpec, err := eventClass.AnonymousEvent.EventSpec()
+		if err != nil {
+			return "", nil, fmt.Errorf("could not get spec of anonymous event %s: %v", eventClass.AnonymousEvent.Name,
+				err)
+		}
+		return "anonymous event " + eventSpec.Name, eventSpec.Inputs, nil
+	case eventClass.EventName != "":
+		return "event " + eventClass.EventName, abiSpec.Events[eventClass.EventName].Inputs, nil
+	default:
+		return "", nil, nil
+	}
+}
+
 func ValidateJSONEventSpec(bs []byte) error {
 	schemaLoader := gojsonschema.NewGoLoader(types.EventSpecSchema())
 	specLoader := gojsonschema.NewBytesLoader(bs)
@@ -255,11 +471,36 @@ func getSQLType(evmSignature string, bytesToString bool) (types.SQLColumnType, i
 		} else {
 			return types.SQLColumnTypeNumeric, 0, nil
 		}
+	case evmSignature == types.EventFieldTypeTimestamp:
+		return types.SQLColumnTypeTimeStamp, 0, nil
 	default:
 		return -1, 0, fmt.Errorf("do not know how to map evmSignature: %s ", evmSignature)
 	}
 }
 
+// getBigIntSQLType maps an integer event field to the SQL column type of its declared big integer representation
+func getBigIntSQLType(mapping *types.EventFieldMapping) (types.SQLColumnType, int, error) {
+	evmSignature := strings.ToLower(mapping.Type)
+	if !strings.HasPrefix(evmSignature, types.EventFieldTypeInt) && !strings.HasPrefix(evmSignature, types.EventFieldTypeUInt) {
+		return -1, 0, fmt.Errorf("BigInt given for field %s but type %s is not an integer type", mapping.Field,
+			mapping.Type)
+	}
+	if mapping.Enum != nil {
+		return -1, 0, fmt.Errorf("field %s cannot be both an Enum and a BigInt", mapping.Field)
+	}
+	switch mapping.BigInt.As {
+	case types.BigIntString:
+		// Allow for the sign
+		return types.SQLColumnTypeVarchar, types.BigIntStringLength + 1, nil
+	case types.BigIntNumeric, types.BigIntDecimal:
+		// Unconstrained precision and scale
+		return types.SQLColumnTypeNumeric, 0, nil
+	default:
+		return -1, 0, fmt.Errorf("unknown big integer representation '%s' for field %s", mapping.BigInt.As,
+			mapping.Field)
+	}
+}
+
 // getGlobalColumns returns global columns for event table structures,
 // these columns will be part of every SQL event table to relate data with source events
 func getGlobalFieldMappings() []*types.EventFieldMapping {
@@ -292,6 +533,84 @@ func getGlobalFieldMappings() []*types.EventFieldMapping {
 	}
 }
 
+// getEventKeyedFieldMappings returns the global field mappings along with the index of the event within its
+// transaction, with which the transaction hash keys each row
+func getEventKeyedFieldMappings() []*types.EventFieldMapping {
+	mappings := getGlobalFieldMappings()
+	for _, mapping := range mappings {
+		if mapping.ColumnName == columns.TxHash {
+			mapping.Primary = true
+		}
+	}
+	return append(mappings, &types.EventFieldMapping{
+		ColumnName: columns.EventIndex,
+		Field:      types.EventIndexLabel,
+		Type:       types.EventFieldTypeString,
+		Primary:    true,
+	})
+}
+
+// getRawFieldMappings returns the global field mappings along with the topics and data of the undecoded events of a
+// raw event class, each row is keyed by the transaction hash and the index of the event within the transaction
+func getRawFieldMappings() []*types.EventFieldMapping {
+	mappings := getEventKeyedFieldMappings()
+	topicColumns := []string{columns.Topic0, columns.Topic1, columns.Topic2, columns.Topic3}
+	for i, topicLabel := range types.TopicLabels {
+		mappings = append(mappings, &types.EventFieldMapping{
+			ColumnName: topicColumns[i],
+			Field:      topicLabel,
+			Type:       types.EventFieldTypeString,
+		})
+	}
+	return append(mappings, &types.EventFieldMapping{
+		ColumnName: columns.Data,
+		Field:      types.DataLabel,
+		Type:       types.EventFieldTypeString,
+	})
+}
+
+// getGovernanceFieldMappings returns the global field mappings along with the account updated by the governance
+// events of a governance event class and what it was updated to, each row is keyed by the transaction hash and the
+// index of the event within the transaction
+func getGovernanceFieldMappings() []*types.EventFieldMapping {
+	return append(getEventKeyedFieldMappings(),
+		&types.EventFieldMapping{
+			ColumnName: columns.AccountAddress,
+			Field:      types.AccountAddressLabel,
+			Type:       types.EventFieldTypeAddress,
+		},
+		&types.EventFieldMapping{
+			ColumnName: columns.AccountName,
+			Field:      types.AccountNameLabel,
+			Type:       types.EventFieldTypeString,
+		},
+		&types.EventFieldMapping{
+			ColumnName: columns.PublicKey,
+			Field:      types.PublicKeyLabel,
+			Type:       types.EventFieldTypeString,
+		},
+		&types.EventFieldMapping{
+			ColumnName: columns.Power,
+			Field:      types.PowerLabel,
+			Type:       "uint64",
+		},
+		&types.EventFieldMapping{
+			ColumnName: columns.Balance,
+			Field:      types.BalanceLabel,
+			Type:       "uint64",
+		},
+		&types.EventFieldMapping{
+			ColumnName: columns.Permissions,
+			Field:      types.PermissionsLabel,
+			Type:       types.EventFieldTypeString,
+		},
+		&types.EventFieldMapping{
+			ColumnName: columns.Roles,
+			Field:      types.RolesLabel,
+			Type:       types.EventFieldTypeString,
+		})
+}
+
 // Merges tables a and b provided the intersection of their columns (by name) are identical
 func mergeTables(tables ...*types.SQLTable) (*types.SQLTable, error) {
 	table := &types.SQLTable{
@@ -338,3 +657,52 @@ func mergeTables(tables ...*types.SQLTable) (*types.SQLTable, error) {
 
 	return table, nil
 }
+
+// checkAmount checks that an Amount is given for an integer event field that has not already been replaced by a label
+// or a decimal
+func checkAmount(mapping *types.EventFieldMapping) error {
+	evmSignature := strings.ToLower(mapping.Type)
+	if !strings.HasPrefix(evmSignature, types.EventFieldTypeInt) && !strings.HasPrefix(evmSignature, types.EventFieldTypeUInt) {
+		return fmt.Errorf("Amount given for field %s but type %s is not an integer type", mapping.Field,
+			mapping.Type)
+	}
+	if mapping.Enum != nil || (mapping.BigInt != nil && mapping.BigInt.As == types.BigIntDecimal) {
+		return fmt.Errorf("field %s cannot be an Amount and also an Enum or Decimal BigInt", mapping.Field)
+	}
+	return nil
+}
+
+// checkDecimalsLookup checks that the columns read by the decimals lookup of the Amount of mapping, a field of
+// tableName, are in the tables of the projection
+func checkDecimalsLookup(tables types.EventTables, tableName string, mapping *types.EventFieldMapping) error {
+	lookup := mapping.Amount.DecimalsLookup
+	if tables[tableName].GetColumn(lookup.TokenColumnName) == nil {
+		return fmt.Errorf("decimals lookup of field %s reads token column %s but table %s has no such column",
+			mapping.Field, lookup.TokenColumnName, tableName)
+	}
+	table, ok := tables[lookup.TableName]
+	if !ok {
+		return fmt.Errorf("decimals lookup of field %s reads table %s which is not in the projection", mapping.Field,
+			lookup.TableName)
+	}
+	for _, columnName := range []string{lookup.KeyColumnName, lookup.DecimalsColumnName} {
+		if table.GetColumn(columnName) == nil {
+			return fmt.Errorf("decimals lookup of field %s reads column %s but table %s has no such column",
+				mapping.Field, columnName, lookup.TableName)
+		}
+	}
+	return nil
+}
+
+// getStringFromBytes32SQLType returns the SQL type of a bytes32 column stored as a string, which is text since the hex
+// fallback for an invalid string is longer than any valid one
+func getStringFromBytes32SQLType(mapping *types.EventFieldMapping) (types.SQLColumnType, int, error) {
+	if strings.ToLower(mapping.Type) != types.EventFieldTypeBytes+"32" {
+		return -1, 0, fmt.Errorf("StringFromBytes32 given for field %s but type %s is not bytes32", mapping.Field,
+			mapping.Type)
+	}
+	if mapping.BigInt != nil || mapping.Enum != nil {
+		return -1, 0, fmt.Errorf("field %s cannot be StringFromBytes32 and also an Enum or BigInt", mapping.Field)
+	}
+	return types.SQLColumnTypeText, 0, nil
+}
@@ -1,8 +1,10 @@
 package sqlsol_test
 
 import (
+	"fmt"
 	"testing"
 
+	"github.com/hyperledger/burrow/execution/evm/abi"
 	"github.com/hyperledger/burrow/vent/sqlsol"
 	"github.com/hyperledger/burrow/vent/test"
 	"github.com/hyperledger/burrow/vent/types"
@@ -225,3 +227,65 @@ func TestNewProjectionFromEventSpec(t *testing.T) {
 	_, err = sqlsol.NewProjectionFromEventSpec(eventSpec)
 	require.Error(t, err)
 }
+
+func TestCheckAbi(t *testing.T) {
+	abiSpec, err := abi.ReadAbiSpec(test.Abi_EventsTest)
+	require.NoError(t, err)
+
+	newProjection := func(eventNames ...string) *sqlsol.Projection {
+		var eventSpec types.EventSpec
+		for i, eventName := range eventNames {
+			eventSpec = append(eventSpec, &types.EventClass{
+				TableName: fmt.Sprintf("Table%d", i),
+				Filter:    "Log1Text = 'EVENT'",
+				EventName: eventName,
+				FieldMappings: []*types.EventFieldMapping{
+					{Field: "name", ColumnName: "name", Type: types.EventFieldTypeString, Primary: true},
+				},
+			})
+		}
+		projection, err := sqlsol.NewProjectionFromEventSpec(eventSpec)
+		require.NoError(t, err)
+		return projection
+	}
+
+	t.Run("passes when all referenced events are in the ABI", func(t *testing.T) {
+		require.NoError(t, newProjection("UpdateTestEvents", "DeleteTestEvents", "").CheckAbi(abiSpec))
+	})
+
+	t.Run("lists events missing from the ABI", func(t *testing.T) {
+		err := newProjection("UpdateTestEvents", "NoSuchEvent", "AlsoMissing", "NoSuchEvent").CheckAbi(abiSpec)
+		require.Error(t, err)
+		require.Equal(t, "projection references events not found in ABI: NoSuchEvent, AlsoMissing", err.Error())
+	})
+
+	t.Run("lists functions missing from the ABI", func(t *testing.T) {
+		projection := newProjection("")
+		projection.EventSpec[0].FunctionName = "addThing"
+		projection.EventSpec[0].GetFieldMapping("name").Field = "_name"
+		require.NoError(t, projection.CheckAbi(abiSpec))
+		projection.EventSpec[0].FunctionName = "addNothing"
+		err := projection.CheckAbi(abiSpec)
+		require.Error(t, err)
+		require.Equal(t, "projection references functions not found in ABI: addNothing", err.Error())
+	})
+
+	t.Run("passes when fields are arguments of their events", func(t *testing.T) {
+		projection := newProjection("UpdateTestEvents")
+		// Stored under its own name by default or under a column of another name
+		projection.EventSpec[0].FieldMappings = append(projection.EventSpec[0].FieldMappings,
+			&types.EventFieldMapping{Field: "description", ColumnName: "description", Type: types.EventFieldTypeString},
+			&types.EventFieldMapping{Field: "key", ColumnName: "recipient", Type: types.EventFieldTypeString})
+		require.NoError(t, projection.CheckAbi(abiSpec))
+	})
+
+	t.Run("lists fields that are not arguments of their events", func(t *testing.T) {
+		projection := newProjection("UpdateTestEvents")
+		projection.EventSpec[0].FieldMappings = append(projection.EventSpec[0].FieldMappings,
+			&types.EventFieldMapping{Field: "recipient", ColumnName: "key", Type: types.EventFieldTypeString})
+		err := projection.CheckAbi(abiSpec)
+		require.Error(t, err)
+		require.Equal(t, "projection maps fields not found in ABI: field recipient of table Table0 is not an argument "+
+			"of event UpdateTestEvents (which has arguments: name, key, description)", err.Error())
+	})
+}
@@ -21,6 +21,12 @@ const (
 
 // SpecLoader loads spec files and parses them
 func SpecLoader(specFileOrDirs []string, opts SpecOpt) (*Projection, error) {
+	return SpecLoaderWithTableNames(specFileOrDirs, opts, types.DefaultSQLTableNames)
+}
+
+// SpecLoaderWithTableNames loads spec files and parses them, naming any block and tx tables according to tableNames
+func SpecLoaderWithTableNames(specFileOrDirs []string, opts SpecOpt, tableNames types.SQLTableNames) (*Projection,
+	error) {
 	var projection *Projection
 	var err error
 
@@ -35,12 +41,12 @@ func SpecLoader(specFileOrDirs []string, opts SpecOpt) (*Projection, error) {
 
 	// add block & tx to tables definition
 	if Block&opts > 0 {
-		for k, v := range blockTables() {
+		for k, v := range blockTables(tableNames.Block) {
 			projection.Tables[k] = v
 		}
 	}
 	if Tx&opts > 0 {
-		for k, v := range txTables() {
+		for k, v := range txTables(tableNames.Tx) {
 			projection.Tables[k] = v
 		}
 	}
@@ -49,10 +55,10 @@ func SpecLoader(specFileOrDirs []string, opts SpecOpt) (*Projection, error) {
 }
 
 // getBlockTxTablesDefinition returns block & transaction structures
-func blockTables() types.EventTables {
+func blockTables(tableName string) types.EventTables {
 	return types.EventTables{
-		tables.Block: &types.SQLTable{
-			Name: tables.Block,
+		tableName: &types.SQLTable{
+			Name: tableName,
 			Columns: []*types.SQLTableColumn{
 				{
 					Name:    columns.Height,
@@ -70,10 +76,62 @@ func blockTables() types.EventTables {
 	}
 }
 
-func txTables() types.EventTables {
+// DeadLetterTables returns the structure of the table in which events that could not be decoded into rows of their
+// projection tables are kept by the dead-letter row error policy
+func DeadLetterTables(tableName string) types.EventTables {
+	return types.EventTables{
+		tableName: &types.SQLTable{
+			Name: tableName,
+			Columns: []*types.SQLTableColumn{
+				{
+					Name:    columns.Height,
+					Type:    types.SQLColumnTypeVarchar,
+					Length:  100,
+					Primary: true,
+				},
+				{
+					Name:    columns.TxHash,
+					Type:    types.SQLColumnTypeVarchar,
+					Length:  txs.HashLengthHex,
+					Primary: true,
+				},
+				{
+					Name:    columns.EventIndex,
+					Type:    types.SQLColumnTypeVarchar,
+					Length:  100,
+					Primary: true,
+				},
+				// The projection table the event was meant for, an event may match several event classes
+				{
+					Name:    columns.TableName,
+					Type:    types.SQLColumnTypeVarchar,
+					Length:  100,
+					Primary: true,
+				},
+				{
+					Name:    columns.EventFilter,
+					Type:    types.SQLColumnTypeText,
+					Primary: false,
+				},
+				{
+					Name:    columns.Error,
+					Type:    types.SQLColumnTypeText,
+					Primary: false,
+				},
+				{
+					Name:    columns.Event,
+					Type:    types.SQLColumnTypeJSON,
+					Primary: false,
+				},
+			},
+		},
+	}
+}
+
+func txTables(tableName string) types.EventTables {
 	return types.EventTables{
-		tables.Tx: &types.SQLTable{
-			Name: tables.Tx,
+		tableName: &types.SQLTable{
+			Name: tableName,
 			Columns: []*types.SQLTableColumn{
 				// transaction table
 				{
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hyperledger/burrow/bcm"
+	"github.com/hyperledger/burrow/event/query"
+	"github.com/hyperledger/burrow/execution/exec"
+	"github.com/hyperledger/burrow/rpc"
+	"github.com/hyperledger/burrow/rpc/rpcevents"
+	"github.com/hyperledger/burrow/rpc/rpcquery"
+	"google.golang.org/grpc"
+)
+
+// FakeBurrow stands in for the gRPC services of a Burrow node that a vent consumer uses, serving predefined blocks
+// so that the consumer can be run in-process. Give its QueryClient and ExecutionEventsClient to the consumer. Streams
+// end once the blocks in their range have been sent rather than waiting for more blocks.
+type FakeBurrow struct {
+	sync.Mutex
+	chainID string
+	blocks  []*exec.BlockExecution
+	// Heights left out of the next stream opened
+	skip map[uint64]bool
+}
+
+// NewFakeBurrow returns a FakeBurrow for chainID serving blocks, which must be in ascending order of height
+func NewFakeBurrow(chainID string, blocks ...*exec.BlockExecution) *FakeBurrow {
+	return &FakeBurrow{
+		chainID: chainID,
+		blocks:  blocks,
+	}
+}
+
+// AddBlocks appends blocks to be served by streams opened from now on
+func (fb *FakeBurrow) AddBlocks(blocks ...*exec.BlockExecution) {
+	fb.Lock()
+	defer fb.Unlock()
+	fb.blocks = append(fb.blocks, blocks...)
+}
+
+// SkipInNextStream leaves the blocks at heights out of the next stream opened, as a node might if blocks were lost
+// across a reconnect, while later streams serve them as normal
+func (fb *FakeBurrow) SkipInNextStream(heights ...uint64) {
+	fb.Lock()
+	defer fb.Unlock()
+	fb.skip = make(map[uint64]bool)
+	for _, height := range heights {
+		fb.skip[height] = true
+	}
+}
+
+// QueryClient returns a client of the query service that implements only Status, other methods panic
+func (fb *FakeBurrow) QueryClient() rpcquery.QueryClient {
+	return &fakeQueryClient{burrow: fb}
+}
+
+// ExecutionEventsClient returns a client of the execution events service that implements only Stream, other methods
+// panic
+func (fb *FakeBurrow) ExecutionEventsClient() rpcevents.ExecutionEventsClient {
+	return &fakeExecutionEventsClient{burrow: fb}
+}
+
+func (fb *FakeBurrow) latestBlockHeight() uint64 {
+	fb.Lock()
+	defer fb.Unlock()
+	if len(fb.blocks) == 0 {
+		return 0
+	}
+	return fb.blocks[len(fb.blocks)-1].Height
+}
+
+type fakeQueryClient struct {
+	rpcquery.QueryClient
+	burrow *FakeBurrow
+}
+
+func (fqc *fakeQueryClient) Status(ctx context.Context, in *rpcquery.StatusParam,
+	opts ...grpc.CallOption) (*rpc.ResultStatus, error) {
+	return &rpc.ResultStatus{
+		ChainID:       fqc.burrow.chainID,
+		BurrowVersion: BurrowVersion,
+		SyncInfo: &bcm.SyncInfo{
+			LatestBlockHeight: fqc.burrow.latestBlockHeight(),
+		},
+	}, nil
+}
+
+type fakeExecutionEventsClient struct {
+	rpcevents.ExecutionEventsClient
+	burrow *FakeBurrow
+}
+
+func (feec *fakeExecutionEventsClient) Stream(ctx context.Context, in *rpcevents.BlocksRequest,
+	opts ...grpc.CallOption) (rpcevents.ExecutionEvents_StreamClient, error) {
+	qry, err := query.NewOrEmpty(in.Query)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse TxExecution query: %v", err)
+	}
+	fb := feec.burrow
+	// The end is exclusive
+	start, end, _ := in.BlockRange.Bounds(fb.latestBlockHeight())
+	fb.Lock()
+	defer fb.Unlock()
+	skip := fb.skip
+	fb.skip = nil
+	var events exec.StreamEvents
+	for _, block := range fb.blocks {
+		if block.Height < start || block.Height >= end || skip[block.Height] {
+			continue
+		}
+		for _, ev := range block.StreamEvents() {
+			if qry.Matches(ev.Tagged()) {
+				events = append(events, ev)
+			}
+		}
+	}
+	return &fakeStreamClient{ctx: ctx, events: events}, nil
+}
+
+// fakeStreamClient returns its events from Recv followed by io.EOF, only Recv, Context, and CloseSend are implemented
+type fakeStreamClient struct {
+	grpc.ClientStream
+	ctx    context.Context
+	events exec.StreamEvents
+}
+
+func (fsc *fakeStreamClient) Recv() (*exec.StreamEvent, error) {
+	if err := fsc.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return fsc.events.Recv()
+}
+
+func (fsc *fakeStreamClient) Context() context.Context {
+	return fsc.ctx
+}
+
+func (fsc *fakeStreamClient) CloseSend() error {
+	return nil
+}
@@ -39,11 +39,18 @@ func NewTestDB(t *testing.T, cfg *config.VentConfig) (*sqldb.SQLDB, func()) {
 	}
 
 	connection := types.SQLConnection{
-		DBAdapter: cfg.DBAdapter,
-		DBURL:     cfg.DBURL,
-		DBSchema:  cfg.DBSchema,
+		DBAdapter:  cfg.DBAdapter,
+		DBURL:      cfg.DBURL,
+		DBSchema:   cfg.DBSchema,
+		TableNames: cfg.SQLTableNames,
 
 		Log: logging.NewNoopLogger(),
+
+		SkipCreateSchema: cfg.DBSkipCreateSchema,
+		SetSearchPath:    cfg.DBSetSearchPath,
+		OrphanedColumns:  cfg.DBOrphanedColumns,
+		RowConflicts:     cfg.DBRowConflicts,
+		MaxRowsPerCommit: cfg.DBMaxRowsPerCommit,
 	}
 
 	db, err := sqldb.NewSQLDB(connection)
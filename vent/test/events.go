@@ -43,6 +43,39 @@ func CreateContract(t testing.TB, cli rpctransact.TransactClient, inputAddress c
 	return txe
 }
 
+// CreateProxyContract deploys a contract that forwards the data of every call it receives to target, so that calling
+// the proxy with the same data as target makes target's events be emitted by an internal call
+func CreateProxyContract(t testing.TB, cli rpctransact.TransactClient, inputAddress,
+	target crypto.Address) *exec.TxExecution {
+	t.Helper()
+
+	// CALLDATACOPY the call data to memory then CALL target with it and all remaining gas
+	runtime := append([]byte{0x36, 0x60, 0x00, 0x60, 0x00, 0x37, 0x60, 0x00, 0x60, 0x00, 0x36, 0x60, 0x00, 0x60, 0x00,
+		0x73}, target.Bytes()...)
+	runtime = append(runtime, 0x5a, 0xf1, 0x50, 0x00)
+	// CODECOPY the runtime code that follows these 12 bytes and RETURN it
+	n := byte(len(runtime))
+	bytecode := append([]byte{0x60, n, 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, n, 0x60, 0x00, 0xf3}, runtime...)
+
+	txe, err := cli.CallTxSync(context.Background(), &payload.CallTx{
+		Input: &payload.TxInput{
+			Address: inputAddress,
+			Amount:  2,
+		},
+		Address:  nil,
+		Data:     bytecode,
+		Fee:      2,
+		GasLimit: 10000,
+	})
+	require.NoError(t, err)
+
+	if txe.Exception != nil {
+		t.Fatalf("call should not generate exception but returned: %v", txe.Exception.Error())
+	}
+
+	return txe
+}
+
 func CallRemoveEvent(t testing.TB, cli rpctransact.TransactClient, inputAddress, contractAddress crypto.Address,
 	name string) *exec.TxExecution {
 	return Call(t, cli, inputAddress, contractAddress, "removeThing", name)
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/burrow/vent/types"
+)
+
+// MemorySink is an in-memory stand in for the SQL database to which a vent consumer commits blocks (it implements
+// service.Sink). It keeps every block committed and the current rows of each table, with upserts replacing, accumulated
+// rows combining with, and deletes removing the row with the same primary key, so that tests can run the consumer
+// without a database. Closing the sink only records that it was closed so that it can be given to another consumer to
+// test resuming.
+type MemorySink struct {
+	sync.Mutex
+	blocks  []types.EventData
+	heights map[string]uint64
+	// table name -> primary key -> row
+	rows   map[string]map[string]map[string]interface{}
+	closed bool
+}
+
+// NewMemorySink returns an empty MemorySink
+func NewMemorySink() *MemorySink {
+	return &MemorySink{
+		heights: make(map[string]uint64),
+		rows:    make(map[string]map[string]map[string]interface{}),
+	}
+}
+
+func (ms *MemorySink) SetBlock(chainID string, eventTables types.EventTables, eventData types.EventData) error {
+	ms.Lock()
+	defer ms.Unlock()
+	for tableName, table := range eventData.Tables {
+		rows := ms.rows[tableName]
+		if rows == nil {
+			rows = make(map[string]map[string]interface{})
+			ms.rows[tableName] = rows
+		}
+		for i, row := range table {
+			key := primaryKey(eventTables[tableName], row.RowData)
+			if key == "" {
+				// Without a primary key every row is kept
+				key = fmt.Sprintf("%d/%d", eventData.BlockHeight, i)
+			}
+			switch row.Action {
+			case types.ActionUpsert:
+				rows[key] = row.RowData
+			case types.ActionAccumulate:
+				accumulated, err := accumulate(eventTables[tableName], rows[key], row.RowData)
+				if err != nil {
+					return fmt.Errorf("MemorySink cannot accumulate row of table %s: %v", tableName, err)
+				}
+				rows[key] = accumulated
+			case types.ActionDelete:
+				delete(rows, key)
+			default:
+				return fmt.Errorf("MemorySink cannot %s row of table %s", row.Action, tableName)
+			}
+		}
+	}
+	ms.blocks = append(ms.blocks, eventData)
+	ms.heights[chainID] = eventData.BlockHeight
+	return nil
+}
+
+// LastBlockHeight returns the height of the last block committed for chainID, which is where a consumer resumes
+func (ms *MemorySink) LastBlockHeight(chainID string) (uint64, error) {
+	ms.Lock()
+	defer ms.Unlock()
+	return ms.heights[chainID], nil
+}
+
+func (ms *MemorySink) Close() {
+	ms.Lock()
+	defer ms.Unlock()
+	ms.closed = true
+}
+
+// Closed returns whether the sink has been closed, as a consumer does when Run returns
+func (ms *MemorySink) Closed() bool {
+	ms.Lock()
+	defer ms.Unlock()
+	return ms.closed
+}
+
+// Blocks returns the blocks committed so far in the order they were committed
+func (ms *MemorySink) Blocks() []types.EventData {
+	ms.Lock()
+	defer ms.Unlock()
+	return append([]types.EventData(nil), ms.blocks...)
+}
+
+// Rows returns the current rows of the named table ordered by primary key
+func (ms *MemorySink) Rows(tableName string) []map[string]interface{} {
+	ms.Lock()
+	defer ms.Unlock()
+	rows := ms.rows[tableName]
+	keys := make([]string, 0, len(rows))
+	for key := range rows {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	ordered := make([]map[string]interface{}, len(keys))
+	for i, key := range keys {
+		ordered[i] = rows[key]
+	}
+	return ordered
+}
+
+// Returns row with the value of each aggregate column of table combined with that of the existing row (if any)
+func accumulate(table *types.SQLTable, existing, row map[string]interface{}) (map[string]interface{}, error) {
+	if existing == nil || table == nil {
+		return row, nil
+	}
+	// As for a SQL DB a block committed again is not accumulated again
+	if height, ok := row[types.DefaultSQLColumnNames.Height].(uint64); ok {
+		if existingHeight, ok := existing[types.DefaultSQLColumnNames.Height].(uint64); ok && existingHeight >= height {
+			return existing, nil
+		}
+	}
+	accumulated := make(map[string]interface{}, len(row))
+	for columnName, value := range row {
+		accumulated[columnName] = value
+	}
+	for _, column := range table.Columns {
+		if column.Aggregate == "" {
+			continue
+		}
+		value, err := column.Aggregate.Combine(existing[column.Name], row[column.Name])
+		if err != nil {
+			return nil, err
+		}
+		accumulated[column.Name] = value
+	}
+	return accumulated, nil
+}
+
+// Returns the values of the primary key columns of table in row, or the empty string if the table has none
+func primaryKey(table *types.SQLTable, row map[string]interface{}) string {
+	if table == nil {
+		return ""
+	}
+	var values []string
+	for _, column := range table.Columns {
+		if column.Primary {
+			values = append(values, fmt.Sprint(row[column.Name]))
+		}
+	}
+	return strings.Join(values, "/")
+}
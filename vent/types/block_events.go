@@ -0,0 +1,132 @@
+package types
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// NewBlockEvents returns the protobuf message of the rows of eventData committed for chainID. Tables and the columns
+// of each row are ordered by name so that the same rows always encode to the same bytes.
+func NewBlockEvents(chainID string, eventData EventData) (*BlockEvents, error) {
+	tableNames := make([]string, 0, len(eventData.Tables))
+	for tableName := range eventData.Tables {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+
+	blockEvents := &BlockEvents{
+		ChainID:     chainID,
+		BlockHeight: eventData.BlockHeight,
+		Tables:      make([]*TableRows, len(tableNames)),
+	}
+	for i, tableName := range tableNames {
+		table := eventData.Tables[tableName]
+		tableRows := &TableRows{
+			Name: tableName,
+			Rows: make([]*Row, len(table)),
+		}
+		for j, row := range table {
+			columnNames := make([]string, 0, len(row.RowData))
+			for columnName := range row.RowData {
+				columnNames = append(columnNames, columnName)
+			}
+			sort.Strings(columnNames)
+			r := &Row{
+				Action:  string(row.Action),
+				Columns: make([]*Column, len(columnNames)),
+			}
+			for k, columnName := range columnNames {
+				value, err := NewValue(row.RowData[columnName])
+				if err != nil {
+					return nil, fmt.Errorf("could not encode column %s of table %s at height %d: %v", columnName,
+						tableName, eventData.BlockHeight, err)
+				}
+				r.Columns[k] = &Column{Name: columnName, Value: value}
+			}
+			tableRows.Rows[j] = r
+		}
+		blockEvents.Tables[i] = tableRows
+	}
+	return blockEvents, nil
+}
+
+// EventData returns the rows of the block with the column values given by Value.Interface
+func (be *BlockEvents) EventData() EventData {
+	eventData := EventData{
+		BlockHeight: be.BlockHeight,
+		Tables:      make(map[string]EventDataTable, len(be.Tables)),
+	}
+	for _, tableRows := range be.Tables {
+		table := make(EventDataTable, len(tableRows.Rows))
+		for i, row := range tableRows.Rows {
+			rowData := make(map[string]interface{}, len(row.Columns))
+			for _, column := range row.Columns {
+				rowData[column.Name] = column.Value.Interface()
+			}
+			table[i] = EventDataRow{Action: DBAction(row.Action), RowData: rowData}
+		}
+		eventData.Tables[tableRows.Name] = table
+	}
+	return eventData
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// NewValue returns the Value of a column value of a row, or nil for a nil value. Pointers are dereferenced, integers
+// are stored as Int or Uint according to their signedness, and strings, bools, byte slices, floats, and times as the
+// kind of the same name. Other types cannot be stored in a Value.
+func NewValue(value interface{}) (*Value, error) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	switch rv.Kind() {
+	case reflect.String:
+		return &Value{Kind: &Value_Text{Text: rv.String()}}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Value{Kind: &Value_Int{Int: rv.Int()}}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Value{Kind: &Value_Uint{Uint: rv.Uint()}}, nil
+	case reflect.Bool:
+		return &Value{Kind: &Value_Bool{Bool: rv.Bool()}}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Value{Kind: &Value_Float{Float: rv.Float()}}, nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return &Value{Kind: &Value_Bytes{Bytes: rv.Bytes()}}, nil
+		}
+	case reflect.Struct:
+		if rv.Type() == timeType {
+			t := rv.Interface().(time.Time)
+			return &Value{Kind: &Value_Time{Time: &t}}, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot store value %v of type %T", value, value)
+}
+
+// Interface returns the Go value held: a string, int64, uint64, bool, float64, []byte, time.Time, or nil
+func (m *Value) Interface() interface{} {
+	switch kind := m.GetKind().(type) {
+	case *Value_Text:
+		return kind.Text
+	case *Value_Int:
+		return kind.Int
+	case *Value_Uint:
+		return kind.Uint
+	case *Value_Bool:
+		return kind.Bool
+	case *Value_Float:
+		return kind.Float
+	case *Value_Bytes:
+		return kind.Bytes
+	case *Value_Time:
+		return *kind.Time
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,83 @@
+package types
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockEvents(t *testing.T) {
+	blockTime := time.Date(2019, 6, 1, 12, 30, 0, 42, time.UTC)
+	enabled := true
+	eventData := EventData{
+		BlockHeight: 42,
+		Tables: map[string]EventDataTable{
+			"Transfers": {
+				{
+					Action: ActionUpsert,
+					RowData: map[string]interface{}{
+						"_height":   "42",
+						"id":        uint64(7),
+						"delta":     -3,
+						"rate":      0.5,
+						"enabled":   &enabled,
+						"memo":      []byte{1, 2, 3},
+						"_time":     blockTime,
+						"recipient": nil,
+					},
+				},
+				{
+					Action:  ActionDelete,
+					RowData: map[string]interface{}{"id": uint64(8)},
+				},
+			},
+			"Empty": {},
+		},
+	}
+
+	blockEvents, err := NewBlockEvents("test-chain", eventData)
+	require.NoError(t, err)
+	assert.Equal(t, "test-chain", blockEvents.ChainID)
+	require.Len(t, blockEvents.Tables, 2)
+	assert.Equal(t, "Empty", blockEvents.Tables[0].Name)
+
+	bs, err := blockEvents.Marshal()
+	require.NoError(t, err)
+	decoded := new(BlockEvents)
+	require.NoError(t, decoded.Unmarshal(bs))
+	assert.Equal(t, "test-chain", decoded.ChainID)
+
+	// Values come back in the widest type of their kind
+	actual := decoded.EventData()
+	assert.Equal(t, uint64(42), actual.BlockHeight)
+	assert.Len(t, actual.Tables["Empty"], 0)
+	transfers := actual.Tables["Transfers"]
+	require.Len(t, transfers, 2)
+	assert.Equal(t, ActionUpsert, transfers[0].Action)
+	assert.Equal(t, map[string]interface{}{
+		"_height":   "42",
+		"id":        uint64(7),
+		"delta":     int64(-3),
+		"rate":      0.5,
+		"enabled":   true,
+		"memo":      []byte{1, 2, 3},
+		"_time":     blockTime,
+		"recipient": nil,
+	}, transfers[0].RowData)
+	assert.Equal(t, ActionDelete, transfers[1].Action)
+	assert.Equal(t, map[string]interface{}{"id": uint64(8)}, transfers[1].RowData)
+
+	// Encodings are deterministic
+	again, err := NewBlockEvents("test-chain", eventData)
+	require.NoError(t, err)
+	bsAgain, err := again.Marshal()
+	require.NoError(t, err)
+	assert.Equal(t, bs, bsAgain)
+
+	_, err = NewBlockEvents("test-chain", EventData{Tables: map[string]EventDataTable{
+		"Bad": {{Action: ActionUpsert, RowData: map[string]interface{}{"map": map[string]string{}}}},
+	}})
+	require.Error(t, err)
+}
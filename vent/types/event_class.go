@@ -1,9 +1,16 @@
 package types
 
 import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
 	"github.com/alecthomas/jsonschema"
 	validation "github.com/go-ozzo/ozzo-validation"
 	"github.com/hyperledger/burrow/event/query"
+	"github.com/hyperledger/burrow/execution/evm/abi"
 )
 
 // EventSpec contains all event class specifications
@@ -19,11 +26,28 @@ type EventClass struct {
 	TableName string
 	// Burrow event filter query in query peg grammar
 	Filter string
+	// The name of the ABI event this class projects, when given it is checked against the ABI at startup
+	EventName string `json:",omitempty"`
 	// The name of a solidity event field that when present indicates that the rest of the event should be interpreted
 	// as requesting a row deletion (rather than upsert) in the projection table.
 	DeleteMarkerField string `json:",omitempty"`
 	// EventFieldMapping from solidity event field name to EventFieldMapping descriptor
-	FieldMappings []*EventFieldMapping
+	FieldMappings []*EventFieldMapping `json:",omitempty"`
+	// The layout of the anonymous event matched by Filter, which is used to decode it in place of the ABI
+	AnonymousEvent *AnonymousEvent `json:",omitempty"`
+	// Store the events matched by Filter without decoding them, as their topics and data in hex, so that contracts
+	// can be indexed without an ABI. FieldMappings are optional for a raw event class.
+	Raw bool `json:",omitempty"`
+	// The name of an ABI function whose calls are projected in place of events. Filter is then matched against the
+	// tags of each transaction (rather than its events) and the arguments of a matched call transaction whose input
+	// calls the function are decoded with the ABI. Only calls made directly by a transaction are seen.
+	FunctionName string `json:",omitempty"`
+	// Store the governance events matched by Filter, which record the accounts updated by a GovTx including changes to
+	// the power of validators, with fixed columns for the account and what it was updated to. FieldMappings are
+	// optional for a governance event class.
+	Governance bool `json:",omitempty"`
+	// Tables of aggregates of the rows of TableName over buckets of block time that are updated as each block commits
+	Rollups []*Rollup `json:",omitempty"`
 	// Memoised lookup/query
 	query  query.Query
 	fields map[string]*EventFieldMapping
@@ -31,10 +55,32 @@ type EventClass struct {
 
 // Validate checks the structure of an EventClass
 func (ec *EventClass) Validate() error {
+	fieldMappingRules := []validation.Rule{validation.Required, validation.Length(1, 0)}
+	if ec.Raw {
+		if ec.AnonymousEvent != nil || ec.EventName != "" || ec.DeleteMarkerField != "" {
+			return fmt.Errorf("raw event class for table %s cannot have an AnonymousEvent, EventName, or "+
+				"DeleteMarkerField since its events are not decoded", ec.TableName)
+		}
+		fieldMappingRules = nil
+	}
+	if ec.Governance {
+		if ec.Raw || ec.AnonymousEvent != nil || ec.EventName != "" || ec.DeleteMarkerField != "" ||
+			ec.FunctionName != "" {
+			return fmt.Errorf("governance event class for table %s cannot be Raw or have an AnonymousEvent, "+
+				"EventName, DeleteMarkerField, or FunctionName since it does not project EVM events", ec.TableName)
+		}
+		fieldMappingRules = nil
+	}
+	if ec.FunctionName != "" && (ec.Raw || ec.AnonymousEvent != nil || ec.EventName != "") {
+		return fmt.Errorf("function call class for table %s cannot be Raw or have an AnonymousEvent or EventName "+
+			"since it does not project events", ec.TableName)
+	}
 	return validation.ValidateStruct(ec,
 		validation.Field(&ec.TableName, validation.Required, validation.Length(1, 60)),
 		validation.Field(&ec.Filter, validation.Required),
-		validation.Field(&ec.FieldMappings, validation.Required, validation.Length(1, 0)),
+		validation.Field(&ec.FieldMappings, fieldMappingRules...),
+		validation.Field(&ec.AnonymousEvent),
+		validation.Field(&ec.Rollups),
 	)
 }
 
@@ -67,9 +113,56 @@ func (ec *EventClass) GetFilter() string {
 	return ec.Filter
 }
 
+// AnonymousEvent declares the parameters of an anonymous event. Anonymous events do not carry their signature hash as
+// the first topic so they cannot be identified from the ABI - the Filter of the EventClass must select them by other
+// means (for example by Address and topics) and they are decoded according to the Inputs given here.
+type AnonymousEvent struct {
+	// The event name (stored in the event name column)
+	Name string
+	// The event parameters in the format of ABI event inputs
+	Inputs []abi.ArgumentJSON
+	// Memoised event spec
+	spec *abi.EventSpec
+}
+
+// Validate checks the structure of an AnonymousEvent
+func (ae *AnonymousEvent) Validate() error {
+	err := validation.ValidateStruct(ae,
+		validation.Field(&ae.Name, validation.Required),
+		validation.Field(&ae.Inputs, validation.Required),
+	)
+	if err != nil {
+		return err
+	}
+	_, err = ae.EventSpec()
+	return err
+}
+
+// Get a (memoised) anonymous abi.EventSpec from the declared Inputs
+func (ae *AnonymousEvent) EventSpec() (*abi.EventSpec, error) {
+	if ae.spec == nil {
+		bs, err := json.Marshal([]abi.AbiSpecJSON{{
+			Type:      "event",
+			Name:      ae.Name,
+			Inputs:    ae.Inputs,
+			Anonymous: true,
+		}})
+		if err != nil {
+			return nil, err
+		}
+		abiSpec, err := abi.ReadAbiSpec(bs)
+		if err != nil {
+			return nil, fmt.Errorf("could not read inputs of anonymous event %s: %v", ae.Name, err)
+		}
+		spec := abiSpec.Events[ae.Name]
+		ae.spec = &spec
+	}
+	return ae.spec, nil
+}
+
 // EventFieldMapping struct (table column definition)
 type EventFieldMapping struct {
-	// EVM event field name to process
+	// EVM event field name to process, the ABI argument whose value is stored in ColumnName
 	Field string
 	// EVM type of this field - used to derive SQL type
 	Type string
@@ -79,14 +172,207 @@ type EventFieldMapping struct {
 	Primary bool `json:",omitempty"`
 	// Whether to convert this event field from bytes32 to string
 	BytesToString bool `json:",omitempty"`
+	// Whether to store this bytes32 event field as the UTF-8 string it holds with trailing zero bytes trimmed, falling
+	// back to hex when it is not valid UTF-8
+	StringFromBytes32 bool `json:",omitempty"`
 	// Notification channels on which submit (via a trigger) a payload that contains this column's new value (upsert) or
 	// old value (delete). The payload will contain all other values with the same channel set as a JSON object.
 	Notify []string `json:",omitempty"`
+	// Resolve integer codes of this event field to named labels
+	Enum *EventFieldEnum `json:",omitempty"`
+	// How to store an integer event field whose values may not fit in a 64-bit SQL integer
+	BigInt *EventFieldBigInt `json:",omitempty"`
+	// Also store this integer event field scaled by its decimals, e.g. for token amounts
+	Amount *EventFieldAmount `json:",omitempty"`
+	// Whether this event field's column is NOT NULL, in which case an event without a value for the field stores the
+	// zero value of the column's type rather than NULL
+	NotNull bool `json:",omitempty"`
 }
 
 // Validate checks the structure of an EventFieldMapping
 func (evColumn EventFieldMapping) Validate() error {
 	return validation.ValidateStruct(&evColumn,
 		validation.Field(&evColumn.ColumnName, validation.Required, validation.Length(1, 60)),
+		validation.Field(&evColumn.Enum),
+		validation.Field(&evColumn.BigInt),
+		validation.Field(&evColumn.Amount),
 	)
 }
+
+// EventFieldEnum maps the codes of an integer event field to labels
+type EventFieldEnum struct {
+	// Map from code (in decimal) to label
+	Labels map[string]string
+	// When given the label is stored in this additional column and the code in ColumnName, otherwise the label is
+	// stored in ColumnName in place of the code
+	LabelColumnName string `json:",omitempty"`
+	// Label to store for codes not found in Labels, if empty an unknown code is an error
+	UnknownLabel string `json:",omitempty"`
+}
+
+// Validate checks the structure of an EventFieldEnum
+func (enum *EventFieldEnum) Validate() error {
+	return validation.ValidateStruct(enum,
+		validation.Field(&enum.Labels, validation.Required),
+		validation.Field(&enum.LabelColumnName, validation.Length(0, 60)),
+	)
+}
+
+// Label returns the label for the decoded code value
+func (enum *EventFieldEnum) Label(value interface{}) (string, error) {
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	if !rv.IsValid() {
+		return "", fmt.Errorf("cannot resolve enum label for nil value")
+	}
+	code := fmt.Sprint(rv.Interface())
+	if label, ok := enum.Labels[code]; ok {
+		return label, nil
+	}
+	if enum.UnknownLabel != "" {
+		return enum.UnknownLabel, nil
+	}
+	return "", fmt.Errorf("code %s is not one of the declared enum codes", code)
+}
+
+// BigIntRepresentation is the SQL representation of an integer event field
+type BigIntRepresentation string
+
+const (
+	// Arbitrary precision NUMERIC - note SQLite stores integers that do not fit in 64 bits as (inexact) REAL
+	BigIntNumeric BigIntRepresentation = "Numeric"
+	// Decimal string zero-padded to BigIntStringLength digits so that non-negative values sort in numeric order
+	BigIntString BigIntRepresentation = "String"
+	// Arbitrary precision NUMERIC holding the integer divided by 10^Decimals, e.g. for token amounts
+	BigIntDecimal BigIntRepresentation = "Decimal"
+)
+
+// The number of decimal digits in the largest uint256
+const BigIntStringLength = 78
+
+// EventFieldBigInt selects how a (u)int<N> event field is stored
+type EventFieldBigInt struct {
+	// The representation: Numeric, String, or Decimal
+	As BigIntRepresentation
+	// For Decimal the number of digits of the integer that lie after the decimal point
+	Decimals int `json:",omitempty"`
+}
+
+// Validate checks the structure of an EventFieldBigInt
+func (bi *EventFieldBigInt) Validate() error {
+	return validation.ValidateStruct(bi,
+		validation.Field(&bi.As, validation.Required, validation.In(BigIntNumeric, BigIntString, BigIntDecimal)),
+		validation.Field(&bi.Decimals, validation.Min(0)),
+	)
+}
+
+// Value returns the decoded integer value formatted for storage in the chosen representation
+func (bi *EventFieldBigInt) Value(value interface{}) (string, error) {
+	n, err := integerValue(value)
+	if err != nil {
+		return "", err
+	}
+	switch bi.As {
+	case BigIntNumeric:
+		return n.String(), nil
+	case BigIntString:
+		digits := new(big.Int).Abs(n).String()
+		sign := ""
+		if n.Sign() < 0 {
+			sign = "-"
+		}
+		if len(digits) < BigIntStringLength {
+			digits = strings.Repeat("0", BigIntStringLength-len(digits)) + digits
+		}
+		return sign + digits, nil
+	case BigIntDecimal:
+		return decimalString(n, bi.Decimals), nil
+	default:
+		return "", fmt.Errorf("unknown big integer representation '%s'", bi.As)
+	}
+}
+
+// EventFieldAmount stores a fixed-point amount held in an integer event field, such as a token amount, divided by
+// 10^decimals in a column alongside the raw integer
+type EventFieldAmount struct {
+	// The column in which to store the scaled amount, the raw integer is stored in ColumnName as usual
+	ScaledColumnName string
+	// The number of digits of the integer that lie after the decimal point when every amount has the same decimals,
+	// ignored if DecimalsLookup is given
+	Decimals int `json:",omitempty"`
+	// Where to look up the decimals of each amount when they depend on the token
+	DecimalsLookup *EventFieldDecimalsLookup `json:",omitempty"`
+}
+
+// Validate checks the structure of an EventFieldAmount
+func (amount *EventFieldAmount) Validate() error {
+	return validation.ValidateStruct(amount,
+		validation.Field(&amount.ScaledColumnName, validation.Required, validation.Length(1, 60)),
+		validation.Field(&amount.Decimals, validation.Min(0)),
+		validation.Field(&amount.DecimalsLookup),
+	)
+}
+
+// Scaled returns the decoded integer value divided by 10^decimals as a decimal string
+func (amount *EventFieldAmount) Scaled(value interface{}, decimals int) (string, error) {
+	if decimals < 0 {
+		return "", fmt.Errorf("cannot scale amount by negative decimals %d", decimals)
+	}
+	n, err := integerValue(value)
+	if err != nil {
+		return "", err
+	}
+	return decimalString(n, decimals), nil
+}
+
+// EventFieldDecimalsLookup looks up the decimals of an amount in another table of the projection, for example one
+// projecting the tokens of a registry, keyed by the address of the token
+type EventFieldDecimalsLookup struct {
+	// The table holding the decimals of each token
+	TableName string
+	// The column of TableName holding the token address
+	KeyColumnName string
+	// The column of TableName holding the decimals
+	DecimalsColumnName string
+	// The column of the amount's own table holding the address of the token to look up
+	TokenColumnName string
+}
+
+// Validate checks the structure of an EventFieldDecimalsLookup
+func (lookup *EventFieldDecimalsLookup) Validate() error {
+	return validation.ValidateStruct(lookup,
+		validation.Field(&lookup.TableName, validation.Required, validation.Length(1, 60)),
+		validation.Field(&lookup.KeyColumnName, validation.Required, validation.Length(1, 60)),
+		validation.Field(&lookup.DecimalsColumnName, validation.Required, validation.Length(1, 60)),
+		validation.Field(&lookup.TokenColumnName, validation.Required, validation.Length(1, 60)),
+	)
+}
+
+// integerValue returns the decoded value of an integer event field as a big.Int
+func integerValue(value interface{}) (*big.Int, error) {
+	rv := reflect.Indirect(reflect.ValueOf(value))
+	if !rv.IsValid() {
+		return nil, fmt.Errorf("cannot convert nil value to big integer")
+	}
+	n, ok := new(big.Int).SetString(fmt.Sprint(rv.Interface()), 10)
+	if !ok {
+		return nil, fmt.Errorf("value %v is not an integer", rv.Interface())
+	}
+	return n, nil
+}
+
+// decimalString formats n divided by 10^decimals exactly as a decimal string
+func decimalString(n *big.Int, decimals int) string {
+	if decimals == 0 {
+		return n.String()
+	}
+	digits := new(big.Int).Abs(n).String()
+	sign := ""
+	if n.Sign() < 0 {
+		sign = "-"
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals+1-len(digits)) + digits
+	}
+	point := len(digits) - decimals
+	return sign + digits[:point] + "." + digits[point:]
+}
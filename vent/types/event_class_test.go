@@ -5,9 +5,55 @@ import (
 	"testing"
 
 	"github.com/hyperledger/burrow/config/source"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEventTablesSchema(t *testing.T) {
 	schema := EventSpecSchema()
 	fmt.Println(source.JSONString(schema))
 }
+
+func TestEventFieldBigIntValue(t *testing.T) {
+	value := func(bi *EventFieldBigInt, v interface{}) string {
+		str, err := bi.Value(v)
+		require.NoError(t, err)
+		return str
+	}
+	decimal := &EventFieldBigInt{As: BigIntDecimal, Decimals: 18}
+	assert.Equal(t, "1.000000000000000000", value(decimal, "1000000000000000000"))
+	assert.Equal(t, "0.000000000000000042", value(decimal, "42"))
+	assert.Equal(t, "-0.500000000000000000", value(decimal, "-500000000000000000"))
+	assert.Equal(t, "0.000000000000000000", value(decimal, "0"))
+
+	uint8Value := uint8(7)
+	str := value(&EventFieldBigInt{As: BigIntString}, &uint8Value)
+	assert.Len(t, str, BigIntStringLength)
+	assert.Equal(t, "07", str[BigIntStringLength-2:])
+
+	assert.Equal(t, "-98765432109876543210", value(&EventFieldBigInt{As: BigIntNumeric}, "-98765432109876543210"))
+
+	_, err := (&EventFieldBigInt{As: BigIntNumeric}).Value("1.5")
+	require.Error(t, err)
+	require.Error(t, (&EventFieldBigInt{As: "Float"}).Validate())
+	require.Error(t, (&EventFieldBigInt{As: BigIntDecimal, Decimals: -1}).Validate())
+	require.NoError(t, decimal.Validate())
+}
+
+func TestEventFieldAmountScaled(t *testing.T) {
+	amount := &EventFieldAmount{ScaledColumnName: "amount_scaled", Decimals: 6}
+	scaled, err := amount.Scaled(uint64(1234500000), amount.Decimals)
+	require.NoError(t, err)
+	assert.Equal(t, "1234.500000", scaled)
+
+	scaled, err = amount.Scaled("-42", 0)
+	require.NoError(t, err)
+	assert.Equal(t, "-42", scaled)
+
+	_, err = amount.Scaled("1", -1)
+	require.Error(t, err)
+	require.NoError(t, amount.Validate())
+	require.Error(t, (&EventFieldAmount{Decimals: 6}).Validate())
+	require.Error(t, (&EventFieldAmount{ScaledColumnName: "amount_scaled",
+		DecimalsLookup: &EventFieldDecimalsLookup{TableName: "Tokens"}}).Validate())
+}
@@ -9,6 +9,23 @@ const (
 	ActionRead        DBAction = "READ"
 	ActionCreateTable DBAction = "CREATE"
 	ActionAlterTable  DBAction = "ALTER"
+
+	// Upserts a row of a rollup table, where a row with the same primary key exists the value of each column with an
+	// Aggregate is combined with the existing value by its AggregateFunction
+	ActionAccumulate DBAction = "ACCUMULATE"
+)
+
+// RowOperation records the effect an upsert had on a row
+type RowOperation string
+
+const (
+	// The effect was not determined - for deletes, rows not yet written, and adapters that cannot report it
+	RowOperationUnknown RowOperation = ""
+	RowOperationInsert  RowOperation = "INSERT"
+	RowOperationUpdate  RowOperation = "UPDATE"
+	// The row violated a constraint of the database and was skipped under SkipRowConflicts, or an upsert left the
+	// conflicting row as it was
+	RowOperationSkipped RowOperation = "SKIPPED"
 )
 
 // EventData contains data for each block of events
@@ -29,5 +46,8 @@ type EventDataRow struct {
 	Action  DBAction
 	RowData map[string]interface{}
 	// The EventClass that caused this row to be emitted (if it was caused by an specific event)
-	EventClass *EventClass
+	EventClass *EventClass `json:"-"`
+	// Set by SetBlock to whether an upsert inserted a new row or updated an existing one where the database adapter
+	// supports it (currently only postgres), or to RowOperationSkipped if it was skipped or left the existing row as is
+	Operation RowOperation `json:",omitempty"`
 }
@@ -9,4 +9,6 @@ const (
 	EventFieldTypeBytes   = "bytes"
 	EventFieldTypeBool    = "bool"
 	EventFieldTypeString  = "string"
+	// Not an EVM type but the type of the blockTime field
+	EventFieldTypeTimestamp = "timestamp"
 )
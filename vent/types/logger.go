@@ -0,0 +1,10 @@
+package types
+
+// Logger is the logging vent depends on, so that embedders can adapt their own logging stack (such as zap or logrus)
+// to it. Each message is followed by alternating keys and values. Logger satisfies Logger.
+type Logger interface {
+	// Logs a message that should be seen when running normally
+	InfoMsg(message string, keyvals ...interface{}) error
+	// Logs a message only of use when debugging
+	TraceMsg(message string, keyvals ...interface{}) error
+}
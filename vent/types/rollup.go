@@ -0,0 +1,156 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// RollupBucket is the width of the intervals of block time over which a Rollup aggregates rows
+type RollupBucket string
+
+const (
+	RollupBucketHour RollupBucket = "hour"
+	RollupBucketDay  RollupBucket = "day"
+)
+
+// Start returns the start of the bucket containing t, in UTC
+func (rb RollupBucket) Start(t time.Time) (time.Time, error) {
+	switch rb {
+	case RollupBucketHour:
+		return t.UTC().Truncate(time.Hour), nil
+	case RollupBucketDay:
+		return t.UTC().Truncate(24 * time.Hour), nil
+	default:
+		return time.Time{}, fmt.Errorf("unknown rollup bucket '%s', expected %s or %s", rb, RollupBucketHour,
+			RollupBucketDay)
+	}
+}
+
+// AggregateFunction combines the values of a column of the rows aggregated by a Rollup
+type AggregateFunction string
+
+const (
+	// The number of rows
+	AggregateCount AggregateFunction = "count"
+	// The sum, least, and greatest of the integer values of a column, rows where it is null are ignored
+	AggregateSum AggregateFunction = "sum"
+	AggregateMin AggregateFunction = "min"
+	AggregateMax AggregateFunction = "max"
+)
+
+// Combine returns the aggregate of two aggregates (or values) a and b of the function, either of which may be nil.
+// Counts are int64, the other aggregates are integers in decimal strings as they are stored in numeric columns.
+func (af AggregateFunction) Combine(a, b interface{}) (interface{}, error) {
+	switch af {
+	case AggregateCount, AggregateSum, AggregateMin, AggregateMax:
+	default:
+		return nil, fmt.Errorf("unknown aggregate function '%s'", af)
+	}
+	x, err := aggregateInteger(a)
+	if err != nil {
+		return nil, err
+	}
+	y, err := aggregateInteger(b)
+	if err != nil {
+		return nil, err
+	}
+	if af == AggregateCount {
+		var count int64
+		for _, i := range []*big.Int{x, y} {
+			if i != nil {
+				count += i.Int64()
+			}
+		}
+		return count, nil
+	}
+	if x == nil {
+		x, y = y, x
+	}
+	if x == nil {
+		return nil, nil
+	}
+	if y != nil {
+		switch af {
+		case AggregateSum:
+			x.Add(x, y)
+		case AggregateMin:
+			if y.Cmp(x) < 0 {
+				x = y
+			}
+		case AggregateMax:
+			if y.Cmp(x) > 0 {
+				x = y
+			}
+		}
+	}
+	return x.String(), nil
+}
+
+// aggregateInteger returns the integer value of a column value (or aggregate), dereferencing pointers, or nil for nil
+func aggregateInteger(value interface{}) (*big.Int, error) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	if bi, ok := rv.Interface().(big.Int); ok {
+		return new(big.Int).Set(&bi), nil
+	}
+	i, ok := new(big.Int).SetString(fmt.Sprint(rv.Interface()), 10)
+	if !ok {
+		return nil, fmt.Errorf("cannot aggregate %v of type %T since it is not an integer", value, value)
+	}
+	return i, nil
+}
+
+// Rollup declares a table of aggregates of the rows of an EventClass grouped by the bucket of block time of each row
+// (and optionally the values of some of its columns), which is maintained as blocks are committed
+type Rollup struct {
+	// Destination table for the aggregates
+	TableName string
+	// The width of the buckets of block time rows are aggregated over, hour or day
+	Bucket RollupBucket
+	// Columns of the EventClass's table whose values rows within a bucket are further grouped by
+	GroupBy []string `json:",omitempty"`
+	// The aggregates kept of each group of rows
+	Aggregates []*RollupAggregate
+}
+
+// Validate checks the structure of a Rollup
+func (r *Rollup) Validate() error {
+	return validation.ValidateStruct(r,
+		validation.Field(&r.TableName, validation.Required, validation.Length(1, 60)),
+		validation.Field(&r.Bucket, validation.Required, validation.In(RollupBucketHour, RollupBucketDay)),
+		validation.Field(&r.Aggregates, validation.Required, validation.Length(1, 0)),
+	)
+}
+
+// RollupAggregate declares an aggregate column of a Rollup
+type RollupAggregate struct {
+	// count, sum, min, or max
+	Function AggregateFunction
+	// The column of the EventClass's table that is aggregated, not used by count
+	ColumnName string `json:",omitempty"`
+	// The column of the rollup table holding the aggregate
+	As string
+}
+
+// Validate checks the structure of a RollupAggregate
+func (ra *RollupAggregate) Validate() error {
+	columnNameRules := []validation.Rule{validation.Required}
+	if ra.Function == AggregateCount {
+		columnNameRules = nil
+	}
+	return validation.ValidateStruct(ra,
+		validation.Field(&ra.Function, validation.Required,
+			validation.In(AggregateCount, AggregateSum, AggregateMin, AggregateMax)),
+		validation.Field(&ra.ColumnName, columnNameRules...),
+		validation.Field(&ra.As, validation.Required),
+	)
+}
@@ -1,5 +1,7 @@
 package types
 
+import "time"
+
 // SQLColumnType to store generic SQL column types
 type SQLColumnType int
 
@@ -47,3 +49,36 @@ func (ct SQLColumnType) String() string {
 func (ct SQLColumnType) IsNumeric() bool {
 	return ct == SQLColumnTypeInt || ct == SQLColumnTypeSerial || ct == SQLColumnTypeNumeric || ct == SQLColumnTypeBigInt
 }
+
+// ZeroValue returns the value stored in a NOT NULL column of this type for an event that lacks the column's field
+func (ct SQLColumnType) ZeroValue() interface{} {
+	switch ct {
+	case SQLColumnTypeBool:
+		return false
+	case SQLColumnTypeByteA:
+		return []byte{}
+	case SQLColumnTypeInt, SQLColumnTypeSerial, SQLColumnTypeNumeric, SQLColumnTypeBigInt:
+		return 0
+	case SQLColumnTypeTimeStamp:
+		return time.Time{}
+	case SQLColumnTypeJSON:
+		return "null"
+	}
+	return ""
+}
+
+// ZeroLiteral returns the SQL literal of ZeroValue, the default of a NOT NULL column of this type so that it can be
+// added to a table with existing rows
+func (ct SQLColumnType) ZeroLiteral() string {
+	switch ct {
+	case SQLColumnTypeBool:
+		return "FALSE"
+	case SQLColumnTypeInt, SQLColumnTypeSerial, SQLColumnTypeNumeric, SQLColumnTypeBigInt:
+		return "0"
+	case SQLColumnTypeTimeStamp:
+		return "'0001-01-01 00:00:00'"
+	case SQLColumnTypeJSON:
+		return "'null'"
+	}
+	return "''"
+}
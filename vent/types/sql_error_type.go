@@ -12,4 +12,6 @@ const (
 	SQLErrorTypeUndefinedTable
 	SQLErrorTypeUndefinedColumn
 	SQLErrorTypeGeneric
+	SQLErrorTypeInsufficientPrivilege
+	SQLErrorTypeConstraintViolation
 )
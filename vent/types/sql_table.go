@@ -30,6 +30,11 @@ type SQLTableColumn struct {
 	Type    SQLColumnType
 	Primary bool
 	Length  int
+	// Whether the column is NOT NULL, a row missing its value stores the ZeroValue of its type rather than NULL
+	NotNull bool
+	// For an aggregate column of a rollup table the function with which an accumulated row combines its value with
+	// that of the existing row, see ActionAccumulate
+	Aggregate AggregateFunction
 }
 
 func (col *SQLTableColumn) String() string {
@@ -41,6 +46,9 @@ func (col *SQLTableColumn) String() string {
 	if col.Length != 0 {
 		lengthString = fmt.Sprintf(" (length %d)", col.Length)
 	}
+	if col.NotNull {
+		lengthString += " (not null)"
+	}
 	return fmt.Sprintf("SQLTableColumn{%s%s: %v%s}",
 		col.Name, primaryString, col.Type, lengthString)
 }
@@ -56,6 +64,9 @@ type UpsertDeleteQuery struct {
 	Query    string
 	Values   string
 	Pointers []interface{}
+	// The query returns a single boolean column that is true if the row was inserted (rather than updated), or no
+	// rows if an existing row was left unchanged
+	ReturnsInserted bool
 }
 
 type SQLNames struct {
@@ -74,6 +85,7 @@ type SQLTableNames struct {
 	Block      string
 	Tx         string
 	ChainInfo  string
+	DeadLetter string
 }
 
 var DefaultSQLTableNames = SQLTableNames{
@@ -82,6 +94,20 @@ var DefaultSQLTableNames = SQLTableNames{
 	Block:      "_vent_block",
 	Tx:         "_vent_tx",
 	ChainInfo:  "_vent_chain",
+	DeadLetter: "_vent_deadletter",
+}
+
+// WithPrefix returns table names with each name prefixed by prefix, allowing multiple vent instances to keep
+// independent system tables (and so independent checkpoints) within a single schema
+func (names SQLTableNames) WithPrefix(prefix string) SQLTableNames {
+	return SQLTableNames{
+		Log:        prefix + names.Log,
+		Dictionary: prefix + names.Dictionary,
+		Block:      prefix + names.Block,
+		Tx:         prefix + names.Tx,
+		ChainInfo:  prefix + names.ChainInfo,
+		DeadLetter: prefix + names.DeadLetter,
+	}
 }
 
 type SQLColumnNames struct {
@@ -117,6 +143,26 @@ type SQLColumnNames struct {
 	Receipt     string
 	Origin      string
 	Exception   string
+	// raw event
+	EventIndex string
+	Topic0     string
+	Topic1     string
+	Topic2     string
+	Topic3     string
+	Data       string
+	// governance event
+	AccountAddress string
+	AccountName    string
+	PublicKey      string
+	Power          string
+	Balance        string
+	Permissions    string
+	Roles          string
+	// rollup
+	Bucket string
+	// dead letter
+	Error string
+	Event string
 }
 
 var DefaultSQLColumnNames = SQLColumnNames{
@@ -152,6 +198,26 @@ var DefaultSQLColumnNames = SQLColumnNames{
 	Receipt:     "_receipt",
 	Origin:      "_origin",
 	Exception:   "_exception",
+	// raw event
+	EventIndex: "_eventindex",
+	Topic0:     "_topic0",
+	Topic1:     "_topic1",
+	Topic2:     "_topic2",
+	Topic3:     "_topic3",
+	Data:       "_data",
+	// governance event
+	AccountAddress: "_address",
+	AccountName:    "_name",
+	PublicKey:      "_publickey",
+	Power:          "_power",
+	Balance:        "_balance",
+	Permissions:    "_permissions",
+	Roles:          "_roles",
+	// rollup
+	Bucket: "_bucket",
+	// dead letter
+	Error: "_error",
+	Event: "_event",
 }
 
 // labels for column mapping
@@ -163,7 +229,28 @@ const (
 	// block related
 	ChainIDLabel     = "chainid"
 	BlockHeightLabel = "height"
+	BlockTimeLabel   = "blockTime"
 
 	// transaction related
 	TxTxHashLabel = "txHash"
+
+	// raw event related
+	EventIndexLabel = "eventIndex"
+	Topic0Label     = "topic0"
+	Topic1Label     = "topic1"
+	Topic2Label     = "topic2"
+	Topic3Label     = "topic3"
+	DataLabel       = "data"
+
+	// governance event related
+	AccountAddressLabel = "accountAddress"
+	AccountNameLabel    = "accountName"
+	PublicKeyLabel      = "publicKey"
+	PowerLabel          = "power"
+	BalanceLabel        = "balance"
+	PermissionsLabel    = "permissions"
+	RolesLabel          = "roles"
 )
+
+// The labels of the topics of a raw event in order
+var TopicLabels = []string{Topic0Label, Topic1Label, Topic2Label, Topic3Label}
@@ -1,15 +1,50 @@
 package types
 
-import "github.com/hyperledger/burrow/logging"
-
 // SQLConnection stores parameters to build a new db connection & initialize the database
 type SQLConnection struct {
 	DBAdapter string
 	DBURL     string
 	DBSchema  string
-	Log       *logging.Logger
+	// Names of the system tables, DefaultSQLTableNames are used if empty
+	TableNames SQLTableNames
+	Log        Logger
+	// If true the schema (of databases that have them) must already exist, otherwise it is created if missing
+	SkipCreateSchema bool
+	// If true the search_path of each connection is set to the schema so unqualified names resolve within it
+	SetSearchPath bool
+	// What SynchronizeDB does with columns of a table that are not in its projection, if empty WarnOrphanedColumns
+	OrphanedColumns OrphanedColumnsPolicy
+	// What SetBlock does with a row that violates a constraint of the database, if empty FailRowConflicts
+	RowConflicts RowConflictsPolicy
+	// If greater than zero SetBlock commits the rows of a block in transactions of at most this many rows, setting the
+	// block height in the last, otherwise each block is committed in a single transaction
+	MaxRowsPerCommit int
 }
 
+// OrphanedColumnsPolicy determines what SynchronizeDB does with orphaned columns, that is columns of a table in the
+// database that are not in the projection (for example left by a previous version of the projection)
+type OrphanedColumnsPolicy string
+
+const (
+	// Log the orphaned columns and leave them in place
+	WarnOrphanedColumns OrphanedColumnsPolicy = "warn"
+	// Fail the synchronization so that the schema drift is resolved by hand
+	ErrorOrphanedColumns OrphanedColumnsPolicy = "error"
+	// Drop the orphaned columns (and their data) in a single transaction per table
+	DropOrphanedColumns OrphanedColumnsPolicy = "drop"
+)
+
+// RowConflictsPolicy determines what SetBlock does with a row that violates a constraint of the database that vent did
+// not expect, for example a unique index violated because of a row inserted behind vent's back
+type RowConflictsPolicy string
+
+const (
+	// Fail the block so that none of its rows are committed
+	FailRowConflicts RowConflictsPolicy = "fail"
+	// Log and skip the conflicting row and commit the rest of the block
+	SkipRowConflicts RowConflictsPolicy = "skip"
+)
+
 // SQLCleanDBQuery stores queries needed to clean the database
 type SQLCleanDBQuery struct {
 	SelectChainIDQry    string
@@ -19,3 +54,4 @@ type SQLCleanDBQuery struct {
 	DeleteDictionaryQry string
 	DeleteLogQry        string
 }
+
@@ -0,0 +1,36 @@
+package types
+
+import "time"
+
+// Layout with which times are written to string columns when none is configured
+const DefaultTimeLayout = time.RFC3339Nano
+
+// TimeFormat determines how time values (such as the blockTime of an event) are written to columns
+type TimeFormat struct {
+	// Go reference time layout for string columns, if empty DefaultTimeLayout
+	Layout string
+	// Zone in which times are written to string columns, if nil UTC
+	Location *time.Location
+}
+
+// Format renders t as a string in the configured layout and zone
+func (tf TimeFormat) Format(t time.Time) string {
+	layout := tf.Layout
+	if layout == "" {
+		layout = DefaultTimeLayout
+	}
+	location := tf.Location
+	if location == nil {
+		location = time.UTC
+	}
+	return t.In(location).Format(layout)
+}
+
+// ColumnValue returns t as it should be stored in a column of columnType - timestamp columns are always given UTC
+// times whatever the configured zone so that the stored values are comparable, other columns get Format(t)
+func (tf TimeFormat) ColumnValue(t time.Time, columnType SQLColumnType) interface{} {
+	if columnType == SQLColumnTypeTimeStamp {
+		return t.UTC()
+	}
+	return tf.Format(t)
+}